@@ -33,14 +33,22 @@ const (
 	configFileName             = "config"
 	defaultSyncInterval        = time.Minute * 15
 	defaultDeleteAfterInterval = 30
+	defaultSyncMinInterval     = time.Minute * 5
+	defaultSyncMaxInterval     = time.Hour * 12
+	defaultSyncMaxErrors       = 10
+	defaultConnectTimeout      = time.Second * 10
+	defaultReadTimeout         = time.Second * 30
+	defaultMaxResponseSize     = 10 << 20
 	defaultHTTPPort            = 8080
 )
 
 type (
 	// Plugin configuration
 	Plugin struct {
-		Name string
-		Path string
+		Name    string
+		Path    string
+		Args    []string
+		Timeout time.Duration
 	}
 
 	// Host configuration
@@ -58,7 +66,25 @@ type (
 	// Sync configuration
 	Sync struct {
 		Interval    time.Duration
-		DeleteAfter int `mapstructure:"delete_after"`
+		MinInterval time.Duration `mapstructure:"min_interval"`
+		MaxInterval time.Duration `mapstructure:"max_interval"`
+		MaxErrors   int           `mapstructure:"max_errors"`
+		DeleteAfter int           `mapstructure:"delete_after"`
+	}
+
+	// Fetcher configuration
+	Fetcher struct {
+		ConnectTimeout  time.Duration `mapstructure:"connect_timeout"`
+		ReadTimeout     time.Duration `mapstructure:"read_timeout"`
+		MaxResponseSize int64         `mapstructure:"max_response_size"`
+		UserAgent       string        `mapstructure:"user_agent"`
+		ContentTypes    []string      `mapstructure:"content_types"`
+		AllowedNetworks []string      `mapstructure:"allowed_networks"`
+	}
+
+	// WebSub configuration
+	WebSub struct {
+		CallbackURL string `mapstructure:"callback_url"`
 	}
 
 	// Config represents a complete configuration
@@ -66,9 +92,13 @@ type (
 		Sync               Sync
 		EnableTLS          bool   `mapstructure:"enable_tls"`
 		AuthSecret         string `mapstructure:"auth_secret"`
+		CredentialsSecret  string `mapstructure:"credentials_secret"`
 		AllowRegistrations bool   `mapstructure:"allow_registrations"`
 		Database           Database
 		Host               Host
+		WebSub             WebSub
+		Fetcher            Fetcher
+		Plugins            []Plugin
 	}
 )
 
@@ -86,7 +116,13 @@ func Execute() error {
 	rootCmd.Flags().StringVar(&cfgFile, "config", "", "config file")
 
 	viper.SetDefault("sync.interval", defaultSyncInterval)
+	viper.SetDefault("sync.min_interval", defaultSyncMinInterval)
+	viper.SetDefault("sync.max_interval", defaultSyncMaxInterval)
+	viper.SetDefault("sync.max_errors", defaultSyncMaxErrors)
 	viper.SetDefault("sync.delete_after", defaultDeleteAfterInterval)
+	viper.SetDefault("fetcher.connect_timeout", defaultConnectTimeout)
+	viper.SetDefault("fetcher.read_timeout", defaultReadTimeout)
+	viper.SetDefault("fetcher.max_response_size", defaultMaxResponseSize)
 	viper.SetDefault("host.port", defaultHTTPPort)
 	viper.SetDefault("host.address", "localhost")
 	viper.SetDefault("database.type", "sqlite3")
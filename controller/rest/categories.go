@@ -213,6 +213,7 @@ func (s *CategoriesController) GetCategoryEntries(c echo.Context) error {
 		Count:          params.Count,
 		Newest:         convertOrderByParamToValue(params.OrderBy),
 		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
 	}
 
 	entries, next, err := s.categories.Entries(userID, page)
@@ -223,7 +224,7 @@ func (s *CategoriesController) GetCategoryEntries(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"entries":        entries,
+		"entries":        selectEntryFields(entries, params.Fields),
 		"continuationID": next,
 	})
 }
@@ -48,6 +48,10 @@ func NewEntriesController(service services.Entries, e *echo.Echo) *EntriesContro
 	v1.GET("/entries/:entryID", controller.GetEntry)
 	v1.PUT("/entries/:entryID/mark", controller.MarkEntry)
 	v1.PUT("/entries/mark", controller.MarkAllEntries)
+	v1.PUT("/entries/:entryID/save", controller.SaveEntry)
+	v1.PUT("/entries/:entryID/unsave", controller.UnsaveEntry)
+	v1.PUT("/entries/save", controller.SaveEntries)
+	v1.PUT("/entries/unsave", controller.UnsaveEntries)
 	v1.GET("/entries/stats", controller.GetEntryStats)
 
 	return &controller
@@ -83,12 +87,13 @@ func (s *EntriesController) GetEntries(c echo.Context) error {
 		Count:          params.Count,
 		Newest:         convertOrderByParamToValue(params.OrderBy),
 		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
 	}
 
 	entries, next := s.entries.Entries(userID, page)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"entries":        entries,
+		"entries":        selectEntryFields(entries, params.Fields),
 		"continuationID": next,
 	})
 }
@@ -131,6 +136,56 @@ func (s *EntriesController) MarkAllEntries(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// SaveEntry saves an entry with id
+func (s *EntriesController) SaveEntry(c echo.Context) error {
+	return s.saveEntry(c, true)
+}
+
+// UnsaveEntry removes an entry with id from the saved entries
+func (s *EntriesController) UnsaveEntry(c echo.Context) error {
+	return s.saveEntry(c, false)
+}
+
+// SaveEntries saves a list of entries
+func (s *EntriesController) SaveEntries(c echo.Context) error {
+	return s.saveEntries(c, true)
+}
+
+// UnsaveEntries removes a list of entries from the saved entries
+func (s *EntriesController) UnsaveEntries(c echo.Context) error {
+	return s.saveEntries(c, false)
+}
+
+func (s *EntriesController) saveEntry(c echo.Context, saved bool) error {
+	userID := c.Get(userContextKey).(string)
+
+	err := s.entries.Save(userID, c.Param("entryID"), saved)
+	if err == services.ErrEntryNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *EntriesController) saveEntries(c echo.Context, saved bool) error {
+	userID := c.Get(userContextKey).(string)
+
+	type EntryIds struct {
+		Entries []string `json:"entries"`
+	}
+
+	entryIds := new(EntryIds)
+	if err := c.Bind(entryIds); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	s.entries.SaveEntries(userID, entryIds.Entries, saved)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // GetEntryStats provides statistics related to Entries
 func (s *EntriesController) GetEntryStats(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
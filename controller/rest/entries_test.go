@@ -18,9 +18,11 @@
 package rest_test
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -49,7 +51,11 @@ type (
 func (c *EntriesControllerSuite) TestGetEntry() {
 	entryID := utils.CreateID()
 
-	c.mockEntries.EXPECT().Entry(gomock.Eq(c.user.ID), gomock.Eq(entryID)).Return(models.Entry{ID: entryID}, nil)
+	c.mockEntries.EXPECT().Entry(gomock.Eq(c.user.ID), gomock.Eq(entryID)).Return(models.Entry{
+		ID:      entryID,
+		Summary: "Summary",
+		Content: "<p>Content</p>",
+	}, nil)
 
 	req := httptest.NewRequest(echo.GET, "/", nil)
 
@@ -63,6 +69,12 @@ func (c *EntriesControllerSuite) TestGetEntry() {
 
 	c.NoError(c.controller.GetEntry(ctx))
 	c.Equal(http.StatusOK, rec.Code)
+
+	var entry models.Entry
+
+	c.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &entry))
+	c.Equal("Summary", entry.Summary)
+	c.Equal("<p>Content</p>", entry.Content)
 }
 
 func (c *EntriesControllerSuite) TestGetUnknownEntry() {
@@ -127,6 +139,64 @@ func (c *EntriesControllerSuite) TestGetEntries() {
 	c.NoError(c.controller.GetEntries(ctx))
 }
 
+func (c *EntriesControllerSuite) TestGetEntriesOmitsContent() {
+	c.mockEntries.EXPECT().Entries(gomock.Eq(c.user.ID), gomock.Any()).Return([]models.Entry{
+		{
+			ID:      utils.CreateID(),
+			Summary: "Summary",
+			Content: "<p>Content</p>",
+		},
+	}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries")
+
+	c.Require().NoError(c.controller.GetEntries(ctx))
+
+	var body struct {
+		Entries []map[string]interface{} `json:"entries"`
+	}
+
+	c.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	c.Require().Len(body.Entries, 1)
+	c.NotContains(body.Entries[0], "summary")
+	c.NotContains(body.Entries[0], "content")
+}
+
+func (c *EntriesControllerSuite) TestGetEntriesWithFields() {
+	c.mockEntries.EXPECT().Entries(gomock.Eq(c.user.ID), gomock.Any()).Return([]models.Entry{
+		{
+			ID:      utils.CreateID(),
+			Summary: "Summary",
+			Content: "<p>Content</p>",
+		},
+	}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1&fields=content", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries")
+
+	c.Require().NoError(c.controller.GetEntries(ctx))
+
+	var body struct {
+		Entries []map[string]interface{} `json:"entries"`
+	}
+
+	c.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &body))
+	c.Require().Len(body.Entries, 1)
+	c.NotContains(body.Entries[0], "summary")
+	c.Equal("<p>Content</p>", body.Entries[0]["content"])
+}
+
 func (c *EntriesControllerSuite) TestGetEntriesBadRequest() {
 	req := httptest.NewRequest(echo.GET, "/?count=true", nil)
 
@@ -246,6 +316,120 @@ func (c *EntriesControllerSuite) TestMarkAllEntriesBadRequest() {
 	)
 }
 
+func (c *EntriesControllerSuite) TestGetSavedEntries() {
+	page := models.Page{
+		Count:  1,
+		Newest: true,
+		Marker: models.MarkerAny,
+		Saved:  true,
+	}
+
+	c.mockEntries.EXPECT().Entries(gomock.Eq(c.user.ID), gomock.Eq(page)).Return([]models.Entry{}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1&saved=true", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries")
+
+	c.NoError(c.controller.GetEntries(ctx))
+}
+
+func (c *EntriesControllerSuite) TestSaveEntry() {
+	entryID := utils.CreateID()
+
+	c.mockEntries.EXPECT().Save(gomock.Eq(c.user.ID), gomock.Eq(entryID), gomock.Eq(true)).Return(nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetParamNames("entryID")
+	ctx.SetParamValues(entryID)
+	ctx.SetPath("/v1/entries/:entryID/save")
+
+	c.NoError(c.controller.SaveEntry(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *EntriesControllerSuite) TestUnsaveEntry() {
+	entryID := utils.CreateID()
+
+	c.mockEntries.EXPECT().Save(gomock.Eq(c.user.ID), gomock.Eq(entryID), gomock.Eq(false)).Return(nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetParamNames("entryID")
+	ctx.SetParamValues(entryID)
+	ctx.SetPath("/v1/entries/:entryID/unsave")
+
+	c.NoError(c.controller.UnsaveEntry(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *EntriesControllerSuite) TestSaveUnknownEntry() {
+	c.mockEntries.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).Return(services.ErrEntryNotFound)
+
+	req := httptest.NewRequest(echo.PUT, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetParamNames("entryID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/entries/:entryID/save")
+
+	c.EqualError(
+		c.controller.SaveEntry(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *EntriesControllerSuite) TestSaveEntries() {
+	entryID := utils.CreateID()
+
+	c.mockEntries.EXPECT().SaveEntries(gomock.Eq(c.user.ID), gomock.Eq([]string{entryID}), gomock.Eq(true))
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"entries": ["`+entryID+`"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries/save")
+
+	c.NoError(c.controller.SaveEntries(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *EntriesControllerSuite) TestUnsaveEntries() {
+	entryID := utils.CreateID()
+
+	c.mockEntries.EXPECT().SaveEntries(gomock.Eq(c.user.ID), gomock.Eq([]string{entryID}), gomock.Eq(false))
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"entries": ["`+entryID+`"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries/unsave")
+
+	c.NoError(c.controller.UnsaveEntries(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
 func (c *EntriesControllerSuite) TestGetEntryStats() {
 	c.mockEntries.EXPECT().Stats(gomock.Eq(c.user.ID)).Return(models.Stats{})
 
@@ -19,6 +19,7 @@ package rest
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
@@ -26,6 +27,13 @@ import (
 	"github.com/jmartinezhern/syndication/services"
 )
 
+const (
+	errUnreachableSubscription = "subscription url is not reachable"
+	errInvalidSelectors        = "'selectors' must hold valid CSS selectors, including item"
+	errUnknownPlugin           = "'plugin' and 'processors' must name configured plugins"
+	errInvalidUntil            = "'until' must be an RFC 3339 time in the future"
+)
+
 type (
 	FeedsController struct {
 		Controller
@@ -46,12 +54,19 @@ func NewFeedsController(service services.Feeds, e *echo.Echo) *FeedsController {
 
 	v1.POST("/feeds", controller.NewFeed)
 	v1.GET("/feeds", controller.GetFeeds)
+	v1.GET("/feeds/discover", controller.DiscoverFeeds)
+	v1.POST("/feeds/preview", controller.PreviewFeed)
 	v1.GET("/feeds/:feedID", controller.GetFeed)
 	v1.PUT("/feeds/:feedID", controller.EditFeed)
 	v1.DELETE("/feeds/:feedID", controller.DeleteFeed)
 	v1.GET("/feeds/:feedID/entries", controller.GetFeedEntries)
 	v1.PUT("/feeds/:feedID/mark", controller.MarkFeed)
+	v1.PUT("/feeds/:feedID/pause", controller.PauseFeed)
+	v1.DELETE("/feeds/:feedID/pause", controller.ResumeFeed)
+	v1.PUT("/feeds/:feedID/mute", controller.MuteFeed)
+	v1.DELETE("/feeds/:feedID/mute", controller.UnmuteFeed)
 	v1.GET("/feeds/:feedID/stats", controller.GetFeedStats)
+	v1.GET("/feeds/:feedID/events", controller.GetFeedEvents)
 
 	return &controller
 }
@@ -60,17 +75,25 @@ func NewFeedsController(service services.Feeds, e *echo.Echo) *FeedsController {
 func (s *FeedsController) NewFeed(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
 
-	newFeed := new(models.Feed)
+	newFeed := new(feedParams)
 	if err := c.Bind(newFeed); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
 
-	feed, err := s.feeds.New(newFeed.Title, newFeed.Subscription, newFeed.Category.ID, userID)
+	newFeed.Feed.Credentials = newFeed.Credentials
+
+	feed, err := s.feeds.New(userID, newFeed.Feed)
 	switch err {
 	case services.ErrFetchingFeed:
-		return echo.NewHTTPError(http.StatusBadRequest, "subscription url is not reachable")
+		return echo.NewHTTPError(http.StatusBadRequest, errUnreachableSubscription)
 	case services.ErrFeedCategoryNotFound:
 		return echo.NewHTTPError(http.StatusBadRequest, "category does not exist")
+	case services.ErrFeedKind:
+		return echo.NewHTTPError(http.StatusBadRequest, "'kind' must be scraper, plugin or empty")
+	case services.ErrFeedSelectors:
+		return echo.NewHTTPError(http.StatusBadRequest, errInvalidSelectors)
+	case services.ErrFeedPlugin:
+		return echo.NewHTTPError(http.StatusBadRequest, errUnknownPlugin)
 	case nil:
 		return c.JSON(http.StatusCreated, feed)
 	}
@@ -78,18 +101,48 @@ func (s *FeedsController) NewFeed(c echo.Context) error {
 	return echo.NewHTTPError(http.StatusInternalServerError)
 }
 
+// PreviewFeed returns the entries a scraper feed would extract from a web page without creating it
+func (s *FeedsController) PreviewFeed(c echo.Context) error {
+	params := new(feedParams)
+	if err := c.Bind(params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	feed, entries, err := s.feeds.Preview(params.Subscription, params.Selectors, params.Credentials)
+	switch err {
+	case services.ErrFetchingFeed:
+		return echo.NewHTTPError(http.StatusBadRequest, errUnreachableSubscription)
+	case services.ErrFeedSelectors:
+		return echo.NewHTTPError(http.StatusBadRequest, errInvalidSelectors)
+	case nil:
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"feed":    feed,
+			"entries": entries,
+		})
+	}
+
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
+
 // GetFeeds returns a list of subscribed feeds
 func (s *FeedsController) GetFeeds(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
 
-	params := paginationParams{}
+	params := listFeedsParams{}
 	if err := c.Bind(&params); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
 
+	switch params.Status {
+	case "", models.FeedStatusOK, models.FeedStatusError, models.FeedStatusDisabled:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "'status' must be one of ok, error or disabled")
+	}
+
 	feeds, next := s.feeds.Feeds(userID, models.Page{
 		ContinuationID: params.ContinuationID,
 		Count:          params.Count,
+		Status:         params.Status,
 	})
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -110,20 +163,31 @@ func (s *FeedsController) GetFeed(c echo.Context) error {
 	return c.JSON(http.StatusOK, feed)
 }
 
-// EditFeed with id
+// EditFeed with id. Fields that are left out of the request keep their value.
 func (s *FeedsController) EditFeed(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
 
-	feed := models.Feed{}
-	if err := c.Bind(&feed); err != nil {
+	current, found := s.feeds.Feed(userID, c.Param("feedID"))
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	params := feedUpdateParams{}
+	if err := c.Bind(&params); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
 
-	feed.ID = c.Param("feedID")
+	feed := params.apply(&current)
 
 	err := s.feeds.Update(userID, &feed)
 	if err == services.ErrFeedNotFound {
 		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err == services.ErrFeedSelectors {
+		return echo.NewHTTPError(http.StatusBadRequest, errInvalidSelectors)
+	} else if err == services.ErrFeedPlugin {
+		return echo.NewHTTPError(http.StatusBadRequest, errUnknownPlugin)
+	} else if err == services.ErrFeedCategoryNotFound {
+		return echo.NewHTTPError(http.StatusBadRequest, "category does not exist")
 	} else if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
@@ -131,6 +195,59 @@ func (s *FeedsController) EditFeed(c echo.Context) error {
 	return c.JSON(http.StatusOK, feed)
 }
 
+// apply returns the user editable fields of current as changed by params
+func (p *feedUpdateParams) apply(current *models.Feed) models.Feed {
+	feed := models.Feed{
+		ID:           current.ID,
+		DisplayTitle: current.DisplayTitle,
+		Notes:        current.Notes,
+		CategoryID:   current.CategoryID,
+		KeepDays:     current.KeepDays,
+		KeepLast:     current.KeepLast,
+		Credentials:  p.Credentials,
+	}
+
+	if p.Subscription != nil {
+		feed.Subscription = *p.Subscription
+	}
+
+	if p.DisplayTitle != nil {
+		feed.DisplayTitle = *p.DisplayTitle
+	}
+
+	if p.Notes != nil {
+		feed.Notes = *p.Notes
+	}
+
+	// An empty category moves the feed out of its category
+	if p.CategoryID != nil {
+		feed.CategoryID = *p.CategoryID
+	}
+
+	if p.KeepDays != nil {
+		feed.KeepDays = *p.KeepDays
+	}
+
+	if p.KeepLast != nil {
+		feed.KeepLast = *p.KeepLast
+	}
+
+	if p.Selectors != nil {
+		feed.Selectors = *p.Selectors
+	}
+
+	if p.Processors != nil {
+		feed.Processors = *p.Processors
+	}
+
+	// A feed is only re-enabled if the request asks for it
+	if p.Status != nil {
+		feed.Status = *p.Status
+	}
+
+	return feed
+}
+
 // DeleteFeed with id
 func (s *FeedsController) DeleteFeed(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
@@ -166,6 +283,60 @@ func (s *FeedsController) MarkFeed(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// PauseFeed stops fetching a Feed, until the optional 'until' time
+func (s *FeedsController) PauseFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	until, err := untilParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.feeds.Pause(userID, c.Param("feedID"), until); err != nil {
+		return feedStateError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ResumeFeed fetches a paused Feed again
+func (s *FeedsController) ResumeFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	if err := s.feeds.Resume(userID, c.Param("feedID")); err != nil {
+		return feedStateError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// MuteFeed hides the entries of a Feed, until the optional 'until' time
+func (s *FeedsController) MuteFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	until, err := untilParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err = s.feeds.Mute(userID, c.Param("feedID"), until); err != nil {
+		return feedStateError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// UnmuteFeed shows the entries of a muted Feed again
+func (s *FeedsController) UnmuteFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	if err := s.feeds.Unmute(userID, c.Param("feedID")); err != nil {
+		return feedStateError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // GetFeedEntries returns a list of entries provided from a feed
 func (s *FeedsController) GetFeedEntries(c echo.Context) error {
 	userID := c.Get(userContextKey).(string)
@@ -181,12 +352,13 @@ func (s *FeedsController) GetFeedEntries(c echo.Context) error {
 		Count:          params.Count,
 		Newest:         convertOrderByParamToValue(params.OrderBy),
 		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
 	}
 
 	entries, next := s.feeds.Entries(userID, page)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"entries":        entries,
+		"entries":        selectEntryFields(entries, params.Fields),
 		"continuationId": next,
 	})
 }
@@ -202,3 +374,74 @@ func (s *FeedsController) GetFeedStats(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, stats)
 }
+
+// GetFeedEvents returns the history of changes made to a Feed
+func (s *FeedsController) GetFeedEvents(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	events, next, err := s.feeds.Events(userID, models.Page{
+		FilterID:       c.Param("feedID"),
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+	if err == services.ErrFeedNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"events":         events,
+		"continuationId": next,
+	})
+}
+
+// DiscoverFeeds returns the feeds available at a website
+func (s *FeedsController) DiscoverFeeds(c echo.Context) error {
+	url := c.QueryParam("url")
+	if url == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "'url' parameter is required")
+	}
+
+	candidates, err := s.feeds.Discover(url)
+	if err == services.ErrFetchingFeed {
+		return echo.NewHTTPError(http.StatusBadRequest, "url is not reachable")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"feeds": candidates,
+	})
+}
+
+// untilParam parses the optional 'until' parameter of a request
+func untilParam(c echo.Context) (*time.Time, error) {
+	param := c.FormValue("until")
+	if param == "" {
+		return nil, nil
+	}
+
+	until, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, errInvalidUntil)
+	}
+
+	return &until, nil
+}
+
+func feedStateError(err error) error {
+	switch err {
+	case services.ErrFeedNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrFeedUntil:
+		return echo.NewHTTPError(http.StatusBadRequest, errInvalidUntil)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+}
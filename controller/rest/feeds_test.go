@@ -24,6 +24,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
@@ -56,7 +57,7 @@ func (c *FeedsControllerSuite) TestNewFeed() {
 	}
 
 	c.mockFeeds.EXPECT().
-		New(gomock.Eq(feed.Title), gomock.Eq(feed.Subscription), gomock.Eq(""), gomock.Eq(c.user.ID)).
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Feed{Title: feed.Title, Subscription: feed.Subscription})).
 		Return(feed, nil)
 
 	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(
@@ -75,9 +76,51 @@ func (c *FeedsControllerSuite) TestNewFeed() {
 	c.Equal(http.StatusCreated, rec.Code)
 }
 
+func (c *FeedsControllerSuite) TestNewFeedWithCredentials() {
+	credentials := &models.FeedCredentials{
+		Username: "user",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+	}
+
+	c.mockFeeds.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Feed{
+			Title:        "Example",
+			Subscription: "https://example.com",
+			Credentials:  credentials,
+		})).
+		Return(models.Feed{
+			ID:                   "feed",
+			Title:                "Example",
+			Subscription:         "https://example.com",
+			EncryptedCredentials: []byte("encrypted"),
+			Credentials:          credentials,
+		}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{
+		"title": "Example",
+		"subscription": "https://example.com",
+		"credentials": {"username": "user", "password": "secret", "headers": {"X-Api-Key": "key"}}
+	}`))
+
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.NoError(c.controller.NewFeed(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+	c.NotContains(rec.Body.String(), "credentials")
+	c.NotContains(rec.Body.String(), "secret")
+	c.NotContains(rec.Body.String(), "key")
+}
+
 func (c *FeedsControllerSuite) TestUnreachableNewFeed() {
 	c.mockFeeds.EXPECT().
-		New(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		New(gomock.Any(), gomock.Any()).
 		Return(models.Feed{}, services.ErrFetchingFeed)
 
 	feed := `{ "title": "Example", "subscription": "bogus" }`
@@ -97,6 +140,151 @@ func (c *FeedsControllerSuite) TestUnreachableNewFeed() {
 	)
 }
 
+func (c *FeedsControllerSuite) TestNewFeedWithUnknownKind() {
+	c.mockFeeds.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Feed{Subscription: "https://example.com", Kind: "bogus"})).
+		Return(models.Feed{}, services.ErrFeedKind)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{
+		"subscription": "https://example.com",
+		"kind": "bogus"
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.EqualError(
+		c.controller.NewFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'kind' must be scraper, plugin or empty").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestNewFeedWithUnknownPlugin() {
+	c.mockFeeds.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Feed{
+			Subscription: "project = SYN",
+			Kind:         models.FeedKindPlugin,
+			Plugin:       "jira",
+			Processors:   "translate",
+		})).
+		Return(models.Feed{}, services.ErrFeedPlugin)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{
+		"subscription": "project = SYN",
+		"kind": "plugin",
+		"plugin": "jira",
+		"processors": "translate"
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.EqualError(
+		c.controller.NewFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'plugin' and 'processors' must name configured plugins").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestNewScraperFeed() {
+	selectors := models.ScraperSelectors{Item: "article", Title: "h2"}
+
+	c.mockFeeds.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Feed{
+			Subscription: "https://example.com",
+			Kind:         models.FeedKindScraper,
+			Selectors:    selectors,
+		})).
+		Return(models.Feed{
+			ID:           "feed",
+			Title:        "Example",
+			Subscription: "https://example.com",
+			Kind:         models.FeedKindScraper,
+			Selectors:    selectors,
+		}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{
+		"subscription": "https://example.com",
+		"kind": "scraper",
+		"selectors": {"item": "article", "title": "h2"}
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.NoError(c.controller.NewFeed(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+	c.Contains(rec.Body.String(), `"kind":"scraper"`)
+}
+
+func (c *FeedsControllerSuite) TestPreviewFeed() {
+	selectors := models.ScraperSelectors{Item: "article", Title: "h2"}
+
+	c.mockFeeds.EXPECT().
+		Preview(gomock.Eq("https://example.com"), gomock.Eq(selectors), gomock.Nil()).
+		Return(
+			models.Feed{Title: "Example", Subscription: "https://example.com", Kind: models.FeedKindScraper},
+			[]models.Entry{{Title: "First", Link: "https://example.com/1"}},
+			nil,
+		)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{
+		"subscription": "https://example.com",
+		"selectors": {"item": "article", "title": "h2"}
+	}`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds/preview")
+
+	c.NoError(c.controller.PreviewFeed(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+
+	var preview struct {
+		Feed    models.Feed    `json:"feed"`
+		Entries []models.Entry `json:"entries"`
+	}
+
+	c.NoError(json.Unmarshal(rec.Body.Bytes(), &preview))
+	c.Equal("Example", preview.Feed.Title)
+	c.Require().Len(preview.Entries, 1)
+	c.Equal("First", preview.Entries[0].Title)
+}
+
+func (c *FeedsControllerSuite) TestPreviewFeedWithInvalidSelectors() {
+	c.mockFeeds.EXPECT().
+		Preview(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(models.Feed{}, nil, services.ErrFeedSelectors)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{ "subscription": "https://example.com" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds/preview")
+
+	c.EqualError(
+		c.controller.PreviewFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'selectors' must hold valid CSS selectors, including item").Error(),
+	)
+}
+
 func (c *FeedsControllerSuite) TestGetFeeds() {
 	page := models.Page{
 		Count: 1,
@@ -116,6 +304,41 @@ func (c *FeedsControllerSuite) TestGetFeeds() {
 	c.Equal(http.StatusOK, rec.Code)
 }
 
+func (c *FeedsControllerSuite) TestGetFeedsWithStatus() {
+	page := models.Page{
+		Count:  1,
+		Status: models.FeedStatusError,
+	}
+
+	c.mockFeeds.EXPECT().Feeds(gomock.Eq(c.user.ID), gomock.Eq(page)).Return([]models.Feed{{ID: utils.CreateID()}}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1&status=error", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.NoError(c.controller.GetFeeds(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *FeedsControllerSuite) TestGetFeedsWithBadStatus() {
+	req := httptest.NewRequest(echo.GET, "/?status=bogus", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds")
+
+	c.EqualError(
+		c.controller.GetFeeds(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'status' must be one of ok, error or disabled").Error(),
+	)
+}
+
 func (c *FeedsControllerSuite) TestGetFeed() {
 	feedID := utils.CreateID()
 
@@ -158,10 +381,58 @@ func (c *FeedsControllerSuite) TestEditFeed() {
 	feedID := utils.CreateID()
 
 	c.mockFeeds.EXPECT().
-		Update(gomock.Eq(c.user.ID), gomock.Eq(&models.Feed{ID: feedID, Title: "NewName"})).
+		Feed(gomock.Eq(c.user.ID), gomock.Eq(feedID)).
+		Return(models.Feed{ID: feedID, Status: models.FeedStatusOK, KeepDays: 7, KeepLast: 100}, true)
+
+	// Fields left out keep their value while explicit zero values reset them
+	c.mockFeeds.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(&models.Feed{
+			ID:           feedID,
+			DisplayTitle: "NewName",
+			Notes:        "Daily",
+			KeepLast:     100,
+		})).
+		DoAndReturn(func(_ string, feed *models.Feed) error {
+			*feed = models.Feed{ID: feedID, Title: "Upstream", DisplayTitle: "NewName", Status: models.FeedStatusOK}
+
+			return nil
+		})
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(
+		`{ "displayTitle": "NewName", "notes": "Daily", "keepDays": 0 }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID")
+
+	c.NoError(c.controller.EditFeed(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+
+	// The updated feed is sent back
+	var feed models.Feed
+	c.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &feed))
+	c.Equal("Upstream", feed.Title)
+	c.Equal(models.FeedStatusOK, feed.Status)
+}
+
+func (c *FeedsControllerSuite) TestEditFeedCredentials() {
+	feedID := utils.CreateID()
+
+	c.mockFeeds.EXPECT().Feed(gomock.Eq(c.user.ID), gomock.Eq(feedID)).Return(models.Feed{ID: feedID}, true)
+
+	c.mockFeeds.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(&models.Feed{
+			ID:          feedID,
+			Credentials: &models.FeedCredentials{Token: "secret"},
+		})).
 		Return(nil)
 
-	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "title": "NewName" }`))
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "credentials": { "token": "secret" } }`))
 	req.Header.Set("Content-Type", "application/json")
 
 	rec := httptest.NewRecorder()
@@ -174,10 +445,68 @@ func (c *FeedsControllerSuite) TestEditFeed() {
 
 	c.NoError(c.controller.EditFeed(ctx))
 	c.Equal(http.StatusOK, rec.Code)
+	c.NotContains(rec.Body.String(), "secret")
+}
+
+func (c *FeedsControllerSuite) TestEditFeedCategory() {
+	feedID := utils.CreateID()
+
+	c.mockFeeds.EXPECT().
+		Feed(gomock.Eq(c.user.ID), gomock.Eq(feedID)).
+		Return(models.Feed{ID: feedID, DisplayTitle: "Mine", CategoryID: "old"}, true).
+		Times(2)
+
+	c.mockFeeds.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(&models.Feed{ID: feedID, DisplayTitle: "Mine", CategoryID: "new"})).
+		Return(nil)
+
+	// Leaving the category out keeps it
+	c.mockFeeds.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(&models.Feed{ID: feedID, DisplayTitle: "Other", CategoryID: "old"})).
+		Return(nil)
+
+	for _, body := range []string{`{ "categoryId": "new" }`, `{ "displayTitle": "Other" }`} {
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		ctx := c.e.NewContext(req, rec)
+		ctx.Set(userContextKey, c.user.ID)
+		ctx.SetParamNames("feedID")
+		ctx.SetParamValues(feedID)
+
+		ctx.SetPath("/v1/feeds/:feedID")
+
+		c.NoError(c.controller.EditFeed(ctx))
+		c.Equal(http.StatusOK, rec.Code)
+	}
+}
+
+func (c *FeedsControllerSuite) TestEditFeedUnknownCategory() {
+	feedID := utils.CreateID()
+
+	c.mockFeeds.EXPECT().Feed(gomock.Eq(c.user.ID), gomock.Eq(feedID)).Return(models.Feed{ID: feedID}, true)
+	c.mockFeeds.EXPECT().Update(gomock.Any(), gomock.Any()).Return(services.ErrFeedCategoryNotFound)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "categoryId": "bogus" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID")
+
+	c.EqualError(
+		c.controller.EditFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "category does not exist").Error(),
+	)
 }
 
 func (c *FeedsControllerSuite) TestEditUnknownFeed() {
-	c.mockFeeds.EXPECT().Update(gomock.Any(), gomock.Any()).Return(services.ErrFeedNotFound)
+	c.mockFeeds.EXPECT().Feed(gomock.Any(), gomock.Any()).Return(models.Feed{}, false)
 
 	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "title": "title" }`))
 	req.Header.Set("Content-Type", "application/json")
@@ -274,6 +603,123 @@ func (c *FeedsControllerSuite) TestMarkUnknownFeeed() {
 	)
 }
 
+func (c *FeedsControllerSuite) TestPauseFeed() {
+	feedID := utils.CreateID()
+	until := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	c.mockFeeds.EXPECT().
+		Pause(gomock.Eq(c.user.ID), gomock.Eq(feedID), gomock.Eq(&until)).
+		Return(nil)
+
+	req := httptest.NewRequest(echo.PUT, "/?until=2030-01-01T12:00:00Z", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID/pause")
+
+	c.NoError(c.controller.PauseFeed(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *FeedsControllerSuite) TestPauseFeedWithInvalidUntil() {
+	req := httptest.NewRequest(echo.PUT, "/?until=tomorrow", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(utils.CreateID())
+
+	ctx.SetPath("/v1/feeds/:feedID/pause")
+
+	c.EqualError(
+		c.controller.PauseFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'until' must be an RFC 3339 time in the future").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestResumeFeed() {
+	feedID := utils.CreateID()
+
+	c.mockFeeds.EXPECT().Resume(gomock.Eq(c.user.ID), gomock.Eq(feedID)).Return(nil)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID/pause")
+
+	c.NoError(c.controller.ResumeFeed(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *FeedsControllerSuite) TestMuteFeed() {
+	feedID := utils.CreateID()
+
+	c.mockFeeds.EXPECT().
+		Mute(gomock.Eq(c.user.ID), gomock.Eq(feedID), gomock.Nil()).
+		Return(nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID/mute")
+
+	c.NoError(c.controller.MuteFeed(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *FeedsControllerSuite) TestMuteFeedUntilPastTime() {
+	c.mockFeeds.EXPECT().Mute(gomock.Any(), gomock.Any(), gomock.Any()).Return(services.ErrFeedUntil)
+
+	req := httptest.NewRequest(echo.PUT, "/?until=2000-01-01T00:00:00Z", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(utils.CreateID())
+
+	ctx.SetPath("/v1/feeds/:feedID/mute")
+
+	c.EqualError(
+		c.controller.MuteFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'until' must be an RFC 3339 time in the future").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestUnmuteUnknownFeed() {
+	c.mockFeeds.EXPECT().Unmute(gomock.Any(), gomock.Any()).Return(services.ErrFeedNotFound)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/feeds/:feedID/mute")
+
+	c.EqualError(
+		c.controller.UnmuteFeed(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
 func (c *FeedsControllerSuite) TestGetFeedEntries() {
 	feedID := utils.CreateID()
 
@@ -324,6 +770,113 @@ func (c *FeedsControllerSuite) TestGetFeedStats() {
 	c.NoError(json.Unmarshal(rec.Body.Bytes(), &stats))
 }
 
+func (c *FeedsControllerSuite) TestDiscoverFeeds() {
+	c.mockFeeds.EXPECT().Discover(gomock.Eq("https://example.com")).Return([]models.FeedCandidate{
+		{
+			Title:        "Example",
+			Subscription: "https://example.com/feed.xml",
+		},
+	}, nil)
+
+	req := httptest.NewRequest(echo.GET, "/?url=https://example.com", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds/discover")
+
+	c.NoError(c.controller.DiscoverFeeds(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.JSONEq(
+		`{"feeds":[{"title":"Example","subscription":"https://example.com/feed.xml"}]}`,
+		rec.Body.String(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestDiscoverFeedsWithoutURL() {
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds/discover")
+
+	c.EqualError(
+		c.controller.DiscoverFeeds(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'url' parameter is required").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestDiscoverUnreachableFeeds() {
+	c.mockFeeds.EXPECT().Discover(gomock.Any()).Return(nil, services.ErrFetchingFeed)
+
+	req := httptest.NewRequest(echo.GET, "/?url=bogus", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/feeds/discover")
+
+	c.EqualError(
+		c.controller.DiscoverFeeds(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "url is not reachable").Error(),
+	)
+}
+
+func (c *FeedsControllerSuite) TestGetFeedEvents() {
+	feedID := utils.CreateID()
+
+	page := models.Page{
+		FilterID: feedID,
+		Count:    2,
+	}
+
+	c.mockFeeds.EXPECT().Events(gomock.Eq(c.user.ID), gomock.Eq(page)).Return([]models.FeedEvent{
+		{
+			ID:   utils.CreateID(),
+			Type: models.FeedEventRedirect,
+			From: "http://example.com/feed",
+			To:   "https://example.com/feed",
+		},
+	}, "", nil)
+
+	req := httptest.NewRequest(echo.GET, "/?count=2", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID/events")
+
+	c.NoError(c.controller.GetFeedEvents(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Contains(rec.Body.String(), `"to":"https://example.com/feed"`)
+}
+
+func (c *FeedsControllerSuite) TestGetUnknownFeedEvents() {
+	c.mockFeeds.EXPECT().Events(gomock.Any(), gomock.Any()).Return(nil, "", services.ErrFeedNotFound)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/feeds/:feedID/events")
+
+	c.EqualError(
+		c.controller.GetFeedEvents(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
 func (c *FeedsControllerSuite) TestGetUnknownFeedStats() {
 	c.mockFeeds.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(models.Stats{}, services.ErrFeedNotFound)
 
@@ -0,0 +1,203 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+// outputFormats maps the extension of an output feed's path to the format it is
+// rendered in and its content type
+var outputFormats = map[string]struct {
+	format      services.OutputFormat
+	contentType string
+}{
+	"atom": {services.OutputFormatAtom, "application/atom+xml; charset=utf-8"},
+	"rss":  {services.OutputFormatRSS, "application/rss+xml; charset=utf-8"},
+	"json": {services.OutputFormatJSON, "application/feed+json; charset=utf-8"},
+}
+
+type (
+	OutputFeedsController struct {
+		Controller
+
+		outputFeeds services.OutputFeeds
+	}
+)
+
+// NewOutputFeedsController registers the routes output feeds are managed with and
+// the public route they are read from. Readers are not authenticated, they are
+// identified by the token in the path instead.
+func NewOutputFeedsController(service services.OutputFeeds, e *echo.Echo) *OutputFeedsController {
+	v1 := e.Group("v1")
+
+	controller := OutputFeedsController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.POST("/output-feeds", controller.NewOutputFeed)
+	v1.GET("/output-feeds", controller.GetOutputFeeds)
+	v1.GET("/output-feeds/:outputFeedID", controller.GetOutputFeed)
+	v1.PUT("/output-feeds/:outputFeedID", controller.EditOutputFeed)
+	v1.DELETE("/output-feeds/:outputFeedID", controller.DeleteOutputFeed)
+	v1.POST("/output-feeds/:outputFeedID/token", controller.RenewOutputFeedToken)
+
+	e.GET("/feeds/out/:token", controller.RenderOutputFeed)
+
+	return &controller
+}
+
+// NewOutputFeed creates a new OutputFeed
+func (s *OutputFeedsController) NewOutputFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	feed := models.OutputFeed{}
+	if err := c.Bind(&feed); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	newFeed, err := s.outputFeeds.New(userID, feed)
+	if err != nil {
+		return outputFeedError(err)
+	}
+
+	return c.JSON(http.StatusCreated, newFeed)
+}
+
+// GetOutputFeeds returns a list of OutputFeeds owned by a user
+func (s *OutputFeedsController) GetOutputFeeds(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	feeds, next := s.outputFeeds.List(userID, models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"outputFeeds":    feeds,
+		"continuationId": next,
+	})
+}
+
+// GetOutputFeed with id
+func (s *OutputFeedsController) GetOutputFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	feed, found := s.outputFeeds.OutputFeed(userID, c.Param("outputFeedID"))
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, feed)
+}
+
+// EditOutputFeed with id
+func (s *OutputFeedsController) EditOutputFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	feed := models.OutputFeed{}
+	if err := c.Bind(&feed); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	feed.ID = c.Param("outputFeedID")
+
+	newFeed, err := s.outputFeeds.Update(userID, feed)
+	if err != nil {
+		return outputFeedError(err)
+	}
+
+	return c.JSON(http.StatusOK, newFeed)
+}
+
+// DeleteOutputFeed with id, revoking its token
+func (s *OutputFeedsController) DeleteOutputFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	if err := s.outputFeeds.Delete(userID, c.Param("outputFeedID")); err != nil {
+		return outputFeedError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RenewOutputFeedToken replaces the token of an OutputFeed with id, revoking the previous one
+func (s *OutputFeedsController) RenewOutputFeedToken(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	feed, err := s.outputFeeds.RenewToken(userID, c.Param("outputFeedID"))
+	if err != nil {
+		return outputFeedError(err)
+	}
+
+	return c.JSON(http.StatusOK, feed)
+}
+
+// RenderOutputFeed renders the OutputFeed accessed with a token in the format
+// named by the extension of the path
+func (s *OutputFeedsController) RenderOutputFeed(c echo.Context) error {
+	param := c.Param("token")
+
+	idx := strings.LastIndex(param, ".")
+	if idx == -1 {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	output, found := outputFormats[strings.ToLower(param[idx+1:])]
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	self := c.Scheme() + "://" + c.Request().Host + c.Request().URL.Path
+
+	b, err := s.outputFeeds.Render(param[:idx], output.format, self)
+	if err == services.ErrOutputFeedNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.Blob(http.StatusOK, output.contentType, b)
+}
+
+func outputFeedError(err error) error {
+	switch err {
+	case services.ErrOutputFeedNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrOutputFeedSource:
+		return echo.NewHTTPError(http.StatusBadRequest, "only one of 'categoryId' and 'tagId' may be set")
+	case services.ErrOutputFeedScope:
+		return echo.NewHTTPError(http.StatusBadRequest, "output feed refers to a missing category or tag")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	OutputFeedsControllerSuite struct {
+		suite.Suite
+
+		ctrl            *gomock.Controller
+		mockOutputFeeds *services.MockOutputFeeds
+
+		controller *rest.OutputFeedsController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *OutputFeedsControllerSuite) TestNewOutputFeed() {
+	tagID := utils.CreateID()
+
+	c.mockOutputFeeds.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.OutputFeed{Title: "Reading list", TagID: tagID})).
+		Return(models.OutputFeed{ID: utils.CreateID(), Token: "token"}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(
+		`{ "title": "Reading list", "tagId": "`+tagID+`" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/output-feeds")
+
+	c.NoError(c.controller.NewOutputFeed(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+}
+
+func (c *OutputFeedsControllerSuite) TestNewInvalidOutputFeed() {
+	c.mockOutputFeeds.EXPECT().New(gomock.Any(), gomock.Any()).Return(models.OutputFeed{}, services.ErrOutputFeedSource)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{ "categoryId": "a", "tagId": "b" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/output-feeds")
+
+	c.EqualError(
+		c.controller.NewOutputFeed(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "only one of 'categoryId' and 'tagId' may be set").Error(),
+	)
+}
+
+func (c *OutputFeedsControllerSuite) TestGetOutputFeeds() {
+	c.mockOutputFeeds.EXPECT().
+		List(gomock.Eq(c.user.ID), gomock.Eq(models.Page{Count: 1})).
+		Return([]models.OutputFeed{{ID: utils.CreateID()}}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/output-feeds")
+
+	c.NoError(c.controller.GetOutputFeeds(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *OutputFeedsControllerSuite) TestGetUnknownOutputFeed() {
+	c.mockOutputFeeds.EXPECT().OutputFeed(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(models.OutputFeed{}, false)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("outputFeedID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/output-feeds/:outputFeedID")
+
+	c.EqualError(
+		c.controller.GetOutputFeed(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *OutputFeedsControllerSuite) TestEditOutputFeed() {
+	feedID := utils.CreateID()
+
+	c.mockOutputFeeds.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(models.OutputFeed{ID: feedID, Title: "Saved"})).
+		Return(models.OutputFeed{ID: feedID}, nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "title": "Saved" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("outputFeedID")
+	ctx.SetParamValues(feedID)
+	ctx.SetPath("/v1/output-feeds/:outputFeedID")
+
+	c.NoError(c.controller.EditOutputFeed(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *OutputFeedsControllerSuite) TestDeleteOutputFeed() {
+	feedID := utils.CreateID()
+
+	c.mockOutputFeeds.EXPECT().Delete(gomock.Eq(c.user.ID), gomock.Eq(feedID)).Return(nil)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("outputFeedID")
+	ctx.SetParamValues(feedID)
+	ctx.SetPath("/v1/output-feeds/:outputFeedID")
+
+	c.NoError(c.controller.DeleteOutputFeed(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *OutputFeedsControllerSuite) TestRenewUnknownOutputFeedToken() {
+	c.mockOutputFeeds.EXPECT().
+		RenewToken(gomock.Eq(c.user.ID), gomock.Eq("bogus")).
+		Return(models.OutputFeed{}, services.ErrOutputFeedNotFound)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("outputFeedID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/output-feeds/:outputFeedID/token")
+
+	c.EqualError(
+		c.controller.RenewOutputFeedToken(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *OutputFeedsControllerSuite) TestRenderOutputFeed() {
+	c.mockOutputFeeds.EXPECT().
+		Render(gomock.Eq("token"), gomock.Eq(services.OutputFormatRSS), gomock.Eq("http://example.com/feeds/out/token.rss")).
+		Return([]byte("<rss></rss>"), nil)
+
+	req := httptest.NewRequest(echo.GET, "http://example.com/feeds/out/token.rss", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("token")
+	ctx.SetParamValues("token.rss")
+	ctx.SetPath("/feeds/out/:token")
+
+	c.NoError(c.controller.RenderOutputFeed(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Equal("application/rss+xml; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	c.Equal("<rss></rss>", rec.Body.String())
+}
+
+func (c *OutputFeedsControllerSuite) TestRenderOutputFeedWithUnknownFormat() {
+	for _, token := range []string{"token", "token.yaml"} {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+
+		rec := httptest.NewRecorder()
+
+		ctx := c.e.NewContext(req, rec)
+		ctx.SetParamNames("token")
+		ctx.SetParamValues(token)
+		ctx.SetPath("/feeds/out/:token")
+
+		c.EqualError(
+			c.controller.RenderOutputFeed(ctx),
+			echo.NewHTTPError(http.StatusNotFound).Error(),
+		)
+	}
+}
+
+func (c *OutputFeedsControllerSuite) TestRenderOutputFeedWithoutCredentials() {
+	e := echo.New()
+	e.HideBanner = true
+
+	rest.NewAuthController(services.NewMockAuth(c.ctrl), "secret", false, e)
+	rest.NewOutputFeedsController(c.mockOutputFeeds, e)
+
+	c.mockOutputFeeds.EXPECT().
+		Render(gomock.Eq("revoked"), gomock.Eq(services.OutputFormatAtom), gomock.Any()).
+		Return(nil, services.ErrOutputFeedNotFound)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/feeds/out/revoked.atom", nil))
+	c.Equal(http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/v1/output-feeds", nil))
+	c.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (c *OutputFeedsControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockOutputFeeds = services.NewMockOutputFeeds(c.ctrl)
+
+	c.controller = rest.NewOutputFeedsController(c.mockOutputFeeds, c.e)
+}
+
+func (c *OutputFeedsControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestOutputFeedsControllerSuite(t *testing.T) {
+	suite.Run(t, new(OutputFeedsControllerSuite))
+}
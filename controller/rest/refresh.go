@@ -0,0 +1,101 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	RefreshController struct {
+		Controller
+
+		refresh services.Refresh
+	}
+)
+
+func NewRefreshController(service services.Refresh, e *echo.Echo) *RefreshController {
+	v1 := e.Group("v1")
+
+	controller := RefreshController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.POST("/refresh", controller.RefreshAll)
+	v1.GET("/refresh/:jobID", controller.GetRefreshJob)
+	v1.POST("/feeds/:feedID/refresh", controller.RefreshFeed)
+	v1.POST("/categories/:categoryID/refresh", controller.RefreshCategory)
+
+	return &controller
+}
+
+// RefreshAll starts refreshing every feed of the authenticated user
+func (s *RefreshController) RefreshAll(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	return c.JSON(http.StatusAccepted, s.refresh.All(userID))
+}
+
+// RefreshFeed starts refreshing a feed with id
+func (s *RefreshController) RefreshFeed(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	job, err := s.refresh.Feed(userID, c.Param("feedID"))
+	if err == services.ErrFeedNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// RefreshCategory starts refreshing every feed in a category with id
+func (s *RefreshController) RefreshCategory(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	job, err := s.refresh.Category(userID, c.Param("categoryID"))
+	if err == services.ErrCategoryNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// GetRefreshJob with id
+func (s *RefreshController) GetRefreshJob(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	job, err := s.refresh.Job(userID, c.Param("jobID"))
+	if err == services.ErrRefreshJobNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,218 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	RefreshControllerSuite struct {
+		suite.Suite
+
+		ctrl        *gomock.Controller
+		mockRefresh *services.MockRefresh
+
+		controller *rest.RefreshController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *RefreshControllerSuite) TestRefreshAll() {
+	c.mockRefresh.EXPECT().All(gomock.Eq(c.user.ID)).Return(models.RefreshJob{
+		ID:     "job",
+		Status: models.RefreshStatusRunning,
+		Feeds: []models.FeedRefresh{
+			{
+				FeedID: "feed",
+				Title:  "Example",
+				Status: models.RefreshStatusRunning,
+			},
+		},
+	})
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/refresh")
+
+	c.NoError(c.controller.RefreshAll(ctx))
+	c.Equal(http.StatusAccepted, rec.Code)
+	c.JSONEq(`{
+		"id": "job",
+		"createdAt": "0001-01-01T00:00:00Z",
+		"status": "running",
+		"newEntries": 0,
+		"feeds": [{"feedId": "feed", "title": "Example", "status": "running", "newEntries": 0}]
+	}`, rec.Body.String())
+}
+
+func (c *RefreshControllerSuite) TestRefreshFeed() {
+	feedID := utils.CreateID()
+
+	c.mockRefresh.EXPECT().Feed(gomock.Eq(c.user.ID), gomock.Eq(feedID)).Return(models.RefreshJob{ID: "job"}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues(feedID)
+
+	ctx.SetPath("/v1/feeds/:feedID/refresh")
+
+	c.NoError(c.controller.RefreshFeed(ctx))
+	c.Equal(http.StatusAccepted, rec.Code)
+}
+
+func (c *RefreshControllerSuite) TestRefreshMissingFeed() {
+	c.mockRefresh.EXPECT().Feed(gomock.Eq(c.user.ID), gomock.Eq("bogus")).
+		Return(models.RefreshJob{}, services.ErrFeedNotFound)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("feedID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/feeds/:feedID/refresh")
+
+	c.EqualError(c.controller.RefreshFeed(ctx), echo.NewHTTPError(http.StatusNotFound).Error())
+}
+
+func (c *RefreshControllerSuite) TestRefreshCategory() {
+	ctgID := utils.CreateID()
+
+	c.mockRefresh.EXPECT().Category(gomock.Eq(c.user.ID), gomock.Eq(ctgID)).Return(models.RefreshJob{ID: "job"}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("categoryID")
+	ctx.SetParamValues(ctgID)
+
+	ctx.SetPath("/v1/categories/:categoryID/refresh")
+
+	c.NoError(c.controller.RefreshCategory(ctx))
+	c.Equal(http.StatusAccepted, rec.Code)
+}
+
+func (c *RefreshControllerSuite) TestRefreshMissingCategory() {
+	c.mockRefresh.EXPECT().Category(gomock.Eq(c.user.ID), gomock.Eq("bogus")).
+		Return(models.RefreshJob{}, services.ErrCategoryNotFound)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("categoryID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/categories/:categoryID/refresh")
+
+	c.EqualError(c.controller.RefreshCategory(ctx), echo.NewHTTPError(http.StatusNotFound).Error())
+}
+
+func (c *RefreshControllerSuite) TestGetRefreshJob() {
+	c.mockRefresh.EXPECT().Job(gomock.Eq(c.user.ID), gomock.Eq("job")).Return(models.RefreshJob{
+		ID:         "job",
+		Status:     models.RefreshStatusDone,
+		NewEntries: 2,
+	}, nil)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("jobID")
+	ctx.SetParamValues("job")
+
+	ctx.SetPath("/v1/refresh/:jobID")
+
+	c.NoError(c.controller.GetRefreshJob(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *RefreshControllerSuite) TestGetMissingRefreshJob() {
+	c.mockRefresh.EXPECT().Job(gomock.Eq(c.user.ID), gomock.Eq("bogus")).
+		Return(models.RefreshJob{}, services.ErrRefreshJobNotFound)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("jobID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/refresh/:jobID")
+
+	c.EqualError(c.controller.GetRefreshJob(ctx), echo.NewHTTPError(http.StatusNotFound).Error())
+}
+
+func (c *RefreshControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockRefresh = services.NewMockRefresh(c.ctrl)
+
+	c.controller = rest.NewRefreshController(c.mockRefresh, c.e)
+}
+
+func (c *RefreshControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestRefreshControllerSuite(t *testing.T) {
+	suite.Run(t, new(RefreshControllerSuite))
+}
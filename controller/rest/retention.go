@@ -0,0 +1,58 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	RetentionController struct {
+		Controller
+
+		retention services.Retention
+	}
+)
+
+func NewRetentionController(service services.Retention, e *echo.Echo) *RetentionController {
+	v1 := e.Group("v1")
+
+	controller := RetentionController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.GET("/retention", controller.GetRetention)
+
+	return &controller
+}
+
+// GetRetention reports the entries of the user that would be deleted by the next
+// retention run without deleting them. Other users are not covered since there
+// are no administrator accounts.
+func (s *RetentionController) GetRetention(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	return c.JSON(http.StatusOK, s.retention.Preview(userID))
+}
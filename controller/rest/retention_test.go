@@ -0,0 +1,95 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	RetentionControllerSuite struct {
+		suite.Suite
+
+		ctrl          *gomock.Controller
+		mockRetention *services.MockRetention
+
+		controller *rest.RetentionController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *RetentionControllerSuite) TestGetRetention() {
+	c.mockRetention.EXPECT().Preview(gomock.Eq(c.user.ID)).Return(models.RetentionReport{
+		Feeds: []models.FeedRetention{
+			{
+				FeedID:  "feed",
+				Title:   "Example",
+				Entries: 3,
+			},
+		},
+		Total: 3,
+	})
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/retention")
+
+	c.NoError(c.controller.GetRetention(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.JSONEq(`{"feeds":[{"feedId":"feed","title":"Example","entries":3}],"total":3}`, rec.Body.String())
+}
+
+func (c *RetentionControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockRetention = services.NewMockRetention(c.ctrl)
+
+	c.controller = rest.NewRetentionController(c.mockRetention, c.e)
+}
+
+func (c *RetentionControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestRetentionControllerSuite(t *testing.T) {
+	suite.Run(t, new(RetentionControllerSuite))
+}
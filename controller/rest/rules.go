@@ -0,0 +1,197 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	RulesController struct {
+		Controller
+
+		rules services.Rules
+	}
+)
+
+func NewRulesController(service services.Rules, e *echo.Echo) *RulesController {
+	v1 := e.Group("v1")
+
+	controller := RulesController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.POST("/rules", controller.NewRule)
+	v1.GET("/rules", controller.GetRules)
+	v1.POST("/rules/test", controller.TestRule)
+	v1.GET("/rules/:ruleID", controller.GetRule)
+	v1.PUT("/rules/:ruleID", controller.EditRule)
+	v1.DELETE("/rules/:ruleID", controller.DeleteRule)
+	v1.POST("/rules/:ruleID/apply", controller.ApplyRule)
+
+	return &controller
+}
+
+// NewRule creates a new Rule
+func (s *RulesController) NewRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	rule := models.Rule{}
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	newRule, err := s.rules.New(userID, rule)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	return c.JSON(http.StatusCreated, newRule)
+}
+
+// GetRules returns a list of Rules owned by a user
+func (s *RulesController) GetRules(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	rules, next := s.rules.List(userID, models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rules":          rules,
+		"continuationId": next,
+	})
+}
+
+// GetRule with id
+func (s *RulesController) GetRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	rule, found := s.rules.Rule(userID, c.Param("ruleID"))
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// EditRule with id
+func (s *RulesController) EditRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	rule := models.Rule{}
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	rule.ID = c.Param("ruleID")
+
+	newRule, err := s.rules.Update(userID, rule)
+	if err != nil {
+		return ruleError(err)
+	}
+
+	return c.JSON(http.StatusOK, newRule)
+}
+
+// DeleteRule with id
+func (s *RulesController) DeleteRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	if err := s.rules.Delete(userID, c.Param("ruleID")); err != nil {
+		return ruleError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ApplyRule with id to every existing entry
+func (s *RulesController) ApplyRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	matched, err := s.rules.Apply(userID, c.Param("ruleID"))
+	if err != nil {
+		return ruleError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"entries": matched,
+	})
+}
+
+// TestRule returns a page of the entries that a rule would match, without creating or applying it
+func (s *RulesController) TestRule(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := new(listEntriesParams)
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	rule := models.Rule{}
+	if err := c.Bind(&rule); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	entries, next, err := s.rules.Test(userID, rule, models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
+	})
+	if err != nil {
+		return ruleError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"entries":        selectEntryFields(entries, params.Fields),
+		"continuationId": next,
+	})
+}
+
+func ruleError(err error) error {
+	switch err {
+	case services.ErrRuleNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrRuleConditions:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"'conditions' must match title, author, link or content with contains, equals or matches, "+
+				"or feed or category with equals")
+	case services.ErrRuleActions:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"'actions' must mark as read or unread, tag, save or delete")
+	case services.ErrRuleScope:
+		return echo.NewHTTPError(http.StatusBadRequest, "rule refers to a missing feed, category or tag")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+}
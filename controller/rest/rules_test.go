@@ -0,0 +1,286 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	RulesControllerSuite struct {
+		suite.Suite
+
+		ctrl      *gomock.Controller
+		mockRules *services.MockRules
+
+		controller *rest.RulesController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+const ruleJSON = `{
+  "name": "ads",
+  "conditions": [{ "field": "title", "operator": "contains", "value": "sponsored" }],
+  "actions": [{ "type": "mark", "marker": "read" }]
+}`
+
+func adsRule() models.Rule {
+	return models.Rule{
+		Name: "ads",
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorContains, Value: "sponsored"},
+		},
+		Actions: []models.RuleAction{{Type: models.RuleActionMark, Marker: "read"}},
+	}
+}
+
+func (c *RulesControllerSuite) TestNewRule() {
+	c.mockRules.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(adsRule())).
+		Return(models.Rule{ID: utils.CreateID()}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(ruleJSON))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/rules")
+
+	c.NoError(c.controller.NewRule(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+}
+
+func (c *RulesControllerSuite) TestNewInvalidRule() {
+	c.mockRules.EXPECT().New(gomock.Any(), gomock.Any()).Return(models.Rule{}, services.ErrRuleActions)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{ "name": "ads" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/rules")
+
+	c.EqualError(
+		c.controller.NewRule(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'actions' must mark as read or unread, tag, save or delete").Error(),
+	)
+}
+
+func (c *RulesControllerSuite) TestGetRules() {
+	c.mockRules.EXPECT().
+		List(gomock.Eq(c.user.ID), gomock.Eq(models.Page{Count: 1})).
+		Return([]models.Rule{{ID: utils.CreateID()}}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/rules")
+
+	c.NoError(c.controller.GetRules(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *RulesControllerSuite) TestGetUnknownRule() {
+	c.mockRules.EXPECT().Rule(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(models.Rule{}, false)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/rules/:ruleID")
+
+	c.EqualError(
+		c.controller.GetRule(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *RulesControllerSuite) TestEditRule() {
+	ruleID := utils.CreateID()
+
+	expected := adsRule()
+	expected.ID = ruleID
+
+	c.mockRules.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(expected)).
+		Return(expected, nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(ruleJSON))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues(ruleID)
+	ctx.SetPath("/v1/rules/:ruleID")
+
+	c.NoError(c.controller.EditRule(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *RulesControllerSuite) TestEditRuleWithMissingTag() {
+	c.mockRules.EXPECT().Update(gomock.Any(), gomock.Any()).Return(models.Rule{}, services.ErrRuleScope)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "actions": [{ "type": "tag", "tagId": "bogus" }] }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues(utils.CreateID())
+	ctx.SetPath("/v1/rules/:ruleID")
+
+	c.EqualError(
+		c.controller.EditRule(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "rule refers to a missing feed, category or tag").Error(),
+	)
+}
+
+func (c *RulesControllerSuite) TestDeleteRule() {
+	ruleID := utils.CreateID()
+
+	c.mockRules.EXPECT().Delete(gomock.Eq(c.user.ID), gomock.Eq(ruleID)).Return(nil)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues(ruleID)
+	ctx.SetPath("/v1/rules/:ruleID")
+
+	c.NoError(c.controller.DeleteRule(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *RulesControllerSuite) TestDeleteUnknownRule() {
+	c.mockRules.EXPECT().Delete(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(services.ErrRuleNotFound)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/rules/:ruleID")
+
+	c.EqualError(
+		c.controller.DeleteRule(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *RulesControllerSuite) TestApplyRule() {
+	ruleID := utils.CreateID()
+
+	c.mockRules.EXPECT().Apply(gomock.Eq(c.user.ID), gomock.Eq(ruleID)).Return(3, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("ruleID")
+	ctx.SetParamValues(ruleID)
+	ctx.SetPath("/v1/rules/:ruleID/apply")
+
+	c.NoError(c.controller.ApplyRule(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.JSONEq(`{"entries": 3}`, rec.Body.String())
+}
+
+func (c *RulesControllerSuite) TestTestRule() {
+	c.mockRules.EXPECT().
+		Test(gomock.Eq(c.user.ID), gomock.Eq(adsRule()), gomock.Eq(models.Page{
+			Count:  5,
+			Marker: models.MarkerAny,
+		})).
+		Return([]models.Entry{{ID: utils.CreateID()}}, "", nil)
+
+	req := httptest.NewRequest(echo.POST, "/?count=5&markedAs=any", strings.NewReader(ruleJSON))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/rules/test")
+
+	c.NoError(c.controller.TestRule(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *RulesControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockRules = services.NewMockRules(c.ctrl)
+
+	c.controller = rest.NewRulesController(c.mockRules, c.e)
+}
+
+func (c *RulesControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestRulesControllerSuite(t *testing.T) {
+	suite.Run(t, new(RulesControllerSuite))
+}
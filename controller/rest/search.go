@@ -0,0 +1,100 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	SearchController struct {
+		Controller
+
+		search services.Search
+	}
+
+	searchEntriesParams struct {
+		Query          string `query:"q"`
+		FeedID         string `query:"feedId"`
+		CategoryID     string `query:"categoryId"`
+		TagID          string `query:"tagId"`
+		ContinuationID string `query:"continuationId"`
+		Count          int    `query:"count"`
+		Marker         string `query:"markedAs"`
+		Saved          bool   `query:"saved"`
+		OrderBy        string `query:"orderBy"`
+		Fields         string `query:"fields"`
+	}
+)
+
+func NewSearchController(service services.Search, e *echo.Echo) *SearchController {
+	v1 := e.Group("v1")
+
+	controller := SearchController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.GET("/entries/search", controller.SearchEntries)
+
+	return &controller
+}
+
+// SearchEntries returns a list of Entries that match a query
+func (s *SearchController) SearchEntries(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := new(searchEntriesParams)
+	if err := c.Bind(params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	query := models.SearchQuery{
+		Text:       params.Query,
+		FeedID:     params.FeedID,
+		CategoryID: params.CategoryID,
+		TagID:      params.TagID,
+	}
+
+	page := models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+		Newest:         convertOrderByParamToValue(params.OrderBy),
+		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
+	}
+
+	entries, next, err := s.search.Entries(userID, query, page)
+	if err == services.ErrSearchQueryEmpty {
+		return echo.NewHTTPError(http.StatusBadRequest, "search query is required")
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"entries":        selectEntryFields(entries, params.Fields),
+		"continuationID": next,
+	})
+}
@@ -0,0 +1,119 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	SearchControllerSuite struct {
+		suite.Suite
+
+		ctrl       *gomock.Controller
+		mockSearch *services.MockSearch
+
+		controller *rest.SearchController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *SearchControllerSuite) TestSearchEntries() {
+	query := models.SearchQuery{
+		Text:   "kernel news",
+		FeedID: "feed",
+	}
+
+	page := models.Page{
+		Count:  2,
+		Newest: true,
+		Marker: models.MarkerUnread,
+	}
+
+	c.mockSearch.EXPECT().
+		Entries(gomock.Eq(c.user.ID), gomock.Eq(query), gomock.Eq(page)).
+		Return([]models.Entry{{ID: utils.CreateID(), Content: "<p>Kernel</p>"}}, "", nil)
+
+	req := httptest.NewRequest(echo.GET, "/?q=kernel+news&feedId=feed&count=2&markedAs=unread", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries/search")
+
+	c.NoError(c.controller.SearchEntries(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.NotContains(rec.Body.String(), "Kernel")
+}
+
+func (c *SearchControllerSuite) TestSearchEntriesWithoutQuery() {
+	c.mockSearch.EXPECT().
+		Entries(gomock.Eq(c.user.ID), gomock.Any(), gomock.Any()).
+		Return(nil, "", services.ErrSearchQueryEmpty)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/entries/search")
+
+	c.EqualError(
+		c.controller.SearchEntries(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "search query is required").Error(),
+	)
+}
+
+func (c *SearchControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockSearch = services.NewMockSearch(c.ctrl)
+
+	c.controller = rest.NewSearchController(c.mockSearch, c.e)
+}
+
+func (c *SearchControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestSearchControllerSuite(t *testing.T) {
+	suite.Run(t, new(SearchControllerSuite))
+}
@@ -24,6 +24,8 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
 )
 
 const (
@@ -36,12 +38,51 @@ type (
 		Count          int    `query:"count"`
 	}
 
+	// feedParams are the fields of a feed a client can set. Credentials are
+	// accepted but, unlike the rest of the feed, never sent back.
+	feedParams struct {
+		models.Feed
+
+		Credentials *models.FeedCredentials `json:"credentials"`
+	}
+
+	// feedUpdateParams are the fields of a feed a client can edit. Fields left
+	// out of a request are nil and keep their value.
+	feedUpdateParams struct {
+		Subscription *string                  `json:"subscription"`
+		DisplayTitle *string                  `json:"displayTitle"`
+		Notes        *string                  `json:"notes"`
+		CategoryID   *string                  `json:"categoryId"`
+		KeepDays     *int                     `json:"keepDays"`
+		KeepLast     *int                     `json:"keepLast"`
+		Selectors    *models.ScraperSelectors `json:"selectors"`
+		Processors   *string                  `json:"processors"`
+		Status       *string                  `json:"status"`
+		Credentials  *models.FeedCredentials  `json:"credentials"`
+	}
+
+	// webhookWithSecret is a webhook along with its secret. The secret is
+	// accepted whenever a webhook is set but only sent back when the webhook
+	// is created or its secret is rotated.
+	webhookWithSecret struct {
+		models.Webhook
+
+		Secret string `json:"secret,omitempty"`
+	}
+
+	listFeedsParams struct {
+		ContinuationID string `query:"continuationId"`
+		Count          int    `query:"count"`
+		Status         string `query:"status"`
+	}
+
 	listEntriesParams struct {
 		ContinuationID string `query:"continuationId"`
 		Count          int    `query:"count"`
 		Marker         string `query:"markedAs"`
 		Saved          bool   `query:"saved"`
 		OrderBy        string `query:"orderBy"`
+		Fields         string `query:"fields"`
 	}
 
 	Controller struct {
@@ -52,3 +93,31 @@ type (
 func convertOrderByParamToValue(param string) bool {
 	return !(param != "" && strings.EqualFold(param, "oldest"))
 }
+
+// selectEntryFields strips the summary and content of entries unless they were
+// requested through a comma separated fields parameter. This keeps list responses
+// small for clients that only render titles.
+func selectEntryFields(entries []models.Entry, fields string) []models.Entry {
+	var withSummary, withContent bool
+
+	for _, field := range strings.Split(fields, ",") {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "summary":
+			withSummary = true
+		case "content":
+			withContent = true
+		}
+	}
+
+	for idx := range entries {
+		if !withSummary {
+			entries[idx].Summary = ""
+		}
+
+		if !withContent {
+			entries[idx].Content = ""
+		}
+	}
+
+	return entries
+}
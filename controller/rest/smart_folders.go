@@ -0,0 +1,204 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	SmartFoldersController struct {
+		Controller
+
+		folders services.SmartFolders
+	}
+)
+
+func NewSmartFoldersController(service services.SmartFolders, e *echo.Echo) *SmartFoldersController {
+	v1 := e.Group("v1")
+
+	controller := SmartFoldersController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.POST("/smart-folders", controller.NewSmartFolder)
+	v1.GET("/smart-folders", controller.GetSmartFolders)
+	v1.GET("/smart-folders/:smartFolderID", controller.GetSmartFolder)
+	v1.PUT("/smart-folders/:smartFolderID", controller.EditSmartFolder)
+	v1.DELETE("/smart-folders/:smartFolderID", controller.DeleteSmartFolder)
+	v1.GET("/smart-folders/:smartFolderID/entries", controller.GetSmartFolderEntries)
+	v1.PUT("/smart-folders/:smartFolderID/mark", controller.MarkSmartFolder)
+	v1.GET("/smart-folders/:smartFolderID/stats", controller.GetSmartFolderStats)
+
+	return &controller
+}
+
+// NewSmartFolder creates a new SmartFolder
+func (s *SmartFoldersController) NewSmartFolder(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	folder := models.SmartFolder{}
+	if err := c.Bind(&folder); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	newFolder, err := s.folders.New(userID, folder)
+	if err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.JSON(http.StatusCreated, newFolder)
+}
+
+// GetSmartFolders returns a list of SmartFolders owned by a user
+func (s *SmartFoldersController) GetSmartFolders(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	folders, next := s.folders.List(userID, models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"smartFolders":   folders,
+		"continuationId": next,
+	})
+}
+
+// GetSmartFolder with id
+func (s *SmartFoldersController) GetSmartFolder(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	folder, found := s.folders.SmartFolder(userID, c.Param("smartFolderID"))
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, folder)
+}
+
+// EditSmartFolder with id
+func (s *SmartFoldersController) EditSmartFolder(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	folder := models.SmartFolder{}
+	if err := c.Bind(&folder); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	folder.ID = c.Param("smartFolderID")
+
+	newFolder, err := s.folders.Update(userID, folder)
+	if err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.JSON(http.StatusOK, newFolder)
+}
+
+// DeleteSmartFolder with id
+func (s *SmartFoldersController) DeleteSmartFolder(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	if err := s.folders.Delete(userID, c.Param("smartFolderID")); err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetSmartFolderEntries returns a list of Entries that match a SmartFolder
+func (s *SmartFoldersController) GetSmartFolderEntries(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := listEntriesParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	page := models.Page{
+		FilterID:       c.Param("smartFolderID"),
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+		Newest:         convertOrderByParamToValue(params.OrderBy),
+		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
+	}
+
+	entries, next, err := s.folders.Entries(userID, page)
+	if err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"entries":        selectEntryFields(entries, params.Fields),
+		"continuationID": next,
+	})
+}
+
+// MarkSmartFolder applies a Marker to the Entries that match a SmartFolder
+func (s *SmartFoldersController) MarkSmartFolder(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	marker := models.MarkerFromString(c.FormValue("as"))
+
+	if err := s.folders.Mark(userID, c.Param("smartFolderID"), marker); err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetSmartFolderStats returns statistics on the Entries that match a SmartFolder
+func (s *SmartFoldersController) GetSmartFolderStats(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	stats, err := s.folders.Stats(userID, c.Param("smartFolderID"))
+	if err != nil {
+		return smartFolderError(err)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+func smartFolderError(err error) error {
+	switch err {
+	case services.ErrSmartFolderNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrSmartFolderCriteria:
+		return echo.NewHTTPError(http.StatusBadRequest,
+			"'marker' must be read, unread or any and 'days' must not be negative")
+	case services.ErrSmartFolderScope:
+		return echo.NewHTTPError(http.StatusBadRequest, "smart folder refers to a missing feed, category or tag")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,300 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	SmartFoldersControllerSuite struct {
+		suite.Suite
+
+		ctrl             *gomock.Controller
+		mockSmartFolders *services.MockSmartFolders
+
+		controller *rest.SmartFoldersController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *SmartFoldersControllerSuite) TestNewSmartFolder() {
+	c.mockSmartFolders.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.SmartFolder{Name: "security", Marker: "unread", Title: "CVE", Days: 7})).
+		Return(models.SmartFolder{ID: utils.CreateID()}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(
+		`{ "name": "security", "marker": "unread", "title": "CVE", "days": 7 }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/smart-folders")
+
+	c.NoError(c.controller.NewSmartFolder(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+}
+
+func (c *SmartFoldersControllerSuite) TestNewInvalidSmartFolder() {
+	c.mockSmartFolders.EXPECT().
+		New(gomock.Any(), gomock.Any()).
+		Return(models.SmartFolder{}, services.ErrSmartFolderCriteria)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{ "days": -1 }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/smart-folders")
+
+	c.EqualError(
+		c.controller.NewSmartFolder(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'marker' must be read, unread or any and 'days' must not be negative").
+			Error(),
+	)
+}
+
+func (c *SmartFoldersControllerSuite) TestGetSmartFolders() {
+	c.mockSmartFolders.EXPECT().
+		List(gomock.Eq(c.user.ID), gomock.Eq(models.Page{Count: 1})).
+		Return([]models.SmartFolder{{ID: utils.CreateID()}}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/smart-folders")
+
+	c.NoError(c.controller.GetSmartFolders(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *SmartFoldersControllerSuite) TestGetUnknownSmartFolder() {
+	c.mockSmartFolders.EXPECT().SmartFolder(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(models.SmartFolder{}, false)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/smart-folders/:smartFolderID")
+
+	c.EqualError(
+		c.controller.GetSmartFolder(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *SmartFoldersControllerSuite) TestEditSmartFolder() {
+	folderID := utils.CreateID()
+
+	c.mockSmartFolders.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(models.SmartFolder{ID: folderID, Name: "saved", Saved: true})).
+		Return(models.SmartFolder{ID: folderID}, nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "name": "saved", "saved": true }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues(folderID)
+	ctx.SetPath("/v1/smart-folders/:smartFolderID")
+
+	c.NoError(c.controller.EditSmartFolder(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *SmartFoldersControllerSuite) TestEditSmartFolderWithMissingTag() {
+	c.mockSmartFolders.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Return(models.SmartFolder{}, services.ErrSmartFolderScope)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "tagId": "bogus" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues(utils.CreateID())
+	ctx.SetPath("/v1/smart-folders/:smartFolderID")
+
+	c.EqualError(
+		c.controller.EditSmartFolder(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "smart folder refers to a missing feed, category or tag").Error(),
+	)
+}
+
+func (c *SmartFoldersControllerSuite) TestDeleteUnknownSmartFolder() {
+	c.mockSmartFolders.EXPECT().Delete(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(services.ErrSmartFolderNotFound)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/smart-folders/:smartFolderID")
+
+	c.EqualError(
+		c.controller.DeleteSmartFolder(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *SmartFoldersControllerSuite) TestGetSmartFolderEntries() {
+	folderID := utils.CreateID()
+
+	c.mockSmartFolders.EXPECT().
+		Entries(gomock.Eq(c.user.ID), gomock.Eq(models.Page{
+			FilterID: folderID,
+			Count:    2,
+			Newest:   true,
+			Marker:   models.MarkerAny,
+		})).
+		Return([]models.Entry{{ID: utils.CreateID()}}, "", nil)
+
+	req := httptest.NewRequest(echo.GET, "/?count=2", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues(folderID)
+	ctx.SetPath("/v1/smart-folders/:smartFolderID/entries")
+
+	c.NoError(c.controller.GetSmartFolderEntries(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *SmartFoldersControllerSuite) TestGetUnknownSmartFolderEntries() {
+	c.mockSmartFolders.EXPECT().
+		Entries(gomock.Eq(c.user.ID), gomock.Any()).
+		Return(nil, "", services.ErrSmartFolderNotFound)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/smart-folders/:smartFolderID/entries")
+
+	c.EqualError(
+		c.controller.GetSmartFolderEntries(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *SmartFoldersControllerSuite) TestMarkSmartFolder() {
+	folderID := utils.CreateID()
+
+	c.mockSmartFolders.EXPECT().Mark(gomock.Eq(c.user.ID), gomock.Eq(folderID), gomock.Eq(models.MarkerRead)).Return(nil)
+
+	req := httptest.NewRequest(echo.PUT, "/?as=read", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues(folderID)
+	ctx.SetPath("/v1/smart-folders/:smartFolderID/mark")
+
+	c.NoError(c.controller.MarkSmartFolder(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *SmartFoldersControllerSuite) TestGetSmartFolderStats() {
+	folderID := utils.CreateID()
+
+	c.mockSmartFolders.EXPECT().
+		Stats(gomock.Eq(c.user.ID), gomock.Eq(folderID)).
+		Return(models.Stats{Unread: 2, Total: 2}, nil)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("smartFolderID")
+	ctx.SetParamValues(folderID)
+	ctx.SetPath("/v1/smart-folders/:smartFolderID/stats")
+
+	c.NoError(c.controller.GetSmartFolderStats(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.JSONEq(`{"unread": 2, "read": 0, "saved": 0, "total": 2}`, rec.Body.String())
+}
+
+func (c *SmartFoldersControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockSmartFolders = services.NewMockSmartFolders(c.ctrl)
+
+	c.controller = rest.NewSmartFoldersController(c.mockSmartFolders, c.e)
+}
+
+func (c *SmartFoldersControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestSmartFoldersControllerSuite(t *testing.T) {
+	suite.Run(t, new(SmartFoldersControllerSuite))
+}
@@ -0,0 +1,181 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+
+	"github.com/jmartinezhern/syndication/services"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+
+	// streamKeepAlive is how often a comment is sent to idle event stream
+	// clients so that proxies do not close their connection
+	streamKeepAlive = time.Second * 30
+)
+
+type (
+	StreamController struct {
+		Controller
+
+		stream services.Stream
+	}
+)
+
+func NewStreamController(service services.Stream, e *echo.Echo) *StreamController {
+	v1 := e.Group("v1")
+
+	controller := StreamController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.GET("/stream", controller.Stream)
+
+	return &controller
+}
+
+// Stream pushes new entries, marks and unread count changes to the client as
+// Server-Sent Events, or as JSON messages over a WebSocket if the client asks
+// to upgrade the connection. Clients resume after the last message they received
+// by sending its ID in the Last-Event-ID header or the lastEventId parameter.
+// Browsers, which cannot set headers on these requests, may pass the access
+// token in the access_token parameter.
+func (s *StreamController) Stream(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	lastID := c.Request().Header.Get(lastEventIDHeader)
+	if lastID == "" {
+		lastID = c.QueryParam("lastEventId")
+	}
+
+	sub := s.stream.Subscribe(userID, lastID)
+	defer sub.Close()
+
+	if strings.EqualFold(c.Request().Header.Get(echo.HeaderUpgrade), "websocket") {
+		websocket.Server{
+			Handshake: checkOrigin,
+			Handler: func(ws *websocket.Conn) {
+				streamWebSocket(ws, sub)
+			},
+		}.ServeHTTP(c.Response(), c.Request())
+
+		return nil
+	}
+
+	return streamEvents(c, sub)
+}
+
+// checkOrigin rejects WebSocket connections opened by pages served from another
+// host. Clients that are not browsers do not send an Origin.
+func checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+
+	if origin != nil && origin.Host != req.Host {
+		return websocket.ErrBadWebSocketOrigin
+	}
+
+	config.Origin = origin
+
+	return nil
+}
+
+func streamEvents(c echo.Context, sub services.StreamSubscription) error {
+	res := c.Response()
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return nil
+			}
+
+			data, err := json.Marshal(msg.Data)
+			if err != nil {
+				log.Error(err)
+				return nil
+			}
+
+			if _, err := fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, data); err != nil {
+				return nil
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+		case <-c.Request().Context().Done():
+			return nil
+		}
+
+		res.Flush()
+	}
+}
+
+func streamWebSocket(ws *websocket.Conn, sub services.StreamSubscription) {
+	defer ws.Close()
+
+	// Clients are not expected to send anything. Reading tells when they leave.
+	closed := make(chan struct{})
+
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, ws)
+
+		close(closed)
+	}()
+
+	for {
+		select {
+		case msg, ok := <-sub.Messages():
+			if !ok {
+				return
+			}
+
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
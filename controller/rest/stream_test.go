@@ -0,0 +1,191 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/websocket"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	StreamControllerSuite struct {
+		suite.Suite
+
+		ctrl       *gomock.Controller
+		mockStream *services.MockStream
+		mockSub    *services.MockStreamSubscription
+
+		controller *rest.StreamController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+// messages returns a closed channel holding msgs
+func messages(msgs ...services.StreamMessage) <-chan services.StreamMessage {
+	ch := make(chan services.StreamMessage, len(msgs))
+	for _, msg := range msgs {
+		ch <- msg
+	}
+
+	close(ch)
+
+	return ch
+}
+
+func (c *StreamControllerSuite) TestStreamEvents() {
+	c.mockStream.EXPECT().Subscribe(gomock.Eq(c.user.ID), gomock.Eq("")).Return(c.mockSub)
+	c.mockSub.EXPECT().Messages().Return(messages(
+		services.StreamMessage{ID: "1", Type: services.StreamEntry, Data: models.Entry{ID: "entry", Title: "New"}},
+		services.StreamMessage{ID: "2", Type: services.StreamUnread, Data: services.StreamUnreadDelta{Delta: 1}},
+	)).AnyTimes()
+	c.mockSub.EXPECT().Close()
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetPath("/v1/stream")
+
+	c.NoError(c.controller.Stream(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Equal("text/event-stream", rec.Header().Get(echo.HeaderContentType))
+
+	body := rec.Body.String()
+	c.Contains(body, "id: 1\nevent: entry\ndata: {\"id\":\"entry\",")
+	c.Contains(body, "id: 2\nevent: unread\ndata: {\"delta\":1}\n\n")
+}
+
+func (c *StreamControllerSuite) TestStreamEventsResumes() {
+	c.mockStream.EXPECT().Subscribe(gomock.Eq(c.user.ID), gomock.Eq("1")).Return(c.mockSub)
+	c.mockSub.EXPECT().Messages().Return(messages()).AnyTimes()
+	c.mockSub.EXPECT().Close()
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetPath("/v1/stream")
+
+	c.NoError(c.controller.Stream(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *StreamControllerSuite) TestStreamWebSocket() {
+	c.e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(userContextKey, c.user.ID)
+			return next(ctx)
+		}
+	})
+
+	c.mockStream.EXPECT().Subscribe(gomock.Eq(c.user.ID), gomock.Eq("1")).Return(c.mockSub)
+	c.mockSub.EXPECT().Messages().Return(messages(
+		services.StreamMessage{ID: "2", Type: services.StreamMark, Data: services.StreamMarkChange{EntryID: "entry"}},
+	)).AnyTimes()
+
+	closed := make(chan struct{})
+	c.mockSub.EXPECT().Close().Do(func() { close(closed) })
+
+	server := httptest.NewServer(c.e)
+	defer server.Close()
+
+	ws, err := websocket.Dial(
+		"ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stream?lastEventId=1", "", server.URL)
+	c.Require().NoError(err)
+
+	defer ws.Close()
+
+	var msg struct {
+		ID   string                    `json:"id"`
+		Type string                    `json:"type"`
+		Data services.StreamMarkChange `json:"data"`
+	}
+
+	c.Require().NoError(websocket.JSON.Receive(ws, &msg))
+	c.Equal("2", msg.ID)
+	c.Equal(services.StreamMark, msg.Type)
+	c.Equal("entry", msg.Data.EntryID)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		c.Fail("subscription was not closed")
+	}
+}
+
+func (c *StreamControllerSuite) TestStreamWebSocketRejectsOtherOrigins() {
+	c.e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(userContextKey, c.user.ID)
+			return next(ctx)
+		}
+	})
+
+	c.mockStream.EXPECT().Subscribe(gomock.Eq(c.user.ID), gomock.Eq("")).Return(c.mockSub)
+	c.mockSub.EXPECT().Close()
+
+	server := httptest.NewServer(c.e)
+	defer server.Close()
+
+	_, err := websocket.Dial(
+		"ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stream", "", "http://example.com")
+	c.Error(err)
+}
+
+func (c *StreamControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockStream = services.NewMockStream(c.ctrl)
+	c.mockSub = services.NewMockStreamSubscription(c.ctrl)
+
+	c.controller = rest.NewStreamController(c.mockStream, c.e)
+}
+
+func (c *StreamControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestStreamControllerSuite(t *testing.T) {
+	suite.Run(t, new(StreamControllerSuite))
+}
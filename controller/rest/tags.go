@@ -183,12 +183,13 @@ func (s *TagsController) GetEntriesFromTag(c echo.Context) error {
 		Count:          params.Count,
 		Newest:         convertOrderByParamToValue(params.OrderBy),
 		Marker:         models.MarkerFromString(params.Marker),
+		Saved:          params.Saved,
 	}
 
 	entries, next := s.tags.Entries(userID, page)
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"entries":        entries,
+		"entries":        selectEntryFields(entries, params.Fields),
 		"continuationID": next,
 	})
 }
@@ -31,9 +31,18 @@ import (
 
 var (
 	unauthorizedPaths = []string{
+		"/feeds/out/:token",
 		"/v1/auth/login",
 		"/v1/auth/register",
 		"/v1/auth/renew",
+		"/v1/websub/:subscriptionID",
+	}
+
+	// queryTokenPaths also accept the access token in the access_token query
+	// parameter, since browsers cannot set headers on EventSource and WebSocket
+	// requests
+	queryTokenPaths = []string{
+		"/v1/stream",
 	}
 )
 
@@ -46,18 +55,39 @@ type (
 )
 
 func isPathUnauthorized(c echo.Context) bool {
+	return pathIn(unauthorizedPaths, c)
+}
+
+func acceptsQueryToken(c echo.Context) bool {
+	return pathIn(queryTokenPaths, c)
+}
+
+// pathIn reports whether the path of the route c matched is one of paths, which are sorted
+func pathIn(paths []string, c echo.Context) bool {
 	path := c.Path()
-	i := sort.SearchStrings(unauthorizedPaths, path)
+	i := sort.SearchStrings(paths, path)
 
-	return i < len(unauthorizedPaths) && unauthorizedPaths[i] == path
+	return i < len(paths) && paths[i] == path
 }
 
 func NewAuthController(service services.Auth, secret string, allowRegistration bool, e *echo.Echo) *AuthController {
 	e.Use(middleware.JWTWithConfig(middleware.JWTConfig{
-		Skipper:       isPathUnauthorized,
+		Skipper: func(c echo.Context) bool {
+			return isPathUnauthorized(c) || acceptsQueryToken(c)
+		},
+		SigningKey:    []byte(secret),
+		SigningMethod: "HS256",
+		ContextKey:    "token",
+	}))
+
+	e.Use(middleware.JWTWithConfig(middleware.JWTConfig{
+		Skipper: func(c echo.Context) bool {
+			return !acceptsQueryToken(c)
+		},
 		SigningKey:    []byte(secret),
 		SigningMethod: "HS256",
 		ContextKey:    "token",
+		TokenLookup:   "header:" + echo.HeaderAuthorization + ",query:access_token",
 	}))
 
 	v1 := e.Group("v1")
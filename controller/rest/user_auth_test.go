@@ -25,6 +25,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/suite"
@@ -193,6 +194,39 @@ func (c *AuthControllerSuite) TestRenew() {
 	c.NoError(c.controller.Renew(ctx))
 }
 
+func (c *AuthControllerSuite) TestQueryTokenOnStream() {
+	c.e.GET("/v1/stream", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, ctx.Get("user").(string))
+	})
+
+	rec := httptest.NewRecorder()
+	c.e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/v1/stream?access_token="+c.accessToken("user-id"), nil))
+
+	c.Equal(http.StatusOK, rec.Code)
+	c.Equal("user-id", rec.Body.String())
+}
+
+func (c *AuthControllerSuite) TestQueryTokenOnlyOnStream() {
+	c.e.GET("/v1/feeds", func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	c.e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/v1/feeds?access_token="+c.accessToken("user-id"), nil))
+
+	c.Equal(http.StatusBadRequest, rec.Code)
+}
+
+func (c *AuthControllerSuite) accessToken(userID string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":  userID,
+		"type": "access",
+	}).SignedString([]byte("secret"))
+	c.Require().NoError(err)
+
+	return token
+}
+
 func (c *AuthControllerSuite) SetupTest() {
 	c.ctrl = gomock.NewController(c.T())
 
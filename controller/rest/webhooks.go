@@ -0,0 +1,205 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	WebhooksController struct {
+		Controller
+
+		webhooks services.Webhooks
+	}
+)
+
+func NewWebhooksController(service services.Webhooks, e *echo.Echo) *WebhooksController {
+	v1 := e.Group("v1")
+
+	controller := WebhooksController{
+		Controller{
+			e,
+		},
+		service,
+	}
+
+	v1.POST("/webhooks", controller.NewWebhook)
+	v1.GET("/webhooks", controller.GetWebhooks)
+	v1.GET("/webhooks/:webhookID", controller.GetWebhook)
+	v1.PUT("/webhooks/:webhookID", controller.EditWebhook)
+	v1.DELETE("/webhooks/:webhookID", controller.DeleteWebhook)
+	v1.GET("/webhooks/:webhookID/deliveries", controller.GetDeliveries)
+	v1.POST("/webhooks/:webhookID/secret", controller.RotateWebhookSecret)
+	v1.POST("/webhooks/:webhookID/test", controller.TestWebhook)
+
+	return &controller
+}
+
+// NewWebhook creates a new Webhook. Its secret is only sent back here and when it is rotated.
+func (s *WebhooksController) NewWebhook(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := webhookWithSecret{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	params.Webhook.Secret = params.Secret
+
+	newHook, err := s.webhooks.New(userID, params.Webhook)
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(http.StatusCreated, webhookWithSecret{Webhook: newHook, Secret: newHook.Secret})
+}
+
+// GetWebhooks returns a list of Webhooks owned by a user
+func (s *WebhooksController) GetWebhooks(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	hooks, next := s.webhooks.List(userID, models.Page{
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"webhooks":       hooks,
+		"continuationId": next,
+	})
+}
+
+// GetWebhook with id
+func (s *WebhooksController) GetWebhook(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	hook, found := s.webhooks.Webhook(userID, c.Param("webhookID"))
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	return c.JSON(http.StatusOK, hook)
+}
+
+// EditWebhook with id
+func (s *WebhooksController) EditWebhook(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := webhookWithSecret{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	hook := params.Webhook
+	hook.ID = c.Param("webhookID")
+	hook.Secret = params.Secret
+
+	newHook, err := s.webhooks.Update(userID, hook)
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(http.StatusOK, newHook)
+}
+
+// DeleteWebhook with id
+func (s *WebhooksController) DeleteWebhook(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	err := s.webhooks.Delete(userID, c.Param("webhookID"))
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RotateWebhookSecret replaces the secret of a Webhook with id with a new one and returns it
+func (s *WebhooksController) RotateWebhookSecret(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	hook, err := s.webhooks.RotateSecret(userID, c.Param("webhookID"))
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(http.StatusOK, webhookWithSecret{Webhook: hook, Secret: hook.Secret})
+}
+
+// GetDeliveries returns the delivery log of a Webhook with id, the most recent first
+func (s *WebhooksController) GetDeliveries(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	params := paginationParams{}
+	if err := c.Bind(&params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	deliveries, next, err := s.webhooks.Deliveries(userID, models.Page{
+		FilterID:       c.Param("webhookID"),
+		ContinuationID: params.ContinuationID,
+		Count:          params.Count,
+	})
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"deliveries":     deliveries,
+		"continuationId": next,
+	})
+}
+
+// TestWebhook queues a test event for a Webhook with id. The returned delivery
+// can be followed in the delivery log.
+func (s *WebhooksController) TestWebhook(c echo.Context) error {
+	userID := c.Get(userContextKey).(string)
+
+	delivery, err := s.webhooks.Test(userID, c.Param("webhookID"))
+	if err != nil {
+		return webhookError(err)
+	}
+
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+func webhookError(err error) error {
+	switch err {
+	case services.ErrWebhookNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrWebhookURL:
+		return echo.NewHTTPError(http.StatusBadRequest, "'url' must be an absolute http or https URL")
+	case services.ErrWebhookEvents:
+		return echo.NewHTTPError(http.StatusBadRequest, "'events' must only list known event types")
+	case services.ErrWebhookScope:
+		return echo.NewHTTPError(http.StatusBadRequest, "'feedId', 'categoryId' and 'tagId' must name existing resources")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+}
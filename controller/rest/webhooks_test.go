@@ -0,0 +1,300 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	WebhooksControllerSuite struct {
+		suite.Suite
+
+		ctrl         *gomock.Controller
+		mockWebhooks *services.MockWebhooks
+
+		controller *rest.WebhooksController
+		e          *echo.Echo
+		user       *models.User
+	}
+)
+
+func (c *WebhooksControllerSuite) TestNewWebhook() {
+	c.mockWebhooks.EXPECT().
+		New(gomock.Eq(c.user.ID), gomock.Eq(models.Webhook{
+			URL:    "https://example.com/hook",
+			Events: "entry.created",
+			Secret: "secret",
+		})).
+		Return(models.Webhook{ID: utils.CreateID(), Secret: "secret"}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(
+		`{ "url": "https://example.com/hook", "events": "entry.created", "secret": "secret" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/webhooks")
+
+	c.NoError(c.controller.NewWebhook(ctx))
+	c.Equal(http.StatusCreated, rec.Code)
+	c.Contains(rec.Body.String(), `"secret":"secret"`)
+}
+
+func (c *WebhooksControllerSuite) TestNewInvalidWebhook() {
+	c.mockWebhooks.EXPECT().New(gomock.Any(), gomock.Any()).Return(models.Webhook{}, services.ErrWebhookURL)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader(`{ "url": "bogus" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/webhooks")
+
+	c.EqualError(
+		c.controller.NewWebhook(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'url' must be an absolute http or https URL").Error(),
+	)
+}
+
+func (c *WebhooksControllerSuite) TestGetWebhooks() {
+	c.mockWebhooks.EXPECT().
+		List(gomock.Eq(c.user.ID), gomock.Eq(models.Page{Count: 1})).
+		Return([]models.Webhook{{ID: utils.CreateID()}}, "")
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+
+	ctx.SetPath("/v1/webhooks")
+
+	c.NoError(c.controller.GetWebhooks(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *WebhooksControllerSuite) TestGetWebhookHidesSecret() {
+	hookID := utils.CreateID()
+
+	c.mockWebhooks.EXPECT().
+		Webhook(gomock.Eq(c.user.ID), gomock.Eq(hookID)).
+		Return(models.Webhook{ID: hookID, Secret: "secret"}, true)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(hookID)
+	ctx.SetPath("/v1/webhooks/:webhookID")
+
+	c.NoError(c.controller.GetWebhook(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.NotContains(rec.Body.String(), "secret")
+}
+
+func (c *WebhooksControllerSuite) TestGetUnknownWebhook() {
+	c.mockWebhooks.EXPECT().Webhook(gomock.Eq(c.user.ID), gomock.Eq("bogus")).Return(models.Webhook{}, false)
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/webhooks/:webhookID")
+
+	c.EqualError(
+		c.controller.GetWebhook(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *WebhooksControllerSuite) TestEditWebhook() {
+	hookID := utils.CreateID()
+
+	c.mockWebhooks.EXPECT().
+		Update(gomock.Eq(c.user.ID), gomock.Eq(models.Webhook{ID: hookID, URL: "https://example.com/hook"})).
+		Return(models.Webhook{ID: hookID}, nil)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{ "url": "https://example.com/hook" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(hookID)
+	ctx.SetPath("/v1/webhooks/:webhookID")
+
+	c.NoError(c.controller.EditWebhook(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+}
+
+func (c *WebhooksControllerSuite) TestEditWebhookWithUnknownScope() {
+	c.mockWebhooks.EXPECT().Update(gomock.Any(), gomock.Any()).Return(models.Webhook{}, services.ErrWebhookScope)
+
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(
+		`{ "url": "https://example.com/hook", "feedId": "bogus" }`))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(utils.CreateID())
+	ctx.SetPath("/v1/webhooks/:webhookID")
+
+	c.EqualError(
+		c.controller.EditWebhook(ctx),
+		echo.NewHTTPError(http.StatusBadRequest, "'feedId', 'categoryId' and 'tagId' must name existing resources").Error(),
+	)
+}
+
+func (c *WebhooksControllerSuite) TestDeleteUnknownWebhook() {
+	c.mockWebhooks.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(services.ErrWebhookNotFound)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues("bogus")
+	ctx.SetPath("/v1/webhooks/:webhookID")
+
+	c.EqualError(
+		c.controller.DeleteWebhook(ctx),
+		echo.NewHTTPError(http.StatusNotFound).Error(),
+	)
+}
+
+func (c *WebhooksControllerSuite) TestRotateWebhookSecret() {
+	hookID := utils.CreateID()
+
+	c.mockWebhooks.EXPECT().
+		RotateSecret(gomock.Eq(c.user.ID), gomock.Eq(hookID)).
+		Return(models.Webhook{ID: hookID, Secret: "rotated"}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(hookID)
+	ctx.SetPath("/v1/webhooks/:webhookID/secret")
+
+	c.NoError(c.controller.RotateWebhookSecret(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Contains(rec.Body.String(), `"secret":"rotated"`)
+}
+
+func (c *WebhooksControllerSuite) TestGetDeliveries() {
+	hookID := utils.CreateID()
+
+	c.mockWebhooks.EXPECT().
+		Deliveries(gomock.Eq(c.user.ID), gomock.Eq(models.Page{FilterID: hookID, Count: 1})).
+		Return([]models.WebhookDelivery{{ID: utils.CreateID()}}, "", nil)
+
+	req := httptest.NewRequest(echo.GET, "/?count=1", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(hookID)
+	ctx.SetPath("/v1/webhooks/:webhookID/deliveries")
+
+	c.NoError(c.controller.GetDeliveries(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Contains(rec.Body.String(), `"deliveries"`)
+}
+
+func (c *WebhooksControllerSuite) TestTestWebhook() {
+	hookID := utils.CreateID()
+
+	c.mockWebhooks.EXPECT().
+		Test(gomock.Eq(c.user.ID), gomock.Eq(hookID)).
+		Return(models.WebhookDelivery{ID: utils.CreateID(), Status: models.WebhookDeliveryPending}, nil)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.Set(userContextKey, c.user.ID)
+	ctx.SetParamNames("webhookID")
+	ctx.SetParamValues(hookID)
+	ctx.SetPath("/v1/webhooks/:webhookID/test")
+
+	c.NoError(c.controller.TestWebhook(ctx))
+	c.Equal(http.StatusAccepted, rec.Code)
+}
+
+func (c *WebhooksControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.user = &models.User{
+		ID: utils.CreateID(),
+	}
+
+	c.mockWebhooks = services.NewMockWebhooks(c.ctrl)
+
+	c.controller = rest.NewWebhooksController(c.mockWebhooks, c.e)
+}
+
+func (c *WebhooksControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestWebhooksControllerSuite(t *testing.T) {
+	suite.Run(t, new(WebhooksControllerSuite))
+}
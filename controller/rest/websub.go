@@ -0,0 +1,115 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/services"
+)
+
+// maxPushedContentBytes limits the size of content pushed by WebSub hubs
+const maxPushedContentBytes = 10 << 20
+
+type (
+	WebSubController struct {
+		Controller
+
+		subscriber services.WebSubSubscriber
+	}
+)
+
+// NewWebSubController registers the callback WebSub hubs use to verify subscriptions
+// and push content. Hubs are not authenticated, they are identified by the
+// subscription in the callback URL and the signature of the content instead.
+func NewWebSubController(subscriber services.WebSubSubscriber, e *echo.Echo) *WebSubController {
+	v1 := e.Group("v1")
+
+	controller := WebSubController{
+		Controller{
+			e,
+		},
+		subscriber,
+	}
+
+	v1.GET("/websub/:subscriptionID", controller.VerifySubscription)
+	v1.POST("/websub/:subscriptionID", controller.ReceiveContent)
+
+	return &controller
+}
+
+// VerifySubscription answers the challenge a hub sends to verify a subscription request
+func (s *WebSubController) VerifySubscription(c echo.Context) error {
+	leaseSeconds, err := strconv.Atoi(c.QueryParam("hub.lease_seconds"))
+	if err != nil {
+		leaseSeconds = 0
+	}
+
+	challenge, err := s.subscriber.Verify(
+		c.Param("subscriptionID"),
+		c.QueryParam("hub.mode"),
+		c.QueryParam("hub.topic"),
+		c.QueryParam("hub.challenge"),
+		leaseSeconds,
+	)
+	if err == services.ErrWebSubNotFound {
+		return echo.NewHTTPError(http.StatusNotFound)
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	return c.String(http.StatusOK, challenge)
+}
+
+// ReceiveContent ingests content pushed by a hub
+func (s *WebSubController) ReceiveContent(c echo.Context) error {
+	if c.Request().ContentLength > maxPushedContentBytes {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request().Body, maxPushedContentBytes+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	// Content that is cut short cannot be parsed
+	if int64(len(body)) > maxPushedContentBytes {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+
+	err = s.subscriber.Deliver(c.Param("subscriptionID"), c.Request().Header.Get("X-Hub-Signature"), body)
+	switch err {
+	case nil:
+	case services.ErrWebSubNotFound:
+		return echo.NewHTTPError(http.StatusNotFound)
+	case services.ErrWebSubSignature:
+		// Hubs must not learn whether the signature matched, so the content
+		// is acknowledged and ignored
+		log.Warn(err)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
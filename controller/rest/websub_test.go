@@ -0,0 +1,206 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/services"
+)
+
+type (
+	WebSubControllerSuite struct {
+		suite.Suite
+
+		ctrl           *gomock.Controller
+		mockSubscriber *services.MockWebSubSubscriber
+
+		controller *rest.WebSubController
+		e          *echo.Echo
+	}
+)
+
+func (c *WebSubControllerSuite) TestVerifySubscription() {
+	c.mockSubscriber.EXPECT().
+		Verify(gomock.Eq("sub"), gomock.Eq("subscribe"), gomock.Eq("http://example.com/feed"), gomock.Eq("abc"),
+			gomock.Eq(3600)).
+		Return("abc", nil)
+
+	req := httptest.NewRequest(
+		echo.GET,
+		"/?hub.mode=subscribe&hub.topic=http%3A%2F%2Fexample.com%2Ffeed&hub.challenge=abc&hub.lease_seconds=3600",
+		nil,
+	)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("sub")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.NoError(c.controller.VerifySubscription(ctx))
+	c.Equal(http.StatusOK, rec.Code)
+	c.Equal("abc", rec.Body.String())
+}
+
+func (c *WebSubControllerSuite) TestVerifyMissingSubscription() {
+	c.mockSubscriber.EXPECT().
+		Verify(gomock.Eq("bogus"), gomock.Eq("subscribe"), gomock.Eq(""), gomock.Eq("abc"), gomock.Eq(0)).
+		Return("", services.ErrWebSubNotFound)
+
+	req := httptest.NewRequest(echo.GET, "/?hub.mode=subscribe&hub.challenge=abc", nil)
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.EqualError(c.controller.VerifySubscription(ctx), echo.NewHTTPError(http.StatusNotFound).Error())
+}
+
+func (c *WebSubControllerSuite) TestReceiveContent() {
+	c.mockSubscriber.EXPECT().
+		Deliver(gomock.Eq("sub"), gomock.Eq("sha256=abc"), gomock.Eq([]byte("<rss></rss>"))).
+		Return(nil)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader("<rss></rss>"))
+	req.Header.Set("X-Hub-Signature", "sha256=abc")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("sub")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.NoError(c.controller.ReceiveContent(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *WebSubControllerSuite) TestReceiveContentWithBadSignature() {
+	c.mockSubscriber.EXPECT().
+		Deliver(gomock.Eq("sub"), gomock.Eq("sha256=bogus"), gomock.Any()).
+		Return(services.ErrWebSubSignature)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader("<rss></rss>"))
+	req.Header.Set("X-Hub-Signature", "sha256=bogus")
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("sub")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.NoError(c.controller.ReceiveContent(ctx))
+	c.Equal(http.StatusNoContent, rec.Code)
+}
+
+func (c *WebSubControllerSuite) TestReceiveContentForMissingSubscription() {
+	c.mockSubscriber.EXPECT().
+		Deliver(gomock.Eq("bogus"), gomock.Any(), gomock.Any()).
+		Return(services.ErrWebSubNotFound)
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader("<rss></rss>"))
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("bogus")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.EqualError(c.controller.ReceiveContent(ctx), echo.NewHTTPError(http.StatusNotFound).Error())
+}
+
+func (c *WebSubControllerSuite) TestReceiveMalformedContent() {
+	c.mockSubscriber.EXPECT().
+		Deliver(gomock.Eq("sub"), gomock.Any(), gomock.Any()).
+		Return(errors.New("failed to detect feed type"))
+
+	req := httptest.NewRequest(echo.POST, "/", strings.NewReader("bogus"))
+
+	rec := httptest.NewRecorder()
+
+	ctx := c.e.NewContext(req, rec)
+	ctx.SetParamNames("subscriptionID")
+	ctx.SetParamValues("sub")
+
+	ctx.SetPath("/v1/websub/:subscriptionID")
+
+	c.EqualError(c.controller.ReceiveContent(ctx), echo.NewHTTPError(http.StatusBadRequest).Error())
+}
+
+func (c *WebSubControllerSuite) TestReceiveOversizedContent() {
+	content := strings.Repeat(" ", 10<<20+1)
+
+	// Content is rejected whether its length is declared or not
+	for _, body := range []io.Reader{strings.NewReader(content), io.MultiReader(strings.NewReader(content))} {
+		req := httptest.NewRequest(echo.POST, "/", body)
+
+		rec := httptest.NewRecorder()
+
+		ctx := c.e.NewContext(req, rec)
+		ctx.SetParamNames("subscriptionID")
+		ctx.SetParamValues("sub")
+
+		ctx.SetPath("/v1/websub/:subscriptionID")
+
+		c.EqualError(
+			c.controller.ReceiveContent(ctx),
+			echo.NewHTTPError(http.StatusRequestEntityTooLarge).Error(),
+		)
+	}
+}
+
+func (c *WebSubControllerSuite) SetupTest() {
+	c.ctrl = gomock.NewController(c.T())
+
+	c.e = echo.New()
+	c.e.HideBanner = true
+
+	c.mockSubscriber = services.NewMockWebSubSubscriber(c.ctrl)
+
+	c.controller = rest.NewWebSubController(c.mockSubscriber, c.e)
+}
+
+func (c *WebSubControllerSuite) TearDownTest() {
+	c.ctrl.Finish()
+}
+
+func TestWebSubControllerSuite(t *testing.T) {
+	suite.Run(t, new(WebSubControllerSuite))
+}
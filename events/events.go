@@ -0,0 +1,267 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package events delivers the changes made to the entities of users to
+// in-process subscribers. Events are published without blocking: a
+// subscriber that does not keep up misses events instead of stalling the
+// publisher, unless its subscription queues them.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+)
+
+// Type alias
+type Type = string
+
+// Types identify what changed
+const (
+	FeedCreated  Type = "feed.created"
+	FeedUpdated  Type = "feed.updated"
+	FeedDeleted  Type = "feed.deleted"
+	FeedFailed   Type = "feed.failed"
+	EntryCreated Type = "entry.created"
+	EntryMarked  Type = "entry.marked"
+)
+
+// Types lists every type of event
+var Types = []Type{FeedCreated, FeedUpdated, FeedDeleted, FeedFailed, EntryCreated, EntryMarked}
+
+// DefaultBuffer is the number of events a subscription holds when no buffer is given
+const DefaultBuffer = 64
+
+type (
+	// Event describes a change made to the entities of a user
+	Event struct {
+		Type   Type      `json:"type"`
+		UserID models.ID `json:"-"`
+		Time   time.Time `json:"time"`
+
+		// Feed is the created, updated or failed feed. Entry is the created entry.
+		Feed  *models.Feed  `json:"feed,omitempty"`
+		Entry *models.Entry `json:"entry,omitempty"`
+
+		// FeedID is the feed an entry was created in or the feed that was deleted.
+		// EntryMarked events hold the ID of the marked entry and its feed, or that
+		// of the feed or category whose entries were marked. Marks applied to all
+		// entries of a user hold none.
+		FeedID        models.ID     `json:"feedId,omitempty"`
+		EntryID       models.ID     `json:"entryId,omitempty"`
+		CategoryID    models.ID     `json:"categoryId,omitempty"`
+		SmartFolderID models.ID     `json:"smartFolderId,omitempty"`
+		Marker        models.Marker `json:"marker,omitempty"`
+
+		// Unread is how much the number of unread entries of the user changed by
+		Unread int `json:"unread,omitempty"`
+
+		// Error describes why a feed failed to be fetched
+		Error string `json:"error,omitempty"`
+	}
+
+	// Bus delivers published events to subscriptions. The zero value is not
+	// usable, a nil Bus discards every event.
+	Bus struct {
+		mu            sync.RWMutex
+		subscriptions map[*Subscription]struct{}
+	}
+
+	// Subscription receives the events of a user, or of every user, that are of
+	// one of its types
+	Subscription struct {
+		bus *Bus
+
+		userID models.ID
+		types  map[Type]bool
+
+		events  chan Event
+		dropped uint64
+
+		// Queued subscriptions hold the events that do not fit in events in
+		// pending until pump can deliver them
+		queued  bool
+		mu      sync.Mutex
+		pending []Event
+		ready   chan struct{}
+		done    chan struct{}
+	}
+)
+
+// NewBus creates a new Bus
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Subscribe returns a subscription to the events of user, or of every user if
+// userID is empty. Only events of types are delivered, all of them if none
+// are given. Up to buffer events are held until they are received.
+func (b *Bus) Subscribe(userID models.ID, buffer int, types ...Type) *Subscription {
+	return b.subscribe(userID, buffer, false, types)
+}
+
+// SubscribeQueued returns a subscription like Subscribe that never misses
+// events. Events that do not fit in its buffer are queued, without limit,
+// until they are received.
+func (b *Bus) SubscribeQueued(userID models.ID, buffer int, types ...Type) *Subscription {
+	return b.subscribe(userID, buffer, true, types)
+}
+
+func (b *Bus) subscribe(userID models.ID, buffer int, queued bool, types []Type) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+
+	sub := &Subscription{
+		bus:    b,
+		userID: userID,
+		events: make(chan Event, buffer),
+		queued: queued,
+	}
+
+	if queued {
+		sub.ready = make(chan struct{}, 1)
+		sub.done = make(chan struct{})
+
+		go sub.pump()
+	}
+
+	if len(types) != 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscriptions[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Publish delivers event to every matching subscription. Subscriptions whose
+// buffer is full miss the event, unless they are queued. Events are shared between subscriptions and
+// must not be modified.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscriptions {
+		if !sub.matches(&event) {
+			continue
+		}
+
+		if sub.queued {
+			sub.enqueue(event)
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddUint64(&sub.dropped, 1)
+		}
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed when the
+// subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many events were missed because the subscription's buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close ends the subscription. Events that were delivered can still be
+// received, events that are still queued are discarded.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	if _, found := s.bus.subscriptions[s]; !found {
+		return
+	}
+
+	delete(s.bus.subscriptions, s)
+
+	// pump closes the events of queued subscriptions once it returns
+	if s.queued {
+		close(s.done)
+	} else {
+		close(s.events)
+	}
+}
+
+func (s *Subscription) enqueue(event Event) {
+	s.mu.Lock()
+	s.pending = append(s.pending, event)
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves the pending events of a queued subscription to its events
+// channel, in order, until the subscription is closed
+func (s *Subscription) pump() {
+	defer close(s.events)
+
+	for {
+		s.mu.Lock()
+		pending := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for idx := range pending {
+			select {
+			case s.events <- pending[idx]:
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case <-s.ready:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Subscription) matches(event *Event) bool {
+	if s.userID != "" && s.userID != event.UserID {
+		return false
+	}
+
+	return s.types == nil || s.types[event.Type]
+}
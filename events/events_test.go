@@ -0,0 +1,152 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package events_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/events"
+)
+
+type EventsSuite struct {
+	suite.Suite
+
+	bus *events.Bus
+}
+
+func (s *EventsSuite) TestPublish() {
+	sub := s.bus.Subscribe("gopher", 0)
+	defer sub.Close()
+
+	s.bus.Publish(events.Event{Type: events.FeedDeleted, UserID: "gopher", FeedID: "feed"})
+
+	event := <-sub.Events()
+	s.Equal(events.FeedDeleted, event.Type)
+	s.Equal("feed", event.FeedID)
+	s.False(event.Time.IsZero())
+}
+
+func (s *EventsSuite) TestSubscriptionsAreScopedToUsers() {
+	gopher := s.bus.Subscribe("gopher", 0)
+	defer gopher.Close()
+
+	all := s.bus.Subscribe("", 0)
+	defer all.Close()
+
+	s.bus.Publish(events.Event{Type: events.FeedDeleted, UserID: "other"})
+	s.bus.Publish(events.Event{Type: events.FeedCreated, UserID: "gopher"})
+
+	s.Len(gopher.Events(), 1)
+	s.Equal(events.FeedCreated, (<-gopher.Events()).Type)
+
+	s.Len(all.Events(), 2)
+}
+
+func (s *EventsSuite) TestSubscriptionsAreFilteredByType() {
+	sub := s.bus.Subscribe("gopher", 0, events.EntryCreated, events.EntryMarked)
+	defer sub.Close()
+
+	s.bus.Publish(events.Event{Type: events.FeedFailed, UserID: "gopher"})
+	s.bus.Publish(events.Event{Type: events.EntryMarked, UserID: "gopher"})
+
+	s.Require().Len(sub.Events(), 1)
+	s.Equal(events.EntryMarked, (<-sub.Events()).Type)
+}
+
+func (s *EventsSuite) TestSlowSubscribersMissEvents() {
+	slow := s.bus.Subscribe("gopher", 1)
+	defer slow.Close()
+
+	fast := s.bus.Subscribe("gopher", 3)
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher"})
+	}
+
+	s.Len(slow.Events(), 1)
+	s.Equal(uint64(2), slow.Dropped())
+
+	s.Len(fast.Events(), 3)
+	s.Zero(fast.Dropped())
+}
+
+func (s *EventsSuite) TestQueuedSubscribersReceiveEveryEvent() {
+	sub := s.bus.SubscribeQueued("gopher", 1)
+	defer sub.Close()
+
+	for i := 0; i < 10; i++ {
+		s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher", EntryID: strconv.Itoa(i)})
+	}
+
+	for i := 0; i < 10; i++ {
+		s.Equal(strconv.Itoa(i), (<-sub.Events()).EntryID)
+	}
+
+	s.Zero(sub.Dropped())
+}
+
+func (s *EventsSuite) TestCloseQueuedSubscription() {
+	sub := s.bus.SubscribeQueued("gopher", 1)
+
+	s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher"})
+	s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher"})
+
+	sub.Close()
+
+	// The channel is closed once the queued events are discarded
+	for range sub.Events() {
+	}
+}
+
+func (s *EventsSuite) TestClose() {
+	sub := s.bus.Subscribe("gopher", 0)
+
+	s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher"})
+
+	sub.Close()
+	sub.Close()
+
+	s.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "gopher"})
+
+	// Delivered events can still be received
+	_, ok := <-sub.Events()
+	s.True(ok)
+
+	_, ok = <-sub.Events()
+	s.False(ok)
+}
+
+func (s *EventsSuite) TestNilBus() {
+	var bus *events.Bus
+
+	s.NotPanics(func() {
+		bus.Publish(events.Event{Type: events.EntryCreated})
+	})
+}
+
+func (s *EventsSuite) SetupTest() {
+	s.bus = events.NewBus()
+}
+
+func TestEventsSuite(t *testing.T) {
+	suite.Run(t, new(EventsSuite))
+}
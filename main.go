@@ -31,15 +31,24 @@ import (
 
 	"github.com/jmartinezhern/syndication/cmd"
 	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/plugins"
 	"github.com/jmartinezhern/syndication/repo/sql"
 	"github.com/jmartinezhern/syndication/services"
 	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
 )
 
 const (
 	oneKilobyte         = 1 << 10
 	defaultHSTSMaxAge   = 3600
 	cancellationTimeout = time.Second * 5
+
+	// webhookRetryInterval is how often failed webhook deliveries are retried at most
+	webhookRetryInterval = time.Minute
+
+	// pruneInterval is how often the expired entries of every user are pruned
+	pruneInterval = time.Hour
 )
 
 func config() cmd.Config {
@@ -70,6 +79,35 @@ func configureMiddleware(e *echo.Echo) {
 	e.Use(middleware.Logger())
 }
 
+// credentialsSecret returns the secret feed credentials are encrypted with
+func credentialsSecret(config *cmd.Config) string {
+	if config.CredentialsSecret != "" {
+		return config.CredentialsSecret
+	}
+
+	return config.AuthSecret
+}
+
+// pluginRegistry loads the plugins declared in config
+func pluginRegistry(config *cmd.Config) *plugins.Registry {
+	configs := make([]plugins.Config, len(config.Plugins))
+	for idx, plugin := range config.Plugins {
+		configs[idx] = plugins.Config{
+			Name:    plugin.Name,
+			Path:    plugin.Path,
+			Args:    plugin.Args,
+			Timeout: plugin.Timeout,
+		}
+	}
+
+	registry, err := plugins.NewRegistry(configs)
+	if err != nil {
+		panic(err)
+	}
+
+	return registry
+}
+
 func main() {
 	config := config()
 
@@ -80,18 +118,76 @@ func main() {
 
 	sql.AutoMigrateTables(db)
 
+	if err := utils.ConfigureFetcher(utils.FetcherConfig{
+		ConnectTimeout:  config.Fetcher.ConnectTimeout,
+		ReadTimeout:     config.Fetcher.ReadTimeout,
+		MaxResponseSize: config.Fetcher.MaxResponseSize,
+		UserAgent:       config.Fetcher.UserAgent,
+		ContentTypes:    config.Fetcher.ContentTypes,
+		AllowedNetworks: config.Fetcher.AllowedNetworks,
+	}); err != nil {
+		panic(err)
+	}
+
+	pluginRegistry := pluginRegistry(&config)
+	bus := events.NewBus()
+
 	usersRepo := sql.NewUsers(db)
 	ctgsRepo := sql.NewCategories(db)
 	entriesRepo := sql.NewEntries(db)
-	feedsRepo := sql.NewFeeds(db)
+	feedsRepo := sql.NewFeeds(db, sql.WithCredentialsSecret(credentialsSecret(&config)))
 	tagsRepo := sql.NewTags(db)
+	searchRepo := sql.NewSearch(db)
+	websubRepo := sql.NewWebSub(db)
+	webhooksRepo := sql.NewWebhooks(db)
+	rulesRepo := sql.NewRules(db)
+	smartFoldersRepo := sql.NewSmartFolders(db)
+	outputFeedsRepo := sql.NewOutputFeeds(db)
+
+	feedsOptions := []services.Option{
+		services.WithEvents(bus), services.WithPlugins(pluginRegistry), services.WithRules(rulesRepo),
+	}
+
+	var websubSubscriber *sync.WebSubSubscriber
+	if config.WebSub.CallbackURL != "" {
+		websubSubscriber = sync.NewWebSubSubscriber(config.WebSub.CallbackURL, websubRepo)
+		feedsOptions = append(feedsOptions, services.WithWebSub(websubSubscriber))
+	}
 
 	authService := services.NewAuthService(config.AuthSecret, usersRepo)
-	ctgsService := services.NewCategoriesService(ctgsRepo, entriesRepo)
-	feedsService := services.NewFeedsService(feedsRepo, ctgsRepo, entriesRepo)
-	entriesService := services.NewEntriesService(entriesRepo)
+	ctgsService := services.NewCategoriesService(ctgsRepo, entriesRepo, services.WithEvents(bus))
+	feedsService := services.NewFeedsService(feedsRepo, ctgsRepo, entriesRepo, feedsOptions...)
+	entriesService := services.NewEntriesService(entriesRepo, services.WithEvents(bus))
 	tagsService := services.NewTagsService(tagsRepo, entriesRepo)
 	usersService := services.NewUsersService(usersRepo)
+	searchService := services.NewSearchService(searchRepo)
+	retentionService := services.NewRetentionService(config.Sync.DeleteAfter, feedsRepo, entriesRepo)
+	webhooksService := services.NewWebhooksService(webhooksRepo, feedsRepo, ctgsRepo, tagsRepo, entriesRepo,
+		services.WithEvents(bus))
+	streamService := services.NewStreamService(services.WithEvents(bus))
+	rulesService := services.NewRulesService(rulesRepo, feedsRepo, ctgsRepo, tagsRepo, entriesRepo,
+		services.WithEvents(bus))
+	smartFoldersService := services.NewSmartFoldersService(smartFoldersRepo, feedsRepo, ctgsRepo, tagsRepo,
+		services.WithEvents(bus))
+	outputFeedsService := services.NewOutputFeedsService(outputFeedsRepo, ctgsRepo, tagsRepo, entriesRepo)
+
+	syncOptions := []sync.Option{
+		sync.WithIntervalBounds(config.Sync.MinInterval, config.Sync.MaxInterval),
+		sync.WithMaxErrors(config.Sync.MaxErrors),
+		sync.WithPlugins(pluginRegistry),
+		sync.WithEvents(bus),
+		sync.WithRules(rulesRepo),
+		sync.WithPruning(usersRepo, pruneInterval),
+	}
+
+	// Hubs can only push updates if they can reach this server
+	if websubSubscriber != nil {
+		syncOptions = append(syncOptions, sync.WithWebSub(websubSubscriber))
+	}
+
+	syncService := sync.NewService(feedsRepo, entriesRepo, retentionService, syncOptions...)
+
+	refreshService := services.NewRefreshService(syncService, feedsRepo, ctgsRepo)
 
 	e := echo.New()
 	e.HideBanner = true
@@ -104,17 +200,37 @@ func main() {
 	rest.NewFeedsController(feedsService, e)
 	rest.NewEntriesController(entriesService, e)
 	rest.NewTagsController(tagsService, e)
+	rest.NewSearchController(searchService, e)
+	rest.NewRetentionController(retentionService, e)
+	rest.NewRefreshController(refreshService, e)
+	rest.NewWebhooksController(webhooksService, e)
+	rest.NewStreamController(streamService, e)
+	rest.NewRulesController(rulesService, e)
+	rest.NewSmartFoldersController(smartFoldersService, e)
+	rest.NewOutputFeedsController(outputFeedsService, e)
+
+	if websubSubscriber != nil {
+		rest.NewWebSubController(websubSubscriber, e)
+	}
+
 	rest.NewImporterController(rest.Importers{
-		"text/xml": services.NewOPMLImporter(ctgsRepo, feedsRepo)}, e)
+		"text/xml": services.NewOPMLImporter(ctgsRepo, feedsRepo, services.WithEvents(bus))}, e)
 	rest.NewExporterController(rest.Exporters{
 		"text/xml": services.NewOPMLExporter(ctgsRepo)}, e)
 
-	syncService := sync.NewService(feedsRepo, usersRepo, entriesRepo)
-
 	syncService.Start(config.Sync.Interval)
 
 	defer syncService.Stop()
 
+	webhooksService.Start(webhookRetryInterval)
+
+	defer webhooksService.Stop()
+
+	streamService.Start()
+
+	// Streams never end on their own and would hold the server up
+	e.Server.RegisterOnShutdown(streamService.Stop)
+
 	go func() {
 		if err := e.Start(config.Host.Address + ":" + strconv.Itoa(config.Host.Port)); err != nil {
 			log.Info("Shutting down...")
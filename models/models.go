@@ -46,6 +46,102 @@ const (
 	AccessKey
 )
 
+// FeedStatus alias
+type FeedStatus = string
+
+// FeedStatuses identify the health of a Feed
+const (
+	FeedStatusOK       FeedStatus = "ok"
+	FeedStatusError    FeedStatus = "error"
+	FeedStatusDisabled FeedStatus = "disabled"
+)
+
+// FeedKind alias
+type FeedKind = string
+
+// FeedKinds identify where the entries of a Feed come from
+const (
+	FeedKindScraper FeedKind = "scraper"
+	FeedKindPlugin  FeedKind = "plugin"
+)
+
+// FeedEventType alias
+type FeedEventType = string
+
+// FeedEventTypes identify what changed in a Feed
+const (
+	FeedEventRedirect FeedEventType = "redirect"
+	FeedEventDisabled FeedEventType = "disabled"
+)
+
+// WebSubState alias
+type WebSubState = string
+
+// WebSubStates identify the progress of a WebSubSubscription
+const (
+	WebSubPending WebSubState = "pending"
+	WebSubActive  WebSubState = "active"
+	WebSubDenied  WebSubState = "denied"
+
+	// WebSubUnsubscribing subscriptions are no longer wanted. They are deleted
+	// once the hub verifies that they are cancelled or their lease ends.
+	WebSubUnsubscribing WebSubState = "unsubscribing"
+)
+
+// RefreshStatus alias
+type RefreshStatus = string
+
+// RefreshStatuses identify the progress of a RefreshJob and of each of its feeds
+const (
+	RefreshStatusRunning RefreshStatus = "running"
+	RefreshStatusDone    RefreshStatus = "done"
+	RefreshStatusFailed  RefreshStatus = "failed"
+)
+
+// WebhookDeliveryStatus alias
+type WebhookDeliveryStatus = string
+
+// WebhookDeliveryStatuses identify the progress of a WebhookDelivery
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// RuleField alias
+type RuleField = string
+
+// RuleFields identify what part of an entry a RuleCondition matches
+const (
+	RuleFieldTitle    RuleField = "title"
+	RuleFieldAuthor   RuleField = "author"
+	RuleFieldLink     RuleField = "link"
+	RuleFieldContent  RuleField = "content"
+	RuleFieldFeed     RuleField = "feed"
+	RuleFieldCategory RuleField = "category"
+)
+
+// RuleOperator alias
+type RuleOperator = string
+
+// RuleOperators identify how a RuleCondition matches its field
+const (
+	RuleOperatorContains RuleOperator = "contains"
+	RuleOperatorEquals   RuleOperator = "equals"
+	RuleOperatorMatches  RuleOperator = "matches"
+)
+
+// RuleActionType alias
+type RuleActionType = string
+
+// RuleActionTypes identify what a RuleAction does to an entry
+const (
+	RuleActionMark   RuleActionType = "mark"
+	RuleActionTag    RuleActionType = "tag"
+	RuleActionSave   RuleActionType = "save"
+	RuleActionDelete RuleActionType = "delete"
+)
+
 // MarkerFromString converts a string to a Marker type
 func MarkerFromString(marker string) Marker {
 	value := strings.ToLower(marker)
@@ -59,6 +155,11 @@ func MarkerFromString(marker string) Marker {
 	}
 }
 
+// FeedMuted reports whether feed is muted at now
+func FeedMuted(feed *Feed, now time.Time) bool {
+	return feed.Muted && (feed.MutedUntil == nil || feed.MutedUntil.After(now))
+}
+
 type (
 	// User represents a user and owner of all other entities.
 	User struct {
@@ -107,14 +208,285 @@ type (
 
 		Entries []Entry `json:"-"`
 
+		// Title, Description, Source and Icon are taken from the fetched feed and
+		// refreshed on every sync. DisplayTitle and Notes are set by the user, as is
+		// the Category, and are never changed by a sync.
 		Title        string    `json:"title"`
 		Description  string    `json:"description,omitempty"`
 		Subscription string    `json:"subscription"`
 		Source       string    `json:"source,omitempty"`
+		Icon         string    `json:"icon,omitempty"`
+		DisplayTitle string    `json:"displayTitle,omitempty"`
+		Notes        string    `json:"notes,omitempty" gorm:"type:text"`
 		TTL          int       `json:"ttl,omitempty"`
 		Etag         string    `json:"-"`
-		LastUpdated  time.Time `json:"-"`
+		LastModified string    `json:"-"`
+		LastUpdated  time.Time `json:"lastUpdated"`
 		Status       string    `json:"status,omitempty"`
+
+		// ErrorCount counts the consecutive times the feed failed to be fetched,
+		// the last of which is described by LastError. HTTPStatus is the status
+		// of the last response. LastUpdated is when the feed was last fetched
+		// successfully and Status is one of the FeedStatuses.
+		ErrorCount int    `json:"errorCount"`
+		LastError  string `json:"lastError,omitempty"`
+		HTTPStatus int    `json:"httpStatus,omitempty"`
+
+		// SkipHours and SkipDays are bit masks of the hours (UTC) and weekdays
+		// the feed asks not to be fetched on.
+		SkipHours int `json:"-"`
+		SkipDays  int `json:"-"`
+
+		// PostingInterval is the average time between the most recent entries of the feed.
+		PostingInterval time.Duration `json:"-"`
+
+		// NextFetchAt is when the feed is due to be fetched again.
+		NextFetchAt time.Time `json:"-" gorm:"index"`
+
+		// Paused feeds are not fetched. Muted feeds are fetched but their entries are
+		// left out of the entries, smart folders and stats of their user, except saved
+		// ones, and of the stream and webhooks. Either state is cleared once the time
+		// it is set until, if any, passes.
+		Paused      bool       `json:"paused"`
+		PausedUntil *time.Time `json:"pausedUntil,omitempty"`
+		Muted       bool       `json:"muted"`
+		MutedUntil  *time.Time `json:"mutedUntil,omitempty"`
+
+		// KeepDays overrides the number of days entries are kept for. Zero uses the configured default.
+		KeepDays int `json:"keepDays,omitempty"`
+
+		// KeepLast limits the feed to its most recent entries. Zero places no limit.
+		KeepLast int `json:"keepLast,omitempty"`
+
+		// XMLBase is the xml:base declared by the fetched feed document. It is not persisted.
+		XMLBase string `json:"-" gorm:"-"`
+
+		// MaxAge is the freshness lifetime declared by the server the feed was fetched from.
+		// It is not persisted.
+		MaxAge time.Duration `json:"-" gorm:"-"`
+
+		// Hub and Topic are the WebSub hub and topic URLs advertised by the fetched
+		// feed. They are not persisted.
+		Hub   string `json:"-" gorm:"-"`
+		Topic string `json:"-" gorm:"-"`
+
+		// SubscriptionKey is the normalized Subscription. Feeds of all users that
+		// share a subscription are looked up by it.
+		SubscriptionKey string `json:"-" gorm:"index"`
+
+		// Kind is one of the FeedKinds. Regular feeds have no kind. Entries of
+		// scraper feeds are extracted from the web page at their subscription
+		// with Selectors. Entries of plugin feeds are produced by the source
+		// Plugin, which is handed their subscription.
+		Kind      FeedKind         `json:"kind,omitempty"`
+		Selectors ScraperSelectors `json:"selectors" gorm:"embedded;embedded_prefix:selector_"`
+		Plugin    string           `json:"plugin,omitempty"`
+
+		// Processors is a comma separated list of the plugins new entries are
+		// passed through, in order, before they are added to the feed.
+		Processors string `json:"processors,omitempty"`
+
+		// EncryptedCredentials holds the encrypted FeedCredentials of the feed, if any.
+		EncryptedCredentials []byte `json:"-"`
+
+		// Credentials replace the credentials of a feed when it is updated. They are
+		// not persisted as is and never serialized.
+		Credentials *FeedCredentials `json:"-" gorm:"-"`
+	}
+
+	// ScraperSelectors are the CSS selectors that extract entries from a web page.
+	// Item matches the element of every entry, the others are matched within it.
+	ScraperSelectors struct {
+		Item    string `json:"item,omitempty"`
+		Title   string `json:"title,omitempty"`
+		Link    string `json:"link,omitempty"`
+		Date    string `json:"date,omitempty"`
+		Content string `json:"content,omitempty"`
+	}
+
+	// FeedCredentials are sent along with every request for a feed
+	FeedCredentials struct {
+		// Username and Password are sent with HTTP Basic authentication
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+
+		// Token is sent as a bearer token
+		Token string `json:"token,omitempty"`
+
+		// Cookie is sent as the Cookie header
+		Cookie string `json:"cookie,omitempty"`
+
+		// Headers are additional request headers
+		Headers map[string]string `json:"headers,omitempty"`
+
+		// Proxy is the URL of the proxy the feed is fetched through
+		Proxy string `json:"proxy,omitempty"`
+	}
+
+	// FeedEvent records a change made to a Feed while it was synced.
+	FeedEvent struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+
+		Feed   Feed `json:"-"`
+		FeedID ID   `json:"-"`
+
+		Type FeedEventType `json:"type"`
+		From string        `json:"from,omitempty"`
+		To   string        `json:"to,omitempty"`
+	}
+
+	// WebSubSubscription is a subscription to a topic at a WebSub hub. Topics are
+	// subscribed to once for every feed that leads to them.
+	WebSubSubscription struct {
+		ID        ID `gorm:"primary_key"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+
+		Topic  string `gorm:"unique_index"`
+		Hub    string
+		Secret string
+
+		// URL is the subscription of the feeds that receive the pushed content
+		URL string
+
+		State WebSubState
+
+		// ExpiresAt is when the lease granted by the hub ends. RenewAt is
+		// when the subscription is requested again.
+		ExpiresAt time.Time
+		RenewAt   time.Time `gorm:"index"`
+	}
+
+	// Webhook sends the events of a user to a URL. Only events of the types
+	// listed in Events, separated by commas, are sent. All of them are sent if
+	// none are listed. Events can be limited further to the ones concerning a
+	// feed, the feeds of a category or the entries tagged with a tag.
+	Webhook struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+
+		User   User `json:"-"`
+		UserID ID   `json:"-"`
+
+		URL    string `json:"url"`
+		Events string `json:"events,omitempty"`
+
+		FeedID     ID `json:"feedId,omitempty"`
+		CategoryID ID `json:"categoryId,omitempty"`
+		TagID      ID `json:"tagId,omitempty"`
+
+		// Secret is the key the body of every delivery is signed with. It is
+		// only sent to the user when the webhook is created or it is rotated.
+		Secret string `json:"-"`
+	}
+
+	// WebhookDelivery records the delivery of an event to a Webhook. Failed
+	// attempts are retried at NextAttemptAt until the delivery is delivered or failed.
+	WebhookDelivery struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+
+		Webhook   Webhook `json:"-"`
+		WebhookID ID      `json:"webhookId"`
+
+		Event   string `json:"event"`
+		Payload string `json:"payload" gorm:"type:text"`
+
+		Status         WebhookDeliveryStatus `json:"status"`
+		Attempts       int                   `json:"attempts"`
+		ResponseStatus int                   `json:"responseStatus,omitempty"`
+		LastError      string                `json:"lastError,omitempty"`
+		NextAttemptAt  time.Time             `json:"-" gorm:"index"`
+	}
+
+	// Rule applies its Actions to the entries that match all of its Conditions.
+	// Rules are applied to new entries before they are added, in the order they
+	// were created.
+	Rule struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+
+		User   User `json:"-"`
+		UserID ID   `json:"-"`
+
+		Name string `json:"name"`
+
+		Conditions []RuleCondition `json:"conditions"`
+		Actions    []RuleAction    `json:"actions"`
+	}
+
+	// RuleCondition matches a field of an entry against Value with one of the
+	// RuleOperators. Text fields are compared regardless of case, matches takes
+	// a regular expression. Feed and category conditions hold the ID of a feed
+	// or category and only support equals.
+	RuleCondition struct {
+		ID     ID `json:"-" gorm:"primary_key"`
+		RuleID ID `json:"-"`
+
+		Field    RuleField    `json:"field"`
+		Operator RuleOperator `json:"operator"`
+		Value    string       `json:"value"`
+	}
+
+	// RuleAction is applied to the entries a Rule matches. Mark actions apply
+	// Marker, either read or unread, and tag actions apply the tag with TagID.
+	RuleAction struct {
+		ID     ID `json:"-" gorm:"primary_key"`
+		RuleID ID `json:"-"`
+
+		Type   RuleActionType `json:"type"`
+		Marker string         `json:"marker,omitempty"`
+		TagID  ID             `json:"tagId,omitempty"`
+	}
+
+	// SmartFolder is a saved query over the entries of a user. Its entries are
+	// listed, marked and counted like those of a category. Entries must meet
+	// every criterion that is set: Marker, either read or unread, Saved, the
+	// feed, category or tag they belong to, a Title that contains a value,
+	// regardless of case, and being published in the last Days days.
+	SmartFolder struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+
+		User   User `json:"-"`
+		UserID ID   `json:"-"`
+
+		Name string `json:"name"`
+
+		Marker     string `json:"marker,omitempty"`
+		Saved      bool   `json:"saved,omitempty"`
+		FeedID     ID     `json:"feedId,omitempty"`
+		CategoryID ID     `json:"categoryId,omitempty"`
+		TagID      ID     `json:"tagId,omitempty"`
+		Title      string `json:"title,omitempty"`
+		Days       int    `json:"days,omitempty"`
+	}
+
+	// OutputFeed republishes the entries of a tag, of the feeds of a category or,
+	// if neither is set, all saved entries of a user as a public feed. The feed
+	// is accessed with Token instead of the credentials of the user and is
+	// revoked by deleting it or renewing its token.
+	OutputFeed struct {
+		ID        ID        `json:"id" gorm:"primary_key"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+
+		User   User `json:"-"`
+		UserID ID   `json:"-"`
+
+		// Title is empty unless one was given. The feed is then named after its
+		// tag or category as they are named when it is rendered.
+		Title string `json:"title"`
+
+		CategoryID ID `json:"categoryId,omitempty"`
+		TagID      ID `json:"tagId,omitempty"`
+
+		Token string `json:"token" gorm:"unique_index"`
 	}
 
 	// Tag represents an identifier object that can be applied to Entry objects.
@@ -149,7 +521,10 @@ type (
 		Title     string    `json:"title"`
 		Link      string    `json:"link"`
 		Author    string    `json:"author,omitempty"`
+		Summary   string    `json:"summary,omitempty" gorm:"type:text"`
+		Content   string    `json:"content,omitempty" gorm:"type:text"`
 		Published time.Time `json:"published"`
+		Updated   time.Time `json:"updated"`
 		Saved     bool      `json:"isSaved"`
 		Mark      Marker    `json:"markedAs"`
 	}
@@ -204,11 +579,72 @@ type (
 		Body    OPMLBody `xml:"body"`
 	}
 
+	// FeedCandidate represents a feed found while discovering the feeds of a website.
+	FeedCandidate struct {
+		Title        string `json:"title"`
+		Subscription string `json:"subscription"`
+	}
+
+	// SearchQuery represents a full-text query over entries, optionally scoped
+	// to a feed, category or tag.
+	SearchQuery struct {
+		Text       string
+		FeedID     ID
+		CategoryID ID
+		TagID      ID
+	}
+
+	// RetentionPolicy selects the entries of a feed that may be pruned. Only read entries
+	// that are neither saved nor tagged are ever pruned.
+	RetentionPolicy struct {
+		FeedID ID
+
+		// Before selects entries created before it. Ignored if zero.
+		Before time.Time
+
+		// KeepLast selects entries older than the KeepLast most recent ones. Ignored if zero.
+		KeepLast int
+	}
+
+	// FeedRetention counts the entries of a feed pruned by retention.
+	FeedRetention struct {
+		FeedID  ID     `json:"feedId"`
+		Title   string `json:"title"`
+		Entries int    `json:"entries"`
+	}
+
+	// RetentionReport summarizes the entries pruned by retention.
+	RetentionReport struct {
+		Feeds []FeedRetention `json:"feeds"`
+		Total int             `json:"total"`
+	}
+
+	// FeedRefresh is the outcome of refreshing a single feed
+	FeedRefresh struct {
+		FeedID     string        `json:"feedId"`
+		Title      string        `json:"title"`
+		Status     RefreshStatus `json:"status"`
+		NewEntries int           `json:"newEntries"`
+		Error      string        `json:"error,omitempty"`
+	}
+
+	// RefreshJob tracks feeds that are refreshed at the request of a user
+	RefreshJob struct {
+		ID         string        `json:"id"`
+		UserID     string        `json:"-"`
+		CreatedAt  time.Time     `json:"createdAt"`
+		Status     RefreshStatus `json:"status"`
+		NewEntries int           `json:"newEntries"`
+		Feeds      []FeedRefresh `json:"feeds"`
+	}
+
 	Page struct {
 		FilterID       string
+		Status         string
 		ContinuationID string
 		Count          int
 		Newest         bool
 		Marker         Marker
+		Saved          bool
 	}
 )
@@ -19,6 +19,7 @@ package models_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 
@@ -37,6 +38,16 @@ func (s *ModelsTestSuite) TestMarkerFromString() {
 	s.EqualValues(models.MarkerAny, models.MarkerFromString("bogus"))
 }
 
+func (s *ModelsTestSuite) TestFeedMuted() {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	s.False(models.FeedMuted(&models.Feed{}, now))
+	s.True(models.FeedMuted(&models.Feed{Muted: true}, now))
+	s.True(models.FeedMuted(&models.Feed{Muted: true, MutedUntil: &future}, now))
+	s.False(models.FeedMuted(&models.Feed{Muted: true, MutedUntil: &past}, now))
+}
+
 func TestImporterTestSuite(t *testing.T) {
 	suite.Run(t, new(ModelsTestSuite))
 }
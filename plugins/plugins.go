@@ -0,0 +1,357 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package plugins runs external executables that produce or process the
+// entries of feeds. A plugin is started for every request. It reads a single
+// Request as JSON from its standard input and writes a single Response as
+// JSON to its standard output before exiting.
+//
+// Source plugins produce the entries of plugin feeds. They are sent a
+// "source" request holding the subscription of the feed, which they are free
+// to interpret, and respond with the feed and its current entries.
+//
+// Processor plugins are sent a "process" request holding a feed and its new
+// entries. They respond with the entries that are kept, which may have been
+// transformed. Entries missing from the response are dropped.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+)
+
+// Request types
+const (
+	RequestSource  = "source"
+	RequestProcess = "process"
+)
+
+const (
+	defaultTimeout = time.Second * 30
+
+	// maxOutputSize limits the response of a plugin
+	maxOutputSize = 10 << 20
+
+	// maxErrorOutput limits how much of the standard error of a failed
+	// plugin is reported
+	maxErrorOutput = 1 << 10
+)
+
+var (
+	// ErrUnknownPlugin signals that no plugin with a given name is configured
+	ErrUnknownPlugin = errors.New("unknown plugin")
+
+	// ErrOutputTooLarge signals that a plugin wrote more than maxOutputSize bytes
+	ErrOutputTooLarge = errors.New("plugin output is too large")
+
+	// ErrTimeout signals that a plugin did not exit in time
+	ErrTimeout = errors.New("plugin timed out")
+)
+
+type (
+	// Config declares a plugin. Path is the executable that is run with Args.
+	// Plugins that do not exit within Timeout are killed.
+	Config struct {
+		Name    string
+		Path    string
+		Args    []string
+		Timeout time.Duration
+	}
+
+	// Registry holds the plugins that feeds can use
+	Registry struct {
+		plugins map[string]Config
+	}
+
+	// Request is written to the standard input of a plugin
+	Request struct {
+		Type         string  `json:"type"`
+		Subscription string  `json:"subscription,omitempty"`
+		Feed         *Feed   `json:"feed,omitempty"`
+		Entries      []Entry `json:"entries,omitempty"`
+	}
+
+	// Response is read from the standard output of a plugin. Plugins that
+	// fail to handle a request respond with an Error.
+	Response struct {
+		Feed    *Feed   `json:"feed,omitempty"`
+		Entries []Entry `json:"entries"`
+		Error   string  `json:"error,omitempty"`
+	}
+
+	// Feed is the representation of a feed exchanged with plugins
+	Feed struct {
+		Title        string `json:"title"`
+		Description  string `json:"description,omitempty"`
+		Subscription string `json:"subscription,omitempty"`
+		Link         string `json:"link,omitempty"`
+	}
+
+	// Entry is the representation of an entry exchanged with plugins. ID
+	// identifies the entry within its feed and defaults to its link.
+	Entry struct {
+		ID        string    `json:"id,omitempty"`
+		Title     string    `json:"title"`
+		Link      string    `json:"link,omitempty"`
+		Author    string    `json:"author,omitempty"`
+		Summary   string    `json:"summary,omitempty"`
+		Content   string    `json:"content,omitempty"`
+		Published time.Time `json:"published"`
+		Updated   time.Time `json:"updated"`
+	}
+
+	// limitedBuffer fails writes past its limit. The buffer is not embedded
+	// so that copies cannot bypass Write through its ReadFrom method.
+	limitedBuffer struct {
+		buf   bytes.Buffer
+		limit int
+	}
+)
+
+// NewRegistry creates a Registry of plugins. Every plugin must have a unique
+// name and an executable that can be found.
+func NewRegistry(configs []Config) (*Registry, error) {
+	r := &Registry{plugins: make(map[string]Config, len(configs))}
+
+	for _, config := range configs {
+		name := strings.TrimSpace(config.Name)
+		if name == "" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("invalid plugin name %q", config.Name)
+		}
+
+		if _, found := r.plugins[name]; found {
+			return nil, fmt.Errorf("plugin %s is declared more than once", name)
+		}
+
+		path, err := exec.LookPath(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", name, err)
+		}
+
+		config.Name = name
+		config.Path = path
+
+		if config.Timeout <= 0 {
+			config.Timeout = defaultTimeout
+		}
+
+		r.plugins[name] = config
+	}
+
+	return r, nil
+}
+
+// Names splits a comma separated list of plugin names
+func Names(list string) []string {
+	var names []string
+
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Check returns ErrUnknownPlugin if any of names is not a configured plugin
+func (r *Registry) Check(names ...string) error {
+	for _, name := range names {
+		if r == nil {
+			return ErrUnknownPlugin
+		}
+
+		if _, found := r.plugins[name]; !found {
+			return ErrUnknownPlugin
+		}
+	}
+
+	return nil
+}
+
+// Source returns the feed and entries the source plugin name produces for subscription
+func (r *Registry) Source(name, subscription string) (models.Feed, []models.Entry, error) {
+	res, err := r.run(name, &Request{
+		Type:         RequestSource,
+		Subscription: subscription,
+	})
+	if err != nil {
+		return models.Feed{}, nil, err
+	}
+
+	feed := models.Feed{Subscription: subscription}
+
+	if res.Feed != nil {
+		feed.Title = res.Feed.Title
+		feed.Description = res.Feed.Description
+		feed.Source = res.Feed.Link
+	}
+
+	feed.LastUpdated = time.Now()
+
+	return feed, toEntries(res.Entries), nil
+}
+
+// Process passes entries of feed through the processor plugins names in order
+// and returns the entries that are kept
+func (r *Registry) Process(names []string, feed *models.Feed, entries []models.Entry) ([]models.Entry, error) {
+	for _, name := range names {
+		if len(entries) == 0 {
+			break
+		}
+
+		res, err := r.run(name, &Request{
+			Type: RequestProcess,
+			Feed: &Feed{
+				Title:        feed.Title,
+				Description:  feed.Description,
+				Subscription: feed.Subscription,
+				Link:         feed.Source,
+			},
+			Entries: fromEntries(entries),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entries = toEntries(res.Entries)
+	}
+
+	return entries, nil
+}
+
+// run sends req to the plugin name and returns its response
+func (r *Registry) run(name string, req *Request) (*Response, error) {
+	if err := r.Check(name); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	config := r.plugins[name]
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxOutputSize}
+	stderr := &limitedBuffer{limit: maxErrorOutput}
+
+	cmd := exec.CommandContext(ctx, config.Path, config.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err = cmd.Run()
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("plugin %s: %w", name, ErrTimeout)
+	case stdout.buf.Len() >= maxOutputSize:
+		return nil, fmt.Errorf("plugin %s: %w", name, ErrOutputTooLarge)
+	case err != nil:
+		return nil, fmt.Errorf("plugin %s: %v: %s", name, err, strings.TrimSpace(stderr.buf.String()))
+	}
+
+	var res Response
+	if err := json.Unmarshal(stdout.buf.Bytes(), &res); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", name, err)
+	}
+
+	if res.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", name, res.Error)
+	}
+
+	return &res, nil
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		// Only what fits is kept. Failing the write stops the copy of the output.
+		b.buf.Write(p[:room])
+
+		return room, ErrOutputTooLarge
+	}
+
+	return b.buf.Write(p)
+}
+
+func fromEntries(entries []models.Entry) []Entry {
+	out := make([]Entry, len(entries))
+
+	for idx := range entries {
+		out[idx] = Entry{
+			ID:        entries[idx].GUID,
+			Title:     entries[idx].Title,
+			Link:      entries[idx].Link,
+			Author:    entries[idx].Author,
+			Summary:   entries[idx].Summary,
+			Content:   entries[idx].Content,
+			Published: entries[idx].Published,
+			Updated:   entries[idx].Updated,
+		}
+	}
+
+	return out
+}
+
+// toEntries converts the entries of a response. Entries without an ID are
+// identified by their link, or their title and content if they have none.
+func toEntries(entries []Entry) []models.Entry {
+	out := make([]models.Entry, len(entries))
+
+	now := time.Now()
+
+	for idx, entry := range entries {
+		out[idx] = models.Entry{
+			GUID:      entry.ID,
+			Title:     entry.Title,
+			Link:      entry.Link,
+			Author:    entry.Author,
+			Summary:   entry.Summary,
+			Content:   entry.Content,
+			Published: entry.Published,
+			Updated:   entry.Updated,
+			Mark:      models.MarkerUnread,
+		}
+
+		if out[idx].GUID == "" {
+			out[idx].GUID = entry.Link
+		}
+
+		if out[idx].GUID == "" {
+			out[idx].GUID = fmt.Sprintf("%x", sha256.Sum256([]byte(entry.Title+entry.Content)))
+		}
+
+		if out[idx].Published.IsZero() {
+			out[idx].Published = now
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,213 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package plugins_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
+)
+
+type PluginsSuite struct {
+	suite.Suite
+
+	dir string
+}
+
+const sourceScript = `cat > "$(dirname "$0")/request.json"
+cat <<'JSON'
+{
+  "feed": {"title": "Issues", "description": "Open issues", "link": "https://example.com/issues"},
+  "entries": [
+    {"id": "SYN-1", "title": "First", "link": "https://example.com/SYN-1", "published": "2021-06-01T10:00:00Z"},
+    {"title": "Second", "link": "https://example.com/SYN-2"},
+    {"title": "Third", "content": "No link"}
+  ]
+}
+JSON`
+
+// writePlugin writes an executable shell script and returns its path
+func (s *PluginsSuite) writePlugin(name, script string) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0700))
+
+	return path
+}
+
+func (s *PluginsSuite) registry(configs ...plugins.Config) *plugins.Registry {
+	registry, err := plugins.NewRegistry(configs)
+	s.Require().NoError(err)
+
+	return registry
+}
+
+func (s *PluginsSuite) TestSource() {
+	registry := s.registry(plugins.Config{Name: "issues", Path: s.writePlugin("issues", sourceScript)})
+
+	feed, entries, err := registry.Source("issues", "project = SYN")
+	s.Require().NoError(err)
+
+	s.Equal("Issues", feed.Title)
+	s.Equal("Open issues", feed.Description)
+	s.Equal("https://example.com/issues", feed.Source)
+	s.Equal("project = SYN", feed.Subscription)
+
+	s.Require().Len(entries, 3)
+
+	s.Equal("SYN-1", entries[0].GUID)
+	s.Equal(time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), entries[0].Published.UTC())
+	s.Equal(models.MarkerUnread, entries[0].Mark)
+
+	// Entries without an ID are identified by their link or content
+	s.Equal("https://example.com/SYN-2", entries[1].GUID)
+	s.WithinDuration(time.Now(), entries[1].Published, 5*time.Second)
+	s.NotEmpty(entries[2].GUID)
+
+	request, err := ioutil.ReadFile(filepath.Join(s.dir, "request.json"))
+	s.Require().NoError(err)
+
+	var req plugins.Request
+	s.Require().NoError(json.Unmarshal(request, &req))
+	s.Equal(plugins.RequestSource, req.Type)
+	s.Equal("project = SYN", req.Subscription)
+}
+
+func (s *PluginsSuite) TestProcess() {
+	registry := s.registry(
+		plugins.Config{
+			Name: "prefix",
+			Path: s.writePlugin("prefix", `sed 's/"title":"/"title":"[SYN] /g'`),
+		},
+		plugins.Config{
+			Name: "first",
+			Path: s.writePlugin("first", `cat > "$(dirname "$0")/request.json"
+echo '{"entries": [{"id": "1", "title": "[SYN] Kept"}]}'`),
+		},
+	)
+
+	entries, err := registry.Process([]string{"prefix", "first"}, &models.Feed{Title: "Issues"}, []models.Entry{
+		{GUID: "1", Title: "Kept"},
+		{GUID: "2", Title: "Dropped"},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Equal("1", entries[0].GUID)
+	s.Equal("[SYN] Kept", entries[0].Title)
+
+	request, err := ioutil.ReadFile(filepath.Join(s.dir, "request.json"))
+	s.Require().NoError(err)
+
+	var req plugins.Request
+	s.Require().NoError(json.Unmarshal(request, &req))
+	s.Equal(plugins.RequestProcess, req.Type)
+	s.Equal("Issues", req.Feed.Title)
+	s.Require().Len(req.Entries, 2)
+	s.Equal("[SYN] Dropped", req.Entries[1].Title)
+}
+
+func (s *PluginsSuite) TestProcessWithoutEntries() {
+	registry := s.registry(plugins.Config{Name: "fail", Path: s.writePlugin("fail", "exit 1")})
+
+	// Processors are not run when there is nothing to process
+	entries, err := registry.Process([]string{"fail"}, &models.Feed{}, nil)
+	s.NoError(err)
+	s.Empty(entries)
+}
+
+func (s *PluginsSuite) TestPluginErrors() {
+	registry := s.registry(
+		plugins.Config{Name: "error", Path: s.writePlugin("error", `echo '{"error": "invalid query"}'`)},
+		plugins.Config{Name: "exit", Path: s.writePlugin("exit", "echo 'connection refused' >&2; exit 3")},
+		plugins.Config{Name: "garbage", Path: s.writePlugin("garbage", "echo garbage")},
+	)
+
+	_, _, err := registry.Source("error", "")
+	s.EqualError(err, "plugin error: invalid query")
+
+	_, _, err = registry.Source("exit", "")
+	s.EqualError(err, "plugin exit: exit status 3: connection refused")
+
+	_, _, err = registry.Source("garbage", "")
+	s.Error(err)
+
+	_, _, err = registry.Source("unknown", "")
+	s.True(errors.Is(err, plugins.ErrUnknownPlugin))
+}
+
+func (s *PluginsSuite) TestPluginTimeout() {
+	registry := s.registry(plugins.Config{
+		Name:    "slow",
+		Path:    s.writePlugin("slow", "exec sleep 5"),
+		Timeout: 100 * time.Millisecond,
+	})
+
+	start := time.Now()
+
+	_, _, err := registry.Source("slow", "")
+	s.True(errors.Is(err, plugins.ErrTimeout))
+	s.Less(int64(time.Since(start)), int64(5*time.Second))
+}
+
+func (s *PluginsSuite) TestPluginOutputTooLarge() {
+	registry := s.registry(plugins.Config{Name: "flood", Path: s.writePlugin("flood", "exec yes")})
+
+	_, _, err := registry.Source("flood", "")
+	s.True(errors.Is(err, plugins.ErrOutputTooLarge))
+}
+
+func (s *PluginsSuite) TestNewRegistry() {
+	path := s.writePlugin("plugin", "exit 0")
+
+	_, err := plugins.NewRegistry([]plugins.Config{{Name: "missing", Path: filepath.Join(s.dir, "missing")}})
+	s.Error(err)
+
+	_, err = plugins.NewRegistry([]plugins.Config{{Name: "a,b", Path: path}})
+	s.Error(err)
+
+	_, err = plugins.NewRegistry([]plugins.Config{{Name: "a", Path: path}, {Name: "a", Path: path}})
+	s.Error(err)
+
+	registry := s.registry(plugins.Config{Name: "a", Path: path}, plugins.Config{Name: "b", Path: path})
+	s.NoError(registry.Check("a", "b"))
+	s.Equal(plugins.ErrUnknownPlugin, registry.Check("a", "c"))
+
+	var none *plugins.Registry
+	s.NoError(none.Check())
+	s.Equal(plugins.ErrUnknownPlugin, none.Check("a"))
+}
+
+func (s *PluginsSuite) TestNames() {
+	s.Equal([]string{"a", "b"}, plugins.Names(" a, ,b,"))
+	s.Empty(plugins.Names(""))
+}
+
+func (s *PluginsSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+}
+
+func TestPluginsSuite(t *testing.T) {
+	suite.Run(t, new(PluginsSuite))
+}
@@ -19,6 +19,7 @@ package repo
 
 import (
 	"errors"
+	"time"
 
 	"github.com/jmartinezhern/syndication/models"
 )
@@ -27,6 +28,10 @@ var (
 	// ErrModelNotFound signals that an operation was attempted
 	// on a model that is not found in the repo.
 	ErrModelNotFound = errors.New("model not found")
+
+	// ErrNoCredentialsKey signals that credentials cannot be stored or read
+	// because no key to encrypt them with is configured.
+	ErrNoCredentialsKey = errors.New("no key to encrypt credentials with")
 )
 
 type (
@@ -58,23 +63,99 @@ type (
 		EntryWithID(userID, id string) (models.Entry, bool)
 		EntryWithGUID(userID, guid string) (models.Entry, bool)
 		List(userID string, page models.Page) ([]models.Entry, string)
+		ListUnmuted(userID string, page models.Page) ([]models.Entry, string)
 		ListFromTags(userID string, tagIDs []string, page models.Page) ([]models.Entry, string)
 		ListFromCategory(userID string, page models.Page) ([]models.Entry, string)
 		ListFromFeed(userID string, page models.Page) ([]models.Entry, string)
 		TagEntries(userID, tagID string, entryIDs []string) error
+		HasTag(userID, entryID, tagID string) bool
+		ListBatch(userID, afterID string, count int) []models.Entry
+		Delete(userID, id string) error
 		Mark(userID, id string, marker models.Marker) error
 		MarkAll(userID string, marker models.Marker)
+		Save(userID, id string, saved bool) error
+		CountExpired(userID string, policy models.RetentionPolicy) int
+		DeleteExpired(userID string, policy models.RetentionPolicy) int
 		Stats(userID string) models.Stats
 	}
 
 	Feeds interface {
 		Create(userID string, feed *models.Feed)
 		Update(userID string, feed *models.Feed) error
+		UpdateSettings(userID string, feed *models.Feed) error
+		UpdateStatus(userID string, feed *models.Feed) error
+		UpdateSchedule(userID string, feed *models.Feed) error
+		SetPaused(userID, id string, paused bool, until *time.Time) error
+		SetMuted(userID, id string, muted bool, until *time.Time) error
+		ClearExpired(now time.Time)
 		Delete(userID, id string) error
 		FeedWithID(userID, id string) (models.Feed, bool)
 		List(userID string, page models.Page) ([]models.Feed, string)
+		ListDue(before time.Time, count int) []models.Feed
+		ListWithSubscription(subscription string) []models.Feed
+		SetCredentials(userID, id string, credentials *models.FeedCredentials) error
+		Credentials(userID, id string) (*models.FeedCredentials, error)
 		Mark(userID, id string, marker models.Marker) error
 		Stats(userID, ctgID string) (models.Stats, error)
+		CreateEvent(userID string, event *models.FeedEvent) error
+		ListEvents(userID string, page models.Page) ([]models.FeedEvent, string)
+	}
+
+	WebSub interface {
+		Create(sub *models.WebSubSubscription)
+		Update(sub *models.WebSubSubscription) error
+		SubscriptionWithID(id string) (models.WebSubSubscription, bool)
+		SubscriptionWithTopic(topic string) (models.WebSubSubscription, bool)
+		ListWithURL(url string) []models.WebSubSubscription
+		ListDue(before time.Time, count int) []models.WebSubSubscription
+		Delete(id string) error
+	}
+
+	Webhooks interface {
+		Create(userID string, hook *models.Webhook)
+		Update(userID string, hook *models.Webhook) error
+		Delete(userID, id string) error
+		WebhookWithID(userID, id string) (models.Webhook, bool)
+		List(userID string, page models.Page) ([]models.Webhook, string)
+		ListAll(userID string) []models.Webhook
+		CreateDelivery(delivery *models.WebhookDelivery)
+		UpdateDelivery(delivery *models.WebhookDelivery) error
+		ListDeliveries(userID string, page models.Page) ([]models.WebhookDelivery, string)
+		ListDueDeliveries(before time.Time, count int) []models.WebhookDelivery
+		DeleteDeliveries(before time.Time) int
+	}
+
+	Rules interface {
+		Create(userID string, rule *models.Rule)
+		Update(userID string, rule *models.Rule) error
+		Delete(userID, id string) error
+		RuleWithID(userID, id string) (models.Rule, bool)
+		List(userID string, page models.Page) ([]models.Rule, string)
+		ListAll(userID string) []models.Rule
+	}
+
+	SmartFolders interface {
+		Create(userID string, folder *models.SmartFolder)
+		Update(userID string, folder *models.SmartFolder) error
+		Delete(userID, id string) error
+		SmartFolderWithID(userID, id string) (models.SmartFolder, bool)
+		List(userID string, page models.Page) ([]models.SmartFolder, string)
+		Entries(userID string, page models.Page) ([]models.Entry, string)
+		Stats(userID, id string) (models.Stats, error)
+		Mark(userID, id string, marker models.Marker) error
+	}
+
+	OutputFeeds interface {
+		Create(userID string, feed *models.OutputFeed)
+		Update(userID string, feed *models.OutputFeed) error
+		Delete(userID, id string) error
+		OutputFeedWithID(userID, id string) (models.OutputFeed, bool)
+		OutputFeedWithToken(token string) (models.OutputFeed, bool)
+		List(userID string, page models.Page) ([]models.OutputFeed, string)
+	}
+
+	Search interface {
+		Entries(userID string, query models.SearchQuery, page models.Page) ([]models.Entry, string)
 	}
 
 	Tags interface {
@@ -18,6 +18,7 @@
 package sql
 
 import (
+	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
@@ -26,6 +27,10 @@ import (
 	"github.com/jmartinezhern/syndication/repo"
 )
 
+// unmutedFeeds leaves out the entries of feeds that are muted
+const unmutedFeeds = "feed_id NOT IN (SELECT id FROM feeds WHERE user_id = ? AND muted = ? AND " +
+	"(muted_until IS NULL OR muted_until > ?))"
+
 type (
 	Entries struct {
 		db *gorm.DB
@@ -40,15 +45,20 @@ func NewEntries(db *gorm.DB) Entries {
 
 // Create a new Entry owned by user
 func (e Entries) Create(userID string, entry *models.Entry) {
-	e.db.Model(&models.User{ID: userID}).Association("Entries").Append(entry)
+	entry.UserID = userID
+	entry.FeedID = ""
 
 	if entry.Feed.ID != "" {
 		var feed models.Feed
 		if !e.db.Model(&models.User{ID: userID}).Where("id = ?", entry.Feed.ID).Related(&feed).
 			RecordNotFound() {
-			e.db.Model(&feed).Association("Entries").Append(entry)
+			entry.FeedID = feed.ID
 		}
 	}
+
+	// Associations are only referenced. Saving them here would overwrite the
+	// feed with the copy held by the entry.
+	e.db.Set("gorm:save_associations", false).Create(entry)
 }
 
 // EntryWithGUID returns an Entry with GUID and owned by user
@@ -63,8 +73,15 @@ func (e Entries) EntryWithGUID(userID, guid string) (entry models.Entry, found b
 
 // List all entries owned by user
 func (e Entries) List(userID string, page models.Page) (entries []models.Entry, next string) {
-	query := e.db.Model(&models.User{ID: userID})
+	return e.list(e.db.Model(&models.User{ID: userID}), userID, page)
+}
 
+// ListUnmuted returns the entries owned by user like List, except the ones of muted feeds
+func (e Entries) ListUnmuted(userID string, page models.Page) (entries []models.Entry, next string) {
+	return e.list(e.unmuted(userID), userID, page)
+}
+
+func (e Entries) list(query *gorm.DB, userID string, page models.Page) (entries []models.Entry, next string) {
 	if page.ContinuationID != "" {
 		entry, found := e.EntryWithID(userID, page.ContinuationID)
 		if found {
@@ -76,6 +93,10 @@ func (e Entries) List(userID string, page models.Page) (entries []models.Entry,
 		query = query.Where("mark = ?", page.Marker)
 	}
 
+	if page.Saved {
+		query = query.Where("saved = ?", true)
+	}
+
 	if page.Newest {
 		query = query.Order("published DESC")
 	} else {
@@ -124,7 +145,7 @@ func (e Entries) ListFromCategory(userID string, page models.Page) (entries []mo
 		feedIds[idx] = feeds[idx].ID
 	}
 
-	query.Where("feed_id in (?)", feedIds)
+	query = query.Where("feed_id in (?)", feedIds)
 
 	return e.paginateList(userID, query, page)
 }
@@ -134,6 +155,10 @@ func (e Entries) paginateList(userID string, query *gorm.DB, page models.Page) (
 		query = query.Where("mark = ?", page.Marker)
 	}
 
+	if page.Saved {
+		query = query.Where("saved = ?", true)
+	}
+
 	if page.Newest {
 		query = query.Order("published DESC")
 	} else {
@@ -163,6 +188,39 @@ func (e Entries) EntryWithID(userID, id string) (entry models.Entry, found bool)
 	return
 }
 
+// HasTag reports whether an entry owned by user is tagged with a tag
+func (e Entries) HasTag(userID, entryID, tagID string) bool {
+	var count int
+
+	e.db.Table("entry_tags").
+		Joins("inner join entries ON entries.id = entry_tags.entry_id").
+		Where("entry_tags.entry_id = ? AND entry_tags.tag_id = ? AND entries.user_id = ?", entryID, tagID, userID).
+		Count(&count)
+
+	return count > 0
+}
+
+// ListBatch returns up to count entries owned by user whose ID follows afterID,
+// ordered by ID and along with their tags. It is used to go through every entry of a user.
+func (e Entries) ListBatch(userID, afterID string, count int) (entries []models.Entry) {
+	e.db.Preload("Tags").Where("user_id = ? AND id > ?", userID, afterID).Order("id").Limit(count).Find(&entries)
+
+	return
+}
+
+// Delete an entry with id owned by user
+func (e Entries) Delete(userID, id string) error {
+	entry, found := e.EntryWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	e.db.Model(&entry).Association("Tags").Clear()
+	e.db.Delete(&entry)
+
+	return nil
+}
+
 // TagEntries with the given tag for user
 func (e Entries) TagEntries(userID, tagID string, entryIDs []string) error {
 	if len(entryIDs) == 0 {
@@ -199,6 +257,17 @@ func (e Entries) Mark(userID, id string, marker models.Marker) error {
 	return repo.ErrModelNotFound
 }
 
+// Save sets the saved state of an entry with id and owned by user
+func (e Entries) Save(userID, id string, saved bool) error {
+	if entry, found := e.EntryWithID(userID, id); found {
+		e.db.Model(&entry).Update("saved", saved)
+
+		return nil
+	}
+
+	return repo.ErrModelNotFound
+}
+
 // MarkAll entries
 func (e Entries) MarkAll(userID string, marker models.Marker) {
 	e.db.Model(new(models.Entry)).Where("user_id = ?", userID).Update(models.Entry{Mark: marker})
@@ -228,27 +297,87 @@ func (e Entries) ListFromTags(userID string, tagIDs []string, page models.Page)
 
 	sql := "inner join entry_tags ON entry_tags.entry_id = entries.id"
 
-	query.Joins(sql).Where("entry_tags.tag_id in (?)", tagPrimaryKeys)
+	query = query.Joins(sql).Where("entry_tags.tag_id in (?)", tagPrimaryKeys)
 
 	return e.paginateList(userID, query, page)
 }
 
-// DeleteOldEntries deletes entries older than a timestamp
-func (e Entries) DeleteOldEntries(userID string, timestamp time.Time) {
-	e.db.Delete(models.Entry{}, "user_id = ? AND created_at < ? AND saved = ?", userID, timestamp, false)
+// CountExpired returns the number of entries owned by user selected by a retention policy
+func (e Entries) CountExpired(userID string, policy models.RetentionPolicy) int {
+	query, ok := e.expired(userID, policy)
+	if !ok {
+		return 0
+	}
+
+	var count int
+
+	query.Model(&models.Entry{}).Count(&count)
+
+	return count
+}
+
+// DeleteExpired deletes all entries owned by user selected by a retention policy
+// and returns the number of entries deleted
+func (e Entries) DeleteExpired(userID string, policy models.RetentionPolicy) int {
+	query, ok := e.expired(userID, policy)
+	if !ok {
+		return 0
+	}
+
+	return int(query.Delete(models.Entry{}).RowsAffected)
+}
+
+func (e Entries) expired(userID string, policy models.RetentionPolicy) (*gorm.DB, bool) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	if !policy.Before.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, policy.Before)
+	}
+
+	if policy.KeepLast > 0 {
+		var last []models.Entry
+
+		e.db.Where("user_id = ? AND feed_id = ?", userID, policy.FeedID).
+			Order("published desc").
+			Offset(policy.KeepLast - 1).
+			Limit(1).
+			Find(&last)
+
+		if len(last) != 0 {
+			conditions = append(conditions, "published < ?")
+			args = append(args, last[0].Published)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return nil, false
+	}
+
+	return e.db.Where("user_id = ? AND feed_id = ? AND mark = ? AND saved = ?",
+		userID, policy.FeedID, models.MarkerRead, false).
+		Where("id NOT IN (SELECT entry_id FROM entry_tags)").
+		Where("("+strings.Join(conditions, " OR ")+")", args...), true
 }
 
-// Stats returns all Stats for feeds owned by user
+// Stats returns all Stats for feeds owned by user. Muted feeds are only
+// counted in the saved entries.
 func (e Entries) Stats(userID string) models.Stats {
 	stats := models.Stats{}
 
-	stats.Unread = e.db.Model(&models.User{ID: userID}).Where("mark = ?", models.MarkerUnread).
-		Association("Entries").Count()
-	stats.Read = e.db.Model(&models.User{ID: userID}).Where("mark = ?", models.MarkerRead).
-		Association("Entries").Count()
+	stats.Unread = e.unmuted(userID).Where("mark = ?", models.MarkerUnread).Association("Entries").Count()
+	stats.Read = e.unmuted(userID).Where("mark = ?", models.MarkerRead).Association("Entries").Count()
 	stats.Saved = e.db.Model(&models.User{ID: userID}).Where("saved = ?", true).
 		Association("Entries").Count()
-	stats.Total = e.db.Model(&models.User{ID: userID}).Association("Entries").Count()
+	stats.Total = e.unmuted(userID).Association("Entries").Count()
 
 	return stats
 }
+
+// unmuted scopes a query to the entries of user that do not belong to a muted feed
+func (e Entries) unmuted(userID string) *gorm.DB {
+	return e.db.Model(&models.User{ID: userID}).Where(unmutedFeeds, userID, true, time.Now())
+}
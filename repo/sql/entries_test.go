@@ -45,8 +45,11 @@ func (s *EntriesSuite) TestCreate() {
 		Title:     "Test Entry",
 		Author:    "John Doe",
 		Link:      "http://example.com",
+		Summary:   "A test entry",
+		Content:   "<p>Test content</p>",
 		Mark:      models.MarkerUnread,
 		Published: time.Now(),
+		Updated:   time.Now(),
 	}
 
 	s.repo.Create(s.user.ID, &entry)
@@ -55,6 +58,31 @@ func (s *EntriesSuite) TestCreate() {
 	s.True(found)
 	s.Equal("Test Entry", entry.Title)
 	s.Equal("John Doe", entry.Author)
+	s.Equal("A test entry", entry.Summary)
+	s.Equal("<p>Test content</p>", entry.Content)
+}
+
+func (s *EntriesSuite) TestCreateKeepsFeed() {
+	feeds := sql.NewFeeds(s.db)
+
+	feed := models.Feed{
+		ID:    utils.CreateID(),
+		Title: "Test site",
+	}
+	feeds.Create(s.user.ID, &feed)
+
+	s.Require().NoError(feeds.Update(s.user.ID, &models.Feed{ID: feed.ID, Title: "Renamed site"}))
+
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Test Entry",
+		Feed:      feed,
+		Published: time.Now(),
+	}
+	s.repo.Create(s.user.ID, &entry)
+
+	feed, _ = feeds.FeedWithID(s.user.ID, feed.ID)
+	s.Equal("Renamed site", feed.Title)
 }
 
 func (s *EntriesSuite) TestList() {
@@ -249,6 +277,169 @@ func (s *EntriesSuite) TestMark() {
 	s.Equal("Article", entry.Title)
 }
 
+func (s *EntriesSuite) TestSave() {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Article",
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	}
+
+	s.repo.Create(s.user.ID, &entry)
+
+	s.Require().NoError(s.repo.Save(s.user.ID, entry.ID, true))
+
+	entry, _ = s.repo.EntryWithID(s.user.ID, entry.ID)
+	s.True(entry.Saved)
+
+	s.Require().NoError(s.repo.Save(s.user.ID, entry.ID, false))
+
+	entry, _ = s.repo.EntryWithID(s.user.ID, entry.ID)
+	s.False(entry.Saved)
+}
+
+func (s *EntriesSuite) TestSaveMissing() {
+	s.EqualError(s.repo.Save(s.user.ID, "bogus", true), repo.ErrModelNotFound.Error())
+}
+
+func (s *EntriesSuite) TestListSaved() {
+	for i := 0; i < 4; i++ {
+		entry := models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Entry " + strconv.Itoa(i),
+			Mark:      models.MarkerUnread,
+			Saved:     i%2 == 0,
+			Published: time.Now(),
+		}
+
+		s.repo.Create(s.user.ID, &entry)
+	}
+
+	entries, _ := s.repo.List(s.user.ID, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+		Saved:  true,
+	})
+	s.Require().Len(entries, 2)
+	s.Equal("Entry 0", entries[0].Title)
+	s.Equal("Entry 2", entries[1].Title)
+}
+
+func (s *EntriesSuite) TestListSavedFromCategory() {
+	ctg := models.Category{
+		ID:   utils.CreateID(),
+		Name: "test_category",
+	}
+
+	s.db.Model(s.user).Association("Categories").Append(&ctg)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+	}
+
+	s.db.Model(s.user).Association("Feeds").Append(&feed)
+	s.db.Model(&ctg).Association("Feeds").Append(&feed)
+
+	for i := 0; i < 4; i++ {
+		entry := models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Entry " + strconv.Itoa(i),
+			Mark:      models.MarkerUnread,
+			Saved:     i%2 == 0,
+			Published: time.Now(),
+		}
+
+		s.db.Model(s.user).Association("Entries").Append(&entry)
+		s.db.Model(&feed).Association("Entries").Append(&entry)
+	}
+
+	uncategorized := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Uncategorized",
+		Mark:      models.MarkerUnread,
+		Saved:     true,
+		Published: time.Now(),
+	}
+	s.repo.Create(s.user.ID, &uncategorized)
+
+	entries, _ := s.repo.ListFromCategory(s.user.ID, models.Page{
+		FilterID: ctg.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+		Saved:    true,
+	})
+	s.Require().Len(entries, 2)
+	s.Equal("Entry 0", entries[0].Title)
+	s.Equal("Entry 2", entries[1].Title)
+}
+
+func (s *EntriesSuite) TestListSavedFromFeed() {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+	}
+
+	s.db.Model(s.user).Association("Feeds").Append(&feed)
+
+	for i := 0; i < 4; i++ {
+		entry := models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Entry " + strconv.Itoa(i),
+			Mark:      models.MarkerUnread,
+			Saved:     i%2 == 0,
+			Published: time.Now(),
+		}
+
+		s.db.Model(s.user).Association("Entries").Append(&entry)
+		s.db.Model(&feed).Association("Entries").Append(&entry)
+	}
+
+	entries, _ := s.repo.ListFromFeed(s.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+		Saved:    true,
+	})
+	s.Require().Len(entries, 2)
+	s.Equal("Entry 0", entries[0].Title)
+	s.Equal("Entry 2", entries[1].Title)
+}
+
+func (s *EntriesSuite) TestListSavedFromTags() {
+	entries := make([]models.Entry, 3)
+	for idx := range entries {
+		entries[idx] = models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Entry " + strconv.Itoa(idx),
+			Mark:      models.MarkerUnread,
+			Saved:     idx < 2,
+			Published: time.Now(),
+		}
+
+		s.repo.Create(s.user.ID, &entries[idx])
+	}
+
+	tagID := utils.CreateID()
+
+	s.db.Model(s.user).Association("Tags").Append(&models.Tag{
+		ID:   tagID,
+		Name: "tag",
+	})
+
+	s.NoError(s.repo.TagEntries(s.user.ID, tagID, []string{entries[1].ID, entries[2].ID}))
+
+	taggedEntries, _ := s.repo.ListFromTags(s.user.ID, []string{tagID}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+		Saved:  true,
+	})
+	s.Require().Len(taggedEntries, 1)
+	s.Equal("Entry 1", taggedEntries[0].Title)
+}
+
 func (s *EntriesSuite) TestMarkAll() {
 	entry := models.Entry{
 		ID:        utils.CreateID(),
@@ -319,6 +510,171 @@ func (s *EntriesSuite) TestListFromTags() {
 	s.Len(taggedEntries, 2)
 }
 
+func (s *EntriesSuite) TestHasTag() {
+	entry := models.Entry{
+		ID:    utils.CreateID(),
+		Title: "Test Entry",
+		Mark:  models.MarkerUnread,
+	}
+
+	s.repo.Create(s.user.ID, &entry)
+
+	tagID := utils.CreateID()
+
+	s.db.Model(s.user).Association("Tags").Append(&models.Tag{
+		ID:   tagID,
+		Name: "tag",
+	})
+
+	s.False(s.repo.HasTag(s.user.ID, entry.ID, tagID))
+
+	s.NoError(s.repo.TagEntries(s.user.ID, tagID, []string{entry.ID}))
+
+	s.True(s.repo.HasTag(s.user.ID, entry.ID, tagID))
+	s.False(s.repo.HasTag("other", entry.ID, tagID))
+	s.False(s.repo.HasTag(s.user.ID, entry.ID, "bogus"))
+}
+
+func (s *EntriesSuite) TestListBatch() {
+	for idx := 0; idx < 3; idx++ {
+		s.repo.Create(s.user.ID, &models.Entry{ID: utils.CreateID(), Title: "Entry " + strconv.Itoa(idx)})
+	}
+
+	first := s.repo.ListBatch(s.user.ID, "", 2)
+	s.Require().Len(first, 2)
+	s.Less(first[0].ID, first[1].ID)
+
+	rest := s.repo.ListBatch(s.user.ID, first[1].ID, 2)
+	s.Require().Len(rest, 1)
+	s.Less(first[1].ID, rest[0].ID)
+
+	tagID := utils.CreateID()
+	s.db.Model(s.user).Association("Tags").Append(&models.Tag{ID: tagID, Name: "tag"})
+	s.Require().NoError(s.repo.TagEntries(s.user.ID, tagID, []string{rest[0].ID}))
+
+	rest = s.repo.ListBatch(s.user.ID, first[1].ID, 2)
+	s.Require().Len(rest, 1)
+	s.Require().Len(rest[0].Tags, 1)
+	s.Equal(tagID, rest[0].Tags[0].ID)
+
+	s.Empty(s.repo.ListBatch(s.user.ID, rest[0].ID, 2))
+	s.Empty(s.repo.ListBatch("other", "", 2))
+}
+
+func (s *EntriesSuite) TestDelete() {
+	entry := models.Entry{ID: utils.CreateID(), Title: "Test Entry"}
+	s.repo.Create(s.user.ID, &entry)
+
+	tagID := utils.CreateID()
+	s.db.Model(s.user).Association("Tags").Append(&models.Tag{ID: tagID, Name: "tag"})
+	s.Require().NoError(s.repo.TagEntries(s.user.ID, tagID, []string{entry.ID}))
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete("other", entry.ID))
+	s.NoError(s.repo.Delete(s.user.ID, entry.ID))
+
+	_, found := s.repo.EntryWithID(s.user.ID, entry.ID)
+	s.False(found)
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete(s.user.ID, entry.ID))
+
+	var tagged int
+	s.db.Table("entry_tags").Where("entry_id = ?", entry.ID).Count(&tagged)
+	s.Zero(tagged)
+}
+
+func (s *EntriesSuite) createRetentionEntries() (models.Feed, []models.Entry) {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+	}
+	sql.NewFeeds(s.db).Create(s.user.ID, &feed)
+
+	entries := make([]models.Entry, 5)
+	for idx := range entries {
+		entries[idx] = models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Entry " + strconv.Itoa(idx),
+			Feed:      feed,
+			Mark:      models.MarkerRead,
+			Published: time.Now().Add(-time.Duration(idx) * time.Hour),
+		}
+	}
+
+	entries[1].Saved = true
+	entries[2].Mark = models.MarkerUnread
+
+	for idx := range entries {
+		s.repo.Create(s.user.ID, &entries[idx])
+	}
+
+	tagID := utils.CreateID()
+	s.db.Model(s.user).Association("Tags").Append(&models.Tag{
+		ID:   tagID,
+		Name: "keep",
+	})
+	s.Require().NoError(s.repo.TagEntries(s.user.ID, tagID, []string{entries[3].ID}))
+
+	return feed, entries
+}
+
+func (s *EntriesSuite) TestCountExpired() {
+	feed, _ := s.createRetentionEntries()
+
+	s.Equal(2, s.repo.CountExpired(s.user.ID, models.RetentionPolicy{
+		FeedID: feed.ID,
+		Before: time.Now().Add(time.Hour),
+	}))
+	s.Zero(s.repo.CountExpired(s.user.ID, models.RetentionPolicy{
+		FeedID: feed.ID,
+		Before: time.Now().Add(-time.Hour),
+	}))
+	s.Zero(s.repo.CountExpired(s.user.ID, models.RetentionPolicy{FeedID: feed.ID}))
+	s.Zero(s.repo.CountExpired("other", models.RetentionPolicy{
+		FeedID: feed.ID,
+		Before: time.Now().Add(time.Hour),
+	}))
+}
+
+func (s *EntriesSuite) TestDeleteExpired() {
+	feed, entries := s.createRetentionEntries()
+
+	s.Equal(2, s.repo.DeleteExpired(s.user.ID, models.RetentionPolicy{
+		FeedID: feed.ID,
+		Before: time.Now().Add(time.Hour),
+	}))
+
+	_, found := s.repo.EntryWithID(s.user.ID, entries[0].ID)
+	s.False(found)
+
+	_, found = s.repo.EntryWithID(s.user.ID, entries[4].ID)
+	s.False(found)
+
+	for _, entry := range entries[1:4] {
+		_, found = s.repo.EntryWithID(s.user.ID, entry.ID)
+		s.True(found)
+	}
+}
+
+func (s *EntriesSuite) TestDeleteExpiredKeepLast() {
+	feed, entries := s.createRetentionEntries()
+
+	s.Equal(1, s.repo.DeleteExpired(s.user.ID, models.RetentionPolicy{
+		FeedID:   feed.ID,
+		KeepLast: 1,
+	}))
+
+	_, found := s.repo.EntryWithID(s.user.ID, entries[0].ID)
+	s.True(found)
+
+	_, found = s.repo.EntryWithID(s.user.ID, entries[4].ID)
+	s.False(found)
+
+	s.Zero(s.repo.DeleteExpired(s.user.ID, models.RetentionPolicy{
+		FeedID:   feed.ID,
+		KeepLast: 10,
+	}))
+}
+
 func (s *EntriesSuite) TestStats() {
 	for i := 0; i < 10; i++ {
 		var marker models.Marker
@@ -347,6 +703,45 @@ func (s *EntriesSuite) TestStats() {
 	s.Equal(10, stats.Total)
 }
 
+func (s *EntriesSuite) TestListUnmutedAndStatsSkipMutedFeeds() {
+	feedsRepo := sql.NewFeeds(s.db)
+
+	past := time.Now().Add(-time.Hour)
+
+	for i, until := range []*time.Time{nil, &past, nil} {
+		feed := models.Feed{ID: utils.CreateID(), Subscription: "http://example.com/" + strconv.Itoa(i)}
+		feedsRepo.Create(s.user.ID, &feed)
+
+		// The last feed is never muted and the mute of the second one expired
+		if i < 2 {
+			s.Require().NoError(feedsRepo.SetMuted(s.user.ID, feed.ID, true, until))
+		}
+
+		s.repo.Create(s.user.ID, &models.Entry{
+			ID:        utils.CreateID(),
+			Title:     "Item " + strconv.Itoa(i),
+			Feed:      feed,
+			Mark:      models.MarkerUnread,
+			Saved:     true,
+			Published: time.Now(),
+		})
+	}
+
+	entries, _ := s.repo.ListUnmuted(s.user.ID, models.Page{Count: 5, Marker: models.MarkerAny})
+	s.Require().Len(entries, 2)
+	s.Equal("Item 1", entries[0].Title)
+	s.Equal("Item 2", entries[1].Title)
+
+	// Other listings still hold the entries of muted feeds
+	entries, _ = s.repo.List(s.user.ID, models.Page{Count: 5, Marker: models.MarkerAny})
+	s.Len(entries, 3)
+
+	stats := s.repo.Stats(s.user.ID)
+	s.Equal(2, stats.Unread)
+	s.Equal(2, stats.Total)
+	s.Equal(3, stats.Saved)
+}
+
 func (s *EntriesSuite) SetupTest() {
 	var err error
 
@@ -18,26 +18,52 @@
 package sql
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/jinzhu/gorm"
 
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
 )
 
+// credentialsPurpose derives the key feed credentials are encrypted with
+const credentialsPurpose = "feed credentials"
+
 type (
 	Feeds struct {
-		db *gorm.DB
+		db             *gorm.DB
+		credentialsKey []byte
 	}
+
+	// FeedsOption configures a Feeds repo
+	FeedsOption func(*Feeds)
 )
 
-func NewFeeds(db *gorm.DB) Feeds {
-	return Feeds{
-		db,
+func NewFeeds(db *gorm.DB, opts ...FeedsOption) Feeds {
+	feeds := Feeds{
+		db: db,
+	}
+
+	for _, opt := range opts {
+		opt(&feeds)
+	}
+
+	return feeds
+}
+
+// WithCredentialsSecret encrypts feed credentials with a key derived from secret
+func WithCredentialsSecret(secret string) FeedsOption {
+	return func(f *Feeds) {
+		f.credentialsKey = utils.DeriveKey(secret, credentialsPurpose)
 	}
 }
 
 // Create a new feed owned by user
 func (f Feeds) Create(userID string, feed *models.Feed) {
+	feed.SubscriptionKey = utils.NormalizeURL(feed.Subscription)
+
 	f.db.Model(&models.User{ID: userID}).Association("Feeds").Append(feed)
 
 	if feed.Category.ID != "" {
@@ -57,11 +83,185 @@ func (f Feeds) Update(userID string, feed *models.Feed) error {
 		return repo.ErrModelNotFound
 	}
 
+	if feed.Subscription != "" {
+		feed.SubscriptionKey = utils.NormalizeURL(feed.Subscription)
+	}
+
 	f.db.Model(&dbFeed).Updates(feed)
 
+	// Selectors are replaced as a whole so that optional ones can be removed
+	if feed.Selectors.Item != "" {
+		f.db.Model(&dbFeed).Updates(map[string]interface{}{
+			"selector_item":    feed.Selectors.Item,
+			"selector_title":   feed.Selectors.Title,
+			"selector_link":    feed.Selectors.Link,
+			"selector_date":    feed.Selectors.Date,
+			"selector_content": feed.Selectors.Content,
+		})
+	}
+
+	return nil
+}
+
+// UpdateSettings sets the display title, notes, category and retention limits
+// of a feed owned by user. Unlike Update, zero values are written as well.
+func (f Feeds) UpdateSettings(userID string, feed *models.Feed) error {
+	dbFeed, found := f.FeedWithID(userID, feed.ID)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	// The category that was loaded with the feed would otherwise be saved
+	// back along with its ID
+	f.db.Model(&dbFeed).Set("gorm:save_associations", false).Updates(map[string]interface{}{
+		"display_title": feed.DisplayTitle,
+		"notes":         feed.Notes,
+		"category_id":   feed.CategoryID,
+		"keep_days":     feed.KeepDays,
+		"keep_last":     feed.KeepLast,
+	})
+
+	return nil
+}
+
+// UpdateStatus sets the health and the next fetch time of a feed owned by user.
+// Unlike Update, zero values are written as well.
+func (f Feeds) UpdateStatus(userID string, feed *models.Feed) error {
+	dbFeed, found := f.FeedWithID(userID, feed.ID)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	f.db.Model(&dbFeed).Updates(map[string]interface{}{
+		"status":        feed.Status,
+		"error_count":   feed.ErrorCount,
+		"last_error":    feed.LastError,
+		"http_status":   feed.HTTPStatus,
+		"next_fetch_at": feed.NextFetchAt,
+	})
+
+	return nil
+}
+
+// UpdateSchedule sets the refresh hints of a feed owned by user. Unlike Update,
+// zero values are written as well so that hints a feed no longer gives are cleared.
+func (f Feeds) UpdateSchedule(userID string, feed *models.Feed) error {
+	dbFeed, found := f.FeedWithID(userID, feed.ID)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	f.db.Model(&dbFeed).Updates(map[string]interface{}{
+		"ttl":              feed.TTL,
+		"skip_hours":       feed.SkipHours,
+		"skip_days":        feed.SkipDays,
+		"posting_interval": feed.PostingInterval,
+	})
+
+	return nil
+}
+
+// SetPaused pauses or resumes a feed owned by user. A paused feed is resumed
+// once until passes, unless until is nil.
+func (f Feeds) SetPaused(userID, id string, paused bool, until *time.Time) error {
+	dbFeed, found := f.FeedWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	f.db.Model(&dbFeed).Updates(map[string]interface{}{
+		"paused":       paused,
+		"paused_until": until,
+	})
+
+	return nil
+}
+
+// SetMuted mutes or unmutes a feed owned by user. A muted feed is unmuted
+// once until passes, unless until is nil.
+func (f Feeds) SetMuted(userID, id string, muted bool, until *time.Time) error {
+	dbFeed, found := f.FeedWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	f.db.Model(&dbFeed).Updates(map[string]interface{}{
+		"muted":       muted,
+		"muted_until": until,
+	})
+
+	return nil
+}
+
+// ClearExpired resumes and unmutes the feeds, of all users, whose pause or
+// mute ended before now
+func (f Feeds) ClearExpired(now time.Time) {
+	f.db.Model(&models.Feed{}).Where("paused = ? AND paused_until <= ?", true, now).
+		Updates(map[string]interface{}{"paused": false, "paused_until": nil})
+	f.db.Model(&models.Feed{}).Where("muted = ? AND muted_until <= ?", true, now).
+		Updates(map[string]interface{}{"muted": false, "muted_until": nil})
+}
+
+// SetCredentials replaces the credentials of a feed owned by user. Credentials
+// are encrypted before they are stored. Empty credentials are removed.
+func (f Feeds) SetCredentials(userID, id string, credentials *models.FeedCredentials) error {
+	dbFeed, found := f.FeedWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	var encrypted []byte
+
+	if !emptyCredentials(credentials) {
+		if f.credentialsKey == nil {
+			return repo.ErrNoCredentialsKey
+		}
+
+		plaintext, err := json.Marshal(credentials)
+		if err != nil {
+			return err
+		}
+
+		encrypted, err = utils.Encrypt(f.credentialsKey, plaintext)
+		if err != nil {
+			return err
+		}
+	}
+
+	f.db.Model(&dbFeed).Update("encrypted_credentials", encrypted)
+
 	return nil
 }
 
+// Credentials returns the decrypted credentials of a feed owned by user, or nil
+// if it has none
+func (f Feeds) Credentials(userID, id string) (*models.FeedCredentials, error) {
+	dbFeed, found := f.FeedWithID(userID, id)
+	if !found {
+		return nil, repo.ErrModelNotFound
+	}
+
+	if len(dbFeed.EncryptedCredentials) == 0 {
+		return nil, nil
+	}
+
+	if f.credentialsKey == nil {
+		return nil, repo.ErrNoCredentialsKey
+	}
+
+	plaintext, err := utils.Decrypt(f.credentialsKey, dbFeed.EncryptedCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := new(models.FeedCredentials)
+	if err := json.Unmarshal(plaintext, credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
 // Delete a feed owned by user
 func (f Feeds) Delete(userID, id string) error {
 	feed, found := f.FeedWithID(userID, id)
@@ -69,6 +269,7 @@ func (f Feeds) Delete(userID, id string) error {
 		return repo.ErrModelNotFound
 	}
 
+	f.db.Delete(models.FeedEvent{}, "feed_id = ?", feed.ID)
 	f.db.Delete(&feed)
 
 	return nil
@@ -88,6 +289,10 @@ func (f Feeds) FeedWithID(userID, id string) (feed models.Feed, found bool) {
 func (f Feeds) List(userID string, page models.Page) (feeds []models.Feed, next string) {
 	query := f.db.Model(&models.User{ID: userID})
 
+	if page.Status != "" {
+		query = query.Where("status = ?", page.Status)
+	}
+
 	if page.ContinuationID != "" {
 		if feed, found := f.FeedWithID(userID, page.ContinuationID); found {
 			query = query.Where("created_at >= ?", feed.CreatedAt)
@@ -104,6 +309,24 @@ func (f Feeds) List(userID string, page models.Page) (feeds []models.Feed, next
 	return
 }
 
+// ListDue returns up to count feeds, of all users, that are due to be fetched
+// before a time, the most overdue first. Disabled and paused feeds are never due.
+func (f Feeds) ListDue(before time.Time, count int) (feeds []models.Feed) {
+	f.db.Where("next_fetch_at <= ? AND (status IS NULL OR status <> ?)", before, models.FeedStatusDisabled).
+		Where("paused IS NULL OR paused = ? OR paused_until <= ?", false, before).
+		Order("next_fetch_at").Limit(count).Find(&feeds)
+
+	return
+}
+
+// ListWithSubscription returns the feeds, of all users, subscribed to a URL.
+// Subscriptions are compared once normalized.
+func (f Feeds) ListWithSubscription(subscription string) (feeds []models.Feed) {
+	f.db.Where("subscription_key = ?", utils.NormalizeURL(subscription)).Find(&feeds)
+
+	return
+}
+
 // Mark applies marker to a Feed with id and owned by user
 func (f Feeds) Mark(userID, id string, marker models.Marker) error {
 	if feed, found := f.FeedWithID(userID, id); found {
@@ -134,3 +357,71 @@ func (f Feeds) Stats(userID, id string) (models.Stats, error) {
 
 	return stats, nil
 }
+
+// CreateEvent records an event for a Feed owned by user
+func (f Feeds) CreateEvent(userID string, event *models.FeedEvent) error {
+	if _, found := f.FeedWithID(userID, event.FeedID); !found {
+		return repo.ErrModelNotFound
+	}
+
+	f.db.Set("gorm:save_associations", false).Create(event)
+
+	return nil
+}
+
+// ListEvents returns the events recorded for a Feed with id page.FilterID and owned by user,
+// most recent first
+func (f Feeds) ListEvents(userID string, page models.Page) (events []models.FeedEvent, next string) {
+	feed, found := f.FeedWithID(userID, page.FilterID)
+	if !found {
+		return nil, ""
+	}
+
+	query := f.db.Where("feed_id = ?", feed.ID)
+
+	if page.ContinuationID != "" {
+		var event models.FeedEvent
+		if !f.db.Where("id = ? AND feed_id = ?", page.ContinuationID, feed.ID).First(&event).RecordNotFound() {
+			query = query.Where("created_at <= ?", event.CreatedAt)
+		}
+	}
+
+	query.Order("created_at desc").Limit(page.Count + 1).Find(&events)
+
+	if len(events) > page.Count {
+		next = events[len(events)-1].ID
+		events = events[:len(events)-1]
+	}
+
+	return
+}
+
+func emptyCredentials(credentials *models.FeedCredentials) bool {
+	return credentials == nil || (credentials.Username == "" && credentials.Password == "" &&
+		credentials.Token == "" && credentials.Cookie == "" && len(credentials.Headers) == 0 &&
+		credentials.Proxy == "")
+}
+
+// migrateDisplayTitles keeps the titles of feeds stored before display titles
+// existed. Users could change titles then and syncs did not refresh them, so
+// every title is taken as the display title of its feed.
+func migrateDisplayTitles(db *gorm.DB) {
+	db.Model(&models.Feed{}).Where("title <> ?", "").UpdateColumn("display_title", gorm.Expr("title"))
+}
+
+// migrateNextFetchTimes makes feeds stored before they were scheduled due right away
+func migrateNextFetchTimes(db *gorm.DB) {
+	db.Model(&models.Feed{}).Where("next_fetch_at IS NULL").UpdateColumn("next_fetch_at", time.Now())
+}
+
+// migrateSubscriptionKeys sets the subscription key of feeds stored before it existed
+func migrateSubscriptionKeys(db *gorm.DB) {
+	var feeds []models.Feed
+
+	db.Select("id, subscription").Where("subscription_key IS NULL OR subscription_key = ?", "").Find(&feeds)
+
+	for idx := range feeds {
+		db.Model(&models.Feed{}).Where("id = ?", feeds[idx].ID).
+			UpdateColumn("subscription_key", utils.NormalizeURL(feeds[idx].Subscription))
+	}
+}
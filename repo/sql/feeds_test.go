@@ -104,6 +104,76 @@ func (s *FeedsSuite) TestUpdate() {
 	s.Equal("http://example.com/feed", updatedFeed.Subscription)
 }
 
+func (s *FeedsSuite) TestUpdateSelectors() {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+		Kind:         models.FeedKindScraper,
+		Selectors: models.ScraperSelectors{
+			Item:  "article",
+			Title: "h2",
+			Link:  "h2 a",
+		},
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	savedFeed, _ := s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Equal(models.FeedKindScraper, savedFeed.Kind)
+	s.Equal(feed.Selectors, savedFeed.Selectors)
+
+	// Selectors are replaced as a whole
+	s.NoError(s.repo.Update(s.user.ID, &models.Feed{
+		ID:        feed.ID,
+		Selectors: models.ScraperSelectors{Item: ".post"},
+	}))
+
+	savedFeed, _ = s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Equal(models.ScraperSelectors{Item: ".post"}, savedFeed.Selectors)
+
+	// and kept when none are given
+	s.NoError(s.repo.Update(s.user.ID, &models.Feed{ID: feed.ID, Title: "New Name"}))
+
+	savedFeed, _ = s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Equal(models.ScraperSelectors{Item: ".post"}, savedFeed.Selectors)
+	s.Equal(models.FeedKindScraper, savedFeed.Kind)
+}
+
+func (s *FeedsSuite) TestUpdateSettings() {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	s.NoError(s.repo.UpdateSettings(s.user.ID, &models.Feed{
+		ID:           feed.ID,
+		DisplayTitle: "Mine",
+		Notes:        "Read on weekends",
+		KeepDays:     7,
+		KeepLast:     100,
+	}))
+
+	updatedFeed, _ := s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Equal("Mine", updatedFeed.DisplayTitle)
+	s.Equal("Read on weekends", updatedFeed.Notes)
+	s.Equal(7, updatedFeed.KeepDays)
+	s.Equal(100, updatedFeed.KeepLast)
+
+	// Zero values clear settings and restore the defaults
+	s.NoError(s.repo.UpdateSettings(s.user.ID, &models.Feed{ID: feed.ID, Notes: "Read on weekends", KeepLast: 100}))
+
+	updatedFeed, _ = s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Empty(updatedFeed.DisplayTitle)
+	s.Equal("Read on weekends", updatedFeed.Notes)
+	s.Zero(updatedFeed.KeepDays)
+	s.Equal(100, updatedFeed.KeepLast)
+	s.Equal("Test site", updatedFeed.Title)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.UpdateSettings(s.user.ID, &models.Feed{ID: "bogus"}))
+}
+
 func (s *FeedsSuite) TestUpdateMissing() {
 	err := s.repo.Update(s.user.ID, &models.Feed{})
 	s.EqualError(err, repo.ErrModelNotFound.Error())
@@ -201,6 +271,407 @@ func (s *FeedsSuite) TestStats() {
 	s.Equal(10, stats.Total)
 }
 
+func (s *FeedsSuite) TestListDue() {
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		s.repo.Create(s.user.ID, &models.Feed{
+			ID:          utils.CreateID(),
+			Title:       "Test site " + strconv.Itoa(i),
+			NextFetchAt: now.Add(time.Duration(1-i) * time.Hour),
+		})
+	}
+
+	feeds := s.repo.ListDue(now, 5)
+	s.Require().Len(feeds, 2)
+	s.Equal("Test site 2", feeds[0].Title)
+	s.Equal("Test site 1", feeds[1].Title)
+	s.Equal(s.user.ID, feeds[0].UserID)
+
+	s.Len(s.repo.ListDue(now, 1), 1)
+}
+
+func (s *FeedsSuite) TestListDueSkipsDisabledFeeds() {
+	feed := models.Feed{
+		ID:          utils.CreateID(),
+		Title:       "Test site",
+		NextFetchAt: time.Now().Add(-time.Hour),
+		Status:      models.FeedStatusDisabled,
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	s.Empty(s.repo.ListDue(time.Now(), 5))
+}
+
+func (s *FeedsSuite) TestListDueSkipsPausedFeeds() {
+	now := time.Now()
+	until := now.Add(time.Hour)
+
+	paused := models.Feed{ID: utils.CreateID(), Title: "Paused", NextFetchAt: now.Add(-time.Hour)}
+	s.repo.Create(s.user.ID, &paused)
+	s.Require().NoError(s.repo.SetPaused(s.user.ID, paused.ID, true, &until))
+
+	feed, _ := s.repo.FeedWithID(s.user.ID, paused.ID)
+	s.True(feed.Paused)
+	s.Require().NotNil(feed.PausedUntil)
+	s.WithinDuration(until, *feed.PausedUntil, time.Second)
+
+	s.Empty(s.repo.ListDue(now, 5))
+
+	// The pause ends by itself
+	s.Len(s.repo.ListDue(until, 5), 1)
+
+	s.Require().NoError(s.repo.SetPaused(s.user.ID, paused.ID, false, nil))
+	s.Len(s.repo.ListDue(now, 5), 1)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.SetPaused(s.user.ID, "bogus", true, nil))
+}
+
+func (s *FeedsSuite) TestClearExpired() {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	expired := models.Feed{ID: utils.CreateID(), Title: "Expired"}
+	s.repo.Create(s.user.ID, &expired)
+	s.Require().NoError(s.repo.SetPaused(s.user.ID, expired.ID, true, &past))
+	s.Require().NoError(s.repo.SetMuted(s.user.ID, expired.ID, true, &past))
+
+	current := models.Feed{ID: utils.CreateID(), Title: "Current"}
+	s.repo.Create(s.user.ID, &current)
+	s.Require().NoError(s.repo.SetPaused(s.user.ID, current.ID, true, &future))
+	s.Require().NoError(s.repo.SetMuted(s.user.ID, current.ID, true, nil))
+
+	s.repo.ClearExpired(now)
+
+	feed, _ := s.repo.FeedWithID(s.user.ID, expired.ID)
+	s.False(feed.Paused)
+	s.Nil(feed.PausedUntil)
+	s.False(feed.Muted)
+	s.Nil(feed.MutedUntil)
+
+	feed, _ = s.repo.FeedWithID(s.user.ID, current.ID)
+	s.True(feed.Paused)
+	s.NotNil(feed.PausedUntil)
+	s.True(feed.Muted)
+	s.Nil(feed.MutedUntil)
+}
+
+func (s *FeedsSuite) TestListWithSubscription() {
+	other := &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_feeds_other",
+	}
+	s.db.Create(other)
+
+	// Subscriptions that only differ once normalized are the same
+	for userID, subscription := range map[string]string{
+		s.user.ID: "http://example.com/feed",
+		other.ID:  "HTTP://Example.com:80/feed#latest",
+	} {
+		s.repo.Create(userID, &models.Feed{
+			ID:           utils.CreateID(),
+			Title:        "Example",
+			Subscription: subscription,
+		})
+	}
+
+	s.repo.Create(s.user.ID, &models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Other",
+		Subscription: "http://example.com/other",
+	})
+
+	feeds := s.repo.ListWithSubscription("http://example.com/feed")
+	s.Require().Len(feeds, 2)
+	s.ElementsMatch([]string{s.user.ID, other.ID}, []string{feeds[0].UserID, feeds[1].UserID})
+
+	s.Empty(s.repo.ListWithSubscription("http://example.com/bogus"))
+
+	// Moved feeds are found by their new subscription
+	moved := models.Feed{ID: utils.CreateID(), Subscription: "http://example.com/feed"}
+	s.repo.Create(s.user.ID, &moved)
+	s.NoError(s.repo.Update(s.user.ID, &models.Feed{ID: moved.ID, Subscription: "http://example.com/moved"}))
+	s.Len(s.repo.ListWithSubscription("http://EXAMPLE.com/moved"), 1)
+}
+
+func (s *FeedsSuite) TestMigrateSubscriptionKeys() {
+	feed := models.Feed{ID: utils.CreateID(), Subscription: "http://Example.com/feed"}
+	s.repo.Create(s.user.ID, &feed)
+
+	// Feeds stored before subscription keys existed have none
+	s.Require().NoError(s.db.Model(&models.Feed{}).Where("id = ?", feed.ID).
+		UpdateColumn("subscription_key", "").Error)
+	s.Empty(s.repo.ListWithSubscription("http://example.com/feed"))
+
+	sql.AutoMigrateTables(s.db)
+
+	s.Len(s.repo.ListWithSubscription("http://example.com/feed"), 1)
+}
+
+func (s *FeedsSuite) TestMigrateDisplayTitles() {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	defer db.Close()
+
+	// Feeds stored before display titles existed
+	s.Require().NoError(db.Exec("CREATE TABLE feeds (id varchar(255) PRIMARY KEY, title varchar(255))").Error)
+	s.Require().NoError(db.Exec("INSERT INTO feeds (id, title) VALUES (?, ?)", "renamed", "My Feed").Error)
+
+	sql.AutoMigrateTables(db)
+
+	var feed models.Feed
+	s.Require().NoError(db.Where("id = ?", "renamed").First(&feed).Error)
+	s.Equal("My Feed", feed.DisplayTitle)
+
+	// Titles are only migrated once
+	s.Require().NoError(db.Model(&feed).UpdateColumn("display_title", "").Error)
+
+	sql.AutoMigrateTables(db)
+
+	s.Require().NoError(db.Where("id = ?", "renamed").First(&feed).Error)
+	s.Empty(feed.DisplayTitle)
+}
+
+func (s *FeedsSuite) TestMigrateNextFetchTimes() {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	defer db.Close()
+
+	// Feeds stored before they were scheduled
+	s.Require().NoError(db.Exec("CREATE TABLE feeds (id varchar(255) PRIMARY KEY, created_at datetime, " +
+		"updated_at datetime, category_id varchar(255), user_id varchar(255), title varchar(255), " +
+		"description varchar(255), subscription varchar(255), source varchar(255), ttl integer, " +
+		"etag varchar(255), last_updated datetime, status varchar(255))").Error)
+	s.Require().NoError(db.Exec("INSERT INTO feeds (id, user_id, title, subscription, status) VALUES (?, ?, ?, ?, ?)",
+		"unscheduled", s.user.ID, "My Feed", "http://example.com/feed", "").Error)
+
+	sql.AutoMigrateTables(db)
+
+	feeds := sql.NewFeeds(db).ListDue(time.Now(), 10)
+	s.Require().Len(feeds, 1)
+	s.Equal("unscheduled", feeds[0].ID)
+}
+
+func (s *FeedsSuite) TestCredentials() {
+	feed := models.Feed{
+		ID:    utils.CreateID(),
+		Title: "Test site",
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	credentials, err := s.repo.Credentials(s.user.ID, feed.ID)
+	s.NoError(err)
+	s.Nil(credentials)
+
+	s.NoError(s.repo.SetCredentials(s.user.ID, feed.ID, &models.FeedCredentials{
+		Username: "user",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+	}))
+
+	// Credentials are encrypted at rest
+	feed, _ = s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.NotEmpty(feed.EncryptedCredentials)
+	s.NotContains(string(feed.EncryptedCredentials), "secret")
+
+	credentials, err = s.repo.Credentials(s.user.ID, feed.ID)
+	s.Require().NoError(err)
+	s.Equal(&models.FeedCredentials{
+		Username: "user",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+	}, credentials)
+
+	// Other secrets cannot decrypt them
+	_, err = sql.NewFeeds(s.db, sql.WithCredentialsSecret("other")).Credentials(s.user.ID, feed.ID)
+	s.Equal(utils.ErrDecrypt, err)
+
+	// Empty credentials remove them
+	s.NoError(s.repo.SetCredentials(s.user.ID, feed.ID, &models.FeedCredentials{}))
+
+	feed, _ = s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Empty(feed.EncryptedCredentials)
+
+	credentials, err = s.repo.Credentials(s.user.ID, feed.ID)
+	s.NoError(err)
+	s.Nil(credentials)
+}
+
+func (s *FeedsSuite) TestCredentialsWithoutKey() {
+	feed := models.Feed{
+		ID:    utils.CreateID(),
+		Title: "Test site",
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	s.Equal(repo.ErrNoCredentialsKey, sql.NewFeeds(s.db).SetCredentials(s.user.ID, feed.ID, &models.FeedCredentials{
+		Token: "secret",
+	}))
+}
+
+func (s *FeedsSuite) TestCredentialsOfMissingFeed() {
+	s.Equal(repo.ErrModelNotFound, s.repo.SetCredentials(s.user.ID, "bogus", nil))
+
+	_, err := s.repo.Credentials(s.user.ID, "bogus")
+	s.Equal(repo.ErrModelNotFound, err)
+}
+
+func (s *FeedsSuite) TestUpdateStatus() {
+	feed := models.Feed{
+		ID:         utils.CreateID(),
+		Title:      "Test site",
+		Status:     models.FeedStatusError,
+		ErrorCount: 3,
+		LastError:  "server responded with status 503",
+		HTTPStatus: 503,
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	next := time.Now().Add(time.Hour)
+	s.NoError(s.repo.UpdateStatus(s.user.ID, &models.Feed{
+		ID:          feed.ID,
+		Status:      models.FeedStatusOK,
+		HTTPStatus:  200,
+		NextFetchAt: next,
+	}))
+
+	updatedFeed, found := s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(models.FeedStatusOK, updatedFeed.Status)
+	s.Zero(updatedFeed.ErrorCount)
+	s.Empty(updatedFeed.LastError)
+	s.Equal(200, updatedFeed.HTTPStatus)
+	s.WithinDuration(next, updatedFeed.NextFetchAt, time.Second)
+	s.Equal("Test site", updatedFeed.Title)
+}
+
+func (s *FeedsSuite) TestUpdateSchedule() {
+	feed := models.Feed{
+		ID:              utils.CreateID(),
+		Title:           "Test site",
+		TTL:             60,
+		SkipHours:       1 << 3,
+		SkipDays:        1 << 2,
+		PostingInterval: time.Hour,
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	// Hints that are no longer given are cleared
+	s.NoError(s.repo.UpdateSchedule(s.user.ID, &models.Feed{ID: feed.ID, TTL: 30}))
+
+	updatedFeed, found := s.repo.FeedWithID(s.user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(30, updatedFeed.TTL)
+	s.Zero(updatedFeed.SkipHours)
+	s.Zero(updatedFeed.SkipDays)
+	s.Zero(updatedFeed.PostingInterval)
+	s.Equal("Test site", updatedFeed.Title)
+
+	err := s.repo.UpdateSchedule(s.user.ID, &models.Feed{ID: "bogus"})
+	s.EqualError(err, repo.ErrModelNotFound.Error())
+}
+
+func (s *FeedsSuite) TestUpdateStatusMissing() {
+	err := s.repo.UpdateStatus(s.user.ID, &models.Feed{ID: "bogus"})
+	s.EqualError(err, repo.ErrModelNotFound.Error())
+}
+
+func (s *FeedsSuite) TestListWithStatus() {
+	for _, status := range []string{models.FeedStatusOK, models.FeedStatusError, models.FeedStatusDisabled} {
+		s.repo.Create(s.user.ID, &models.Feed{
+			ID:     utils.CreateID(),
+			Title:  "Test site " + status,
+			Status: status,
+		})
+	}
+
+	feeds, _ := s.repo.List(s.user.ID, models.Page{
+		Status: models.FeedStatusError,
+		Count:  5,
+	})
+	s.Require().Len(feeds, 1)
+	s.Equal("Test site error", feeds[0].Title)
+
+	feeds, _ = s.repo.List(s.user.ID, models.Page{Count: 5})
+	s.Len(feeds, 3)
+}
+
+func (s *FeedsSuite) TestCreateEvent() {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Subscription: "http://example.com/feed",
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	s.NoError(s.repo.CreateEvent(s.user.ID, &models.FeedEvent{
+		ID:     utils.CreateID(),
+		FeedID: feed.ID,
+		Type:   models.FeedEventRedirect,
+		From:   "http://example.com/feed",
+		To:     "https://example.com/feed",
+	}))
+
+	events, next := s.repo.ListEvents(s.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+	})
+	s.Empty(next)
+	s.Require().Len(events, 1)
+	s.Equal(models.FeedEventRedirect, events[0].Type)
+	s.Equal("https://example.com/feed", events[0].To)
+}
+
+func (s *FeedsSuite) TestCreateEventMissing() {
+	s.Equal(repo.ErrModelNotFound, s.repo.CreateEvent(s.user.ID, &models.FeedEvent{
+		ID:     utils.CreateID(),
+		FeedID: "bogus",
+	}))
+}
+
+func (s *FeedsSuite) TestListEvents() {
+	feed := models.Feed{
+		ID: utils.CreateID(),
+	}
+	s.repo.Create(s.user.ID, &feed)
+
+	for i := 0; i < 5; i++ {
+		s.Require().NoError(s.repo.CreateEvent(s.user.ID, &models.FeedEvent{
+			ID:        utils.CreateID(),
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+			FeedID:    feed.ID,
+			Type:      models.FeedEventRedirect,
+			To:        strconv.Itoa(i),
+		}))
+	}
+
+	events, next := s.repo.ListEvents(s.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    2,
+	})
+	s.Require().Len(events, 2)
+	s.NotEmpty(next)
+	s.Equal("4", events[0].To)
+	s.Equal("3", events[1].To)
+
+	events, next = s.repo.ListEvents(s.user.ID, models.Page{
+		FilterID:       feed.ID,
+		ContinuationID: next,
+		Count:          3,
+	})
+	s.Require().Len(events, 3)
+	s.Empty(next)
+	s.Equal("2", events[0].To)
+	s.Equal("0", events[2].To)
+
+	events, _ = s.repo.ListEvents("other", models.Page{
+		FilterID: feed.ID,
+		Count:    2,
+	})
+	s.Empty(events)
+}
+
 func (s *FeedsSuite) SetupTest() {
 	var err error
 
@@ -221,7 +692,7 @@ func (s *FeedsSuite) SetupTest() {
 	}
 	s.db.Create(&s.ctg)
 
-	s.repo = sql.NewFeeds(s.db)
+	s.repo = sql.NewFeeds(s.db, sql.WithCredentialsSecret("secret"))
 }
 
 func (s *FeedsSuite) TearDownTest() {
@@ -0,0 +1,106 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+type (
+	OutputFeeds struct {
+		db *gorm.DB
+	}
+)
+
+func NewOutputFeeds(db *gorm.DB) OutputFeeds {
+	return OutputFeeds{
+		db,
+	}
+}
+
+// Create a new output feed for user
+func (o OutputFeeds) Create(userID string, feed *models.OutputFeed) {
+	feed.UserID = userID
+	o.db.Create(feed)
+}
+
+// Update an output feed owned by user
+func (o OutputFeeds) Update(userID string, feed *models.OutputFeed) error {
+	current, found := o.OutputFeedWithID(userID, feed.ID)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	feed.UserID = userID
+	feed.CreatedAt = current.CreatedAt
+	o.db.Save(feed)
+
+	return nil
+}
+
+// Delete an output feed owned by user
+func (o OutputFeeds) Delete(userID, id string) error {
+	feed, found := o.OutputFeedWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	o.db.Delete(&feed)
+
+	return nil
+}
+
+// OutputFeedWithID returns an output feed with id owned by user
+func (o OutputFeeds) OutputFeedWithID(userID, id string) (feed models.OutputFeed, found bool) {
+	found = !o.db.Where("id = ? AND user_id = ?", id, userID).First(&feed).RecordNotFound()
+	return
+}
+
+// OutputFeedWithToken returns the output feed accessed with token
+func (o OutputFeeds) OutputFeedWithToken(token string) (feed models.OutputFeed, found bool) {
+	if token == "" {
+		return models.OutputFeed{}, false
+	}
+
+	found = !o.db.Where("token = ?", token).First(&feed).RecordNotFound()
+
+	return
+}
+
+// List the output feeds owned by user in the order they were created
+func (o OutputFeeds) List(userID string, page models.Page) (feeds []models.OutputFeed, next string) {
+	query := o.db.Where("user_id = ?", userID)
+
+	if page.ContinuationID != "" {
+		if feed, found := o.OutputFeedWithID(userID, page.ContinuationID); found {
+			query = query.Where("created_at >= ?", feed.CreatedAt)
+		}
+	}
+
+	query.Order("created_at").Limit(page.Count + 1).Find(&feeds)
+
+	if len(feeds) > page.Count {
+		next = feeds[len(feeds)-1].ID
+		feeds = feeds[:len(feeds)-1]
+	}
+
+	return
+}
@@ -0,0 +1,145 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type OutputFeedsSuite struct {
+	suite.Suite
+
+	db   *gorm.DB
+	repo repo.OutputFeeds
+	user *models.User
+}
+
+func (s *OutputFeedsSuite) newOutputFeed(title string) models.OutputFeed {
+	feed := models.OutputFeed{
+		ID:    utils.CreateID(),
+		Title: title,
+		Token: utils.CreateID(),
+	}
+
+	s.repo.Create(s.user.ID, &feed)
+
+	return feed
+}
+
+func (s *OutputFeedsSuite) TestCreate() {
+	feed := s.newOutputFeed("saved")
+
+	found, ok := s.repo.OutputFeedWithID(s.user.ID, feed.ID)
+	s.Require().True(ok)
+	s.Equal("saved", found.Title)
+	s.Equal(feed.Token, found.Token)
+
+	_, ok = s.repo.OutputFeedWithID(utils.CreateID(), feed.ID)
+	s.False(ok)
+}
+
+func (s *OutputFeedsSuite) TestOutputFeedWithToken() {
+	feed := s.newOutputFeed("saved")
+
+	found, ok := s.repo.OutputFeedWithToken(feed.Token)
+	s.Require().True(ok)
+	s.Equal(feed.ID, found.ID)
+	s.Equal(s.user.ID, found.UserID)
+
+	_, ok = s.repo.OutputFeedWithToken("bogus")
+	s.False(ok)
+
+	_, ok = s.repo.OutputFeedWithToken("")
+	s.False(ok)
+}
+
+func (s *OutputFeedsSuite) TestUpdate() {
+	feed := s.newOutputFeed("saved")
+	token := utils.CreateID()
+
+	s.NoError(s.repo.Update(s.user.ID, &models.OutputFeed{ID: feed.ID, Title: "renamed", Token: token}))
+
+	found, _ := s.repo.OutputFeedWithID(s.user.ID, feed.ID)
+	s.Equal("renamed", found.Title)
+	s.Equal(token, found.Token)
+
+	_, ok := s.repo.OutputFeedWithToken(feed.Token)
+	s.False(ok)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Update(s.user.ID, &models.OutputFeed{ID: "bogus"}))
+}
+
+func (s *OutputFeedsSuite) TestDelete() {
+	feed := s.newOutputFeed("saved")
+
+	s.NoError(s.repo.Delete(s.user.ID, feed.ID))
+
+	_, found := s.repo.OutputFeedWithToken(feed.Token)
+	s.False(found)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete(s.user.ID, feed.ID))
+}
+
+func (s *OutputFeedsSuite) TestList() {
+	first := s.newOutputFeed("first")
+	second := s.newOutputFeed("second")
+
+	feeds, next := s.repo.List(s.user.ID, models.Page{Count: 1})
+	s.Require().Len(feeds, 1)
+	s.Equal(first.ID, feeds[0].ID)
+	s.Equal(second.ID, next)
+
+	feeds, next = s.repo.List(s.user.ID, models.Page{Count: 1, ContinuationID: next})
+	s.Require().Len(feeds, 1)
+	s.Equal(second.ID, feeds[0].ID)
+	s.Empty(next)
+}
+
+func (s *OutputFeedsSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_output_feeds",
+	}
+
+	s.db.Create(s.user)
+
+	s.repo = sql.NewOutputFeeds(s.db)
+}
+
+func (s *OutputFeedsSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestOutputFeedsSuite(t *testing.T) {
+	suite.Run(t, new(OutputFeedsSuite))
+}
@@ -0,0 +1,132 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	Rules struct {
+		db *gorm.DB
+	}
+)
+
+func NewRules(db *gorm.DB) Rules {
+	return Rules{
+		db,
+	}
+}
+
+// Create a new rule for user along with its conditions and actions
+func (r Rules) Create(userID string, rule *models.Rule) {
+	rule.UserID = userID
+	assignRuleIDs(rule)
+
+	r.db.Create(rule)
+}
+
+// Update replaces a rule owned by user along with its conditions and actions
+func (r Rules) Update(userID string, rule *models.Rule) error {
+	if _, found := r.RuleWithID(userID, rule.ID); !found {
+		return repo.ErrModelNotFound
+	}
+
+	rule.UserID = userID
+	assignRuleIDs(rule)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.RuleCondition{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.RuleAction{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Save(rule).Error
+	})
+}
+
+// Delete a rule owned by user along with its conditions and actions
+func (r Rules) Delete(userID, id string) error {
+	rule, found := r.RuleWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	r.db.Where("rule_id = ?", rule.ID).Delete(&models.RuleCondition{})
+	r.db.Where("rule_id = ?", rule.ID).Delete(&models.RuleAction{})
+	r.db.Delete(&rule)
+
+	return nil
+}
+
+// RuleWithID returns a rule with id owned by user
+func (r Rules) RuleWithID(userID, id string) (rule models.Rule, found bool) {
+	found = !r.preload().Where("id = ? AND user_id = ?", id, userID).First(&rule).RecordNotFound()
+	return
+}
+
+// List the rules owned by user in the order they were created
+func (r Rules) List(userID string, page models.Page) (rules []models.Rule, next string) {
+	query := r.preload().Where("user_id = ?", userID)
+
+	if page.ContinuationID != "" {
+		if rule, found := r.RuleWithID(userID, page.ContinuationID); found {
+			query = query.Where("created_at >= ?", rule.CreatedAt)
+		}
+	}
+
+	query.Order("created_at").Limit(page.Count + 1).Find(&rules)
+
+	if len(rules) > page.Count {
+		next = rules[len(rules)-1].ID
+		rules = rules[:len(rules)-1]
+	}
+
+	return
+}
+
+// ListAll returns every rule owned by user in the order they were created
+func (r Rules) ListAll(userID string) (rules []models.Rule) {
+	r.preload().Where("user_id = ?", userID).Order("created_at").Find(&rules)
+
+	return
+}
+
+func (r Rules) preload() *gorm.DB {
+	return r.db.Preload("Conditions").Preload("Actions")
+}
+
+// assignRuleIDs gives the conditions and actions of rule an ID
+func assignRuleIDs(rule *models.Rule) {
+	for idx := range rule.Conditions {
+		rule.Conditions[idx].ID = utils.CreateID()
+		rule.Conditions[idx].RuleID = rule.ID
+	}
+
+	for idx := range rule.Actions {
+		rule.Actions[idx].ID = utils.CreateID()
+		rule.Actions[idx].RuleID = rule.ID
+	}
+}
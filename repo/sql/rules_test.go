@@ -0,0 +1,161 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type RulesSuite struct {
+	suite.Suite
+
+	db   *gorm.DB
+	repo repo.Rules
+	user *models.User
+}
+
+func (s *RulesSuite) newRule(name string) models.Rule {
+	rule := models.Rule{
+		ID:   utils.CreateID(),
+		Name: name,
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorContains, Value: "sponsored"},
+		},
+		Actions: []models.RuleAction{
+			{Type: models.RuleActionMark, Marker: "read"},
+			{Type: models.RuleActionSave},
+		},
+	}
+
+	s.repo.Create(s.user.ID, &rule)
+
+	return rule
+}
+
+func (s *RulesSuite) TestCreate() {
+	rule := s.newRule("sponsored")
+
+	found, ok := s.repo.RuleWithID(s.user.ID, rule.ID)
+	s.Require().True(ok)
+	s.Equal("sponsored", found.Name)
+	s.Require().Len(found.Conditions, 1)
+	s.Equal("sponsored", found.Conditions[0].Value)
+	s.Len(found.Actions, 2)
+
+	_, ok = s.repo.RuleWithID("other", rule.ID)
+	s.False(ok)
+}
+
+func (s *RulesSuite) TestUpdate() {
+	rule := s.newRule("sponsored")
+
+	rule.Name = "ads"
+	rule.Conditions = []models.RuleCondition{
+		{Field: models.RuleFieldLink, Operator: models.RuleOperatorMatches, Value: "/ads/"},
+		{Field: models.RuleFieldAuthor, Operator: models.RuleOperatorEquals, Value: "ads"},
+	}
+	rule.Actions = []models.RuleAction{{Type: models.RuleActionDelete}}
+
+	s.Require().NoError(s.repo.Update(s.user.ID, &rule))
+
+	found, _ := s.repo.RuleWithID(s.user.ID, rule.ID)
+	s.Equal("ads", found.Name)
+	s.Len(found.Conditions, 2)
+	s.Require().Len(found.Actions, 1)
+	s.Equal(models.RuleActionDelete, found.Actions[0].Type)
+
+	var conditions int
+	s.db.Model(&models.RuleCondition{}).Count(&conditions)
+	s.Equal(2, conditions)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Update("other", &rule))
+}
+
+func (s *RulesSuite) TestDelete() {
+	rule := s.newRule("sponsored")
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete("other", rule.ID))
+	s.NoError(s.repo.Delete(s.user.ID, rule.ID))
+
+	_, found := s.repo.RuleWithID(s.user.ID, rule.ID)
+	s.False(found)
+
+	var actions int
+	s.db.Model(&models.RuleAction{}).Count(&actions)
+	s.Zero(actions)
+}
+
+func (s *RulesSuite) TestList() {
+	first := s.newRule("first")
+	second := s.newRule("second")
+
+	s.db.Model(&first).UpdateColumn("created_at", time.Now().Add(-time.Hour))
+
+	rules, next := s.repo.List(s.user.ID, models.Page{Count: 1})
+	s.Require().Len(rules, 1)
+	s.Equal(first.ID, rules[0].ID)
+	s.Len(rules[0].Actions, 2)
+	s.Equal(second.ID, next)
+
+	rules, next = s.repo.List(s.user.ID, models.Page{Count: 1, ContinuationID: next})
+	s.Require().Len(rules, 1)
+	s.Equal(second.ID, rules[0].ID)
+	s.Empty(next)
+
+	rules = s.repo.ListAll(s.user.ID)
+	s.Require().Len(rules, 2)
+	s.Equal(first.ID, rules[0].ID)
+	s.Len(rules[1].Conditions, 1)
+
+	s.Empty(s.repo.ListAll("other"))
+}
+
+func (s *RulesSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_rules",
+	}
+
+	s.db.Create(s.user)
+
+	s.repo = sql.NewRules(s.db)
+}
+
+func (s *RulesSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestRulesSuite(t *testing.T) {
+	suite.Run(t, new(RulesSuite))
+}
@@ -0,0 +1,241 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/models"
+)
+
+const (
+	searchTable    = "entries_fts"
+	searchIndex    = "idx_entries_search"
+	searchDocument = "to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(author, '') || ' ' || " +
+		"coalesce(content, '') || ' ' || coalesce(summary, ''))"
+	searchColumns = "title, author, content, summary"
+)
+
+var (
+	sqliteSearchTriggers = []string{"entries_fts_insert", "entries_fts_delete", "entries_fts_update"}
+
+	sqliteSearchStatements = []string{
+		"CREATE VIRTUAL TABLE " + searchTable + " USING fts5(entry_id UNINDEXED, " + searchColumns + ")",
+		`CREATE TRIGGER IF NOT EXISTS entries_fts_insert AFTER INSERT ON entries BEGIN
+			INSERT INTO entries_fts (entry_id, title, author, content, summary)
+			VALUES (new.id, new.title, new.author, new.content, new.summary);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS entries_fts_delete AFTER DELETE ON entries BEGIN
+			DELETE FROM entries_fts WHERE entry_id = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS entries_fts_update AFTER UPDATE OF title, author, content, summary ON entries BEGIN
+			DELETE FROM entries_fts WHERE entry_id = old.id;
+			INSERT INTO entries_fts (entry_id, title, author, content, summary)
+			VALUES (new.id, new.title, new.author, new.content, new.summary);
+		END`,
+		"INSERT INTO entries_fts (entry_id, " + searchColumns + ") SELECT id, " + searchColumns + " FROM entries",
+	}
+)
+
+type (
+	// Search implements full-text search over entries. The implementation
+	// depends on the database type in use: SQLite relies on an FTS5 table,
+	// PostgreSQL on a tsvector index and MySQL on a FULLTEXT index.
+	Search struct {
+		db      *gorm.DB
+		entries Entries
+		match   func(query *gorm.DB, terms []string) *gorm.DB
+	}
+)
+
+func NewSearch(db *gorm.DB) Search {
+	search := Search{
+		db:      db,
+		entries: NewEntries(db),
+		match:   likeMatch,
+	}
+
+	switch db.Dialect().GetName() {
+	case "sqlite3":
+		if db.HasTable(searchTable) {
+			search.match = fts5Match
+		}
+	case "postgres":
+		search.match = tsvectorMatch
+	case "mysql":
+		search.match = fulltextMatch
+	}
+
+	return search
+}
+
+// autoMigrateSearch creates the full-text index used by Search. Indexes that
+// were created before summaries were indexed are rebuilt.
+func autoMigrateSearch(db *gorm.DB) {
+	switch db.Dialect().GetName() {
+	case "sqlite3":
+		if db.HasTable(searchTable) {
+			if searchIndexCount(db, "SELECT count(*) FROM sqlite_master WHERE name = ? AND sql LIKE ?",
+				searchTable, "%summary%") > 0 {
+				return
+			}
+
+			dropSQLiteSearch(db)
+		}
+
+		// SQLite must be built with FTS5 (-tags sqlite_fts5) for full-text search to be
+		// available. Search falls back to pattern matching otherwise.
+		var fts5 bool
+		if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Row().Scan(&fts5); err != nil || !fts5 {
+			log.Warn("SQLite was built without FTS5. Full-text search is not available.")
+			return
+		}
+
+		for _, statement := range sqliteSearchStatements {
+			if err := db.Exec(statement).Error; err != nil {
+				log.Error(err)
+				dropSQLiteSearch(db)
+
+				return
+			}
+		}
+	case "postgres":
+		if searchIndexCount(db, "SELECT count(*) FROM pg_indexes WHERE indexname = ? AND indexdef LIKE ?",
+			searchIndex, "%summary%") == 0 {
+			db.Exec("DROP INDEX IF EXISTS " + searchIndex)
+		}
+
+		db.Exec("CREATE INDEX IF NOT EXISTS " + searchIndex + " ON entries USING GIN (" + searchDocument + ")")
+	case "mysql":
+		if searchIndexCount(db, "SELECT count(*) FROM INFORMATION_SCHEMA.STATISTICS WHERE table_schema = DATABASE() "+
+			"AND table_name = 'entries' AND index_name = ? AND column_name = 'summary'", searchIndex) > 0 {
+			return
+		}
+
+		if db.Dialect().HasIndex("entries", searchIndex) {
+			db.Exec("ALTER TABLE entries DROP INDEX " + searchIndex)
+		}
+
+		db.Exec("ALTER TABLE entries ADD FULLTEXT INDEX " + searchIndex + " (" + searchColumns + ")")
+	}
+}
+
+func searchIndexCount(db *gorm.DB, query string, args ...interface{}) int {
+	var count int
+	if err := db.Raw(query, args...).Row().Scan(&count); err != nil {
+		log.Error(err)
+	}
+
+	return count
+}
+
+func dropSQLiteSearch(db *gorm.DB) {
+	for _, trigger := range sqliteSearchTriggers {
+		db.Exec("DROP TRIGGER IF EXISTS " + trigger)
+	}
+
+	db.Exec("DROP TABLE IF EXISTS " + searchTable)
+}
+
+// Entries returns all entries owned by user that match a query
+func (s Search) Entries(userID string, query models.SearchQuery, page models.Page) (
+	entries []models.Entry, next string) {
+	terms := strings.Fields(query.Text)
+	if len(terms) == 0 {
+		return nil, ""
+	}
+
+	scope := s.db.Model(&models.User{ID: userID})
+
+	switch {
+	case query.FeedID != "":
+		if s.db.Model(&models.User{ID: userID}).Where("id = ?", query.FeedID).Related(&models.Feed{}).
+			RecordNotFound() {
+			return nil, ""
+		}
+
+		scope = scope.Where("feed_id = ?", query.FeedID)
+	case query.CategoryID != "":
+		var ctg models.Category
+		if s.db.Model(&models.User{ID: userID}).Where("id = ?", query.CategoryID).Related(&ctg).RecordNotFound() {
+			return nil, ""
+		}
+
+		var feeds []models.Feed
+
+		s.db.Model(&ctg).Related(&feeds)
+
+		feedIds := make([]models.ID, len(feeds))
+		for idx := range feeds {
+			feedIds[idx] = feeds[idx].ID
+		}
+
+		scope = scope.Where("feed_id in (?)", feedIds)
+	case query.TagID != "":
+		if s.db.Model(&models.User{ID: userID}).Where("id = ?", query.TagID).Related(&models.Tag{}).
+			RecordNotFound() {
+			return nil, ""
+		}
+
+		scope = scope.Joins("inner join entry_tags ON entry_tags.entry_id = entries.id").
+			Where("entry_tags.tag_id = ?", query.TagID)
+	}
+
+	return s.entries.paginateList(userID, s.match(scope, terms), page)
+}
+
+func fts5Match(query *gorm.DB, terms []string) *gorm.DB {
+	phrases := make([]string, len(terms))
+	for idx, term := range terms {
+		phrases[idx] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+
+	return query.Joins("inner join "+searchTable+" ON "+searchTable+".entry_id = entries.id").
+		Where(searchTable+" MATCH ?", strings.Join(phrases, " "))
+}
+
+func tsvectorMatch(query *gorm.DB, terms []string) *gorm.DB {
+	return query.Where(searchDocument+" @@ plainto_tsquery('simple', ?)", strings.Join(terms, " "))
+}
+
+func fulltextMatch(query *gorm.DB, terms []string) *gorm.DB {
+	var words []string
+
+	for _, term := range terms {
+		term = strings.Trim(term, `+-<>()~*"@`)
+		if term != "" {
+			words = append(words, "+"+term+"*")
+		}
+	}
+
+	return query.Where("MATCH ("+searchColumns+") AGAINST (? IN BOOLEAN MODE)", strings.Join(words, " "))
+}
+
+func likeMatch(query *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		query = query.Where("(entries.title LIKE ? ESCAPE ? OR entries.author LIKE ? ESCAPE ? OR "+
+			"entries.content LIKE ? ESCAPE ? OR entries.summary LIKE ? ESCAPE ?)",
+			pattern, `\`, pattern, `\`, pattern, `\`, pattern, `\`)
+	}
+
+	return query
+}
@@ -0,0 +1,339 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type SearchSuite struct {
+	suite.Suite
+
+	db          *gorm.DB
+	user        *models.User
+	feed        models.Feed
+	repo        repo.Search
+	entriesRepo repo.Entries
+}
+
+func (s *SearchSuite) createEntry(title, author, content string) models.Entry {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     title,
+		Author:    author,
+		Content:   content,
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	}
+
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	return entry
+}
+
+func (s *SearchSuite) TestSearch() {
+	s.createEntry("Kernel release", "Linus", "<p>A new kernel version</p>")
+	s.createEntry("Gardening tips", "Jane Doe", "<p>Tomatoes need sun</p>")
+	s.createEntry("Weekly digest", "John Doe", "<p>This week in kernel news</p>")
+
+	entries, next := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Empty(next)
+	s.Require().Len(entries, 2)
+	s.Equal("Kernel release", entries[0].Title)
+	s.Equal("Weekly digest", entries[1].Title)
+
+	entries, _ = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "jane"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Gardening tips", entries[0].Title)
+
+	entries, _ = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel news"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Weekly digest", entries[0].Title)
+}
+
+func (s *SearchSuite) TestSearchSummary() {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Weekly digest",
+		Summary:   "This week in kernel news",
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	}
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	s.createEntry("Gardening tips", "Jane Doe", "<p>Tomatoes need sun</p>")
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel news"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(entry.ID, entries[0].ID)
+}
+
+func (s *SearchSuite) TestSearchRebuildsOutdatedIndex() {
+	for _, trigger := range []string{"entries_fts_insert", "entries_fts_delete", "entries_fts_update"} {
+		s.Require().NoError(s.db.Exec("DROP TRIGGER IF EXISTS " + trigger).Error)
+	}
+
+	s.Require().NoError(s.db.Exec("DROP TABLE IF EXISTS entries_fts").Error)
+
+	// Search tables created before summaries were indexed
+	if err := s.db.Exec("CREATE VIRTUAL TABLE entries_fts USING fts5(entry_id UNINDEXED, title, author, content)").
+		Error; err != nil {
+		s.T().Skip("SQLite was built without FTS5")
+	}
+
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Weekly digest",
+		Summary:   "This week in kernel news",
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	}
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	sql.AutoMigrateTables(s.db)
+	s.repo = sql.NewSearch(s.db)
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(entry.ID, entries[0].ID)
+}
+
+func (s *SearchSuite) TestSearchPagination() {
+	for i := 0; i < 3; i++ {
+		s.createEntry("Kernel release", "Linus", "")
+	}
+
+	entries, next := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  2,
+		Marker: models.MarkerAny,
+	})
+	s.Len(entries, 2)
+	s.NotEmpty(next)
+
+	entries, next = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		ContinuationID: next,
+		Count:          2,
+		Marker:         models.MarkerAny,
+	})
+	s.Len(entries, 1)
+	s.Empty(next)
+}
+
+func (s *SearchSuite) TestSearchWithMarker() {
+	entry := s.createEntry("Kernel release", "Linus", "")
+	s.createEntry("Kernel patches", "Greg", "")
+
+	s.Require().NoError(s.entriesRepo.Mark(s.user.ID, entry.ID, models.MarkerRead))
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerUnread,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Kernel patches", entries[0].Title)
+}
+
+func (s *SearchSuite) TestSearchInFeed() {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Kernel release",
+		Feed:      s.feed,
+		Published: time.Now(),
+	}
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	s.createEntry("Kernel patches", "Greg", "")
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel", FeedID: s.feed.ID}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(entry.ID, entries[0].ID)
+
+	entries, _ = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel", FeedID: "bogus"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SearchSuite) TestSearchInCategory() {
+	ctg := models.Category{
+		ID:   utils.CreateID(),
+		Name: "news",
+	}
+	sql.NewCategories(s.db).Create(s.user.ID, &ctg)
+	s.Require().NoError(sql.NewCategories(s.db).AddFeed(s.user.ID, s.feed.ID, ctg.ID))
+
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Kernel release",
+		Feed:      s.feed,
+		Published: time.Now(),
+	}
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	s.createEntry("Kernel patches", "Greg", "")
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel", CategoryID: ctg.ID}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(entry.ID, entries[0].ID)
+}
+
+func (s *SearchSuite) TestSearchInTag() {
+	tag := models.Tag{
+		ID:   utils.CreateID(),
+		Name: "linux",
+	}
+	sql.NewTags(s.db).Create(s.user.ID, &tag)
+
+	entry := s.createEntry("Kernel release", "Linus", "")
+	s.createEntry("Kernel patches", "Greg", "")
+
+	s.Require().NoError(s.entriesRepo.TagEntries(s.user.ID, tag.ID, []string{entry.ID}))
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel", TagID: tag.ID}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(entry.ID, entries[0].ID)
+}
+
+func (s *SearchSuite) TestSearchOtherUser() {
+	s.createEntry("Kernel release", "Linus", "")
+
+	entries, _ := s.repo.Entries(utils.CreateID(), models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SearchSuite) TestSearchEmptyQuery() {
+	s.createEntry("Kernel release", "Linus", "")
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "  "}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SearchSuite) TestSearchWithoutFTS5() {
+	// SQLite builds without FTS5 have no search table and match patterns instead
+	for _, trigger := range []string{"entries_fts_insert", "entries_fts_delete", "entries_fts_update"} {
+		s.Require().NoError(s.db.Exec("DROP TRIGGER IF EXISTS " + trigger).Error)
+	}
+
+	s.Require().NoError(s.db.Exec("DROP TABLE IF EXISTS entries_fts").Error)
+
+	s.repo = sql.NewSearch(s.db)
+
+	s.createEntry("Kernel release", "Linus", "<p>A new kernel version</p>")
+	s.createEntry("Gardening tips", "Jane Doe", "<p>Tomatoes need sun</p>")
+	s.createEntry("Weekly digest", "John Doe", "<p>This week in kernel news</p>")
+
+	entries, _ := s.repo.Entries(s.user.ID, models.SearchQuery{Text: "kernel news"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Weekly digest", entries[0].Title)
+
+	// Wildcards in the query are matched literally
+	s.createEntry("Sale: 100% off", "", "")
+	s.createEntry("1000 visitors", "", "")
+	s.createEntry("file_name", "", "")
+	s.createEntry("filename", "", "")
+
+	entries, _ = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "100%"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Sale: 100% off", entries[0].Title)
+
+	entries, _ = s.repo.Entries(s.user.ID, models.SearchQuery{Text: "file_"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("file_name", entries[0].Title)
+}
+
+func (s *SearchSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_search",
+	}
+	sql.NewUsers(s.db).Create(s.user)
+
+	s.feed = models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Test site",
+		Subscription: "http://example.com",
+	}
+	sql.NewFeeds(s.db).Create(s.user.ID, &s.feed)
+
+	s.entriesRepo = sql.NewEntries(s.db)
+	s.repo = sql.NewSearch(s.db)
+}
+
+func (s *SearchSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestSearchSuite(t *testing.T) {
+	suite.Run(t, new(SearchSuite))
+}
@@ -0,0 +1,203 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns with a backslash. The
+// escape character is bound as an argument since MySQL reads a backslash in a
+// string literal as an escape of its own.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+type (
+	SmartFolders struct {
+		db      *gorm.DB
+		entries Entries
+	}
+)
+
+func NewSmartFolders(db *gorm.DB) SmartFolders {
+	return SmartFolders{
+		db:      db,
+		entries: NewEntries(db),
+	}
+}
+
+// Create a new smart folder for user
+func (s SmartFolders) Create(userID string, folder *models.SmartFolder) {
+	folder.UserID = userID
+	s.db.Create(folder)
+}
+
+// Update a smart folder owned by user
+func (s SmartFolders) Update(userID string, folder *models.SmartFolder) error {
+	current, found := s.SmartFolderWithID(userID, folder.ID)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	folder.UserID = userID
+	folder.CreatedAt = current.CreatedAt
+	s.db.Save(folder)
+
+	return nil
+}
+
+// Delete a smart folder owned by user
+func (s SmartFolders) Delete(userID, id string) error {
+	folder, found := s.SmartFolderWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	s.db.Delete(&folder)
+
+	return nil
+}
+
+// SmartFolderWithID returns a smart folder with id owned by user
+func (s SmartFolders) SmartFolderWithID(userID, id string) (folder models.SmartFolder, found bool) {
+	found = !s.db.Where("id = ? AND user_id = ?", id, userID).First(&folder).RecordNotFound()
+	return
+}
+
+// List the smart folders owned by user in the order they were created
+func (s SmartFolders) List(userID string, page models.Page) (folders []models.SmartFolder, next string) {
+	query := s.db.Where("user_id = ?", userID)
+
+	if page.ContinuationID != "" {
+		if folder, found := s.SmartFolderWithID(userID, page.ContinuationID); found {
+			query = query.Where("created_at >= ?", folder.CreatedAt)
+		}
+	}
+
+	query.Order("created_at").Limit(page.Count + 1).Find(&folders)
+
+	if len(folders) > page.Count {
+		next = folders[len(folders)-1].ID
+		folders = folders[:len(folders)-1]
+	}
+
+	return
+}
+
+// Entries returns the entries that match a smart folder owned by user. The smart
+// folder is identified by the page's FilterID. Like the entries of user, the ones
+// of muted feeds are left out unless the folder only matches saved entries.
+func (s SmartFolders) Entries(userID string, page models.Page) (entries []models.Entry, next string) {
+	folder, found := s.SmartFolderWithID(userID, page.FilterID)
+	if !found {
+		return nil, ""
+	}
+
+	query := matchSmartFolder(s.db.Model(&models.User{ID: userID}), folder)
+	if !folder.Saved {
+		query = query.Where(unmutedFeeds, userID, true, time.Now())
+	}
+
+	return s.entries.paginateList(userID, query, page)
+}
+
+// Stats returns statistics on the entries that match a smart folder owned by user
+func (s SmartFolders) Stats(userID, id string) (models.Stats, error) {
+	folder, found := s.SmartFolderWithID(userID, id)
+	if !found {
+		return models.Stats{}, repo.ErrModelNotFound
+	}
+
+	query := matchSmartFolder(s.db.Model(&models.User{ID: userID}), folder)
+
+	// Muted feeds are only counted in the saved entries, as in the stats of user
+	visible := query
+	if !folder.Saved {
+		visible = query.Where(unmutedFeeds, userID, true, time.Now())
+	}
+
+	stats := models.Stats{}
+
+	stats.Unread = visible.Where("mark = ?", models.MarkerUnread).Association("Entries").Count()
+	stats.Read = visible.Where("mark = ?", models.MarkerRead).Association("Entries").Count()
+	stats.Saved = query.Where("saved = ?", true).Association("Entries").Count()
+	stats.Total = visible.Association("Entries").Count()
+
+	return stats, nil
+}
+
+// Mark applies marker to the entries that match a smart folder owned by user.
+// As with Entries, the ones of muted feeds are left untouched unless the folder
+// only matches saved entries.
+func (s SmartFolders) Mark(userID, id string, marker models.Marker) error {
+	folder, found := s.SmartFolderWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	markedEntry := &models.Entry{Mark: marker}
+
+	query := matchSmartFolder(s.db.Model(markedEntry).Where("user_id = ?", userID), folder)
+	if !folder.Saved {
+		query = query.Where(unmutedFeeds, userID, true, time.Now())
+	}
+
+	query.Update(markedEntry)
+
+	return nil
+}
+
+// matchSmartFolder restricts a query on entries to those that meet the criteria of a smart folder.
+// Subqueries are used instead of joins so that the query can also be used to update entries.
+func matchSmartFolder(query *gorm.DB, folder models.SmartFolder) *gorm.DB {
+	if marker := models.MarkerFromString(folder.Marker); marker != models.MarkerAny {
+		query = query.Where("entries.mark = ?", marker)
+	}
+
+	if folder.Saved {
+		query = query.Where("entries.saved = ?", true)
+	}
+
+	if folder.FeedID != "" {
+		query = query.Where("entries.feed_id = ?", folder.FeedID)
+	}
+
+	if folder.CategoryID != "" {
+		query = query.Where("entries.feed_id IN (SELECT id FROM feeds WHERE category_id = ?)", folder.CategoryID)
+	}
+
+	if folder.TagID != "" {
+		query = query.Where("entries.id IN (SELECT entry_id FROM entry_tags WHERE tag_id = ?)", folder.TagID)
+	}
+
+	if folder.Title != "" {
+		query = query.Where("LOWER(entries.title) LIKE ? ESCAPE ?",
+			"%"+likeEscaper.Replace(strings.ToLower(folder.Title))+"%", `\`)
+	}
+
+	if folder.Days > 0 {
+		query = query.Where("entries.published >= ?", time.Now().AddDate(0, 0, -folder.Days))
+	}
+
+	return query
+}
@@ -0,0 +1,316 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type SmartFoldersSuite struct {
+	suite.Suite
+
+	db          *gorm.DB
+	repo        repo.SmartFolders
+	entriesRepo repo.Entries
+	user        *models.User
+	ctg         models.Category
+	feed        models.Feed
+	other       models.Feed
+	tag         models.Tag
+}
+
+func (s *SmartFoldersSuite) newEntry(feed models.Feed, title string, mark models.Marker,
+	age time.Duration) models.Entry {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     title,
+		Mark:      mark,
+		Published: time.Now().Add(-age),
+		Feed:      feed,
+	}
+
+	s.entriesRepo.Create(s.user.ID, &entry)
+
+	return entry
+}
+
+func (s *SmartFoldersSuite) newSmartFolder(folder models.SmartFolder) models.SmartFolder {
+	folder.ID = utils.CreateID()
+	s.repo.Create(s.user.ID, &folder)
+
+	return folder
+}
+
+func (s *SmartFoldersSuite) TestCreate() {
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", CategoryID: s.ctg.ID, Days: 7})
+
+	found, ok := s.repo.SmartFolderWithID(s.user.ID, folder.ID)
+	s.Require().True(ok)
+	s.Equal("security", found.Name)
+	s.Equal(s.ctg.ID, found.CategoryID)
+	s.Equal(7, found.Days)
+
+	_, ok = s.repo.SmartFolderWithID(utils.CreateID(), folder.ID)
+	s.False(ok)
+}
+
+func (s *SmartFoldersSuite) TestUpdate() {
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", Title: "cve"})
+
+	s.NoError(s.repo.Update(s.user.ID, &models.SmartFolder{ID: folder.ID, Name: "saved", Saved: true}))
+
+	found, _ := s.repo.SmartFolderWithID(s.user.ID, folder.ID)
+	s.Equal("saved", found.Name)
+	s.True(found.Saved)
+	s.Empty(found.Title)
+	s.Equal(folder.CreatedAt.Unix(), found.CreatedAt.Unix())
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Update(s.user.ID, &models.SmartFolder{ID: "bogus"}))
+}
+
+func (s *SmartFoldersSuite) TestDelete() {
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security"})
+
+	s.NoError(s.repo.Delete(s.user.ID, folder.ID))
+
+	_, found := s.repo.SmartFolderWithID(s.user.ID, folder.ID)
+	s.False(found)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete(s.user.ID, folder.ID))
+}
+
+func (s *SmartFoldersSuite) TestList() {
+	first := s.newSmartFolder(models.SmartFolder{Name: "first"})
+	second := s.newSmartFolder(models.SmartFolder{Name: "second"})
+
+	folders, next := s.repo.List(s.user.ID, models.Page{Count: 1})
+	s.Require().Len(folders, 1)
+	s.Equal(first.ID, folders[0].ID)
+	s.Equal(second.ID, next)
+
+	folders, next = s.repo.List(s.user.ID, models.Page{Count: 1, ContinuationID: next})
+	s.Require().Len(folders, 1)
+	s.Equal(second.ID, folders[0].ID)
+	s.Empty(next)
+}
+
+func (s *SmartFoldersSuite) TestEntries() {
+	match := s.newEntry(s.feed, "CVE-2021-3449 in OpenSSL", models.MarkerUnread, time.Hour)
+	s.newEntry(s.feed, "Release notes", models.MarkerUnread, time.Hour)
+	s.newEntry(s.feed, "Old cve", models.MarkerUnread, 30*24*time.Hour)
+	s.newEntry(s.feed, "Read cve", models.MarkerRead, time.Hour)
+	s.newEntry(s.other, "Uncategorized cve", models.MarkerUnread, time.Hour)
+
+	folder := s.newSmartFolder(models.SmartFolder{
+		Name:       "security",
+		Marker:     "unread",
+		CategoryID: s.ctg.ID,
+		Title:      "cve",
+		Days:       7,
+	})
+
+	entries, next := s.repo.Entries(s.user.ID, models.Page{
+		FilterID: folder.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(next)
+	s.Require().Len(entries, 1)
+	s.Equal(match.ID, entries[0].ID)
+
+	entries, _ = s.repo.Entries(s.user.ID, models.Page{FilterID: "bogus", Count: 10, Marker: models.MarkerAny})
+	s.Empty(entries)
+}
+
+func (s *SmartFoldersSuite) TestEntriesTitleIsLiteral() {
+	match := s.newEntry(s.feed, "50% off_today", models.MarkerUnread, time.Hour)
+	s.newEntry(s.feed, "500 offers today", models.MarkerUnread, time.Hour)
+	s.newEntry(s.feed, `C:\dir`, models.MarkerUnread, time.Hour)
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "deals", Title: "0% off_"})
+
+	entries, _ := s.repo.Entries(s.user.ID, models.Page{FilterID: folder.ID, Count: 10, Marker: models.MarkerAny})
+	s.Require().Len(entries, 1)
+	s.Equal(match.ID, entries[0].ID)
+
+	folder = s.newSmartFolder(models.SmartFolder{Name: "paths", Title: `:\d`})
+
+	entries, _ = s.repo.Entries(s.user.ID, models.Page{FilterID: folder.ID, Count: 10, Marker: models.MarkerAny})
+	s.Require().Len(entries, 1)
+	s.Equal(`C:\dir`, entries[0].Title)
+}
+
+func (s *SmartFoldersSuite) TestEntriesFromTag() {
+	tagged := s.newEntry(s.other, "Tagged", models.MarkerRead, time.Hour)
+	s.newEntry(s.other, "Untagged", models.MarkerRead, time.Hour)
+
+	s.Require().NoError(s.entriesRepo.TagEntries(s.user.ID, s.tag.ID, []string{tagged.ID}))
+	s.Require().NoError(s.entriesRepo.Save(s.user.ID, tagged.ID, true))
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "tagged", TagID: s.tag.ID, FeedID: s.other.ID, Saved: true})
+
+	entries, _ := s.repo.Entries(s.user.ID, models.Page{FilterID: folder.ID, Count: 10, Marker: models.MarkerAny})
+	s.Require().Len(entries, 1)
+	s.Equal(tagged.ID, entries[0].ID)
+}
+
+func (s *SmartFoldersSuite) TestStats() {
+	s.newEntry(s.feed, "CVE one", models.MarkerUnread, time.Hour)
+	s.newEntry(s.feed, "CVE two", models.MarkerRead, time.Hour)
+	s.newEntry(s.feed, "Release notes", models.MarkerUnread, time.Hour)
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", Title: "cve"})
+
+	stats, err := s.repo.Stats(s.user.ID, folder.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Stats{Unread: 1, Read: 1, Total: 2}, stats)
+
+	_, err = s.repo.Stats(s.user.ID, "bogus")
+	s.Equal(repo.ErrModelNotFound, err)
+}
+
+func (s *SmartFoldersSuite) TestEntriesAndStatsSkipMutedFeeds() {
+	s.newEntry(s.feed, "CVE one", models.MarkerUnread, time.Hour)
+	muted := s.newEntry(s.other, "CVE two", models.MarkerUnread, time.Hour)
+
+	s.Require().NoError(s.entriesRepo.Save(s.user.ID, muted.ID, true))
+	s.Require().NoError(sql.NewFeeds(s.db).SetMuted(s.user.ID, s.other.ID, true, nil))
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", Title: "cve"})
+
+	entries, _ := s.repo.Entries(s.user.ID, models.Page{FilterID: folder.ID, Count: 10, Marker: models.MarkerAny})
+	s.Require().Len(entries, 1)
+	s.Equal("CVE one", entries[0].Title)
+
+	stats, err := s.repo.Stats(s.user.ID, folder.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Stats{Unread: 1, Saved: 1, Total: 1}, stats)
+
+	// Saved entries of muted feeds are still listed
+	saved := s.newSmartFolder(models.SmartFolder{Name: "saved", Saved: true})
+
+	entries, _ = s.repo.Entries(s.user.ID, models.Page{FilterID: saved.ID, Count: 10, Marker: models.MarkerAny})
+	s.Require().Len(entries, 1)
+	s.Equal(muted.ID, entries[0].ID)
+
+	stats, err = s.repo.Stats(s.user.ID, saved.ID)
+	s.Require().NoError(err)
+	s.Equal(models.Stats{Unread: 1, Saved: 1, Total: 1}, stats)
+}
+
+func (s *SmartFoldersSuite) TestMark() {
+	match := s.newEntry(s.feed, "CVE one", models.MarkerUnread, time.Hour)
+	other := s.newEntry(s.other, "CVE two", models.MarkerUnread, time.Hour)
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", Title: "cve", CategoryID: s.ctg.ID})
+
+	s.NoError(s.repo.Mark(s.user.ID, folder.ID, models.MarkerRead))
+
+	entry, _ := s.entriesRepo.EntryWithID(s.user.ID, match.ID)
+	s.Equal(models.MarkerRead, entry.Mark)
+
+	entry, _ = s.entriesRepo.EntryWithID(s.user.ID, other.ID)
+	s.Equal(models.MarkerUnread, entry.Mark)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Mark(s.user.ID, "bogus", models.MarkerRead))
+}
+
+func (s *SmartFoldersSuite) TestMarkSkipsMutedFeeds() {
+	unmuted := s.newEntry(s.feed, "CVE one", models.MarkerUnread, time.Hour)
+	muted := s.newEntry(s.other, "CVE two", models.MarkerUnread, time.Hour)
+	mutedSaved := s.newEntry(s.other, "CVE three", models.MarkerUnread, time.Hour)
+
+	s.Require().NoError(s.entriesRepo.Save(s.user.ID, mutedSaved.ID, true))
+	s.Require().NoError(sql.NewFeeds(s.db).SetMuted(s.user.ID, s.other.ID, true, nil))
+
+	folder := s.newSmartFolder(models.SmartFolder{Name: "security", Title: "cve"})
+
+	s.NoError(s.repo.Mark(s.user.ID, folder.ID, models.MarkerRead))
+
+	entry, _ := s.entriesRepo.EntryWithID(s.user.ID, unmuted.ID)
+	s.Equal(models.MarkerRead, entry.Mark)
+
+	entry, _ = s.entriesRepo.EntryWithID(s.user.ID, muted.ID)
+	s.Equal(models.MarkerUnread, entry.Mark)
+
+	entry, _ = s.entriesRepo.EntryWithID(s.user.ID, mutedSaved.ID)
+	s.Equal(models.MarkerUnread, entry.Mark)
+
+	// Saved entries of muted feeds are still marked from saved folders
+	saved := s.newSmartFolder(models.SmartFolder{Name: "saved", Saved: true})
+
+	s.NoError(s.repo.Mark(s.user.ID, saved.ID, models.MarkerRead))
+
+	entry, _ = s.entriesRepo.EntryWithID(s.user.ID, mutedSaved.ID)
+	s.Equal(models.MarkerRead, entry.Mark)
+
+	entry, _ = s.entriesRepo.EntryWithID(s.user.ID, muted.ID)
+	s.Equal(models.MarkerUnread, entry.Mark)
+}
+
+func (s *SmartFoldersSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_smart_folders",
+	}
+
+	s.db.Create(s.user)
+
+	s.repo = sql.NewSmartFolders(s.db)
+	s.entriesRepo = sql.NewEntries(s.db)
+
+	ctgsRepo := sql.NewCategories(s.db)
+	feedsRepo := sql.NewFeeds(s.db)
+
+	s.ctg = models.Category{ID: utils.CreateID(), Name: "security"}
+	ctgsRepo.Create(s.user.ID, &s.ctg)
+
+	s.feed = models.Feed{ID: utils.CreateID(), Subscription: "https://example.com/security"}
+	feedsRepo.Create(s.user.ID, &s.feed)
+	s.Require().NoError(ctgsRepo.AddFeed(s.user.ID, s.feed.ID, s.ctg.ID))
+
+	s.other = models.Feed{ID: utils.CreateID(), Subscription: "https://example.com/other"}
+	feedsRepo.Create(s.user.ID, &s.other)
+
+	s.tag = models.Tag{ID: utils.CreateID(), Name: "later"}
+	sql.NewTags(s.db).Create(s.user.ID, &s.tag)
+}
+
+func (s *SmartFoldersSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestSmartFoldersSuite(t *testing.T) {
+	suite.Run(t, new(SmartFoldersSuite))
+}
@@ -29,10 +29,30 @@ import (
 )
 
 func AutoMigrateTables(db *gorm.DB) {
+	// Display titles are migrated once, when their column is added
+	keepTitles := db.HasTable(&models.Feed{}) && !db.Dialect().HasColumn("feeds", "display_title")
+
 	db.AutoMigrate(&models.Feed{})
 	db.AutoMigrate(&models.Category{})
 	db.AutoMigrate(&models.User{})
 	db.AutoMigrate(&models.Entry{})
 	db.AutoMigrate(&models.Tag{})
 	db.AutoMigrate(&models.APIKey{})
+	db.AutoMigrate(&models.FeedEvent{})
+	db.AutoMigrate(&models.WebSubSubscription{})
+	db.AutoMigrate(&models.Webhook{})
+	db.AutoMigrate(&models.WebhookDelivery{})
+	db.AutoMigrate(&models.Rule{})
+	db.AutoMigrate(&models.RuleCondition{})
+	db.AutoMigrate(&models.RuleAction{})
+	db.AutoMigrate(&models.SmartFolder{})
+	db.AutoMigrate(&models.OutputFeed{})
+
+	if keepTitles {
+		migrateDisplayTitles(db)
+	}
+
+	migrateSubscriptionKeys(db)
+	migrateNextFetchTimes(db)
+	autoMigrateSearch(db)
 }
@@ -0,0 +1,164 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+type (
+	Webhooks struct {
+		db *gorm.DB
+	}
+)
+
+func NewWebhooks(db *gorm.DB) Webhooks {
+	return Webhooks{
+		db,
+	}
+}
+
+// Create a new webhook for user
+func (w Webhooks) Create(userID string, hook *models.Webhook) {
+	hook.UserID = userID
+	w.db.Create(hook)
+}
+
+// Update replaces a webhook owned by user
+func (w Webhooks) Update(userID string, hook *models.Webhook) error {
+	if _, found := w.WebhookWithID(userID, hook.ID); !found {
+		return repo.ErrModelNotFound
+	}
+
+	hook.UserID = userID
+	w.db.Save(hook)
+
+	return nil
+}
+
+// Delete a webhook owned by user along with its deliveries
+func (w Webhooks) Delete(userID, id string) error {
+	hook, found := w.WebhookWithID(userID, id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	w.db.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{})
+	w.db.Delete(&hook)
+
+	return nil
+}
+
+// WebhookWithID returns a webhook with id owned by user
+func (w Webhooks) WebhookWithID(userID, id string) (hook models.Webhook, found bool) {
+	found = !w.db.Where("id = ? AND user_id = ?", id, userID).First(&hook).RecordNotFound()
+	return
+}
+
+// List the webhooks owned by user
+func (w Webhooks) List(userID string, page models.Page) (hooks []models.Webhook, next string) {
+	query := w.db.Where("user_id = ?", userID)
+
+	if page.ContinuationID != "" {
+		if hook, found := w.WebhookWithID(userID, page.ContinuationID); found {
+			query = query.Where("created_at >= ?", hook.CreatedAt)
+		}
+	}
+
+	query.Order("created_at").Limit(page.Count + 1).Find(&hooks)
+
+	if len(hooks) > page.Count {
+		next = hooks[len(hooks)-1].ID
+		hooks = hooks[:len(hooks)-1]
+	}
+
+	return
+}
+
+// ListAll returns every webhook owned by user
+func (w Webhooks) ListAll(userID string) (hooks []models.Webhook) {
+	w.db.Where("user_id = ?", userID).Find(&hooks)
+
+	return
+}
+
+// CreateDelivery records a new delivery
+func (w Webhooks) CreateDelivery(delivery *models.WebhookDelivery) {
+	w.db.Set("gorm:save_associations", false).Create(delivery)
+}
+
+// UpdateDelivery replaces a delivery. Its webhook is left untouched.
+func (w Webhooks) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	if w.db.Where("id = ?", delivery.ID).First(&models.WebhookDelivery{}).RecordNotFound() {
+		return repo.ErrModelNotFound
+	}
+
+	w.db.Set("gorm:save_associations", false).Save(delivery)
+
+	return nil
+}
+
+// ListDeliveries returns the deliveries of a webhook owned by user, the most recent first.
+// The webhook is identified by the page's FilterID.
+func (w Webhooks) ListDeliveries(userID string, page models.Page) (deliveries []models.WebhookDelivery, next string) {
+	hook, found := w.WebhookWithID(userID, page.FilterID)
+	if !found {
+		return nil, ""
+	}
+
+	query := w.db.Where("webhook_id = ?", hook.ID)
+
+	if page.ContinuationID != "" {
+		var delivery models.WebhookDelivery
+		if !w.db.Where("id = ? AND webhook_id = ?", page.ContinuationID, hook.ID).First(&delivery).RecordNotFound() {
+			query = query.Where("created_at <= ?", delivery.CreatedAt)
+		}
+	}
+
+	query.Order("created_at desc").Limit(page.Count + 1).Find(&deliveries)
+
+	if len(deliveries) > page.Count {
+		next = deliveries[len(deliveries)-1].ID
+		deliveries = deliveries[:len(deliveries)-1]
+	}
+
+	return
+}
+
+// ListDueDeliveries returns up to count pending deliveries, of all users, that
+// are due to be attempted before a time, the most overdue first. Their webhook
+// is loaded along with them.
+func (w Webhooks) ListDueDeliveries(before time.Time, count int) (deliveries []models.WebhookDelivery) {
+	w.db.Preload("Webhook").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, before).
+		Order("next_attempt_at").Limit(count).Find(&deliveries)
+
+	return
+}
+
+// DeleteDeliveries deletes the deliveries that were created before a time and
+// are no longer pending. It returns how many were deleted.
+func (w Webhooks) DeleteDeliveries(before time.Time) int {
+	return int(w.db.Where("created_at < ? AND status <> ?", before, models.WebhookDeliveryPending).
+		Delete(&models.WebhookDelivery{}).RowsAffected)
+}
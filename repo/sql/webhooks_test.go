@@ -0,0 +1,219 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type WebhooksSuite struct {
+	suite.Suite
+
+	db   *gorm.DB
+	repo repo.Webhooks
+	user *models.User
+}
+
+func (s *WebhooksSuite) newWebhook() models.Webhook {
+	hook := models.Webhook{
+		ID:     utils.CreateID(),
+		URL:    "http://example.com/hook",
+		Secret: "secret",
+	}
+
+	s.repo.Create(s.user.ID, &hook)
+
+	return hook
+}
+
+func (s *WebhooksSuite) newDelivery(hookID, status string, nextAttemptAt time.Time) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		ID:            utils.CreateID(),
+		WebhookID:     hookID,
+		Event:         "entry.created",
+		Status:        status,
+		NextAttemptAt: nextAttemptAt,
+	}
+
+	s.repo.CreateDelivery(&delivery)
+
+	return delivery
+}
+
+func (s *WebhooksSuite) TestCreate() {
+	hook := s.newWebhook()
+
+	found, ok := s.repo.WebhookWithID(s.user.ID, hook.ID)
+	s.True(ok)
+	s.Equal("http://example.com/hook", found.URL)
+
+	_, ok = s.repo.WebhookWithID("other", hook.ID)
+	s.False(ok)
+}
+
+func (s *WebhooksSuite) TestUpdate() {
+	hook := s.newWebhook()
+
+	hook.URL = "http://example.com/other"
+	s.NoError(s.repo.Update(s.user.ID, &hook))
+
+	found, _ := s.repo.WebhookWithID(s.user.ID, hook.ID)
+	s.Equal("http://example.com/other", found.URL)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Update("other", &hook))
+}
+
+func (s *WebhooksSuite) TestDelete() {
+	hook := s.newWebhook()
+	s.newDelivery(hook.ID, models.WebhookDeliveryPending, time.Now())
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete("other", hook.ID))
+	s.NoError(s.repo.Delete(s.user.ID, hook.ID))
+
+	_, found := s.repo.WebhookWithID(s.user.ID, hook.ID)
+	s.False(found)
+	s.Empty(s.repo.ListDueDeliveries(time.Now(), 10))
+}
+
+func (s *WebhooksSuite) TestList() {
+	first := s.newWebhook()
+	second := s.newWebhook()
+
+	hooks, next := s.repo.List(s.user.ID, models.Page{Count: 1})
+	s.Require().Len(hooks, 1)
+	s.Equal(first.ID, hooks[0].ID)
+	s.Equal(second.ID, next)
+
+	hooks, next = s.repo.List(s.user.ID, models.Page{Count: 1, ContinuationID: next})
+	s.Require().Len(hooks, 1)
+	s.Equal(second.ID, hooks[0].ID)
+	s.Empty(next)
+
+	s.Len(s.repo.ListAll(s.user.ID), 2)
+	s.Empty(s.repo.ListAll("other"))
+}
+
+func (s *WebhooksSuite) TestListDeliveries() {
+	hook := s.newWebhook()
+
+	first := s.newDelivery(hook.ID, models.WebhookDeliveryDelivered, time.Time{})
+	second := s.newDelivery(hook.ID, models.WebhookDeliveryPending, time.Now())
+
+	s.db.Model(&first).UpdateColumn("created_at", time.Now().Add(-time.Hour))
+
+	deliveries, next := s.repo.ListDeliveries(s.user.ID, models.Page{FilterID: hook.ID, Count: 1})
+	s.Require().Len(deliveries, 1)
+	s.Equal(second.ID, deliveries[0].ID)
+	s.Equal(first.ID, next)
+
+	deliveries, _ = s.repo.ListDeliveries("other", models.Page{FilterID: hook.ID, Count: 1})
+	s.Empty(deliveries)
+}
+
+func (s *WebhooksSuite) TestListDueDeliveries() {
+	hook := s.newWebhook()
+	now := time.Now()
+
+	overdue := s.newDelivery(hook.ID, models.WebhookDeliveryPending, now.Add(-time.Hour))
+	due := s.newDelivery(hook.ID, models.WebhookDeliveryPending, now.Add(-time.Minute))
+	s.newDelivery(hook.ID, models.WebhookDeliveryPending, now.Add(time.Hour))
+	s.newDelivery(hook.ID, models.WebhookDeliveryFailed, now.Add(-time.Hour))
+
+	deliveries := s.repo.ListDueDeliveries(now, 10)
+	s.Require().Len(deliveries, 2)
+	s.Equal(overdue.ID, deliveries[0].ID)
+	s.Equal(due.ID, deliveries[1].ID)
+	s.Equal(hook.URL, deliveries[0].Webhook.URL)
+
+	s.Len(s.repo.ListDueDeliveries(now, 1), 1)
+}
+
+func (s *WebhooksSuite) TestUpdateDelivery() {
+	hook := s.newWebhook()
+	s.newDelivery(hook.ID, models.WebhookDeliveryPending, time.Now())
+
+	delivery := s.repo.ListDueDeliveries(time.Now(), 1)[0]
+
+	// The loaded webhook must not overwrite later changes to it
+	hook.URL = "http://example.com/other"
+	s.Require().NoError(s.repo.Update(s.user.ID, &hook))
+
+	delivery.Status = models.WebhookDeliveryDelivered
+	delivery.Attempts = 1
+	s.NoError(s.repo.UpdateDelivery(&delivery))
+
+	deliveries, _ := s.repo.ListDeliveries(s.user.ID, models.Page{FilterID: hook.ID, Count: 1})
+	s.Require().Len(deliveries, 1)
+	s.Equal(models.WebhookDeliveryDelivered, deliveries[0].Status)
+	s.Equal(1, deliveries[0].Attempts)
+
+	found, _ := s.repo.WebhookWithID(s.user.ID, hook.ID)
+	s.Equal("http://example.com/other", found.URL)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.UpdateDelivery(&models.WebhookDelivery{ID: "bogus"}))
+}
+
+func (s *WebhooksSuite) TestDeleteDeliveries() {
+	hook := s.newWebhook()
+
+	s.newDelivery(hook.ID, models.WebhookDeliveryDelivered, time.Time{})
+	s.newDelivery(hook.ID, models.WebhookDeliveryFailed, time.Time{})
+	s.newDelivery(hook.ID, models.WebhookDeliveryPending, time.Now())
+
+	s.Equal(0, s.repo.DeleteDeliveries(time.Now().Add(-time.Hour)))
+	s.Equal(2, s.repo.DeleteDeliveries(time.Now().Add(time.Hour)))
+
+	deliveries, _ := s.repo.ListDeliveries(s.user.ID, models.Page{FilterID: hook.ID, Count: 10})
+	s.Require().Len(deliveries, 1)
+	s.Equal(models.WebhookDeliveryPending, deliveries[0].Status)
+}
+
+func (s *WebhooksSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "test_webhooks",
+	}
+
+	s.db.Create(s.user)
+
+	s.repo = sql.NewWebhooks(s.db)
+}
+
+func (s *WebhooksSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestWebhooksSuite(t *testing.T) {
+	suite.Run(t, new(WebhooksSuite))
+}
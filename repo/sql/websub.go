@@ -0,0 +1,94 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+type (
+	WebSub struct {
+		db *gorm.DB
+	}
+)
+
+func NewWebSub(db *gorm.DB) WebSub {
+	return WebSub{
+		db,
+	}
+}
+
+// Create a new WebSub subscription
+func (w WebSub) Create(sub *models.WebSubSubscription) {
+	w.db.Create(sub)
+}
+
+// Update a WebSub subscription
+func (w WebSub) Update(sub *models.WebSubSubscription) error {
+	if _, found := w.SubscriptionWithID(sub.ID); !found {
+		return repo.ErrModelNotFound
+	}
+
+	w.db.Save(sub)
+
+	return nil
+}
+
+// SubscriptionWithID returns a WebSub subscription with id
+func (w WebSub) SubscriptionWithID(id string) (sub models.WebSubSubscription, found bool) {
+	found = !w.db.Where("id = ?", id).First(&sub).RecordNotFound()
+	return
+}
+
+// SubscriptionWithTopic returns the WebSub subscription to a topic
+func (w WebSub) SubscriptionWithTopic(topic string) (sub models.WebSubSubscription, found bool) {
+	found = !w.db.Where("topic = ?", topic).First(&sub).RecordNotFound()
+	return
+}
+
+// ListWithURL returns the WebSub subscriptions that push content to the feeds subscribed to url
+func (w WebSub) ListWithURL(url string) (subs []models.WebSubSubscription) {
+	w.db.Where("url = ?", url).Find(&subs)
+
+	return
+}
+
+// ListDue returns up to count WebSub subscriptions that must be requested again
+// before a time, the most overdue first
+func (w WebSub) ListDue(before time.Time, count int) (subs []models.WebSubSubscription) {
+	w.db.Where("renew_at <= ?", before).Order("renew_at").Limit(count).Find(&subs)
+
+	return
+}
+
+// Delete a WebSub subscription with id
+func (w WebSub) Delete(id string) error {
+	sub, found := w.SubscriptionWithID(id)
+	if !found {
+		return repo.ErrModelNotFound
+	}
+
+	w.db.Delete(&sub)
+
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sql_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type WebSubSuite struct {
+	suite.Suite
+
+	db   *gorm.DB
+	repo repo.WebSub
+}
+
+func (s *WebSubSuite) TestCreate() {
+	sub := models.WebSubSubscription{
+		ID:    utils.CreateID(),
+		Topic: "http://example.com/feed",
+		Hub:   "http://hub.example.com",
+		State: models.WebSubPending,
+	}
+
+	s.repo.Create(&sub)
+
+	sub, found := s.repo.SubscriptionWithID(sub.ID)
+	s.True(found)
+	s.Equal("http://example.com/feed", sub.Topic)
+	s.Equal(models.WebSubPending, sub.State)
+
+	sub, found = s.repo.SubscriptionWithTopic("http://example.com/feed")
+	s.True(found)
+	s.Equal("http://hub.example.com", sub.Hub)
+
+	_, found = s.repo.SubscriptionWithTopic("http://example.com/bogus")
+	s.False(found)
+}
+
+func (s *WebSubSuite) TestUpdate() {
+	sub := models.WebSubSubscription{
+		ID:    utils.CreateID(),
+		Topic: "http://example.com/feed",
+		State: models.WebSubPending,
+	}
+
+	s.repo.Create(&sub)
+
+	sub.State = models.WebSubActive
+	s.NoError(s.repo.Update(&sub))
+
+	sub, _ = s.repo.SubscriptionWithID(sub.ID)
+	s.Equal(models.WebSubActive, sub.State)
+}
+
+func (s *WebSubSuite) TestUpdateMissing() {
+	s.Equal(repo.ErrModelNotFound, s.repo.Update(&models.WebSubSubscription{ID: "bogus"}))
+}
+
+func (s *WebSubSuite) TestListWithURL() {
+	for i := 0; i < 2; i++ {
+		s.repo.Create(&models.WebSubSubscription{
+			ID:    utils.CreateID(),
+			Topic: "http://example.com/" + strconv.Itoa(i),
+			URL:   "http://example.com/feed",
+		})
+	}
+
+	s.Len(s.repo.ListWithURL("http://example.com/feed"), 2)
+	s.Empty(s.repo.ListWithURL("http://example.com/bogus"))
+}
+
+func (s *WebSubSuite) TestDelete() {
+	sub := models.WebSubSubscription{
+		ID:    utils.CreateID(),
+		Topic: "http://example.com/feed",
+	}
+
+	s.repo.Create(&sub)
+
+	s.NoError(s.repo.Delete(sub.ID))
+
+	_, found := s.repo.SubscriptionWithID(sub.ID)
+	s.False(found)
+
+	s.Equal(repo.ErrModelNotFound, s.repo.Delete(sub.ID))
+}
+
+func (s *WebSubSuite) TestListDue() {
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		s.repo.Create(&models.WebSubSubscription{
+			ID:      utils.CreateID(),
+			Topic:   "http://example.com/" + strconv.Itoa(i),
+			RenewAt: now.Add(time.Duration(1-i) * time.Hour),
+		})
+	}
+
+	subs := s.repo.ListDue(now, 5)
+	s.Require().Len(subs, 2)
+	s.Equal("http://example.com/2", subs[0].Topic)
+	s.Equal("http://example.com/1", subs[1].Topic)
+
+	s.Len(s.repo.ListDue(now, 1), 1)
+}
+
+func (s *WebSubSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.repo = sql.NewWebSub(s.db)
+}
+
+func (s *WebSubSuite) TearDownTest() {
+	s.NoError(s.db.Close())
+}
+
+func TestWebSubSuite(t *testing.T) {
+	suite.Run(t, new(WebSubSuite))
+}
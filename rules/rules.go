@@ -0,0 +1,237 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package rules applies the filter rules of users to their entries
+package rules
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+var (
+	// ErrInvalidConditions signals that a rule has no conditions or that one of them is invalid
+	ErrInvalidConditions = errors.New("invalid rule conditions")
+
+	// ErrInvalidActions signals that a rule has no actions or that one of them is invalid
+	ErrInvalidActions = errors.New("invalid rule actions")
+)
+
+type (
+	// Rule is a models.Rule that is ready to be applied
+	Rule struct {
+		models.Rule
+
+		conditions []condition
+	}
+
+	condition struct {
+		field models.RuleField
+		match func(value string) bool
+	}
+
+	// Outcome is the result of applying rules to an entry. Mark is zero and
+	// Save is false unless a rule changes them.
+	Outcome struct {
+		Delete bool
+		Mark   models.Marker
+		Save   bool
+		TagIDs []models.ID
+	}
+)
+
+// Compile checks the conditions and actions of rule and prepares it to be applied
+func Compile(rule models.Rule) (*Rule, error) {
+	if len(rule.Conditions) == 0 {
+		return nil, ErrInvalidConditions
+	}
+
+	if len(rule.Actions) == 0 {
+		return nil, ErrInvalidActions
+	}
+
+	compiled := &Rule{Rule: rule, conditions: make([]condition, len(rule.Conditions))}
+
+	for idx, cond := range rule.Conditions {
+		match, err := matcher(cond)
+		if err != nil {
+			return nil, err
+		}
+
+		compiled.conditions[idx] = condition{cond.Field, match}
+	}
+
+	for _, action := range rule.Actions {
+		switch action.Type {
+		case models.RuleActionMark:
+			if marker := models.MarkerFromString(action.Marker); marker != models.MarkerRead &&
+				marker != models.MarkerUnread {
+				return nil, ErrInvalidActions
+			}
+		case models.RuleActionTag:
+			if action.TagID == "" {
+				return nil, ErrInvalidActions
+			}
+		case models.RuleActionSave, models.RuleActionDelete:
+		default:
+			return nil, ErrInvalidActions
+		}
+	}
+
+	return compiled, nil
+}
+
+// Load returns the rules of user that are ready to be applied. Rules that no
+// longer compile are skipped.
+func Load(rulesRepo repo.Rules, userID string) []*Rule {
+	if rulesRepo == nil {
+		return nil
+	}
+
+	var compiled []*Rule
+
+	for _, rule := range rulesRepo.ListAll(userID) {
+		r, err := Compile(rule)
+		if err != nil {
+			log.Warnf("Skipping rule %s: %s", rule.ID, err)
+			continue
+		}
+
+		compiled = append(compiled, r)
+	}
+
+	return compiled
+}
+
+// Matches reports whether entry, which belongs to feed, matches every condition of the rule
+func (r *Rule) Matches(entry *models.Entry, feed *models.Feed) bool {
+	for _, cond := range r.conditions {
+		if !cond.matches(entry, feed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches reports whether any value of the condition's field matches. The
+// content field covers the summary of an entry too since many feeds only
+// provide one.
+func (c condition) matches(entry *models.Entry, feed *models.Feed) bool {
+	if c.field == models.RuleFieldContent {
+		return c.match(entry.Content) || (entry.Summary != "" && c.match(entry.Summary))
+	}
+
+	return c.match(field(c.field, entry, feed))
+}
+
+// Apply returns the outcome of applying rules to entry, which belongs to
+// feed. Every rule that matches is applied in order, later marks override
+// earlier ones.
+func Apply(rules []*Rule, entry *models.Entry, feed *models.Feed) Outcome {
+	var outcome Outcome
+
+	for _, rule := range rules {
+		if !rule.Matches(entry, feed) {
+			continue
+		}
+
+		for _, action := range rule.Actions {
+			switch action.Type {
+			case models.RuleActionMark:
+				outcome.Mark = models.MarkerFromString(action.Marker)
+			case models.RuleActionTag:
+				outcome.TagIDs = appendUnique(outcome.TagIDs, action.TagID)
+			case models.RuleActionSave:
+				outcome.Save = true
+			case models.RuleActionDelete:
+				outcome.Delete = true
+			}
+		}
+	}
+
+	return outcome
+}
+
+func matcher(cond models.RuleCondition) (func(string) bool, error) {
+	switch cond.Field {
+	case models.RuleFieldFeed, models.RuleFieldCategory:
+		if cond.Operator != models.RuleOperatorEquals || cond.Value == "" {
+			return nil, ErrInvalidConditions
+		}
+
+		return func(value string) bool {
+			return value == cond.Value
+		}, nil
+	case models.RuleFieldTitle, models.RuleFieldAuthor, models.RuleFieldLink, models.RuleFieldContent:
+	default:
+		return nil, ErrInvalidConditions
+	}
+
+	switch cond.Operator {
+	case models.RuleOperatorContains:
+		return func(value string) bool {
+			return strings.Contains(strings.ToLower(value), strings.ToLower(cond.Value))
+		}, nil
+	case models.RuleOperatorEquals:
+		return func(value string) bool {
+			return strings.EqualFold(value, cond.Value)
+		}, nil
+	case models.RuleOperatorMatches:
+		re, err := regexp.Compile("(?i)" + cond.Value)
+		if err != nil {
+			return nil, ErrInvalidConditions
+		}
+
+		return re.MatchString, nil
+	default:
+		return nil, ErrInvalidConditions
+	}
+}
+
+func field(name models.RuleField, entry *models.Entry, feed *models.Feed) string {
+	switch name {
+	case models.RuleFieldTitle:
+		return entry.Title
+	case models.RuleFieldAuthor:
+		return entry.Author
+	case models.RuleFieldLink:
+		return entry.Link
+	case models.RuleFieldFeed:
+		return feed.ID
+	case models.RuleFieldCategory:
+		return feed.CategoryID
+	default:
+		return ""
+	}
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+
+	return append(list, value)
+}
@@ -0,0 +1,162 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package rules_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/rules"
+)
+
+type RulesSuite struct {
+	suite.Suite
+
+	feed  models.Feed
+	entry models.Entry
+}
+
+func rule(field models.RuleField, operator models.RuleOperator, value string,
+	actions ...models.RuleAction) models.Rule {
+	return models.Rule{
+		Conditions: []models.RuleCondition{{Field: field, Operator: operator, Value: value}},
+		Actions:    actions,
+	}
+}
+
+func (s *RulesSuite) compile(rule models.Rule) *rules.Rule {
+	compiled, err := rules.Compile(rule)
+	s.Require().NoError(err)
+
+	return compiled
+}
+
+func (s *RulesSuite) TestCompileInvalidConditions() {
+	save := models.RuleAction{Type: models.RuleActionSave}
+
+	for _, r := range []models.Rule{
+		{Actions: []models.RuleAction{save}},
+		rule("summary", models.RuleOperatorContains, "x", save),
+		rule(models.RuleFieldTitle, "starts", "x", save),
+		rule(models.RuleFieldTitle, models.RuleOperatorMatches, "(", save),
+		rule(models.RuleFieldFeed, models.RuleOperatorContains, "feed", save),
+		rule(models.RuleFieldCategory, models.RuleOperatorEquals, "", save),
+	} {
+		_, err := rules.Compile(r)
+		s.Equal(rules.ErrInvalidConditions, err, r)
+	}
+}
+
+func (s *RulesSuite) TestCompileInvalidActions() {
+	for _, actions := range [][]models.RuleAction{
+		nil,
+		{{Type: "archive"}},
+		{{Type: models.RuleActionMark, Marker: "all"}},
+		{{Type: models.RuleActionTag}},
+	} {
+		_, err := rules.Compile(rule(models.RuleFieldTitle, models.RuleOperatorContains, "x", actions...))
+		s.Equal(rules.ErrInvalidActions, err, actions)
+	}
+}
+
+func (s *RulesSuite) TestMatches() {
+	for _, r := range []models.Rule{
+		rule(models.RuleFieldTitle, models.RuleOperatorContains, "SPONSORED"),
+		rule(models.RuleFieldTitle, models.RuleOperatorMatches, "^sponsored:"),
+		rule(models.RuleFieldAuthor, models.RuleOperatorEquals, "jane doe"),
+		rule(models.RuleFieldLink, models.RuleOperatorMatches, `/ads/\d+$`),
+		rule(models.RuleFieldContent, models.RuleOperatorContains, "buy now"),
+		rule(models.RuleFieldFeed, models.RuleOperatorEquals, "feed"),
+		rule(models.RuleFieldCategory, models.RuleOperatorEquals, "ctg"),
+	} {
+		r.Actions = []models.RuleAction{{Type: models.RuleActionSave}}
+		s.True(s.compile(r).Matches(&s.entry, &s.feed), r.Conditions[0])
+	}
+
+	for _, r := range []models.Rule{
+		rule(models.RuleFieldTitle, models.RuleOperatorEquals, "sponsored"),
+		rule(models.RuleFieldAuthor, models.RuleOperatorContains, "john"),
+		rule(models.RuleFieldLink, models.RuleOperatorMatches, "^/ads"),
+		rule(models.RuleFieldFeed, models.RuleOperatorEquals, "other"),
+	} {
+		r.Actions = []models.RuleAction{{Type: models.RuleActionSave}}
+		s.False(s.compile(r).Matches(&s.entry, &s.feed), r.Conditions[0])
+	}
+}
+
+func (s *RulesSuite) TestMatchesSummary() {
+	entry := models.Entry{Title: "A new gadget", Summary: "Buy now while it lasts"}
+
+	s.True(s.compile(rule(models.RuleFieldContent, models.RuleOperatorContains, "buy now",
+		models.RuleAction{Type: models.RuleActionSave})).Matches(&entry, &s.feed))
+	s.True(s.compile(rule(models.RuleFieldContent, models.RuleOperatorEquals, "buy now while it lasts",
+		models.RuleAction{Type: models.RuleActionSave})).Matches(&entry, &s.feed))
+	s.False(s.compile(rule(models.RuleFieldContent, models.RuleOperatorContains, "sold out",
+		models.RuleAction{Type: models.RuleActionSave})).Matches(&entry, &s.feed))
+}
+
+func (s *RulesSuite) TestMatchesAllConditions() {
+	r := rule(models.RuleFieldTitle, models.RuleOperatorContains, "sponsored",
+		models.RuleAction{Type: models.RuleActionSave})
+	r.Conditions = append(r.Conditions, models.RuleCondition{
+		Field:    models.RuleFieldFeed,
+		Operator: models.RuleOperatorEquals,
+		Value:    "other",
+	})
+
+	s.False(s.compile(r).Matches(&s.entry, &s.feed))
+}
+
+func (s *RulesSuite) TestApply() {
+	ruleSet := []*rules.Rule{
+		s.compile(rule(models.RuleFieldTitle, models.RuleOperatorContains, "sponsored",
+			models.RuleAction{Type: models.RuleActionMark, Marker: "read"},
+			models.RuleAction{Type: models.RuleActionTag, TagID: "ads"})),
+		s.compile(rule(models.RuleFieldTitle, models.RuleOperatorContains, "nothing",
+			models.RuleAction{Type: models.RuleActionDelete})),
+		s.compile(rule(models.RuleFieldAuthor, models.RuleOperatorEquals, "Jane Doe",
+			models.RuleAction{Type: models.RuleActionMark, Marker: "unread"},
+			models.RuleAction{Type: models.RuleActionTag, TagID: "ads"},
+			models.RuleAction{Type: models.RuleActionSave})),
+	}
+
+	s.Equal(rules.Outcome{
+		Mark:   models.MarkerUnread,
+		Save:   true,
+		TagIDs: []models.ID{"ads"},
+	}, rules.Apply(ruleSet, &s.entry, &s.feed))
+
+	s.Equal(rules.Outcome{}, rules.Apply(ruleSet, &models.Entry{Title: "Other"}, &s.feed))
+	s.Equal(rules.Outcome{}, rules.Apply(nil, &s.entry, &s.feed))
+}
+
+func (s *RulesSuite) SetupTest() {
+	s.feed = models.Feed{ID: "feed", CategoryID: "ctg"}
+	s.entry = models.Entry{
+		Title:   "Sponsored: a new gadget",
+		Author:  "Jane Doe",
+		Link:    "https://example.com/ads/42",
+		Content: "<p>Buy now!</p>",
+	}
+}
+
+func TestRulesSuite(t *testing.T) {
+	suite.Run(t, new(RulesSuite))
+}
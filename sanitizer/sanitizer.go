@@ -0,0 +1,355 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+// Package sanitizer removes unsafe markup from HTML provided by feeds
+// so that it can be served to clients as is.
+package sanitizer
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// globalAttributes are allowed in every allowed element
+	globalAttributes = []string{"title", "lang", "dir"}
+
+	// allowedElements maps every element that is kept to the attributes it may carry
+	allowedElements = map[string][]string{
+		"a":          {"href"},
+		"abbr":       nil,
+		"address":    nil,
+		"article":    nil,
+		"aside":      nil,
+		"audio":      {"src", "controls"},
+		"b":          nil,
+		"bdi":        nil,
+		"bdo":        nil,
+		"blockquote": {"cite"},
+		"br":         nil,
+		"caption":    nil,
+		"cite":       nil,
+		"code":       nil,
+		"col":        {"span"},
+		"colgroup":   {"span"},
+		"dd":         nil,
+		"del":        {"cite", "datetime"},
+		"details":    nil,
+		"dfn":        nil,
+		"div":        nil,
+		"dl":         nil,
+		"dt":         nil,
+		"em":         nil,
+		"figcaption": nil,
+		"figure":     nil,
+		"footer":     nil,
+		"h1":         nil,
+		"h2":         nil,
+		"h3":         nil,
+		"h4":         nil,
+		"h5":         nil,
+		"h6":         nil,
+		"header":     nil,
+		"hr":         nil,
+		"i":          nil,
+		"img":        {"src", "srcset", "alt", "width", "height"},
+		"ins":        {"cite", "datetime"},
+		"kbd":        nil,
+		"li":         nil,
+		"mark":       nil,
+		"ol":         {"start", "reversed", "type"},
+		"p":          nil,
+		"picture":    nil,
+		"pre":        nil,
+		"q":          {"cite"},
+		"rp":         nil,
+		"rt":         nil,
+		"ruby":       nil,
+		"s":          nil,
+		"samp":       nil,
+		"section":    nil,
+		"small":      nil,
+		"source":     {"src", "srcset", "type", "media"},
+		"span":       nil,
+		"strike":     nil,
+		"strong":     nil,
+		"sub":        nil,
+		"summary":    nil,
+		"sup":        nil,
+		"table":      nil,
+		"tbody":      nil,
+		"td":         {"colspan", "rowspan", "headers"},
+		"tfoot":      nil,
+		"th":         {"colspan", "rowspan", "headers", "scope"},
+		"thead":      nil,
+		"time":       {"datetime"},
+		"tr":         nil,
+		"tt":         nil,
+		"u":          nil,
+		"ul":         nil,
+		"var":        nil,
+		"video":      {"src", "poster", "controls", "width", "height"},
+		"wbr":        nil,
+	}
+
+	// droppedElements are removed along with everything they contain
+	droppedElements = map[string]bool{
+		"applet":   true,
+		"embed":    true,
+		"form":     true,
+		"frame":    true,
+		"frameset": true,
+		"head":     true,
+		"iframe":   true,
+		"math":     true,
+		"noscript": true,
+		"object":   true,
+		"script":   true,
+		"style":    true,
+		"svg":      true,
+		"template": true,
+		"textarea": true,
+		"title":    true,
+	}
+
+	// voidElements are never closed
+	voidElements = map[string]bool{
+		"br":     true,
+		"col":    true,
+		"hr":     true,
+		"img":    true,
+		"source": true,
+		"wbr":    true,
+	}
+
+	// urlAttributes hold URLs that are resolved and checked against allowedSchemes
+	urlAttributes = map[string]bool{
+		"href":   true,
+		"src":    true,
+		"cite":   true,
+		"poster": true,
+	}
+
+	allowedSchemes = map[string]bool{
+		"http":   true,
+		"https":  true,
+		"mailto": true,
+	}
+)
+
+// Sanitize returns a copy of input that only contains allowed elements and attributes.
+// Scripts, styles, embedded frames, event handlers and URLs with schemes other than
+// http, https and mailto are removed. Relative URLs are resolved against base.
+func Sanitize(input, base string) string {
+	if input == "" {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		baseURL = nil
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(input), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for _, node := range nodes {
+		render(&b, node, baseURL)
+	}
+
+	return b.String()
+}
+
+// ResolveBase returns the base URL of the content of a feed fetched from the
+// document URL. An xml:base declared by the feed is resolved against the document
+// URL, as XML Base requires. Feeds without one are resolved against their link,
+// which is what relative references in RSS feeds usually point to.
+func ResolveBase(document, link, xmlBase string) string {
+	if strings.TrimSpace(xmlBase) != "" {
+		return resolveReferences(document, xmlBase)
+	}
+
+	return resolveReferences(document, link)
+}
+
+// resolveReferences resolves every reference against the ones preceding it and
+// returns the resulting absolute URL
+func resolveReferences(refs ...string) string {
+	var base *url.URL
+
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+
+		u, err := url.Parse(ref)
+		if err != nil {
+			continue
+		}
+
+		if base != nil {
+			u = base.ResolveReference(u)
+		}
+
+		if u.IsAbs() {
+			base = u
+		}
+	}
+
+	if base == nil {
+		return ""
+	}
+
+	return base.String()
+}
+
+func render(b *strings.Builder, node *html.Node, base *url.URL) {
+	switch node.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(node.Data))
+	case html.ElementNode:
+		renderElement(b, node, base)
+	case html.DocumentNode:
+		renderChildren(b, node, base)
+	}
+}
+
+func renderChildren(b *strings.Builder, node *html.Node, base *url.URL) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		render(b, child, base)
+	}
+}
+
+func renderElement(b *strings.Builder, node *html.Node, base *url.URL) {
+	name := strings.ToLower(node.Data)
+
+	if droppedElements[name] {
+		return
+	}
+
+	attrs, allowed := allowedElements[name]
+	if !allowed {
+		// Unknown elements are unwrapped so that their text is not lost
+		renderChildren(b, node, base)
+		return
+	}
+
+	b.WriteString("<" + name)
+
+	for _, attr := range node.Attr {
+		if attr.Namespace != "" || !isAllowedAttribute(attr.Key, attrs) {
+			continue
+		}
+
+		value, ok := sanitizeAttribute(attr.Key, attr.Val, base)
+		if !ok {
+			continue
+		}
+
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(value) + `"`)
+	}
+
+	if name == "a" {
+		b.WriteString(` rel="noopener noreferrer"`)
+	}
+
+	b.WriteString(">")
+
+	if voidElements[name] {
+		return
+	}
+
+	renderChildren(b, node, base)
+
+	b.WriteString("</" + name + ">")
+}
+
+func isAllowedAttribute(key string, attrs []string) bool {
+	for _, attr := range globalAttributes {
+		if key == attr {
+			return true
+		}
+	}
+
+	for _, attr := range attrs {
+		if key == attr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sanitizeAttribute(key, value string, base *url.URL) (string, bool) {
+	switch {
+	case key == "srcset":
+		return sanitizeSrcSet(value, base)
+	case urlAttributes[key]:
+		return sanitizeURL(value, base)
+	default:
+		return value, true
+	}
+}
+
+func sanitizeURL(value string, base *url.URL) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	if u.Scheme != "" && !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+
+	return u.String(), true
+}
+
+func sanitizeSrcSet(value string, base *url.URL) (string, bool) {
+	var candidates []string
+
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+
+		src, ok := sanitizeURL(fields[0], base)
+		if !ok {
+			continue
+		}
+
+		candidates = append(candidates, strings.Join(append([]string{src}, fields[1:]...), " "))
+	}
+
+	return strings.Join(candidates, ", "), len(candidates) > 0
+}
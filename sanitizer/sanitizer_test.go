@@ -0,0 +1,112 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sanitizer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/sanitizer"
+)
+
+type SanitizerSuite struct {
+	suite.Suite
+}
+
+func (s *SanitizerSuite) TestKeepsAllowedMarkup() {
+	s.Equal(
+		"<p>Hello <strong>world</strong><br></p>",
+		sanitizer.Sanitize("<p>Hello <strong>world</strong><br/></p>", ""),
+	)
+}
+
+func (s *SanitizerSuite) TestStripsScripts() {
+	s.Equal(
+		"<p>Hello</p>",
+		sanitizer.Sanitize(`<p>Hello</p><script>alert("xss")</script><style>p {}</style>`, ""),
+	)
+}
+
+func (s *SanitizerSuite) TestStripsEventHandlers() {
+	s.Equal(
+		`<img src="https://example.com/a.png" alt="a">`,
+		sanitizer.Sanitize(`<img src="https://example.com/a.png" alt="a" onerror="alert(1)">`, ""),
+	)
+}
+
+func (s *SanitizerSuite) TestStripsJavascriptURLs() {
+	s.Equal(
+		`<a rel="noopener noreferrer">click</a>`,
+		sanitizer.Sanitize(`<a href="javascript:alert(1)">click</a>`, ""),
+	)
+	s.Equal(
+		`<a rel="noopener noreferrer">click</a>`,
+		sanitizer.Sanitize(`<a href="  JaVaScRiPt:alert(1)">click</a>`, ""),
+	)
+}
+
+func (s *SanitizerSuite) TestUnwrapsUnknownElements() {
+	s.Equal(
+		"<p>Hello world</p>",
+		sanitizer.Sanitize(`<p><font color="red">Hello</font> world</p>`, ""),
+	)
+}
+
+func (s *SanitizerSuite) TestEscapesText() {
+	s.Equal(
+		"<p>1 &lt; 2 &amp;&amp; 3 &gt; 2</p>",
+		sanitizer.Sanitize("<p>1 &lt; 2 &amp;&amp; 3 &gt; 2</p>", ""),
+	)
+}
+
+func (s *SanitizerSuite) TestResolvesRelativeURLs() {
+	s.Equal(
+		`<a href="https://example.com/blog/post" rel="noopener noreferrer">post</a>`+
+			`<img src="https://example.com/img/a.png" srcset="https://example.com/blog/a.png 1x, https://example.com/b.png 2x">`,
+		sanitizer.Sanitize(
+			`<a href="post">post</a><img src="/img/a.png" srcset="a.png 1x, /b.png 2x">`,
+			"https://example.com/blog/",
+		),
+	)
+}
+
+func (s *SanitizerSuite) TestResolveBase() {
+	s.Equal("https://example.com/blog/", sanitizer.ResolveBase("https://example.com/feed.xml", "/blog/", ""))
+	s.Equal(
+		"https://cdn.example.com/",
+		sanitizer.ResolveBase("https://example.com/feed.xml", "https://example.com", "https://cdn.example.com/"),
+	)
+	s.Equal("https://example.com/feed.xml", sanitizer.ResolveBase("https://example.com/feed.xml", "", ""))
+	s.Empty(sanitizer.ResolveBase("", "relative/", ""))
+}
+
+func (s *SanitizerSuite) TestResolveBaseWithRelativeXMLBase() {
+	s.Equal(
+		"https://example.com/posts/",
+		sanitizer.ResolveBase("https://example.com/feeds/atom.xml", "https://blog.example.org/", "/posts/"),
+	)
+	s.Equal(
+		"https://example.com/feeds/posts/",
+		sanitizer.ResolveBase("https://example.com/feeds/atom.xml", "https://blog.example.org/", "posts/"),
+	)
+}
+
+func TestSanitizerSuite(t *testing.T) {
+	suite.Run(t, new(SanitizerSuite))
+}
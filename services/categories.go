@@ -21,6 +21,7 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/utils"
@@ -70,6 +71,8 @@ type (
 	CategoriesService struct {
 		ctgsRepo    repo.Categories
 		entriesRepo repo.Entries
+
+		events *events.Bus
 	}
 )
 
@@ -81,10 +84,11 @@ var (
 	ErrCategoryConflicts = errors.New("categories conflicts")
 )
 
-func NewCategoriesService(ctgsRepo repo.Categories, entriesRepo repo.Entries) CategoriesService {
+func NewCategoriesService(ctgsRepo repo.Categories, entriesRepo repo.Entries, opts ...Option) CategoriesService {
 	return CategoriesService{
-		ctgsRepo,
-		entriesRepo,
+		ctgsRepo:    ctgsRepo,
+		entriesRepo: entriesRepo,
+		events:      newOptions(opts).events,
 	}
 }
 
@@ -161,12 +165,29 @@ func (c CategoriesService) Delete(userID, id string) error {
 
 // Mark a category
 func (c CategoriesService) Mark(userID, id string, marker models.Marker) error {
-	err := c.ctgsRepo.Mark(userID, id, marker)
+	stats, err := c.ctgsRepo.Stats(userID, id)
 	if err == repo.ErrModelNotFound {
 		return ErrCategoryNotFound
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	err = c.ctgsRepo.Mark(userID, id, marker)
+	if err == repo.ErrModelNotFound {
+		return ErrCategoryNotFound
+	} else if err != nil {
+		return err
+	}
+
+	c.events.Publish(events.Event{
+		Type:       events.EntryMarked,
+		UserID:     userID,
+		CategoryID: id,
+		Marker:     marker,
+		Unread:     unreadDelta(stats, marker),
+	})
+
+	return nil
 }
 
 // Entries returns all entries associated to a category
@@ -23,6 +23,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/repo/sql"
@@ -226,6 +227,27 @@ func (t *CategoriesSuite) TestMarkCategory() {
 	t.Len(entries, 1)
 }
 
+func (t *CategoriesSuite) TestMarkCategoryPublishesEvent() {
+	bus := events.NewBus()
+	sub := bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	service := services.NewCategoriesService(sql.NewCategories(t.db), sql.NewEntries(t.db), services.WithEvents(bus))
+
+	ctg, err := service.New(t.user.ID, "news")
+	t.Require().NoError(err)
+
+	t.NoError(service.Mark(t.user.ID, ctg.ID, models.MarkerRead))
+	t.Error(service.Mark(t.user.ID, "bogus", models.MarkerRead))
+
+	t.Require().Len(sub.Events(), 1)
+
+	event := <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Equal(ctg.ID, event.CategoryID)
+	t.Equal(models.MarkerRead, event.Marker)
+}
+
 func (t *CategoriesSuite) TestMarkMissingCategory() {
 	err := t.service.Mark(t.user.ID, "bogus", models.MarkerRead)
 	t.EqualError(err, services.ErrCategoryNotFound.Error())
@@ -20,6 +20,7 @@ package services
 import (
 	"errors"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 )
@@ -32,7 +33,8 @@ type (
 		// Entry returns an entry with id that belongs to user
 		Entry(userID, id string) (models.Entry, error)
 
-		// Entries returns all entries belong to a user with a marker
+		// Entries returns all entries belong to a user with a marker. Entries
+		// of muted feeds are left out unless saved entries are listed.
 		Entries(userID string, page models.Page) ([]models.Entry, string)
 
 		// Mark entry with id
@@ -41,6 +43,12 @@ type (
 		// MarkAll entries
 		MarkAll(userID string, marker models.Marker)
 
+		// Save sets the saved state of an entry with id
+		Save(userID, id string, saved bool) error
+
+		// SaveEntries sets the saved state of a list of entries
+		SaveEntries(userID string, ids []string, saved bool)
+
 		// Stats returns statistics for all entries
 		Stats(userID string) models.Stats
 	}
@@ -48,6 +56,8 @@ type (
 	// EntriesService implements Entries service
 	EntriesService struct {
 		repo repo.Entries
+
+		events *events.Bus
 	}
 )
 
@@ -56,9 +66,10 @@ var (
 	ErrEntryNotFound = errors.New("entry not found")
 )
 
-func NewEntriesService(entriesRepo repo.Entries) EntriesService {
+func NewEntriesService(entriesRepo repo.Entries, opts ...Option) EntriesService {
 	return EntriesService{
-		entriesRepo,
+		repo:   entriesRepo,
+		events: newOptions(opts).events,
 	}
 }
 
@@ -72,27 +83,104 @@ func (e EntriesService) Entry(userID, id string) (models.Entry, error) {
 	return entry, nil
 }
 
-// Entries returns all entries belong to a user with a marker
+// Entries returns all entries belong to a user with a marker. The entries of
+// muted feeds are left out unless saved entries are listed.
 func (e EntriesService) Entries(userID string, page models.Page) (entries []models.Entry, next string) {
-	return e.repo.List(userID, page)
+	if page.Saved {
+		return e.repo.List(userID, page)
+	}
+
+	return e.repo.ListUnmuted(userID, page)
 }
 
 // Mark entry with id
 func (e EntriesService) Mark(userID, id string, marker models.Marker) error {
+	entry, found := e.repo.EntryWithID(userID, id)
+	if !found {
+		return ErrEntryNotFound
+	}
+
 	err := e.repo.Mark(userID, id, marker)
 	if err == repo.ErrModelNotFound {
 		return ErrEntryNotFound
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	unread := 0
+	if entry.Mark != marker {
+		unread = unreadDelta(models.Stats{
+			Unread: boolToInt(entry.Mark == models.MarkerUnread),
+			Read:   boolToInt(entry.Mark == models.MarkerRead),
+		}, marker)
+	}
+
+	e.events.Publish(events.Event{
+		Type:    events.EntryMarked,
+		UserID:  userID,
+		EntryID: id,
+		FeedID:  entry.FeedID,
+		Marker:  marker,
+		Unread:  unread,
+	})
+
+	return nil
 }
 
 // MarkAll entries
 func (e EntriesService) MarkAll(userID string, marker models.Marker) {
+	stats := e.repo.Stats(userID)
+
 	e.repo.MarkAll(userID, marker)
+
+	e.events.Publish(events.Event{
+		Type:   events.EntryMarked,
+		UserID: userID,
+		Marker: marker,
+		Unread: unreadDelta(stats, marker),
+	})
+}
+
+// Save sets the saved state of an entry with id
+func (e EntriesService) Save(userID, id string, saved bool) error {
+	err := e.repo.Save(userID, id, saved)
+	if err == repo.ErrModelNotFound {
+		return ErrEntryNotFound
+	}
+
+	return err
+}
+
+// SaveEntries sets the saved state of a list of entries. Entries that
+// cannot be found are ignored.
+func (e EntriesService) SaveEntries(userID string, ids []string, saved bool) {
+	for _, id := range ids {
+		_ = e.repo.Save(userID, id, saved)
+	}
 }
 
 // Stats returns statistics for all entries
 func (e EntriesService) Stats(userID string) models.Stats {
 	return e.repo.Stats(userID)
 }
+
+// unreadDelta returns how much marking entries described by stats with marker
+// changes the number of unread entries by
+func unreadDelta(stats models.Stats, marker models.Marker) int {
+	switch marker {
+	case models.MarkerRead:
+		return -stats.Unread
+	case models.MarkerUnread:
+		return stats.Read
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -90,6 +90,32 @@ func (mr *MockEntriesMockRecorder) MarkAll(userID, marker interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAll", reflect.TypeOf((*MockEntries)(nil).MarkAll), userID, marker)
 }
 
+// Save mocks base method.
+func (m *MockEntries) Save(userID, id string, saved bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", userID, id, saved)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockEntriesMockRecorder) Save(userID, id, saved interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockEntries)(nil).Save), userID, id, saved)
+}
+
+// SaveEntries mocks base method.
+func (m *MockEntries) SaveEntries(userID string, ids []string, saved bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SaveEntries", userID, ids, saved)
+}
+
+// SaveEntries indicates an expected call of SaveEntries.
+func (mr *MockEntriesMockRecorder) SaveEntries(userID, ids, saved interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveEntries", reflect.TypeOf((*MockEntries)(nil).SaveEntries), userID, ids, saved)
+}
+
 // Stats mocks base method.
 func (m *MockEntries) Stats(userID string) models.Stats {
 	m.ctrl.T.Helper()
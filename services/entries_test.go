@@ -23,6 +23,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/repo/sql"
@@ -79,6 +80,27 @@ func (t *EntriesSuite) TestEntries() {
 	t.Equal(entry.Title, entries[0].Title)
 }
 
+func (t *EntriesSuite) TestEntriesOfMutedFeeds() {
+	entry := models.Entry{
+		ID:    utils.CreateID(),
+		Title: "Muted",
+		Mark:  models.MarkerUnread,
+		Saved: true,
+		Feed:  t.feed,
+	}
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	t.Require().NoError(t.feedsRepo.SetMuted(t.user.ID, t.feed.ID, true, nil))
+
+	entries, _ := t.service.Entries(t.user.ID, models.Page{Count: 1, Marker: models.MarkerAny})
+	t.Empty(entries)
+
+	// Saved entries are listed whether their feed is muted or not
+	entries, _ = t.service.Entries(t.user.ID, models.Page{Count: 1, Marker: models.MarkerAny, Saved: true})
+	t.Require().Len(entries, 1)
+	t.Equal(entry.ID, entries[0].ID)
+}
+
 func (t *EntriesSuite) TestMarkEntry() {
 	entry := models.Entry{
 		ID:    utils.CreateID(),
@@ -101,6 +123,46 @@ func (t *EntriesSuite) TestMarkEntry() {
 	t.Equal(entry.Title, entries[0].Title)
 }
 
+func (t *EntriesSuite) TestMarkPublishesEvents() {
+	bus := events.NewBus()
+	sub := bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	service := services.NewEntriesService(t.entriesRepo, services.WithEvents(bus))
+
+	entry := models.Entry{
+		ID:    utils.CreateID(),
+		Title: "Test Entries",
+		Mark:  models.MarkerUnread,
+		Feed:  t.feed,
+	}
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	t.NoError(service.Mark(t.user.ID, entry.ID, models.MarkerRead))
+	t.NoError(service.Mark(t.user.ID, entry.ID, models.MarkerRead))
+	t.Error(service.Mark(t.user.ID, "bogus", models.MarkerRead))
+	service.MarkAll(t.user.ID, models.MarkerUnread)
+
+	t.Require().Len(sub.Events(), 3)
+
+	event := <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Equal(entry.ID, event.EntryID)
+	t.Equal(t.feed.ID, event.FeedID)
+	t.Equal(models.MarkerRead, event.Marker)
+	t.Equal(-1, event.Unread)
+
+	event = <-sub.Events()
+	t.Equal(entry.ID, event.EntryID)
+	t.Zero(event.Unread)
+
+	event = <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Empty(event.EntryID)
+	t.Equal(models.MarkerUnread, event.Marker)
+	t.Equal(1, event.Unread)
+}
+
 func (t *EntriesSuite) TestMarkMissingEntry() {
 	err := t.service.Mark(t.user.ID, "bogus", models.MarkerRead)
 	t.EqualError(err, services.ErrEntryNotFound.Error())
@@ -127,6 +189,52 @@ func (t *EntriesSuite) TestMarkAll() {
 	t.Equal(entry.Title, entries[0].Title)
 }
 
+func (t *EntriesSuite) TestSave() {
+	entry := models.Entry{
+		ID:    utils.CreateID(),
+		Title: "Test Entries",
+		Mark:  models.MarkerUnread,
+		Feed:  t.feed,
+	}
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	t.NoError(t.service.Save(t.user.ID, entry.ID, true))
+
+	entries, _ := t.service.Entries(t.user.ID, models.Page{
+		Count:  2,
+		Marker: models.MarkerAny,
+		Saved:  true,
+	})
+	t.Require().Len(entries, 1)
+	t.Equal(entry.ID, entries[0].ID)
+}
+
+func (t *EntriesSuite) TestSaveMissingEntry() {
+	err := t.service.Save(t.user.ID, "bogus", true)
+	t.EqualError(err, services.ErrEntryNotFound.Error())
+}
+
+func (t *EntriesSuite) TestSaveEntries() {
+	ids := make([]string, 2)
+	for idx := range ids {
+		entry := models.Entry{
+			ID:    utils.CreateID(),
+			Title: "Test Entries",
+			Mark:  models.MarkerUnread,
+			Feed:  t.feed,
+		}
+		t.entriesRepo.Create(t.user.ID, &entry)
+
+		ids[idx] = entry.ID
+	}
+
+	t.service.SaveEntries(t.user.ID, append(ids, "bogus"), true)
+	t.Equal(2, t.service.Stats(t.user.ID).Saved)
+
+	t.service.SaveEntries(t.user.ID, ids[:1], false)
+	t.Equal(1, t.service.Stats(t.user.ID).Saved)
+}
+
 func (t *EntriesSuite) SetupTest() {
 	var err error
 
@@ -55,9 +55,16 @@ func marshal(feeds []models.Feed) []models.OPMLOutline {
 
 	for idx := range feeds {
 		feed := feeds[idx]
+
+		// The title a user gave to a feed is the one they expect to see again
+		title := feed.Title
+		if feed.DisplayTitle != "" {
+			title = feed.DisplayTitle
+		}
+
 		items[idx] = models.OPMLOutline{
-			Title:   feed.Title,
-			Text:    feed.Title,
+			Title:   title,
+			Text:    title,
 			Type:    "rss",
 			XMLUrl:  feed.Subscription,
 			HTMLUrl: feed.Subscription,
@@ -19,9 +19,13 @@ package services
 
 import (
 	"errors"
+	"time"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
 	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/sanitizer"
 	"github.com/jmartinezhern/syndication/utils"
 )
 
@@ -30,8 +34,13 @@ import (
 type (
 	// Feeds defines the Feeds service interface
 	Feeds interface {
-		// New creates a new Feed
-		New(title, subscription string, ctgID string, userID string) (models.Feed, error)
+		// New creates a new Feed from the subscription, category, credentials, kind,
+		// selectors and plugins of newFeed
+		New(userID string, newFeed models.Feed) (models.Feed, error)
+
+		// Preview returns the feed and entries a scraper feed would extract from a web page
+		Preview(subscription string, selectors models.ScraperSelectors, credentials *models.FeedCredentials) (
+			models.Feed, []models.Entry, error)
 
 		// Feeds returns all feeds owned by user
 		Feeds(userID string, page models.Page) ([]models.Feed, string)
@@ -48,11 +57,29 @@ type (
 		// Mark a feed with id
 		Mark(userID string, id string, marker models.Marker) error
 
+		// Pause fetching a feed with id until a time, or until it is resumed if until is nil
+		Pause(userID string, id string, until *time.Time) error
+
+		// Resume fetching a paused feed with id
+		Resume(userID string, id string) error
+
+		// Mute a feed with id until a time, or until it is unmuted if until is nil
+		Mute(userID string, id string, until *time.Time) error
+
+		// Unmute a muted feed with id
+		Unmute(userID string, id string) error
+
 		// Entries returns all entry items associated to a feed
 		Entries(userID string, page models.Page) ([]models.Entry, string)
 
 		// Stats returns statistics of a feed
 		Stats(userID string, id string) (models.Stats, error)
+
+		// Events returns the history of changes made to a feed
+		Events(userID string, page models.Page) ([]models.FeedEvent, string, error)
+
+		// Discover returns the feeds available at a website
+		Discover(url string) ([]models.FeedCandidate, error)
 	}
 
 	// FeedService implementation
@@ -60,6 +87,11 @@ type (
 		feedsRepo   repo.Feeds
 		ctgsRepo    repo.Categories
 		entriesRepo repo.Entries
+
+		events    *events.Bus
+		plugins   *plugins.Registry
+		rulesRepo repo.Rules
+		websub    WebSubReleaser
 	}
 )
 
@@ -74,44 +106,150 @@ var (
 	// ErrFetchingFeed Signals that an error occurred while fetching
 	// a RSS or Atom feed
 	ErrFetchingFeed = errors.New("could not fetch feed")
+
+	// ErrFeedKind signals that a feed is of an unknown kind
+	ErrFeedKind = errors.New("unknown feed kind")
+
+	// ErrFeedSelectors signals that the selectors of a scraper feed are missing or invalid
+	ErrFeedSelectors = errors.New("invalid scraper selectors")
+
+	// ErrFeedPlugin signals that a feed uses a plugin that is not configured
+	ErrFeedPlugin = errors.New("unknown feed plugin")
+
+	// ErrFeedUntil signals that a feed was paused or muted until a time that already passed
+	ErrFeedUntil = errors.New("feed state must end in the future")
 )
 
-func NewFeedsService(feedsRepo repo.Feeds, ctgsRepo repo.Categories, entriesRepo repo.Entries) FeedService {
+func NewFeedsService(
+	feedsRepo repo.Feeds,
+	ctgsRepo repo.Categories,
+	entriesRepo repo.Entries,
+	opts ...Option) FeedService {
+	o := newOptions(opts)
+
 	return FeedService{
-		feedsRepo,
-		ctgsRepo,
-		entriesRepo,
+		feedsRepo:   feedsRepo,
+		ctgsRepo:    ctgsRepo,
+		entriesRepo: entriesRepo,
+		events:      o.events,
+		plugins:     o.plugins,
+		rulesRepo:   o.rules,
+		websub:      o.websub,
 	}
 }
 
-// New creates a new Feed
-func (f FeedService) New(title, subscription, ctgID, userID string) (models.Feed, error) {
+// New creates a new Feed. The credentials of newFeed, if any, are sent whenever the feed is fetched.
+func (f FeedService) New(userID string, newFeed models.Feed) (models.Feed, error) {
+	subscription, credentials := newFeed.Subscription, newFeed.Credentials
+
 	feed := models.Feed{
 		ID:           utils.CreateID(),
 		Subscription: subscription,
+		Kind:         newFeed.Kind,
+		Selectors:    newFeed.Selectors,
+		Processors:   newFeed.Processors,
+		DisplayTitle: newFeed.DisplayTitle,
+		Notes:        newFeed.Notes,
+		KeepDays:     newFeed.KeepDays,
+		KeepLast:     newFeed.KeepLast,
+	}
+
+	// The title given to a new feed used to replace the upstream one and is
+	// now taken as its display title
+	if feed.DisplayTitle == "" {
+		feed.DisplayTitle = newFeed.Title
+	}
+
+	processors := plugins.Names(feed.Processors)
+	if f.plugins.Check(processors...) != nil {
+		return models.Feed{}, ErrFeedPlugin
 	}
 
-	if ctgID != "" {
+	if ctgID := newFeed.Category.ID; ctgID != "" {
 		ctg, found := f.ctgsRepo.CategoryWithID(userID, ctgID)
 		if !found {
 			return models.Feed{}, ErrFeedCategoryNotFound
 		}
 
 		feed.Category = ctg
+		feed.CategoryID = ctg.ID
 	}
 
-	f.feedsRepo.Create(userID, &feed)
+	var (
+		fetchedFeed models.Feed
+		entries     []models.Entry
+		err         error
+	)
+
+	switch feed.Kind {
+	case "":
+		subscription, fetchedFeed, entries, err = pullFeed(subscription, credentials)
+	case models.FeedKindScraper:
+		if utils.ValidateSelectors(feed.Selectors) != nil {
+			return models.Feed{}, ErrFeedSelectors
+		}
+
+		fetchedFeed, entries, err = utils.ScrapePage(subscription, "", "", feed.Selectors, credentials)
+	case models.FeedKindPlugin:
+		if newFeed.Plugin == "" || f.plugins.Check(newFeed.Plugin) != nil {
+			return models.Feed{}, ErrFeedPlugin
+		}
+
+		feed.Plugin = newFeed.Plugin
+
+		fetchedFeed, entries, err = f.plugins.Source(feed.Plugin, subscription)
+	default:
+		return models.Feed{}, ErrFeedKind
+	}
 
-	fetchedFeed, entries, err := utils.PullFeed(subscription, "")
 	if err != nil {
 		return models.Feed{}, ErrFetchingFeed
 	}
 
-	if feed.Title != "" {
-		fetchedFeed.Title = feed.Title
+	document := fetchedFeed.Subscription
+	if document == "" {
+		document = subscription
+	}
+
+	base := sanitizer.ResolveBase(document, fetchedFeed.Source, fetchedFeed.XMLBase)
+
+	ingest := NewIngest(f.entriesRepo, f.rulesRepo, userID, &feed, base, entries)
+	if ingest.Process(f.plugins, processors, &fetchedFeed) != nil {
+		return models.Feed{}, ErrFetchingFeed
+	}
+
+	feed.Subscription = subscription
+
+	f.feedsRepo.Create(userID, &feed)
+
+	if credentials != nil {
+		if err = f.feedsRepo.SetCredentials(userID, feed.ID, credentials); err != nil {
+			// The feed is not kept if it cannot be fetched as requested
+			if deleteErr := f.feedsRepo.Delete(userID, feed.ID); deleteErr != nil {
+				return models.Feed{}, deleteErr
+			}
+
+			return models.Feed{}, err
+		}
 	}
 
 	fetchedFeed.ID = feed.ID
+	fetchedFeed.Status = models.FeedStatusOK
+
+	if fetchedFeed.Subscription == "" {
+		fetchedFeed.Subscription = subscription
+	} else if fetchedFeed.Subscription != subscription {
+		err = f.feedsRepo.CreateEvent(userID, &models.FeedEvent{
+			ID:     utils.CreateID(),
+			FeedID: feed.ID,
+			Type:   models.FeedEventRedirect,
+			From:   subscription,
+			To:     fetchedFeed.Subscription,
+		})
+		if err != nil {
+			return models.Feed{}, err
+		}
+	}
 
 	err = f.feedsRepo.Update(userID, &fetchedFeed)
 	if err == repo.ErrModelNotFound {
@@ -120,16 +258,65 @@ func (f FeedService) New(title, subscription, ctgID, userID string) (models.Feed
 		return models.Feed{}, err
 	}
 
-	for idx := range entries {
-		entry := entries[idx]
-		entry.ID = utils.CreateID()
-		entry.Feed = feed
-		f.entriesRepo.Create(userID, &entry)
+	created, found := f.feedsRepo.FeedWithID(userID, feed.ID)
+	if found {
+		f.events.Publish(events.Event{Type: events.FeedCreated, UserID: userID, Feed: &created})
 	}
 
+	ingest.Store(f.entriesRepo, f.events, userID, &feed)
+
 	return fetchedFeed, nil
 }
 
+// Preview returns the feed and entries a scraper feed would extract from a web page
+func (f FeedService) Preview(subscription string, selectors models.ScraperSelectors,
+	credentials *models.FeedCredentials) (models.Feed, []models.Entry, error) {
+	if utils.ValidateSelectors(selectors) != nil {
+		return models.Feed{}, nil, ErrFeedSelectors
+	}
+
+	feed, entries, err := utils.ScrapePage(subscription, "", "", selectors, credentials)
+	if err != nil {
+		return models.Feed{}, nil, ErrFetchingFeed
+	}
+
+	if feed.Subscription == "" {
+		feed.Subscription = subscription
+	}
+
+	feed.Kind = models.FeedKindScraper
+	feed.Selectors = selectors
+
+	base := sanitizer.ResolveBase(feed.Subscription, feed.Source, feed.XMLBase)
+
+	for idx := range entries {
+		entries[idx].Content = sanitizer.Sanitize(entries[idx].Content, base)
+	}
+
+	return feed, entries, nil
+}
+
+// pullFeed returns the feed at subscription. If subscription is a website that
+// links to a single feed, that feed and its subscription are returned instead.
+func pullFeed(subscription string, credentials *models.FeedCredentials) (
+	string, models.Feed, []models.Entry, error) {
+	fetchedFeed, entries, err := utils.PullFeedWithCredentials(subscription, "", "", credentials)
+	if err == nil {
+		return subscription, fetchedFeed, entries, nil
+	}
+
+	candidates, discoverErr := utils.DiscoverFeeds(subscription)
+	if discoverErr != nil || len(candidates) != 1 {
+		return "", models.Feed{}, nil, err
+	}
+
+	subscription = candidates[0].Subscription
+
+	fetchedFeed, entries, err = utils.PullFeedWithCredentials(subscription, "", "", credentials)
+
+	return subscription, fetchedFeed, entries, err
+}
+
 // Feeds returns all feeds owned by user
 func (f FeedService) Feeds(userID string, page models.Page) (feeds []models.Feed, next string) {
 	return f.feedsRepo.List(userID, page)
@@ -140,34 +327,177 @@ func (f FeedService) Feed(userID, id string) (models.Feed, bool) {
 	return f.feedsRepo.FeedWithID(userID, id)
 }
 
-// Update a feed owned by user
+// Update a feed owned by user. The health of a feed is tracked while syncing
+// and cannot be changed, except to re-enable the feed by setting its status to ok.
+// Credentials are only replaced if the feed holds some, empty ones remove them.
+// The kind and source plugin of a feed cannot be changed and its selectors are
+// replaced as a whole. Upstream metadata is refreshed by syncs and cannot be
+// changed either, the display title and notes are set instead. The display
+// title, notes, category and retention limits are always replaced, an empty
+// category leaves the feed uncategorized and zero retention limits restore the
+// defaults. Fields of feed users cannot edit are ignored. On success,
+// feed is replaced by the updated feed.
+//
+// Feeds are paused and muted with Pause and Mute, Update leaves their state as is.
 func (f FeedService) Update(userID string, feed *models.Feed) error {
-	err := f.feedsRepo.Update(userID, feed)
+	if feed.Selectors != (models.ScraperSelectors{}) && utils.ValidateSelectors(feed.Selectors) != nil {
+		return ErrFeedSelectors
+	}
+
+	if f.plugins.Check(plugins.Names(feed.Processors)...) != nil {
+		return ErrFeedPlugin
+	}
+
+	current, found := f.feedsRepo.FeedWithID(userID, feed.ID)
+	if !found {
+		return ErrFeedNotFound
+	}
+
+	if feed.CategoryID != "" {
+		if _, found := f.ctgsRepo.CategoryWithID(userID, feed.CategoryID); !found {
+			return ErrFeedCategoryNotFound
+		}
+	}
+
+	// Only the fields users edit are written so that the ones a sync
+	// refreshes in the meantime are not overwritten
+	err := f.feedsRepo.Update(userID, &models.Feed{
+		ID:           feed.ID,
+		Subscription: feed.Subscription,
+		Selectors:    feed.Selectors,
+		Processors:   feed.Processors,
+	})
 	if err == repo.ErrModelNotFound {
 		return ErrFeedNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err = f.feedsRepo.UpdateSettings(userID, feed); err != nil {
+		return err
+	}
+
+	if feed.Credentials != nil {
+		if err = f.feedsRepo.SetCredentials(userID, feed.ID, feed.Credentials); err != nil {
+			return err
+		}
+
+		feed.Credentials = nil
+	}
+
+	if feed.Subscription != "" && feed.Subscription != current.Subscription {
+		f.releaseWebSub(current.Subscription)
+	}
+
+	if feed.Status == models.FeedStatusOK {
+		// Resetting the error count and the next fetch time makes the feed due immediately
+		if err = f.feedsRepo.UpdateStatus(userID, &models.Feed{ID: feed.ID, Status: models.FeedStatusOK}); err != nil {
+			return err
+		}
+	}
+
+	if updated, found := f.feedsRepo.FeedWithID(userID, feed.ID); found {
+		*feed = updated
+
+		f.events.Publish(events.Event{Type: events.FeedUpdated, UserID: userID, Feed: &updated})
 	}
 
-	return err
+	return nil
 }
 
 // Delete a feed with id
 func (f FeedService) Delete(userID, id string) error {
+	feed, found := f.feedsRepo.FeedWithID(userID, id)
+	if !found {
+		return ErrFeedNotFound
+	}
+
 	err := f.feedsRepo.Delete(userID, id)
 	if err == repo.ErrModelNotFound {
 		return ErrFeedNotFound
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	f.releaseWebSub(feed.Subscription)
+
+	f.events.Publish(events.Event{Type: events.FeedDeleted, UserID: userID, FeedID: id})
+
+	return nil
 }
 
 // Mark a feed with id
 func (f FeedService) Mark(userID, id string, marker models.Marker) error {
-	err := f.feedsRepo.Mark(userID, id, marker)
+	stats, err := f.feedsRepo.Stats(userID, id)
 	if err == repo.ErrModelNotFound {
 		return ErrFeedNotFound
+	} else if err != nil {
+		return err
+	}
+
+	err = f.feedsRepo.Mark(userID, id, marker)
+	if err == repo.ErrModelNotFound {
+		return ErrFeedNotFound
+	} else if err != nil {
+		return err
 	}
 
-	return err
+	f.events.Publish(events.Event{
+		Type:   events.EntryMarked,
+		UserID: userID,
+		FeedID: id,
+		Marker: marker,
+		Unread: unreadDelta(stats, marker),
+	})
+
+	return nil
+}
+
+// Pause fetching a feed owned by user. The feed is resumed once until passes,
+// unless until is nil. Its entries are kept.
+func (f FeedService) Pause(userID, id string, until *time.Time) error {
+	if until != nil && !until.After(time.Now()) {
+		return ErrFeedUntil
+	}
+
+	return f.stateChanged(userID, id, f.feedsRepo.SetPaused(userID, id, true, until))
+}
+
+// Resume fetching a feed owned by user
+func (f FeedService) Resume(userID, id string) error {
+	return f.stateChanged(userID, id, f.feedsRepo.SetPaused(userID, id, false, nil))
+}
+
+// Mute a feed owned by user. The feed is still fetched but its entries are
+// left out of the entries and stats of user. The feed is unmuted once until
+// passes, unless until is nil.
+func (f FeedService) Mute(userID, id string, until *time.Time) error {
+	if until != nil && !until.After(time.Now()) {
+		return ErrFeedUntil
+	}
+
+	return f.stateChanged(userID, id, f.feedsRepo.SetMuted(userID, id, true, until))
+}
+
+// Unmute a feed owned by user
+func (f FeedService) Unmute(userID, id string) error {
+	return f.stateChanged(userID, id, f.feedsRepo.SetMuted(userID, id, false, nil))
+}
+
+// stateChanged publishes a feed owned by user after it was paused, resumed,
+// muted or unmuted with the outcome err
+func (f FeedService) stateChanged(userID, id string, err error) error {
+	if err == repo.ErrModelNotFound {
+		return ErrFeedNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if updated, found := f.feedsRepo.FeedWithID(userID, id); found {
+		f.events.Publish(events.Event{Type: events.FeedUpdated, UserID: userID, Feed: &updated})
+	}
+
+	return nil
 }
 
 // Entries returns all entry items associated to a feed
@@ -186,3 +516,32 @@ func (f FeedService) Stats(userID, id string) (models.Stats, error) {
 
 	return stats, nil
 }
+
+// Events returns the history of changes made to a feed
+func (f FeedService) Events(userID string, page models.Page) (events []models.FeedEvent, next string, err error) {
+	if _, found := f.feedsRepo.FeedWithID(userID, page.FilterID); !found {
+		return nil, "", ErrFeedNotFound
+	}
+
+	events, next = f.feedsRepo.ListEvents(userID, page)
+
+	return events, next, nil
+}
+
+// Discover returns the feeds available at a website
+func (f FeedService) Discover(url string) ([]models.FeedCandidate, error) {
+	candidates, err := utils.DiscoverFeeds(url)
+	if err != nil {
+		return nil, ErrFetchingFeed
+	}
+
+	return candidates, nil
+}
+
+// releaseWebSub cancels the WebSub subscriptions of a feed URL that is no
+// longer subscribed to
+func (f FeedService) releaseWebSub(url string) {
+	if f.websub != nil {
+		f.websub.Release(url)
+	}
+}
@@ -6,6 +6,7 @@ package services
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	models "github.com/jmartinezhern/syndication/models"
@@ -48,6 +49,21 @@ func (mr *MockFeedsMockRecorder) Delete(userID, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFeeds)(nil).Delete), userID, id)
 }
 
+// Discover mocks base method.
+func (m *MockFeeds) Discover(url string) ([]models.FeedCandidate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discover", url)
+	ret0, _ := ret[0].([]models.FeedCandidate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discover indicates an expected call of Discover.
+func (mr *MockFeedsMockRecorder) Discover(url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discover", reflect.TypeOf((*MockFeeds)(nil).Discover), url)
+}
+
 // Entries mocks base method.
 func (m *MockFeeds) Entries(userID string, page models.Page) ([]models.Entry, string) {
 	m.ctrl.T.Helper()
@@ -63,6 +79,22 @@ func (mr *MockFeedsMockRecorder) Entries(userID, page interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockFeeds)(nil).Entries), userID, page)
 }
 
+// Events mocks base method.
+func (m *MockFeeds) Events(userID string, page models.Page) ([]models.FeedEvent, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Events", userID, page)
+	ret0, _ := ret[0].([]models.FeedEvent)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Events indicates an expected call of Events.
+func (mr *MockFeedsMockRecorder) Events(userID, page interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Events", reflect.TypeOf((*MockFeeds)(nil).Events), userID, page)
+}
+
 // Feed mocks base method.
 func (m *MockFeeds) Feed(userID, id string) (models.Feed, bool) {
 	m.ctrl.T.Helper()
@@ -107,19 +139,77 @@ func (mr *MockFeedsMockRecorder) Mark(userID, id, marker interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mark", reflect.TypeOf((*MockFeeds)(nil).Mark), userID, id, marker)
 }
 
+// Mute mocks base method.
+func (m *MockFeeds) Mute(userID, id string, until *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mute", userID, id, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mute indicates an expected call of Mute.
+func (mr *MockFeedsMockRecorder) Mute(userID, id, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mute", reflect.TypeOf((*MockFeeds)(nil).Mute), userID, id, until)
+}
+
 // New mocks base method.
-func (m *MockFeeds) New(title, subscription, ctgID, userID string) (models.Feed, error) {
+func (m *MockFeeds) New(userID string, newFeed models.Feed) (models.Feed, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "New", title, subscription, ctgID, userID)
+	ret := m.ctrl.Call(m, "New", userID, newFeed)
 	ret0, _ := ret[0].(models.Feed)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // New indicates an expected call of New.
-func (mr *MockFeedsMockRecorder) New(title, subscription, ctgID, userID interface{}) *gomock.Call {
+func (mr *MockFeedsMockRecorder) New(userID, newFeed interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "New", reflect.TypeOf((*MockFeeds)(nil).New), userID, newFeed)
+}
+
+// Pause mocks base method.
+func (m *MockFeeds) Pause(userID, id string, until *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pause", userID, id, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Pause indicates an expected call of Pause.
+func (mr *MockFeedsMockRecorder) Pause(userID, id, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockFeeds)(nil).Pause), userID, id, until)
+}
+
+// Preview mocks base method.
+func (m *MockFeeds) Preview(subscription string, selectors models.ScraperSelectors, credentials *models.FeedCredentials) (models.Feed, []models.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Preview", subscription, selectors, credentials)
+	ret0, _ := ret[0].(models.Feed)
+	ret1, _ := ret[1].([]models.Entry)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Preview indicates an expected call of Preview.
+func (mr *MockFeedsMockRecorder) Preview(subscription, selectors, credentials interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "New", reflect.TypeOf((*MockFeeds)(nil).New), title, subscription, ctgID, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preview", reflect.TypeOf((*MockFeeds)(nil).Preview), subscription, selectors, credentials)
+}
+
+// Resume mocks base method.
+func (m *MockFeeds) Resume(userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Resume", userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Resume indicates an expected call of Resume.
+func (mr *MockFeedsMockRecorder) Resume(userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockFeeds)(nil).Resume), userID, id)
 }
 
 // Stats mocks base method.
@@ -137,6 +227,20 @@ func (mr *MockFeedsMockRecorder) Stats(userID, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockFeeds)(nil).Stats), userID, id)
 }
 
+// Unmute mocks base method.
+func (m *MockFeeds) Unmute(userID, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unmute", userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unmute indicates an expected call of Unmute.
+func (mr *MockFeedsMockRecorder) Unmute(userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmute", reflect.TypeOf((*MockFeeds)(nil).Unmute), userID, id)
+}
+
 // Update mocks base method.
 func (m *MockFeeds) Update(userID string, feed *models.Feed) error {
 	m.ctrl.T.Helper()
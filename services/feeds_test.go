@@ -19,14 +19,19 @@ package services_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/repo/sql"
 	"github.com/jmartinezhern/syndication/services"
@@ -54,15 +59,533 @@ func (t *FeedsSuite) TestNewFeed() {
 	}))
 	defer ts.Close()
 
-	feed, err := t.service.New("Example", ts.URL, "", t.user.ID)
+	feed, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL})
 	t.NoError(err)
 	_, found := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
 	t.True(found)
 }
 
+func (t *FeedsSuite) TestNewFeedWithRetention() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, "<rss></rss>")
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Subscription: ts.URL, KeepDays: 7, KeepLast: 100})
+	t.Require().NoError(err)
+
+	created, _ := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Equal(7, created.KeepDays)
+	t.Equal(100, created.KeepLast)
+}
+
+func (t *FeedsSuite) TestNewFeedWithDisplayTitle() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, `<rss><channel><title>Upstream</title><image><url>http://example.com/icon.png</url>`+
+			`</image></channel></rss>`)
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{
+		Subscription: ts.URL,
+		DisplayTitle: "Mine",
+		Notes:        "Read on weekends",
+	})
+	t.Require().NoError(err)
+
+	created, found := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Require().True(found)
+	t.Equal("Upstream", created.Title)
+	t.Equal("http://example.com/icon.png", created.Icon)
+	t.Equal("Mine", created.DisplayTitle)
+	t.Equal("Read on weekends", created.Notes)
+}
+
+func (t *FeedsSuite) TestNewFeedWithTitle() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, `<rss><channel><title>Upstream</title></channel></rss>`)
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	// Titles given to new feeds are taken as their display title
+	feed, err := t.service.New(t.user.ID, models.Feed{Subscription: ts.URL, Title: "Mine"})
+	t.Require().NoError(err)
+
+	created, found := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Require().True(found)
+	t.Equal("Upstream", created.Title)
+	t.Equal("Mine", created.DisplayTitle)
+}
+
+func (t *FeedsSuite) TestFeedChangesPublishEvents() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintln(w, `<rss><channel><title>Example</title><item><title>First</title></item></channel></rss>`)
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	bus := events.NewBus()
+	sub := bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	service := services.NewFeedsService(t.feedsRepo, t.ctgsRepo, t.entriesRepo, services.WithEvents(bus))
+
+	feed, err := service.New(t.user.ID, models.Feed{Subscription: ts.URL})
+	t.Require().NoError(err)
+
+	event := <-sub.Events()
+	t.Equal(events.FeedCreated, event.Type)
+	t.Equal(feed.ID, event.Feed.ID)
+	t.Equal("Example", event.Feed.Title)
+
+	event = <-sub.Events()
+	t.Equal(events.EntryCreated, event.Type)
+	t.Equal(feed.ID, event.FeedID)
+	t.Equal("First", event.Entry.Title)
+	t.Equal(1, event.Unread)
+
+	t.NoError(service.Update(t.user.ID, &models.Feed{ID: feed.ID, DisplayTitle: "Renamed"}))
+
+	event = <-sub.Events()
+	t.Equal(events.FeedUpdated, event.Type)
+	t.Equal("Renamed", event.Feed.DisplayTitle)
+
+	t.NoError(service.Mark(t.user.ID, feed.ID, models.MarkerRead))
+
+	event = <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Equal(feed.ID, event.FeedID)
+	t.Equal(models.MarkerRead, event.Marker)
+	t.Equal(-1, event.Unread)
+
+	t.NoError(service.Delete(t.user.ID, feed.ID))
+
+	event = <-sub.Events()
+	t.Equal(events.FeedDeleted, event.Type)
+	t.Equal(feed.ID, event.FeedID)
+
+	// Failed changes are not published
+	t.Error(service.Delete(t.user.ID, feed.ID))
+	t.Empty(sub.Events())
+}
+
+func (t *FeedsSuite) TestNewFeedWithCredentials() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "user" || password != "secret" || r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, err := fmt.Fprintln(w, "<rss></rss>")
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	_, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL})
+	t.Equal(services.ErrFetchingFeed, err)
+
+	credentials := &models.FeedCredentials{
+		Username: "user",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+	}
+
+	feed, err := t.service.New(t.user.ID, models.Feed{
+		Title:        "Example",
+		Subscription: ts.URL,
+		Credentials:  credentials,
+	})
+	t.Require().NoError(err)
+
+	stored, err := t.feedsRepo.Credentials(t.user.ID, feed.ID)
+	t.NoError(err)
+	t.Equal(credentials, stored)
+}
+
+func (t *FeedsSuite) TestNewFeedSanitizesContent() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `<rss><channel><link>/blog/</link><item><guid>1</guid>`+
+			`<description>&lt;img src="a.png" onerror="steal()"&gt;&lt;script&gt;steal()&lt;/script&gt;</description>`+
+			`</item></channel></rss>`)
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL + "/feed.xml"})
+	t.Require().NoError(err)
+
+	entries, _ := t.service.Entries(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    1,
+		Marker:   models.MarkerAny,
+	})
+	t.Require().Len(entries, 1)
+	t.Equal(`<img src="`+ts.URL+`/blog/a.png">`, entries[0].Summary)
+}
+
+func (t *FeedsSuite) TestNewFeedResolvesXMLBaseAgainstDocument() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `<rss xml:base="/posts/"><channel><link>https://blog.example.org/</link>`+
+			`<item><guid>1</guid><description>&lt;img src="a.png"&gt;</description></item></channel></rss>`)
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL + "/feeds/rss.xml"})
+	t.Require().NoError(err)
+
+	entries, _ := t.service.Entries(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    1,
+		Marker:   models.MarkerAny,
+	})
+	t.Require().Len(entries, 1)
+	t.Equal(`<img src="`+ts.URL+`/posts/a.png">`, entries[0].Summary)
+}
+
+func (t *FeedsSuite) TestNewFeedFollowsPermanentRedirect() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old.xml" {
+			http.Redirect(w, r, "/feed.xml", http.StatusMovedPermanently)
+			return
+		}
+
+		_, err := fmt.Fprintln(w, "<rss></rss>")
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL + "/old.xml"})
+	t.Require().NoError(err)
+	t.Equal(ts.URL+"/feed.xml", feed.Subscription)
+
+	feed, _ = t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Equal(ts.URL+"/feed.xml", feed.Subscription)
+
+	events, _, err := t.service.Events(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+	})
+	t.Require().NoError(err)
+	t.Require().Len(events, 1)
+	t.Equal(models.FeedEventRedirect, events[0].Type)
+	t.Equal(ts.URL+"/old.xml", events[0].From)
+	t.Equal(ts.URL+"/feed.xml", events[0].To)
+}
+
+func (t *FeedsSuite) TestNewFeedFollowsTemporaryRedirect() {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old.xml" {
+			http.Redirect(w, r, "/feed.xml", http.StatusFound)
+			return
+		}
+
+		_, err := fmt.Fprintln(w, "<rss></rss>")
+		t.Require().NoError(err)
+	}))
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: ts.URL + "/old.xml"})
+	t.Require().NoError(err)
+
+	feed, _ = t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Equal(ts.URL+"/old.xml", feed.Subscription)
+
+	events, _, err := t.service.Events(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+	})
+	t.Require().NoError(err)
+	t.Empty(events)
+}
+
 func (t *FeedsSuite) TestUnreachableNewFeed() {
-	_, err := t.service.New("Example", "bogus", "", t.user.ID)
+	_, err := t.service.New(t.user.ID, models.Feed{Title: "Example", Subscription: "bogus"})
+	t.EqualError(err, services.ErrFetchingFeed.Error())
+}
+
+func (t *FeedsSuite) discoveryServer(page string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		switch r.URL.Path {
+		case "/":
+			_, err = fmt.Fprint(w, page)
+		case "/feed.xml", "/rss.xml":
+			_, err = fmt.Fprint(w, `<rss><channel><title>Example Feed</title></channel></rss>`)
+		case "/atom.xml":
+			_, err = fmt.Fprint(w, `<feed xmlns="http://www.w3.org/2005/Atom"><title>Example Atom</title></feed>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+		t.Require().NoError(err)
+	}))
+}
+
+func (t *FeedsSuite) TestNewFeedDiscoversFeed() {
+	ts := t.discoveryServer(`<html><head>` +
+		`<link rel="alternate" type="application/rss+xml" title="Example" href="/feed.xml">` +
+		`</head></html>`)
+	defer ts.Close()
+
+	feed, err := t.service.New(t.user.ID, models.Feed{Subscription: ts.URL})
+	t.Require().NoError(err)
+	t.Equal(ts.URL+"/feed.xml", feed.Subscription)
+	t.Equal("Example Feed", feed.Title)
+}
+
+func (t *FeedsSuite) TestNewFeedWithManyCandidates() {
+	ts := t.discoveryServer(`<html><head>` +
+		`<link rel="alternate" type="application/rss+xml" href="/feed.xml">` +
+		`<link rel="alternate" type="application/atom+xml" href="/atom.xml">` +
+		`</head></html>`)
+	defer ts.Close()
+
+	_, err := t.service.New(t.user.ID, models.Feed{Subscription: ts.URL})
 	t.EqualError(err, services.ErrFetchingFeed.Error())
+
+	feeds, _ := t.service.Feeds(t.user.ID, models.Page{Count: 5})
+	t.Len(feeds, 1)
+}
+
+func (t *FeedsSuite) TestDiscover() {
+	ts := t.discoveryServer(`<html><head>` +
+		`<link rel="Alternate" type="application/rss+xml" title="Posts" href="feed.xml">` +
+		`<link rel="alternate" type="application/atom+xml" href="/atom.xml">` +
+		`<link rel="alternate" type="application/json" href="/wp-json/">` +
+		`<link rel="stylesheet" type="text/css" href="/style.css">` +
+		`</head></html>`)
+	defer ts.Close()
+
+	candidates, err := t.service.Discover(ts.URL)
+	t.Require().NoError(err)
+	t.Equal([]models.FeedCandidate{
+		{Title: "Posts", Subscription: ts.URL + "/feed.xml"},
+		{Title: "Example Atom", Subscription: ts.URL + "/atom.xml"},
+	}, candidates)
+}
+
+func (t *FeedsSuite) TestDiscoverCommonPaths() {
+	ts := t.discoveryServer(`<html><head><title>Example</title></head></html>`)
+	defer ts.Close()
+
+	candidates, err := t.service.Discover(ts.URL)
+	t.Require().NoError(err)
+	t.Equal([]models.FeedCandidate{
+		{Title: "Example Feed", Subscription: ts.URL + "/rss.xml"},
+	}, candidates)
+}
+
+func (t *FeedsSuite) TestDiscoverFeed() {
+	ts := t.discoveryServer("")
+	defer ts.Close()
+
+	candidates, err := t.service.Discover(ts.URL + "/atom.xml")
+	t.Require().NoError(err)
+	t.Equal([]models.FeedCandidate{
+		{Title: "Example Atom", Subscription: ts.URL + "/atom.xml"},
+	}, candidates)
+}
+
+func (t *FeedsSuite) TestDiscoverUnreachable() {
+	_, err := t.service.Discover("bogus")
+	t.EqualError(err, services.ErrFetchingFeed.Error())
+}
+
+func (t *FeedsSuite) scraperServer() *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+
+		_, err := fmt.Fprint(w, `<html><head><title>News</title></head><body>
+			<article><h2><a href="/news/1">First</a></h2><p>Hello <script>alert(1)</script>world</p></article>
+			<article><h2><a href="/news/2">Second</a></h2><p>Bye</p></article>
+		</body></html>`)
+		t.Require().NoError(err)
+	}))
+	t.T().Cleanup(ts.Close)
+
+	return ts
+}
+
+func (t *FeedsSuite) TestNewScraperFeed() {
+	ts := t.scraperServer()
+
+	selectors := models.ScraperSelectors{Item: "article", Title: "h2", Content: "p"}
+
+	feed, err := t.service.New(t.user.ID, models.Feed{
+		Subscription: ts.URL,
+		Kind:         models.FeedKindScraper,
+		Selectors:    selectors,
+	})
+	t.Require().NoError(err)
+	t.Equal("News", feed.Title)
+
+	saved, found := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Require().True(found)
+	t.Equal(models.FeedKindScraper, saved.Kind)
+	t.Equal(selectors, saved.Selectors)
+
+	entries, _ := t.entriesRepo.ListFromFeed(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	t.Require().Len(entries, 2)
+
+	for _, entry := range entries {
+		t.NotContains(entry.Content, "script")
+	}
+}
+
+func (t *FeedsSuite) TestNewScraperFeedWithInvalidSelectors() {
+	ts := t.scraperServer()
+
+	_, err := t.service.New(t.user.ID, models.Feed{Subscription: ts.URL, Kind: models.FeedKindScraper})
+	t.Equal(services.ErrFeedSelectors, err)
+
+	_, err = t.service.New(t.user.ID, models.Feed{
+		Subscription: ts.URL,
+		Kind:         models.FeedKindScraper,
+		Selectors:    models.ScraperSelectors{Item: "article["},
+	})
+	t.Equal(services.ErrFeedSelectors, err)
+}
+
+func (t *FeedsSuite) TestNewFeedWithUnknownKind() {
+	_, err := t.service.New(t.user.ID, models.Feed{Subscription: "http://localhost:9876", Kind: "bogus"})
+	t.Equal(services.ErrFeedKind, err)
+}
+
+func (t *FeedsSuite) TestPreview() {
+	ts := t.scraperServer()
+
+	feed, entries, err := t.service.Preview(ts.URL, models.ScraperSelectors{
+		Item:    "article",
+		Title:   "h2",
+		Content: "p",
+	}, nil)
+	t.Require().NoError(err)
+	t.Equal("News", feed.Title)
+	t.Equal(models.FeedKindScraper, feed.Kind)
+
+	t.Require().Len(entries, 2)
+	t.Equal("First", entries[0].Title)
+	t.Equal(ts.URL+"/news/1", entries[0].Link)
+	t.Equal("Hello world", entries[0].Content)
+
+	// Nothing is saved
+	feeds, _ := t.feedsRepo.List(t.user.ID, models.Page{Count: 10})
+	t.Len(feeds, 1)
+}
+
+func (t *FeedsSuite) TestPreviewWithInvalidSelectors() {
+	_, _, err := t.service.Preview("http://localhost:9876", models.ScraperSelectors{Title: "h2"}, nil)
+	t.Equal(services.ErrFeedSelectors, err)
+}
+
+func (t *FeedsSuite) TestPreviewUnreachablePage() {
+	_, _, err := t.service.Preview("http://localhost:9876", models.ScraperSelectors{Item: "article"}, nil)
+	t.Equal(services.ErrFetchingFeed, err)
+}
+
+// pluginsService returns a service with a source plugin named issues and a
+// processor plugin named drop, which drops every entry
+func (t *FeedsSuite) pluginsService() services.Feeds {
+	dir := t.T().TempDir()
+
+	scripts := map[string]string{
+		"issues": `echo '{"feed": {"title": "Issues"}, "entries": [{"id": "SYN-1", "title": "First"}]}'`,
+		"drop":   `echo '{"entries": []}'`,
+	}
+
+	var configs []plugins.Config
+
+	for name, script := range scripts {
+		path := filepath.Join(dir, name)
+		t.Require().NoError(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0700))
+
+		configs = append(configs, plugins.Config{Name: name, Path: path})
+	}
+
+	registry, err := plugins.NewRegistry(configs)
+	t.Require().NoError(err)
+
+	return services.NewFeedsService(t.feedsRepo, t.ctgsRepo, t.entriesRepo, services.WithPlugins(registry))
+}
+
+func (t *FeedsSuite) TestNewPluginFeed() {
+	service := t.pluginsService()
+
+	feed, err := service.New(t.user.ID, models.Feed{
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+	})
+	t.Require().NoError(err)
+	t.Equal("Issues", feed.Title)
+
+	saved, found := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Require().True(found)
+	t.Equal(models.FeedKindPlugin, saved.Kind)
+	t.Equal("issues", saved.Plugin)
+	t.Equal("project = SYN", saved.Subscription)
+
+	entries, _ := t.entriesRepo.ListFromFeed(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	t.Require().Len(entries, 1)
+	t.Equal("First", entries[0].Title)
+}
+
+func (t *FeedsSuite) TestNewFeedWithProcessors() {
+	service := t.pluginsService()
+
+	feed, err := service.New(t.user.ID, models.Feed{
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+		Processors:   "drop",
+	})
+	t.Require().NoError(err)
+
+	saved, _ := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Equal("drop", saved.Processors)
+
+	entries, _ := t.entriesRepo.ListFromFeed(t.user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	t.Empty(entries)
+}
+
+func (t *FeedsSuite) TestNewFeedWithUnknownPlugin() {
+	service := t.pluginsService()
+
+	_, err := service.New(t.user.ID, models.Feed{Subscription: "query", Kind: models.FeedKindPlugin})
+	t.Equal(services.ErrFeedPlugin, err)
+
+	_, err = service.New(t.user.ID, models.Feed{Subscription: "query", Kind: models.FeedKindPlugin, Plugin: "bogus"})
+	t.Equal(services.ErrFeedPlugin, err)
+
+	_, err = service.New(t.user.ID, models.Feed{
+		Subscription: "query",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+		Processors:   "drop,bogus",
+	})
+	t.Equal(services.ErrFeedPlugin, err)
+
+	// Without plugins configured, none can be used
+	_, err = t.service.New(t.user.ID, models.Feed{Subscription: "query", Kind: models.FeedKindPlugin, Plugin: "issues"})
+	t.Equal(services.ErrFeedPlugin, err)
 }
 
 func (t *FeedsSuite) TestFeeds() {
@@ -80,12 +603,201 @@ func (t *FeedsSuite) TestFeed() {
 }
 
 func (t *FeedsSuite) TestEditFeed() {
-	feed := models.Feed{ID: t.feed.ID, Title: "New Title"}
+	feed := models.Feed{ID: t.feed.ID, DisplayTitle: "New Title", Notes: "Weekly digest"}
 	err := t.service.Update(t.user.ID, &feed)
 	t.NoError(err)
 
 	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
-	t.Equal("New Title", updatedFeed.Title)
+	t.Equal("New Title", updatedFeed.DisplayTitle)
+	t.Equal("Weekly digest", updatedFeed.Notes)
+	t.Equal(t.feed.Title, updatedFeed.Title)
+
+	t.Equal("New Title", feed.DisplayTitle)
+	t.Equal(t.feed.Title, feed.Title)
+	t.Equal(t.feed.Subscription, feed.Subscription)
+}
+
+func (t *FeedsSuite) TestEditFeedRetention() {
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, KeepDays: 7, KeepLast: 100}))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal(7, updatedFeed.KeepDays)
+	t.Equal(100, updatedFeed.KeepLast)
+
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID}))
+
+	updatedFeed, _ = t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Zero(updatedFeed.KeepDays)
+	t.Zero(updatedFeed.KeepLast)
+}
+
+func (t *FeedsSuite) TestEditFeedClearsDisplayTitleAndNotes() {
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, DisplayTitle: "Mine", Notes: "Read on weekends"}))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal("Mine", updatedFeed.DisplayTitle)
+	t.Equal("Read on weekends", updatedFeed.Notes)
+
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID}))
+
+	updatedFeed, _ = t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Empty(updatedFeed.DisplayTitle)
+	t.Empty(updatedFeed.Notes)
+	t.Equal(t.feed.Title, updatedFeed.Title)
+}
+
+func (t *FeedsSuite) TestEditFeedKeepsUpstreamMetadata() {
+	feed := models.Feed{
+		ID:          t.feed.ID,
+		Title:       "New Title",
+		Description: "New description",
+		Source:      "http://example.com/other",
+		Icon:        "http://example.com/icon.png",
+	}
+	t.NoError(t.service.Update(t.user.ID, &feed))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal(t.feed.Title, updatedFeed.Title)
+	t.Equal(t.feed.Description, updatedFeed.Description)
+	t.Equal(t.feed.Source, updatedFeed.Source)
+	t.Empty(updatedFeed.Icon)
+}
+
+func (t *FeedsSuite) TestEditFeedKeepsSyncedFields() {
+	ctg := models.Category{ID: utils.CreateID(), Name: "News"}
+	t.ctgsRepo.Create(t.user.ID, &ctg)
+
+	feed := models.Feed{ID: utils.CreateID(), Subscription: "example.com/news", Category: ctg, Etag: "v1", TTL: 30}
+	t.feedsRepo.Create(t.user.ID, &feed)
+
+	stale, _ := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+
+	// A sync finishes after the feed to edit was read
+	next := time.Now().Add(time.Hour)
+	t.NoError(t.feedsRepo.Update(t.user.ID, &models.Feed{ID: feed.ID, Etag: "v2", LastModified: "Mon, 02 Jan 2006"}))
+	t.NoError(t.feedsRepo.UpdateSchedule(t.user.ID, &models.Feed{ID: feed.ID, TTL: 60}))
+	t.NoError(t.feedsRepo.UpdateStatus(t.user.ID, &models.Feed{ID: feed.ID, NextFetchAt: next}))
+
+	stale.DisplayTitle = "Mine"
+	t.NoError(t.service.Update(t.user.ID, &stale))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, feed.ID)
+	t.Equal("Mine", updatedFeed.DisplayTitle)
+	t.Equal("v2", updatedFeed.Etag)
+	t.Equal("Mon, 02 Jan 2006", updatedFeed.LastModified)
+	t.Equal(60, updatedFeed.TTL)
+	t.WithinDuration(next, updatedFeed.NextFetchAt, time.Second)
+	t.Equal(ctg.ID, updatedFeed.Category.ID)
+
+	t.Equal(updatedFeed.Etag, stale.Etag)
+}
+
+func (t *FeedsSuite) TestEditFeedKeepsStatus() {
+	t.NoError(t.feedsRepo.UpdateStatus(t.user.ID, &models.Feed{
+		ID:         t.feed.ID,
+		Status:     models.FeedStatusError,
+		ErrorCount: 2,
+		LastError:  "server responded with status 500",
+	}))
+
+	feed := models.Feed{ID: t.feed.ID, Status: models.FeedStatusDisabled, ErrorCount: 5, LastError: "bogus"}
+	t.NoError(t.service.Update(t.user.ID, &feed))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal(models.FeedStatusError, updatedFeed.Status)
+	t.Equal(2, updatedFeed.ErrorCount)
+	t.Equal("server responded with status 500", updatedFeed.LastError)
+}
+
+func (t *FeedsSuite) TestEditFeedReenables() {
+	t.NoError(t.feedsRepo.UpdateStatus(t.user.ID, &models.Feed{
+		ID:          t.feed.ID,
+		Status:      models.FeedStatusDisabled,
+		ErrorCount:  10,
+		LastError:   "server responded with status 500",
+		NextFetchAt: time.Now().Add(time.Hour),
+	}))
+
+	feed := models.Feed{ID: t.feed.ID, Status: models.FeedStatusOK}
+	t.NoError(t.service.Update(t.user.ID, &feed))
+
+	updatedFeed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal(models.FeedStatusOK, updatedFeed.Status)
+	t.Zero(updatedFeed.ErrorCount)
+	t.Empty(updatedFeed.LastError)
+	t.True(updatedFeed.NextFetchAt.Before(time.Now()))
+}
+
+func (t *FeedsSuite) TestEditFeedCredentials() {
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{
+		ID:          t.feed.ID,
+		Credentials: &models.FeedCredentials{Token: "secret"},
+	}))
+
+	credentials, err := t.feedsRepo.Credentials(t.user.ID, t.feed.ID)
+	t.NoError(err)
+	t.Equal(&models.FeedCredentials{Token: "secret"}, credentials)
+
+	// Credentials are kept unless new ones are given
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, DisplayTitle: "New Title"}))
+
+	credentials, err = t.feedsRepo.Credentials(t.user.ID, t.feed.ID)
+	t.NoError(err)
+	t.Equal(&models.FeedCredentials{Token: "secret"}, credentials)
+
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{
+		ID:          t.feed.ID,
+		Credentials: &models.FeedCredentials{},
+	}))
+
+	credentials, err = t.feedsRepo.Credentials(t.user.ID, t.feed.ID)
+	t.NoError(err)
+	t.Nil(credentials)
+}
+
+func (t *FeedsSuite) TestEditFeedWithInvalidSelectors() {
+	err := t.service.Update(t.user.ID, &models.Feed{
+		ID:        t.feed.ID,
+		Selectors: models.ScraperSelectors{Title: "h2"},
+	})
+	t.Equal(services.ErrFeedSelectors, err)
+}
+
+func (t *FeedsSuite) TestEditFeedProcessors() {
+	service := t.pluginsService()
+
+	t.Equal(services.ErrFeedPlugin, service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, Processors: "bogus"}))
+
+	t.NoError(service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, Processors: "drop", Plugin: "issues"}))
+
+	feed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.Equal("drop", feed.Processors)
+
+	// The source plugin of a feed cannot be changed
+	t.Empty(feed.Plugin)
+}
+
+func (t *FeedsSuite) TestEditFeedCategory() {
+	ctg := models.Category{ID: utils.CreateID(), Name: "news"}
+	t.ctgsRepo.Create(t.user.ID, &ctg)
+
+	feed := models.Feed{ID: t.feed.ID, CategoryID: ctg.ID}
+	t.Require().NoError(t.service.Update(t.user.ID, &feed))
+	t.Equal(ctg.ID, feed.Category.ID)
+
+	feeds, _ := t.ctgsRepo.Feeds(t.user.ID, models.Page{FilterID: ctg.ID, Count: 10})
+	t.Len(feeds, 1)
+
+	err := t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, CategoryID: "bogus"})
+	t.Equal(services.ErrFeedCategoryNotFound, err)
+
+	// An empty category leaves the feed uncategorized
+	feed = models.Feed{ID: t.feed.ID}
+	t.Require().NoError(t.service.Update(t.user.ID, &feed))
+	t.Empty(feed.Category.ID)
+
+	feeds, _ = t.ctgsRepo.Feeds(t.user.ID, models.Page{FilterID: ctg.ID, Count: 10})
+	t.Empty(feeds)
 }
 
 func (t *FeedsSuite) TestEditMissingFeed() {
@@ -135,6 +847,55 @@ func (t *FeedsSuite) TestMarkMissingFeed() {
 	t.EqualError(err, services.ErrFeedNotFound.Error())
 }
 
+func (t *FeedsSuite) TestPauseFeed() {
+	until := time.Now().Add(time.Hour)
+	t.NoError(t.service.Pause(t.user.ID, t.feed.ID, &until))
+
+	feed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.True(feed.Paused)
+	t.Require().NotNil(feed.PausedUntil)
+
+	// Feeds are neither resumed nor muted when they are edited
+	t.NoError(t.service.Update(t.user.ID, &models.Feed{ID: t.feed.ID, Muted: true, DisplayTitle: "Paused"}))
+
+	feed, _ = t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.True(feed.Paused)
+	t.False(feed.Muted)
+
+	t.NoError(t.service.Resume(t.user.ID, t.feed.ID))
+
+	feed, _ = t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.False(feed.Paused)
+	t.Nil(feed.PausedUntil)
+}
+
+func (t *FeedsSuite) TestMuteFeed() {
+	t.NoError(t.service.Mute(t.user.ID, t.feed.ID, nil))
+
+	feed, _ := t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.True(feed.Muted)
+	t.Nil(feed.MutedUntil)
+
+	t.NoError(t.service.Unmute(t.user.ID, t.feed.ID))
+
+	feed, _ = t.feedsRepo.FeedWithID(t.user.ID, t.feed.ID)
+	t.False(feed.Muted)
+}
+
+func (t *FeedsSuite) TestPauseFeedUntilPastTime() {
+	past := time.Now().Add(-time.Minute)
+
+	t.Equal(services.ErrFeedUntil, t.service.Pause(t.user.ID, t.feed.ID, &past))
+	t.Equal(services.ErrFeedUntil, t.service.Mute(t.user.ID, t.feed.ID, &past))
+}
+
+func (t *FeedsSuite) TestPauseMissingFeed() {
+	t.Equal(services.ErrFeedNotFound, t.service.Pause(t.user.ID, "bogus", nil))
+	t.Equal(services.ErrFeedNotFound, t.service.Resume(t.user.ID, "bogus"))
+	t.Equal(services.ErrFeedNotFound, t.service.Mute(t.user.ID, "bogus", nil))
+	t.Equal(services.ErrFeedNotFound, t.service.Unmute(t.user.ID, "bogus"))
+}
+
 func (t *FeedsSuite) TestFeedEntries() {
 	entry := models.Entry{
 		ID:    utils.CreateID(),
@@ -176,6 +937,25 @@ func (t *FeedsSuite) TestMissingFeedStats() {
 	t.EqualError(err, services.ErrFeedNotFound.Error())
 }
 
+func (t *FeedsSuite) TestMissingFeedEvents() {
+	_, _, err := t.service.Events(t.user.ID, models.Page{
+		FilterID: "bogus",
+		Count:    5,
+	})
+	t.EqualError(err, services.ErrFeedNotFound.Error())
+}
+
+func (t *FeedsSuite) SetupSuite() {
+	// Test servers listen on loopback addresses
+	t.Require().NoError(utils.ConfigureFetcher(utils.FetcherConfig{
+		AllowedNetworks: []string{"127.0.0.0/8", "::1"},
+	}))
+}
+
+func (t *FeedsSuite) TearDownSuite() {
+	t.NoError(utils.ConfigureFetcher(utils.FetcherConfig{}))
+}
+
 func (t *FeedsSuite) SetupTest() {
 	var err error
 
@@ -184,7 +964,7 @@ func (t *FeedsSuite) SetupTest() {
 
 	sql.AutoMigrateTables(t.db)
 
-	t.feedsRepo = sql.NewFeeds(t.db)
+	t.feedsRepo = sql.NewFeeds(t.db, sql.WithCredentialsSecret("secret"))
 	t.entriesRepo = sql.NewEntries(t.db)
 	t.ctgsRepo = sql.NewCategories(t.db)
 
@@ -20,6 +20,7 @@ package services
 import (
 	"encoding/xml"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/utils"
@@ -39,13 +40,16 @@ type (
 	OPMLImporter struct {
 		ctgsRepo  repo.Categories
 		feedsRepo repo.Feeds
+
+		events *events.Bus
 	}
 )
 
-func NewOPMLImporter(ctgsRepo repo.Categories, feedsRepo repo.Feeds) OPMLImporter {
+func NewOPMLImporter(ctgsRepo repo.Categories, feedsRepo repo.Feeds, opts ...Option) OPMLImporter {
 	return OPMLImporter{
-		ctgsRepo,
-		feedsRepo,
+		ctgsRepo:  ctgsRepo,
+		feedsRepo: feedsRepo,
+		events:    newOptions(opts).events,
 	}
 }
 
@@ -56,7 +60,7 @@ func (i OPMLImporter) extractFeeds(items []models.OPMLOutline) []models.Feed {
 		outline := items[idx]
 		if outline.Type == "rss" {
 			feed := models.Feed{
-				Title:        outline.Title,
+				DisplayTitle: outline.Title,
 				Subscription: outline.XMLUrl,
 			}
 
@@ -69,7 +73,7 @@ func (i OPMLImporter) extractFeeds(items []models.OPMLOutline) []models.Feed {
 			for idx := range outline.Items {
 				ctgOutline := outline.Items[idx]
 				feed := models.Feed{
-					Title:        ctgOutline.Title,
+					DisplayTitle: ctgOutline.Title,
 					Subscription: ctgOutline.XMLUrl,
 					Category:     ctg,
 				}
@@ -118,6 +122,8 @@ func (i OPMLImporter) Import(data []byte, userID string) error {
 			feed.ID = utils.CreateID()
 			i.feedsRepo.Create(userID, &feed)
 		}
+
+		i.events.Publish(events.Event{Type: events.FeedCreated, UserID: userID, Feed: &feed})
 	}
 
 	return nil
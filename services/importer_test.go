@@ -18,12 +18,14 @@
 package services_test
 
 import (
+	"encoding/xml"
 	"sort"
 	"testing"
 
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/repo/sql"
@@ -77,7 +79,7 @@ func (t *ImporterSuite) TestOPMLImporter() {
 		Count:          10,
 	})
 	t.Require().Len(ctgFeeds, 1)
-	t.Equal(ctgFeeds[0].Title, "Example")
+	t.Equal("Example", ctgFeeds[0].DisplayTitle)
 
 	feeds, _ := t.feedsRepo.List(t.user.ID, models.Page{
 		ContinuationID: "",
@@ -85,10 +87,65 @@ func (t *ImporterSuite) TestOPMLImporter() {
 	})
 
 	t.NotZero(sort.Search(len(feeds), func(i int) bool {
-		return feeds[i].Title == "Empty"
+		return feeds[i].DisplayTitle == "Empty"
 	}))
 }
 
+func (t *ImporterSuite) TestOPMLImporterPublishesEvents() {
+	bus := events.NewBus()
+	sub := bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	importer := services.NewOPMLImporter(t.ctgsRepo, t.feedsRepo, services.WithEvents(bus))
+	t.NoError(importer.Import([]byte(opml), t.user.ID))
+
+	t.Require().Len(sub.Events(), 2)
+
+	var titles []string
+
+	for i := 0; i < 2; i++ {
+		event := <-sub.Events()
+		t.Equal(events.FeedCreated, event.Type)
+		t.NotEmpty(event.Feed.ID)
+
+		titles = append(titles, event.Feed.DisplayTitle)
+	}
+
+	t.ElementsMatch([]string{"Example", "Empty"}, titles)
+}
+
+func (t *ImporterSuite) TestOPMLImportExportKeepsTitles() {
+	t.NoError(t.importer.Import([]byte(opml), t.user.ID))
+
+	// Syncs replace the upstream titles of the imported feeds
+	feeds, _ := t.feedsRepo.List(t.user.ID, models.Page{Count: 2})
+	t.Require().Len(feeds, 2)
+
+	for idx := range feeds {
+		t.NoError(t.feedsRepo.Update(t.user.ID, &models.Feed{ID: feeds[idx].ID, Title: "Upstream"}))
+	}
+
+	data, err := services.NewOPMLExporter(t.ctgsRepo).Export(t.user.ID)
+	t.Require().NoError(err)
+
+	b := models.OPML{}
+	t.Require().NoError(xml.Unmarshal(data, &b))
+
+	var titles []string
+
+	for _, item := range b.Body.Items {
+		if len(item.Items) > 0 {
+			t.Equal("Test", item.Title)
+
+			item = item.Items[0]
+		}
+
+		titles = append(titles, item.Title)
+	}
+
+	t.ElementsMatch([]string{"Example", "Empty"}, titles)
+}
+
 func (t *ImporterSuite) SetupTest() {
 	var err error
 
@@ -0,0 +1,142 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/rules"
+	"github.com/jmartinezhern/syndication/sanitizer"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	// Ingest holds the new entries of a feed from when the rules of their
+	// user are applied to them until they are stored
+	Ingest struct {
+		entries  []models.Entry
+		outcomes map[string]rules.Outcome
+		base     string
+	}
+)
+
+// NewIngest returns the entries fetched for feed that user does not have yet.
+// Their content is sanitized against base and the rules of user are applied to
+// them. The entries that rules delete are left out right away so that they are
+// neither processed nor stored.
+func NewIngest(entriesRepo repo.Entries, rulesRepo repo.Rules, userID string, feed *models.Feed, base string,
+	entries []models.Entry) *Ingest {
+	ingest := &Ingest{
+		outcomes: map[string]rules.Outcome{},
+		base:     base,
+	}
+
+	var (
+		userRules   []*rules.Rule
+		rulesLoaded bool
+	)
+
+	for idx := range entries {
+		entry := entries[idx]
+
+		if _, found := entriesRepo.EntryWithGUID(userID, entry.GUID); found {
+			continue
+		}
+
+		entry.Summary = sanitizer.Sanitize(entry.Summary, base)
+		entry.Content = sanitizer.Sanitize(entry.Content, base)
+
+		if !rulesLoaded {
+			userRules, rulesLoaded = rules.Load(rulesRepo, userID), true
+		}
+
+		outcome := rules.Apply(userRules, &entry, feed)
+		if outcome.Delete {
+			continue
+		}
+
+		ingest.entries = append(ingest.entries, entry)
+		ingest.outcomes[entry.GUID] = outcome
+	}
+
+	return ingest
+}
+
+// Process passes the entries through the processor plugins names of feed and
+// keeps the ones they return
+func (i *Ingest) Process(registry *plugins.Registry, names []string, feed *models.Feed) error {
+	if len(names) == 0 || len(i.entries) == 0 {
+		return nil
+	}
+
+	entries, err := registry.Process(names, feed, i.entries)
+	if err != nil {
+		return err
+	}
+
+	// Processors may add content of their own
+	for idx := range entries {
+		entries[idx].Summary = sanitizer.Sanitize(entries[idx].Summary, i.base)
+		entries[idx].Content = sanitizer.Sanitize(entries[idx].Content, i.base)
+	}
+
+	i.entries = entries
+
+	return nil
+}
+
+// Store creates the entries as entries of feed owned by user with the marks,
+// saves and tags the rules of user gave them, publishes them on bus and
+// returns how many were created
+func (i *Ingest) Store(entriesRepo repo.Entries, bus *events.Bus, userID string, feed *models.Feed) int {
+	for idx := range i.entries {
+		entry := &i.entries[idx]
+		outcome := i.outcomes[entry.GUID]
+
+		entry.ID = utils.CreateID()
+		entry.Feed = *feed
+
+		if outcome.Mark != 0 {
+			entry.Mark = outcome.Mark
+		}
+
+		entry.Saved = entry.Saved || outcome.Save
+
+		entriesRepo.Create(userID, entry)
+
+		for _, tagID := range outcome.TagIDs {
+			if err := entriesRepo.TagEntries(userID, tagID, []string{entry.ID}); err != nil {
+				log.Warnf("Could not tag entry %s with %s: %s", entry.ID, tagID, err)
+			}
+		}
+
+		bus.Publish(events.Event{
+			Type:   events.EntryCreated,
+			UserID: userID,
+			FeedID: feed.ID,
+			Entry:  entry,
+			Unread: boolToInt(entry.Mark == models.MarkerUnread),
+		})
+	}
+
+	return len(i.entries)
+}
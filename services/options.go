@@ -0,0 +1,74 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/plugins"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+type (
+	// Option configures the optional dependencies of a service
+	Option func(*options)
+
+	options struct {
+		events  *events.Bus
+		plugins *plugins.Registry
+		rules   repo.Rules
+		websub  WebSubReleaser
+	}
+)
+
+// WithEvents publishes the changes made through a service on bus
+func WithEvents(bus *events.Bus) Option {
+	return func(o *options) {
+		o.events = bus
+	}
+}
+
+// WithPlugins makes the plugins of registry available to feeds
+func WithPlugins(registry *plugins.Registry) Option {
+	return func(o *options) {
+		o.plugins = registry
+	}
+}
+
+// WithRules applies the rules of users to the entries that are added through a service
+func WithRules(rulesRepo repo.Rules) Option {
+	return func(o *options) {
+		o.rules = rulesRepo
+	}
+}
+
+// WithWebSub cancels the WebSub subscriptions of the feeds deleted or moved through a service
+func WithWebSub(releaser WebSubReleaser) Option {
+	return func(o *options) {
+		o.websub = releaser
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,546 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+//go:generate mockgen -source=output_feeds.go -destination=output_feeds_mock.go -package=services
+
+// OutputFormat alias
+type OutputFormat = string
+
+// Formats output feeds are rendered in
+const (
+	OutputFormatAtom OutputFormat = "atom"
+	OutputFormatRSS  OutputFormat = "rss"
+	OutputFormatJSON OutputFormat = "json"
+)
+
+const (
+	// outputFeedSize is how many of the most recent entries an output feed holds
+	outputFeedSize = 50
+
+	outputFeedTokenLength = 24
+
+	savedEntriesTitle = "Saved entries"
+)
+
+type (
+	// OutputFeeds defines the OutputFeeds service interface
+	OutputFeeds interface {
+		// New creates an output feed for user along with the token it is accessed with
+		New(userID string, feed models.OutputFeed) (models.OutputFeed, error)
+
+		// OutputFeed returns an output feed with id owned by user
+		OutputFeed(userID, id string) (models.OutputFeed, bool)
+
+		// List returns the output feeds owned by user
+		List(userID string, page models.Page) ([]models.OutputFeed, string)
+
+		// Update replaces the title, category and tag of an output feed owned by user
+		Update(userID string, feed models.OutputFeed) (models.OutputFeed, error)
+
+		// Delete an output feed with id owned by user, revoking its token
+		Delete(userID, id string) error
+
+		// RenewToken replaces the token of an output feed owned by user, revoking the previous one
+		RenewToken(userID, id string) (models.OutputFeed, error)
+
+		// Render the output feed accessed with token in format. The feed refers
+		// to itself with self.
+		Render(token string, format OutputFormat, self string) ([]byte, error)
+	}
+
+	// OutputFeedsService implementation
+	OutputFeedsService struct {
+		outputRepo  repo.OutputFeeds
+		ctgsRepo    repo.Categories
+		tagsRepo    repo.Tags
+		entriesRepo repo.Entries
+	}
+)
+
+var (
+	// ErrOutputFeedNotFound signals that an output feed could not be found
+	ErrOutputFeedNotFound = errors.New("output feed not found")
+
+	// ErrOutputFeedSource signals that an output feed refers to both a category and a tag
+	ErrOutputFeedSource = errors.New("output feed refers to both a category and a tag")
+
+	// ErrOutputFeedScope signals that the category or tag an output feed refers to does not exist
+	ErrOutputFeedScope = errors.New("output feed refers to a missing category or tag")
+
+	// ErrOutputFormat signals that an output feed cannot be rendered in a format
+	ErrOutputFormat = errors.New("unknown output format")
+)
+
+// NewOutputFeedsService creates an OutputFeeds service
+func NewOutputFeedsService(
+	outputRepo repo.OutputFeeds,
+	ctgsRepo repo.Categories,
+	tagsRepo repo.Tags,
+	entriesRepo repo.Entries) OutputFeedsService {
+	return OutputFeedsService{
+		outputRepo:  outputRepo,
+		ctgsRepo:    ctgsRepo,
+		tagsRepo:    tagsRepo,
+		entriesRepo: entriesRepo,
+	}
+}
+
+// New creates an output feed for user along with the token it is accessed with
+func (o OutputFeedsService) New(userID string, feed models.OutputFeed) (models.OutputFeed, error) {
+	if err := o.validate(userID, &feed); err != nil {
+		return models.OutputFeed{}, err
+	}
+
+	feed.ID = utils.CreateID()
+	feed.Token = newOutputFeedToken()
+
+	o.outputRepo.Create(userID, &feed)
+
+	return feed, nil
+}
+
+// OutputFeed returns an output feed with id owned by user
+func (o OutputFeedsService) OutputFeed(userID, id string) (models.OutputFeed, bool) {
+	return o.outputRepo.OutputFeedWithID(userID, id)
+}
+
+// List returns the output feeds owned by user
+func (o OutputFeedsService) List(userID string, page models.Page) ([]models.OutputFeed, string) {
+	return o.outputRepo.List(userID, page)
+}
+
+// Update replaces the title, category and tag of an output feed owned by user
+func (o OutputFeedsService) Update(userID string, feed models.OutputFeed) (models.OutputFeed, error) {
+	current, found := o.outputRepo.OutputFeedWithID(userID, feed.ID)
+	if !found {
+		return models.OutputFeed{}, ErrOutputFeedNotFound
+	}
+
+	if err := o.validate(userID, &feed); err != nil {
+		return models.OutputFeed{}, err
+	}
+
+	current.Title = feed.Title
+	current.CategoryID = feed.CategoryID
+	current.TagID = feed.TagID
+
+	return current, o.update(userID, &current)
+}
+
+// Delete an output feed with id owned by user, revoking its token
+func (o OutputFeedsService) Delete(userID, id string) error {
+	err := o.outputRepo.Delete(userID, id)
+	if err == repo.ErrModelNotFound {
+		return ErrOutputFeedNotFound
+	}
+
+	return err
+}
+
+// RenewToken replaces the token of an output feed owned by user, revoking the previous one
+func (o OutputFeedsService) RenewToken(userID, id string) (models.OutputFeed, error) {
+	feed, found := o.outputRepo.OutputFeedWithID(userID, id)
+	if !found {
+		return models.OutputFeed{}, ErrOutputFeedNotFound
+	}
+
+	feed.Token = newOutputFeedToken()
+
+	return feed, o.update(userID, &feed)
+}
+
+// Render the output feed accessed with token in format. The feed refers to itself with self.
+func (o OutputFeedsService) Render(token string, format OutputFormat, self string) ([]byte, error) {
+	feed, found := o.outputRepo.OutputFeedWithToken(token)
+	if !found {
+		return nil, ErrOutputFeedNotFound
+	}
+
+	page := models.Page{
+		Count:  outputFeedSize,
+		Newest: true,
+		Marker: models.MarkerAny,
+	}
+
+	var entries []models.Entry
+
+	switch {
+	case feed.TagID != "":
+		entries, _ = o.entriesRepo.ListFromTags(feed.UserID, []string{feed.TagID}, page)
+	case feed.CategoryID != "":
+		page.FilterID = feed.CategoryID
+		entries, _ = o.entriesRepo.ListFromCategory(feed.UserID, page)
+	default:
+		page.Saved = true
+		entries, _ = o.entriesRepo.List(feed.UserID, page)
+	}
+
+	feed.Title = o.title(&feed)
+
+	switch format {
+	case OutputFormatAtom:
+		return renderAtom(&feed, entries, self)
+	case OutputFormatRSS:
+		return renderRSS(&feed, entries, self)
+	case OutputFormatJSON:
+		return renderJSONFeed(&feed, entries, self)
+	default:
+		return nil, ErrOutputFormat
+	}
+}
+
+func (o OutputFeedsService) update(userID string, feed *models.OutputFeed) error {
+	err := o.outputRepo.Update(userID, feed)
+	if err == repo.ErrModelNotFound {
+		return ErrOutputFeedNotFound
+	}
+
+	return err
+}
+
+// validate checks that the category or tag of an output feed are owned by user
+func (o OutputFeedsService) validate(userID string, feed *models.OutputFeed) error {
+	if feed.CategoryID != "" && feed.TagID != "" {
+		return ErrOutputFeedSource
+	}
+
+	if feed.CategoryID != "" {
+		if _, found := o.ctgsRepo.CategoryWithID(userID, feed.CategoryID); !found {
+			return ErrOutputFeedScope
+		}
+	}
+
+	if feed.TagID != "" {
+		if _, found := o.tagsRepo.TagWithID(userID, feed.TagID); !found {
+			return ErrOutputFeedScope
+		}
+	}
+
+	return nil
+}
+
+// title returns the title of an output feed, or the current name of its tag or
+// category if it was not given one
+func (o OutputFeedsService) title(feed *models.OutputFeed) string {
+	if feed.Title != "" {
+		return feed.Title
+	}
+
+	switch {
+	case feed.TagID != "":
+		if tag, found := o.tagsRepo.TagWithID(feed.UserID, feed.TagID); found {
+			return tag.Name
+		}
+	case feed.CategoryID != "":
+		if ctg, found := o.ctgsRepo.CategoryWithID(feed.UserID, feed.CategoryID); found {
+			return ctg.Name
+		}
+	default:
+		return savedEntriesTitle
+	}
+
+	return ""
+}
+
+func newOutputFeedToken() string {
+	b := make([]byte, outputFeedTokenLength)
+
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+type (
+	atomFeed struct {
+		XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string      `xml:"id"`
+		Title   string      `xml:"title"`
+		Updated string      `xml:"updated"`
+		Author  *atomAuthor `xml:"author"`
+		Link    atomLink    `xml:"link"`
+		Entries []atomEntry `xml:"entry"`
+	}
+
+	atomLink struct {
+		Rel  string `xml:"rel,attr,omitempty"`
+		Href string `xml:"href,attr"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomText struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+
+	atomEntry struct {
+		ID        string      `xml:"id"`
+		Title     string      `xml:"title"`
+		Updated   string      `xml:"updated"`
+		Published string      `xml:"published,omitempty"`
+		Link      *atomLink   `xml:"link"`
+		Author    *atomAuthor `xml:"author"`
+		Summary   *atomText   `xml:"summary"`
+		Content   *atomText   `xml:"content"`
+	}
+
+	rssFeed struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		Atom    string     `xml:"xmlns:atom,attr"`
+		DC      string     `xml:"xmlns:dc,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		Title         string    `xml:"title"`
+		Link          string    `xml:"link"`
+		Self          rssLink   `xml:"atom:link"`
+		Description   string    `xml:"description"`
+		LastBuildDate string    `xml:"lastBuildDate"`
+		Items         []rssItem `xml:"item"`
+	}
+
+	rssLink struct {
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	}
+
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+
+	rssItem struct {
+		GUID        rssGUID `xml:"guid"`
+		Title       string  `xml:"title"`
+		Link        string  `xml:"link,omitempty"`
+		Creator     string  `xml:"dc:creator,omitempty"`
+		Description string  `xml:"description,omitempty"`
+		PubDate     string  `xml:"pubDate,omitempty"`
+	}
+
+	jsonFeed struct {
+		Version string         `json:"version"`
+		Title   string         `json:"title"`
+		FeedURL string         `json:"feed_url"`
+		Items   []jsonFeedItem `json:"items"`
+	}
+
+	jsonFeedAuthor struct {
+		Name string `json:"name"`
+	}
+
+	jsonFeedItem struct {
+		ID            string           `json:"id"`
+		URL           string           `json:"url,omitempty"`
+		Title         string           `json:"title"`
+		ContentHTML   string           `json:"content_html"`
+		Summary       string           `json:"summary,omitempty"`
+		DatePublished string           `json:"date_published,omitempty"`
+		DateModified  string           `json:"date_modified,omitempty"`
+		Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	}
+)
+
+// renderAtom renders entries as an Atom 1.0 feed defined by RFC 4287
+func renderAtom(feed *models.OutputFeed, entries []models.Entry, self string) ([]byte, error) {
+	doc := atomFeed{
+		ID:      "urn:uuid:" + feed.ID,
+		Title:   feed.Title,
+		Updated: lastUpdated(feed, entries).Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: self},
+		Entries: make([]atomEntry, len(entries)),
+	}
+
+	for idx := range entries {
+		entry := &entries[idx]
+
+		item := atomEntry{
+			ID:      "urn:uuid:" + entry.ID,
+			Title:   entry.Title,
+			Updated: entryUpdated(entry).Format(time.RFC3339),
+		}
+
+		if !entry.Published.IsZero() {
+			item.Published = entry.Published.Format(time.RFC3339)
+		}
+
+		if entry.Link != "" {
+			item.Link = &atomLink{Rel: "alternate", Href: entry.Link}
+		}
+
+		if entry.Author != "" {
+			item.Author = &atomAuthor{Name: entry.Author}
+		} else {
+			// Every entry needs an author, which entries without one
+			// inherit from the feed
+			doc.Author = &atomAuthor{Name: feed.Title}
+		}
+
+		if entry.Summary != "" {
+			item.Summary = &atomText{Type: "html", Body: entry.Summary}
+		}
+
+		if entry.Content != "" {
+			item.Content = &atomText{Type: "html", Body: entry.Content}
+		}
+
+		doc.Entries[idx] = item
+	}
+
+	return marshalXML(doc)
+}
+
+// renderRSS renders entries as an RSS 2.0 feed
+func renderRSS(feed *models.OutputFeed, entries []models.Entry, self string) ([]byte, error) {
+	doc := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          self,
+			Self:          rssLink{Rel: "self", Type: "application/rss+xml", Href: self},
+			Description:   feed.Title,
+			LastBuildDate: lastUpdated(feed, entries).Format(time.RFC1123Z),
+			Items:         make([]rssItem, len(entries)),
+		},
+	}
+
+	for idx := range entries {
+		entry := &entries[idx]
+
+		item := rssItem{
+			GUID:        rssGUID{Value: "urn:uuid:" + entry.ID},
+			Title:       entry.Title,
+			Link:        entry.Link,
+			Creator:     entry.Author,
+			Description: entry.Content,
+		}
+
+		if item.Description == "" {
+			item.Description = entry.Summary
+		}
+
+		if !entry.Published.IsZero() {
+			item.PubDate = entry.Published.Format(time.RFC1123Z)
+		}
+
+		doc.Channel.Items[idx] = item
+	}
+
+	return marshalXML(doc)
+}
+
+// renderJSONFeed renders entries as a JSON Feed 1.1 defined by https://jsonfeed.org/version/1.1
+func renderJSONFeed(feed *models.OutputFeed, entries []models.Entry, self string) ([]byte, error) {
+	doc := jsonFeed{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   feed.Title,
+		FeedURL: self,
+		Items:   make([]jsonFeedItem, len(entries)),
+	}
+
+	for idx := range entries {
+		entry := &entries[idx]
+
+		item := jsonFeedItem{
+			ID:          entry.ID,
+			URL:         entry.Link,
+			Title:       entry.Title,
+			ContentHTML: entry.Content,
+			Summary:     entry.Summary,
+		}
+
+		if item.ContentHTML == "" {
+			item.ContentHTML = entry.Summary
+		}
+
+		if !entry.Published.IsZero() {
+			item.DatePublished = entry.Published.Format(time.RFC3339)
+		}
+
+		if !entry.Updated.IsZero() {
+			item.DateModified = entry.Updated.Format(time.RFC3339)
+		}
+
+		if entry.Author != "" {
+			item.Authors = []jsonFeedAuthor{{Name: entry.Author}}
+		}
+
+		doc.Items[idx] = item
+	}
+
+	return json.Marshal(doc)
+}
+
+func marshalXML(doc interface{}) ([]byte, error) {
+	b, err := xml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// entryUpdated returns when an entry was last updated, falling back to when it was published
+func entryUpdated(entry *models.Entry) time.Time {
+	if !entry.Updated.IsZero() {
+		return entry.Updated
+	}
+
+	if !entry.Published.IsZero() {
+		return entry.Published
+	}
+
+	return entry.CreatedAt
+}
+
+// lastUpdated returns when the most recently updated of entries was updated,
+// or when feed was last updated if it has no entries
+func lastUpdated(feed *models.OutputFeed, entries []models.Entry) time.Time {
+	updated := feed.UpdatedAt
+
+	for idx := range entries {
+		if t := entryUpdated(&entries[idx]); t.After(updated) {
+			updated = t
+		}
+	}
+
+	return updated.UTC()
+}
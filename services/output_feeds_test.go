@@ -0,0 +1,318 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+const outputFeedURL = "https://example.com/feeds/out/token.atom"
+
+type OutputFeedsSuite struct {
+	suite.Suite
+
+	service     services.OutputFeeds
+	db          *gorm.DB
+	entriesRepo repo.Entries
+	user        *models.User
+	feed        models.Feed
+	ctg         models.Category
+	tag         models.Tag
+}
+
+func (t *OutputFeedsSuite) newEntry(title string, saved bool) models.Entry {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     title,
+		Link:      "https://example.com/" + title,
+		Author:    "gopher",
+		Content:   "<p>" + title + "</p>",
+		Published: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		Saved:     saved,
+		Mark:      models.MarkerUnread,
+		Feed:      t.feed,
+	}
+
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	return entry
+}
+
+func (t *OutputFeedsSuite) parse(b []byte) *gofeed.Feed {
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(b))
+	t.Require().NoError(err)
+
+	return feed
+}
+
+func (t *OutputFeedsSuite) TestNewOutputFeed() {
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{TagID: t.tag.ID})
+	t.Require().NoError(err)
+	t.NotEmpty(feed.ID)
+	t.NotEmpty(feed.Token)
+	t.Empty(feed.Title)
+
+	feed, err = t.service.New(t.user.ID, models.OutputFeed{Title: "Favorites"})
+	t.Require().NoError(err)
+	t.Equal("Favorites", feed.Title)
+
+	found, ok := t.service.OutputFeed(t.user.ID, feed.ID)
+	t.Require().True(ok)
+	t.Equal(feed.Token, found.Token)
+
+	feeds, _ := t.service.List(t.user.ID, models.Page{Count: 10})
+	t.Len(feeds, 2)
+}
+
+func (t *OutputFeedsSuite) TestNewInvalidOutputFeed() {
+	_, err := t.service.New(t.user.ID, models.OutputFeed{CategoryID: t.ctg.ID, TagID: t.tag.ID})
+	t.Equal(services.ErrOutputFeedSource, err)
+
+	_, err = t.service.New(t.user.ID, models.OutputFeed{TagID: "bogus"})
+	t.Equal(services.ErrOutputFeedScope, err)
+
+	_, err = t.service.New(t.user.ID, models.OutputFeed{CategoryID: "bogus"})
+	t.Equal(services.ErrOutputFeedScope, err)
+}
+
+func (t *OutputFeedsSuite) TestUpdateOutputFeed() {
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{TagID: t.tag.ID})
+	t.Require().NoError(err)
+
+	updated, err := t.service.Update(t.user.ID, models.OutputFeed{ID: feed.ID, CategoryID: t.ctg.ID, Token: "ignored"})
+	t.Require().NoError(err)
+	t.Empty(updated.Title)
+	t.Equal(feed.Token, updated.Token)
+
+	found, _ := t.service.OutputFeed(t.user.ID, feed.ID)
+	t.Equal(t.ctg.ID, found.CategoryID)
+	t.Empty(found.TagID)
+
+	_, err = t.service.Update(t.user.ID, models.OutputFeed{ID: "bogus"})
+	t.Equal(services.ErrOutputFeedNotFound, err)
+}
+
+func (t *OutputFeedsSuite) TestRenewToken() {
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{})
+	t.Require().NoError(err)
+
+	renewed, err := t.service.RenewToken(t.user.ID, feed.ID)
+	t.Require().NoError(err)
+	t.NotEqual(feed.Token, renewed.Token)
+
+	_, err = t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Equal(services.ErrOutputFeedNotFound, err)
+
+	_, err = t.service.Render(renewed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.NoError(err)
+
+	_, err = t.service.RenewToken(t.user.ID, "bogus")
+	t.Equal(services.ErrOutputFeedNotFound, err)
+}
+
+func (t *OutputFeedsSuite) TestDeleteOutputFeed() {
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{})
+	t.Require().NoError(err)
+
+	t.NoError(t.service.Delete(t.user.ID, feed.ID))
+
+	_, err = t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Equal(services.ErrOutputFeedNotFound, err)
+
+	t.Equal(services.ErrOutputFeedNotFound, t.service.Delete(t.user.ID, feed.ID))
+}
+
+func (t *OutputFeedsSuite) TestRenderTag() {
+	tagged := t.newEntry("tagged", false)
+	t.newEntry("other", false)
+
+	t.Require().NoError(t.entriesRepo.TagEntries(t.user.ID, t.tag.ID, []string{tagged.ID}))
+
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{TagID: t.tag.ID})
+	t.Require().NoError(err)
+
+	b, err := t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Require().NoError(err)
+
+	parsed := t.parse(b)
+	t.Equal("atom", parsed.FeedType)
+	t.Equal("1.0", parsed.FeedVersion)
+	t.Equal("curated", parsed.Title)
+	t.Equal(outputFeedURL, parsed.FeedLink)
+	t.Require().Len(parsed.Items, 1)
+	t.Equal("tagged", parsed.Items[0].Title)
+	t.Equal("https://example.com/tagged", parsed.Items[0].Link)
+	t.Equal("<p>tagged</p>", parsed.Items[0].Content)
+	t.Equal("gopher", parsed.Items[0].Author.Name)
+	t.Equal(tagged.Published, parsed.Items[0].PublishedParsed.UTC())
+}
+
+func (t *OutputFeedsSuite) TestRenderFollowsRenames() {
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{TagID: t.tag.ID})
+	t.Require().NoError(err)
+
+	named, err := t.service.New(t.user.ID, models.OutputFeed{Title: "Reading list", TagID: t.tag.ID})
+	t.Require().NoError(err)
+
+	t.Require().NoError(sql.NewTags(t.db).Update(t.user.ID, &models.Tag{ID: t.tag.ID, Name: "picks"}))
+
+	b, err := t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Require().NoError(err)
+	t.Equal("picks", t.parse(b).Title)
+
+	b, err = t.service.Render(named.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Require().NoError(err)
+	t.Equal("Reading list", t.parse(b).Title)
+}
+
+func (t *OutputFeedsSuite) TestRenderAtomAuthors() {
+	t.newEntry("saved", true)
+
+	anonymous := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "anonymous",
+		Published: time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC),
+		Saved:     true,
+		Feed:      t.feed,
+	}
+	t.entriesRepo.Create(t.user.ID, &anonymous)
+
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{})
+	t.Require().NoError(err)
+
+	b, err := t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Require().NoError(err)
+
+	var doc struct {
+		Author  string `xml:"author>name"`
+		Entries []struct {
+			Author string `xml:"author>name"`
+		} `xml:"entry"`
+	}
+	t.Require().NoError(xml.Unmarshal(b, &doc))
+	t.Equal("Saved entries", doc.Author)
+	t.Require().Len(doc.Entries, 2)
+	t.Empty(doc.Entries[0].Author)
+	t.Equal("gopher", doc.Entries[1].Author)
+
+	// Feeds whose entries all have authors do not need one
+	t.Require().NoError(t.entriesRepo.Delete(t.user.ID, anonymous.ID))
+
+	b, err = t.service.Render(feed.Token, services.OutputFormatAtom, outputFeedURL)
+	t.Require().NoError(err)
+	t.NotContains(string(b), "<name>Saved entries</name>")
+}
+
+func (t *OutputFeedsSuite) TestRenderCategory() {
+	t.newEntry("news", false)
+
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{CategoryID: t.ctg.ID})
+	t.Require().NoError(err)
+
+	b, err := t.service.Render(feed.Token, services.OutputFormatRSS, outputFeedURL)
+	t.Require().NoError(err)
+
+	parsed := t.parse(b)
+	t.Equal("rss", parsed.FeedType)
+	t.Equal("2.0", parsed.FeedVersion)
+	t.Equal("news", parsed.Title)
+	t.Require().Len(parsed.Items, 1)
+	t.Equal("news", parsed.Items[0].Title)
+	t.Equal("https://example.com/news", parsed.Items[0].Link)
+	t.Equal("<p>news</p>", parsed.Items[0].Description)
+	t.Equal("gopher", parsed.Items[0].Author.Name)
+}
+
+func (t *OutputFeedsSuite) TestRenderSaved() {
+	t.newEntry("saved", true)
+	t.newEntry("unsaved", false)
+
+	feed, err := t.service.New(t.user.ID, models.OutputFeed{})
+	t.Require().NoError(err)
+
+	b, err := t.service.Render(feed.Token, services.OutputFormatJSON, outputFeedURL)
+	t.Require().NoError(err)
+
+	parsed := t.parse(b)
+	t.Equal("json", parsed.FeedType)
+	t.Equal("Saved entries", parsed.Title)
+	t.Require().Len(parsed.Items, 1)
+	t.Equal("saved", parsed.Items[0].Title)
+	t.Equal("https://example.com/saved", parsed.Items[0].Link)
+	t.Equal("<p>saved</p>", parsed.Items[0].Content)
+
+	_, err = t.service.Render(feed.Token, "yaml", outputFeedURL)
+	t.Equal(services.ErrOutputFormat, err)
+}
+
+func (t *OutputFeedsSuite) SetupTest() {
+	var err error
+
+	t.db, err = gorm.Open("sqlite3", ":memory:")
+	t.Require().NoError(err)
+
+	sql.AutoMigrateTables(t.db)
+
+	feedsRepo := sql.NewFeeds(t.db)
+	ctgsRepo := sql.NewCategories(t.db)
+	tagsRepo := sql.NewTags(t.db)
+	t.entriesRepo = sql.NewEntries(t.db)
+
+	t.service = services.NewOutputFeedsService(sql.NewOutputFeeds(t.db), ctgsRepo, tagsRepo, t.entriesRepo)
+
+	t.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(t.db).Create(t.user)
+
+	t.ctg = models.Category{ID: utils.CreateID(), Name: "news"}
+	ctgsRepo.Create(t.user.ID, &t.ctg)
+
+	t.feed = models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Example",
+		Subscription: "http://example.com",
+	}
+	feedsRepo.Create(t.user.ID, &t.feed)
+	t.Require().NoError(ctgsRepo.AddFeed(t.user.ID, t.feed.ID, t.ctg.ID))
+
+	t.tag = models.Tag{ID: utils.CreateID(), Name: "curated"}
+	tagsRepo.Create(t.user.ID, &t.tag)
+}
+
+func (t *OutputFeedsSuite) TearDownTest() {
+	t.NoError(t.db.Close())
+}
+
+func TestOutputFeeds(t *testing.T) {
+	suite.Run(t, new(OutputFeedsSuite))
+}
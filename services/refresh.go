@@ -0,0 +1,299 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+//go:generate mockgen -source=refresh.go -destination=refresh_mock.go -package=services
+
+const (
+	refreshWorkers  = 4
+	refreshPageSize = 100
+
+	// refreshJobTTL is how long a finished job can be polled for
+	refreshJobTTL = time.Hour
+)
+
+type (
+	// FeedRefresher fetches a feed right away and returns how many new entries
+	// were added
+	FeedRefresher interface {
+		RefreshFeed(userID string, feed *models.Feed) (int, error)
+	}
+
+	// Refresh defines the Refresh service interface
+	Refresh interface {
+		// Feed starts refreshing a feed owned by user
+		Feed(userID, feedID string) (models.RefreshJob, error)
+
+		// Category starts refreshing every feed in a category owned by user
+		Category(userID, ctgID string) (models.RefreshJob, error)
+
+		// All starts refreshing every feed owned by user
+		All(userID string) models.RefreshJob
+
+		// Job returns the progress of a refresh job started by user
+		Job(userID, id string) (models.RefreshJob, error)
+	}
+
+	// RefreshService implementation. Jobs run in the background and are kept
+	// in memory until refreshJobTTL after they finish.
+	RefreshService struct {
+		refresher FeedRefresher
+		feedsRepo repo.Feeds
+		ctgsRepo  repo.Categories
+
+		mu      sync.Mutex
+		jobs    map[string]*refreshJob
+		running map[string]*feedRefresh
+	}
+
+	refreshJob struct {
+		models.RefreshJob
+
+		finishedAt time.Time
+	}
+
+	// feedRefresh is a refresh of a single feed that other jobs can wait on
+	feedRefresh struct {
+		done       chan struct{}
+		newEntries int
+		err        error
+	}
+)
+
+var (
+	// ErrRefreshJobNotFound signals that a refresh job could not be found
+	ErrRefreshJobNotFound = errors.New("refresh job not found")
+)
+
+// NewRefreshService creates a Refresh service that fetches feeds with refresher
+func NewRefreshService(refresher FeedRefresher, feedsRepo repo.Feeds, ctgsRepo repo.Categories) *RefreshService {
+	return &RefreshService{
+		refresher: refresher,
+		feedsRepo: feedsRepo,
+		ctgsRepo:  ctgsRepo,
+		jobs:      make(map[string]*refreshJob),
+		running:   make(map[string]*feedRefresh),
+	}
+}
+
+// Feed starts refreshing a feed owned by user
+func (r *RefreshService) Feed(userID, feedID string) (models.RefreshJob, error) {
+	feed, found := r.feedsRepo.FeedWithID(userID, feedID)
+	if !found {
+		return models.RefreshJob{}, ErrFeedNotFound
+	}
+
+	return r.start(userID, []models.Feed{feed}), nil
+}
+
+// Category starts refreshing every feed in a category owned by user
+func (r *RefreshService) Category(userID, ctgID string) (models.RefreshJob, error) {
+	if _, found := r.ctgsRepo.CategoryWithID(userID, ctgID); !found {
+		return models.RefreshJob{}, ErrCategoryNotFound
+	}
+
+	var (
+		feeds          []models.Feed
+		page           []models.Feed
+		continuationID string
+	)
+
+	for {
+		page, continuationID = r.ctgsRepo.Feeds(userID, models.Page{
+			FilterID:       ctgID,
+			ContinuationID: continuationID,
+			Count:          refreshPageSize,
+		})
+
+		feeds = append(feeds, page...)
+
+		if continuationID == "" {
+			break
+		}
+	}
+
+	return r.start(userID, feeds), nil
+}
+
+// All starts refreshing every feed owned by user
+func (r *RefreshService) All(userID string) models.RefreshJob {
+	var (
+		feeds          []models.Feed
+		page           []models.Feed
+		continuationID string
+	)
+
+	for {
+		page, continuationID = r.feedsRepo.List(userID, models.Page{
+			ContinuationID: continuationID,
+			Count:          refreshPageSize,
+		})
+
+		feeds = append(feeds, page...)
+
+		if continuationID == "" {
+			break
+		}
+	}
+
+	return r.start(userID, feeds)
+}
+
+// Job returns the progress of a refresh job started by user
+func (r *RefreshService) Job(userID, id string) (models.RefreshJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, found := r.jobs[id]
+	if !found || job.UserID != userID {
+		return models.RefreshJob{}, ErrRefreshJobNotFound
+	}
+
+	return job.snapshot(), nil
+}
+
+func (r *RefreshService) start(userID string, feeds []models.Feed) models.RefreshJob {
+	now := time.Now()
+
+	job := &refreshJob{
+		RefreshJob: models.RefreshJob{
+			ID:        utils.CreateID(),
+			UserID:    userID,
+			CreatedAt: now,
+			Status:    models.RefreshStatusRunning,
+			Feeds:     make([]models.FeedRefresh, len(feeds)),
+		},
+	}
+
+	for idx := range feeds {
+		job.Feeds[idx] = models.FeedRefresh{
+			FeedID: feeds[idx].ID,
+			Title:  feeds[idx].Title,
+			Status: models.RefreshStatusRunning,
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, other := range r.jobs {
+		if other.Status == models.RefreshStatusDone && now.Sub(other.finishedAt) > refreshJobTTL {
+			delete(r.jobs, id)
+		}
+	}
+
+	r.jobs[job.ID] = job
+
+	go r.run(job, feeds)
+
+	return job.snapshot()
+}
+
+func (r *RefreshService) run(job *refreshJob, feeds []models.Feed) {
+	queue := make(chan int)
+
+	var wg sync.WaitGroup
+
+	wg.Add(refreshWorkers)
+
+	for i := 0; i < refreshWorkers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for idx := range queue {
+				newEntries, err := r.refreshFeed(job.UserID, &feeds[idx])
+
+				r.mu.Lock()
+
+				result := &job.Feeds[idx]
+				result.NewEntries = newEntries
+				result.Status = models.RefreshStatusDone
+
+				if err != nil {
+					result.Status = models.RefreshStatusFailed
+					result.Error = err.Error()
+				}
+
+				job.NewEntries += newEntries
+
+				r.mu.Unlock()
+			}
+		}()
+	}
+
+	for idx := range feeds {
+		queue <- idx
+	}
+
+	close(queue)
+
+	wg.Wait()
+
+	r.mu.Lock()
+	job.Status = models.RefreshStatusDone
+	job.finishedAt = time.Now()
+	r.mu.Unlock()
+}
+
+// refreshFeed refreshes feed unless it is already being refreshed, in which
+// case the outcome of the running refresh is returned once it completes
+func (r *RefreshService) refreshFeed(userID string, feed *models.Feed) (int, error) {
+	r.mu.Lock()
+
+	refresh, running := r.running[feed.ID]
+	if !running {
+		refresh = &feedRefresh{done: make(chan struct{})}
+		r.running[feed.ID] = refresh
+	}
+
+	r.mu.Unlock()
+
+	if running {
+		<-refresh.done
+
+		return refresh.newEntries, refresh.err
+	}
+
+	refresh.newEntries, refresh.err = r.refresher.RefreshFeed(userID, feed)
+
+	r.mu.Lock()
+	delete(r.running, feed.ID)
+	r.mu.Unlock()
+
+	close(refresh.done)
+
+	return refresh.newEntries, refresh.err
+}
+
+// snapshot copies a job so that it can be read while the job runs
+func (j *refreshJob) snapshot() models.RefreshJob {
+	job := j.RefreshJob
+	job.Feeds = append(make([]models.FeedRefresh, 0, len(j.Feeds)), j.Feeds...)
+
+	return job
+}
@@ -0,0 +1,219 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type RefreshSuite struct {
+	suite.Suite
+
+	ctrl      *gomock.Controller
+	refresher *services.MockFeedRefresher
+
+	service   services.Refresh
+	feedsRepo repo.Feeds
+	ctgsRepo  repo.Categories
+	db        *gorm.DB
+	user      *models.User
+	ctg       models.Category
+	feeds     []models.Feed
+}
+
+func (s *RefreshSuite) waitForJob(id string) models.RefreshJob {
+	var job models.RefreshJob
+
+	s.Require().Eventually(func() bool {
+		job, _ = s.service.Job(s.user.ID, id)
+		return job.Status == models.RefreshStatusDone
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return job
+}
+
+func (s *RefreshSuite) TestRefreshFeed() {
+	s.refresher.EXPECT().RefreshFeed(gomock.Eq(s.user.ID), gomock.Any()).Return(3, nil)
+
+	job, err := s.service.Feed(s.user.ID, s.feeds[0].ID)
+	s.Require().NoError(err)
+	s.NotEmpty(job.ID)
+	s.Require().Len(job.Feeds, 1)
+	s.Equal(s.feeds[0].ID, job.Feeds[0].FeedID)
+
+	job = s.waitForJob(job.ID)
+	s.Equal(3, job.NewEntries)
+	s.Equal(models.RefreshStatusDone, job.Feeds[0].Status)
+	s.Equal(3, job.Feeds[0].NewEntries)
+}
+
+func (s *RefreshSuite) TestRefreshMissingFeed() {
+	_, err := s.service.Feed(s.user.ID, "bogus")
+	s.EqualError(err, services.ErrFeedNotFound.Error())
+}
+
+func (s *RefreshSuite) TestRefreshFeedFails() {
+	s.refresher.EXPECT().RefreshFeed(gomock.Eq(s.user.ID), gomock.Any()).Return(0, errors.New("unreachable"))
+
+	job, err := s.service.Feed(s.user.ID, s.feeds[0].ID)
+	s.Require().NoError(err)
+
+	job = s.waitForJob(job.ID)
+	s.Zero(job.NewEntries)
+	s.Equal(models.RefreshStatusFailed, job.Feeds[0].Status)
+	s.Equal("unreachable", job.Feeds[0].Error)
+}
+
+func (s *RefreshSuite) TestRefreshCategory() {
+	s.refresher.EXPECT().RefreshFeed(gomock.Eq(s.user.ID), gomock.Any()).Return(1, nil)
+
+	job, err := s.service.Category(s.user.ID, s.ctg.ID)
+	s.Require().NoError(err)
+	s.Require().Len(job.Feeds, 1)
+	s.Equal(s.feeds[1].ID, job.Feeds[0].FeedID)
+
+	job = s.waitForJob(job.ID)
+	s.Equal(1, job.NewEntries)
+}
+
+func (s *RefreshSuite) TestRefreshMissingCategory() {
+	_, err := s.service.Category(s.user.ID, "bogus")
+	s.EqualError(err, services.ErrCategoryNotFound.Error())
+}
+
+func (s *RefreshSuite) TestRefreshAll() {
+	s.refresher.EXPECT().RefreshFeed(gomock.Eq(s.user.ID), gomock.Any()).Return(2, nil).Times(len(s.feeds))
+
+	job := s.service.All(s.user.ID)
+	s.Len(job.Feeds, len(s.feeds))
+
+	job = s.waitForJob(job.ID)
+	s.Equal(2*len(s.feeds), job.NewEntries)
+}
+
+func (s *RefreshSuite) TestRefreshCoalesces() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	s.refresher.EXPECT().RefreshFeed(gomock.Eq(s.user.ID), gomock.Any()).
+		DoAndReturn(func(string, *models.Feed) (int, error) {
+			close(started)
+			<-release
+
+			return 2, nil
+		})
+
+	first, err := s.service.Feed(s.user.ID, s.feeds[0].ID)
+	s.Require().NoError(err)
+
+	<-started
+
+	second, err := s.service.Feed(s.user.ID, s.feeds[0].ID)
+	s.Require().NoError(err)
+	s.NotEqual(first.ID, second.ID)
+
+	// Give the second job time to join the running refresh
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	s.Equal(2, s.waitForJob(first.ID).NewEntries)
+	s.Equal(2, s.waitForJob(second.ID).NewEntries)
+}
+
+func (s *RefreshSuite) TestJobOfOtherUser() {
+	s.refresher.EXPECT().RefreshFeed(gomock.Any(), gomock.Any()).Return(0, nil).AnyTimes()
+
+	job, err := s.service.Feed(s.user.ID, s.feeds[0].ID)
+	s.Require().NoError(err)
+
+	_, err = s.service.Job("other", job.ID)
+	s.EqualError(err, services.ErrRefreshJobNotFound.Error())
+}
+
+func (s *RefreshSuite) TestMissingJob() {
+	_, err := s.service.Job(s.user.ID, "bogus")
+	s.EqualError(err, services.ErrRefreshJobNotFound.Error())
+}
+
+func (s *RefreshSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.ctrl = gomock.NewController(s.T())
+	s.refresher = services.NewMockFeedRefresher(s.ctrl)
+
+	s.feedsRepo = sql.NewFeeds(s.db)
+	s.ctgsRepo = sql.NewCategories(s.db)
+
+	s.service = services.NewRefreshService(s.refresher, s.feedsRepo, s.ctgsRepo)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(s.db).Create(s.user)
+
+	s.ctg = models.Category{
+		ID:   utils.CreateID(),
+		Name: "News",
+	}
+	s.ctgsRepo.Create(s.user.ID, &s.ctg)
+
+	s.feeds = []models.Feed{
+		{
+			ID:           utils.CreateID(),
+			Title:        "Example",
+			Subscription: "http://example.com",
+		},
+		{
+			ID:           utils.CreateID(),
+			Title:        "News",
+			Subscription: "http://news.example.com",
+			Category:     s.ctg,
+		},
+	}
+
+	for idx := range s.feeds {
+		s.feedsRepo.Create(s.user.ID, &s.feeds[idx])
+	}
+}
+
+func (s *RefreshSuite) TearDownTest() {
+	s.ctrl.Finish()
+	s.NoError(s.db.Close())
+}
+
+func TestRefreshSuite(t *testing.T) {
+	suite.Run(t, new(RefreshSuite))
+}
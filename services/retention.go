@@ -0,0 +1,137 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+//go:generate mockgen -source=retention.go -destination=retention_mock.go -package=services
+
+const retentionPageSize = 100
+
+type (
+	// Retention defines the Retention service interface
+	Retention interface {
+		// Preview reports the entries owned by user that Prune would delete
+		Preview(userID string) models.RetentionReport
+
+		// Prune deletes expired entries owned by user
+		Prune(userID string) models.RetentionReport
+
+		// PruneFeed deletes expired entries of a feed owned by user and returns
+		// the number of entries deleted
+		PruneFeed(userID string, feed *models.Feed) int
+	}
+
+	// RetentionService implementation
+	RetentionService struct {
+		keepDays    int
+		feedsRepo   repo.Feeds
+		entriesRepo repo.Entries
+	}
+)
+
+// NewRetentionService creates a Retention service that keeps entries for keepDays
+// unless a feed overrides it. Entries never expire by age if keepDays is zero.
+func NewRetentionService(keepDays int, feedsRepo repo.Feeds, entriesRepo repo.Entries) RetentionService {
+	return RetentionService{
+		keepDays,
+		feedsRepo,
+		entriesRepo,
+	}
+}
+
+// Preview reports the entries owned by user that Prune would delete
+func (r RetentionService) Preview(userID string) models.RetentionReport {
+	return r.apply(userID, r.entriesRepo.CountExpired)
+}
+
+// Prune deletes expired entries owned by user
+func (r RetentionService) Prune(userID string) models.RetentionReport {
+	return r.apply(userID, r.entriesRepo.DeleteExpired)
+}
+
+// PruneFeed deletes expired entries of a feed owned by user and returns
+// the number of entries deleted
+func (r RetentionService) PruneFeed(userID string, feed *models.Feed) int {
+	return r.entriesRepo.DeleteExpired(userID, r.policy(feed, time.Now()))
+}
+
+func (r RetentionService) apply(
+	userID string,
+	op func(userID string, policy models.RetentionPolicy) int) models.RetentionReport {
+	report := models.RetentionReport{
+		Feeds: []models.FeedRetention{},
+	}
+
+	now := time.Now()
+
+	var (
+		feeds          []models.Feed
+		continuationID string
+	)
+
+	for {
+		feeds, continuationID = r.feedsRepo.List(userID, models.Page{
+			ContinuationID: continuationID,
+			Count:          retentionPageSize,
+		})
+
+		for idx := range feeds {
+			count := op(userID, r.policy(&feeds[idx], now))
+			if count == 0 {
+				continue
+			}
+
+			report.Feeds = append(report.Feeds, models.FeedRetention{
+				FeedID:  feeds[idx].ID,
+				Title:   feeds[idx].Title,
+				Entries: count,
+			})
+			report.Total += count
+		}
+
+		if continuationID == "" {
+			break
+		}
+	}
+
+	return report
+}
+
+func (r RetentionService) policy(feed *models.Feed, now time.Time) models.RetentionPolicy {
+	policy := models.RetentionPolicy{
+		FeedID:   feed.ID,
+		KeepLast: feed.KeepLast,
+	}
+
+	keepDays := r.keepDays
+	if feed.KeepDays > 0 {
+		keepDays = feed.KeepDays
+	}
+
+	if keepDays > 0 {
+		policy.Before = now.AddDate(0, 0, -keepDays)
+	}
+
+	return policy
+}
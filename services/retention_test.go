@@ -0,0 +1,174 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type RetentionSuite struct {
+	suite.Suite
+
+	service     services.Retention
+	feedsRepo   repo.Feeds
+	entriesRepo repo.Entries
+	db          *gorm.DB
+	user        *models.User
+}
+
+func (s *RetentionSuite) createFeed(keepDays, keepLast int) (models.Feed, []models.Entry) {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Example",
+		Subscription: "http://example.com",
+		KeepDays:     keepDays,
+		KeepLast:     keepLast,
+	}
+	s.feedsRepo.Create(s.user.ID, &feed)
+
+	entries := []models.Entry{
+		{
+			ID:        utils.CreateID(),
+			CreatedAt: time.Now().AddDate(0, 0, -40),
+			Feed:      feed,
+			Mark:      models.MarkerRead,
+			Published: time.Now().AddDate(0, 0, -40),
+		},
+		{
+			ID:        utils.CreateID(),
+			CreatedAt: time.Now().AddDate(0, 0, -40),
+			Feed:      feed,
+			Mark:      models.MarkerUnread,
+			Published: time.Now().AddDate(0, 0, -40),
+		},
+		{
+			ID:        utils.CreateID(),
+			Feed:      feed,
+			Mark:      models.MarkerRead,
+			Published: time.Now(),
+		},
+	}
+
+	for idx := range entries {
+		s.entriesRepo.Create(s.user.ID, &entries[idx])
+	}
+
+	return feed, entries
+}
+
+func (s *RetentionSuite) TestPreview() {
+	feed, entries := s.createFeed(0, 0)
+
+	report := s.service.Preview(s.user.ID)
+	s.Equal(1, report.Total)
+	s.Require().Len(report.Feeds, 1)
+	s.Equal(feed.ID, report.Feeds[0].FeedID)
+	s.Equal(1, report.Feeds[0].Entries)
+
+	_, found := s.entriesRepo.EntryWithID(s.user.ID, entries[0].ID)
+	s.True(found)
+}
+
+func (s *RetentionSuite) TestPrune() {
+	_, entries := s.createFeed(0, 0)
+
+	report := s.service.Prune(s.user.ID)
+	s.Equal(1, report.Total)
+
+	_, found := s.entriesRepo.EntryWithID(s.user.ID, entries[0].ID)
+	s.False(found)
+
+	_, found = s.entriesRepo.EntryWithID(s.user.ID, entries[1].ID)
+	s.True(found)
+
+	_, found = s.entriesRepo.EntryWithID(s.user.ID, entries[2].ID)
+	s.True(found)
+}
+
+func (s *RetentionSuite) TestPruneFeed() {
+	feed, entries := s.createFeed(0, 0)
+	s.createFeed(0, 0)
+
+	s.Equal(1, s.service.PruneFeed(s.user.ID, &feed))
+
+	_, found := s.entriesRepo.EntryWithID(s.user.ID, entries[0].ID)
+	s.False(found)
+
+	s.Equal(1, s.service.Preview(s.user.ID).Total)
+}
+
+func (s *RetentionSuite) TestPruneWithKeepDays() {
+	s.createFeed(60, 0)
+
+	s.Zero(s.service.Prune(s.user.ID).Total)
+}
+
+func (s *RetentionSuite) TestPruneWithKeepLast() {
+	_, entries := s.createFeed(60, 1)
+
+	s.Equal(1, s.service.Prune(s.user.ID).Total)
+
+	_, found := s.entriesRepo.EntryWithID(s.user.ID, entries[0].ID)
+	s.False(found)
+}
+
+func (s *RetentionSuite) TestPruneDisabled() {
+	s.createFeed(0, 0)
+
+	service := services.NewRetentionService(0, s.feedsRepo, s.entriesRepo)
+	s.Zero(service.Prune(s.user.ID).Total)
+}
+
+func (s *RetentionSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(s.db).Create(s.user)
+
+	s.feedsRepo = sql.NewFeeds(s.db)
+	s.entriesRepo = sql.NewEntries(s.db)
+
+	s.service = services.NewRetentionService(30, s.feedsRepo, s.entriesRepo)
+}
+
+func (s *RetentionSuite) TearDownTest() {
+	err := s.db.Close()
+	s.NoError(err)
+}
+
+func TestRetention(t *testing.T) {
+	suite.Run(t, new(RetentionSuite))
+}
@@ -0,0 +1,347 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/rules"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+//go:generate mockgen -source=rules.go -destination=rules_mock.go -package=services
+
+// rulesBatchSize is how many entries are loaded at once when rules are applied to existing entries
+const rulesBatchSize = 100
+
+type (
+	// Rules defines the Rules service interface
+	Rules interface {
+		// New creates a rule for user
+		New(userID string, rule models.Rule) (models.Rule, error)
+
+		// Rule returns a rule with id owned by user
+		Rule(userID, id string) (models.Rule, bool)
+
+		// List returns the rules owned by user
+		List(userID string, page models.Page) ([]models.Rule, string)
+
+		// Update replaces the name, conditions and actions of a rule owned by user
+		Update(userID string, rule models.Rule) (models.Rule, error)
+
+		// Delete a rule with id owned by user
+		Delete(userID, id string) error
+
+		// Apply a rule with id owned by user to every existing entry and return
+		// how many entries it matched. Saved and tagged entries are not deleted.
+		Apply(userID, id string) (int, error)
+
+		// Test returns a page of the entries of user that rule would match,
+		// without applying it
+		Test(userID string, rule models.Rule, page models.Page) ([]models.Entry, string, error)
+	}
+
+	// RulesService implementation
+	RulesService struct {
+		rulesRepo   repo.Rules
+		feedsRepo   repo.Feeds
+		ctgsRepo    repo.Categories
+		tagsRepo    repo.Tags
+		entriesRepo repo.Entries
+
+		events *events.Bus
+	}
+)
+
+var (
+	// ErrRuleNotFound signals that a rule could not be found
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrRuleConditions signals that a rule has no conditions or that one of them is invalid
+	ErrRuleConditions = errors.New("invalid rule conditions")
+
+	// ErrRuleActions signals that a rule has no actions or that one of them is invalid
+	ErrRuleActions = errors.New("invalid rule actions")
+
+	// ErrRuleScope signals that the feed, category or tag a rule refers to does not exist
+	ErrRuleScope = errors.New("rule refers to a missing feed, category or tag")
+)
+
+// NewRulesService creates a Rules service
+func NewRulesService(
+	rulesRepo repo.Rules,
+	feedsRepo repo.Feeds,
+	ctgsRepo repo.Categories,
+	tagsRepo repo.Tags,
+	entriesRepo repo.Entries,
+	opts ...Option) RulesService {
+	return RulesService{
+		rulesRepo:   rulesRepo,
+		feedsRepo:   feedsRepo,
+		ctgsRepo:    ctgsRepo,
+		tagsRepo:    tagsRepo,
+		entriesRepo: entriesRepo,
+		events:      newOptions(opts).events,
+	}
+}
+
+// New creates a rule for user
+func (r RulesService) New(userID string, rule models.Rule) (models.Rule, error) {
+	if _, err := r.compile(userID, rule); err != nil {
+		return models.Rule{}, err
+	}
+
+	rule.ID = utils.CreateID()
+
+	r.rulesRepo.Create(userID, &rule)
+
+	return rule, nil
+}
+
+// Rule returns a rule with id owned by user
+func (r RulesService) Rule(userID, id string) (models.Rule, bool) {
+	return r.rulesRepo.RuleWithID(userID, id)
+}
+
+// List returns the rules owned by user
+func (r RulesService) List(userID string, page models.Page) ([]models.Rule, string) {
+	return r.rulesRepo.List(userID, page)
+}
+
+// Update replaces the name, conditions and actions of a rule owned by user
+func (r RulesService) Update(userID string, rule models.Rule) (models.Rule, error) {
+	current, found := r.rulesRepo.RuleWithID(userID, rule.ID)
+	if !found {
+		return models.Rule{}, ErrRuleNotFound
+	}
+
+	if _, err := r.compile(userID, rule); err != nil {
+		return models.Rule{}, err
+	}
+
+	current.Name = rule.Name
+	current.Conditions = rule.Conditions
+	current.Actions = rule.Actions
+
+	if err := r.rulesRepo.Update(userID, &current); err == repo.ErrModelNotFound {
+		return models.Rule{}, ErrRuleNotFound
+	} else if err != nil {
+		return models.Rule{}, err
+	}
+
+	return current, nil
+}
+
+// Delete a rule with id owned by user
+func (r RulesService) Delete(userID, id string) error {
+	err := r.rulesRepo.Delete(userID, id)
+	if err == repo.ErrModelNotFound {
+		return ErrRuleNotFound
+	}
+
+	return err
+}
+
+// Apply a rule with id owned by user to every existing entry and return how
+// many entries it matched. Entries the user saved or tagged are kept, the
+// other actions of the rule are applied to them.
+func (r RulesService) Apply(userID, id string) (int, error) {
+	rule, found := r.rulesRepo.RuleWithID(userID, id)
+	if !found {
+		return 0, ErrRuleNotFound
+	}
+
+	compiled, err := r.compile(userID, rule)
+	if err != nil {
+		return 0, err
+	}
+
+	feeds := make(map[models.ID]models.Feed)
+	matched := 0
+
+	for afterID := ""; ; {
+		entries := r.entriesRepo.ListBatch(userID, afterID, rulesBatchSize)
+		if len(entries) == 0 {
+			return matched, nil
+		}
+
+		afterID = entries[len(entries)-1].ID
+
+		for idx := range entries {
+			feed := r.feed(userID, entries[idx].FeedID, feeds)
+
+			if !compiled.Matches(&entries[idx], &feed) {
+				continue
+			}
+
+			matched++
+
+			outcome := rules.Apply([]*rules.Rule{compiled}, &entries[idx], &feed)
+			if entries[idx].Saved || len(entries[idx].Tags) != 0 {
+				outcome.Delete = false
+			}
+
+			if err := r.apply(userID, &entries[idx], outcome); err != nil {
+				return matched, err
+			}
+		}
+	}
+}
+
+// Test returns up to page.Count of the entries of user that rule would match,
+// without applying it. Every entry is considered, in the order Apply goes
+// through them, and only filtered by the marker and saved state of page.
+func (r RulesService) Test(userID string, rule models.Rule, page models.Page) ([]models.Entry, string, error) {
+	compiled, err := r.compile(userID, rule)
+	if err != nil {
+		return nil, "", err
+	}
+
+	feeds := make(map[models.ID]models.Feed)
+	matched := []models.Entry{}
+
+	for afterID := page.ContinuationID; ; {
+		entries := r.entriesRepo.ListBatch(userID, afterID, rulesBatchSize)
+		if len(entries) == 0 {
+			return matched, "", nil
+		}
+
+		afterID = entries[len(entries)-1].ID
+
+		for idx := range entries {
+			if (page.Marker != models.MarkerAny && entries[idx].Mark != page.Marker) ||
+				(page.Saved && !entries[idx].Saved) {
+				continue
+			}
+
+			feed := r.feed(userID, entries[idx].FeedID, feeds)
+			if !compiled.Matches(&entries[idx], &feed) {
+				continue
+			}
+
+			// There is another page once one more entry matches
+			if len(matched) == page.Count {
+				next := page.ContinuationID
+				if len(matched) != 0 {
+					next = matched[len(matched)-1].ID
+				}
+
+				return matched, next, nil
+			}
+
+			matched = append(matched, entries[idx])
+		}
+	}
+}
+
+// apply the outcome of rules to an existing entry
+func (r RulesService) apply(userID string, entry *models.Entry, outcome rules.Outcome) error {
+	if outcome.Delete {
+		err := r.entriesRepo.Delete(userID, entry.ID)
+		if err == repo.ErrModelNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	if outcome.Mark != 0 && outcome.Mark != entry.Mark {
+		if err := r.entriesRepo.Mark(userID, entry.ID, outcome.Mark); err != nil {
+			return err
+		}
+
+		r.events.Publish(events.Event{
+			Type:    events.EntryMarked,
+			UserID:  userID,
+			EntryID: entry.ID,
+			FeedID:  entry.FeedID,
+			Marker:  outcome.Mark,
+			Unread: unreadDelta(models.Stats{
+				Unread: boolToInt(entry.Mark == models.MarkerUnread),
+				Read:   boolToInt(entry.Mark == models.MarkerRead),
+			}, outcome.Mark),
+		})
+	}
+
+	if outcome.Save && !entry.Saved {
+		if err := r.entriesRepo.Save(userID, entry.ID, true); err != nil {
+			return err
+		}
+	}
+
+	for _, tagID := range outcome.TagIDs {
+		if err := r.entriesRepo.TagEntries(userID, tagID, []string{entry.ID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compile checks rule and that the feeds, categories and tags it refers to exist
+func (r RulesService) compile(userID string, rule models.Rule) (*rules.Rule, error) {
+	compiled, err := rules.Compile(rule)
+	switch err {
+	case nil:
+	case rules.ErrInvalidConditions:
+		return nil, ErrRuleConditions
+	case rules.ErrInvalidActions:
+		return nil, ErrRuleActions
+	default:
+		return nil, err
+	}
+
+	for _, cond := range rule.Conditions {
+		switch cond.Field {
+		case models.RuleFieldFeed:
+			if _, found := r.feedsRepo.FeedWithID(userID, cond.Value); !found {
+				return nil, ErrRuleScope
+			}
+		case models.RuleFieldCategory:
+			if _, found := r.ctgsRepo.CategoryWithID(userID, cond.Value); !found {
+				return nil, ErrRuleScope
+			}
+		}
+	}
+
+	for _, action := range rule.Actions {
+		if action.Type != models.RuleActionTag {
+			continue
+		}
+
+		if _, found := r.tagsRepo.TagWithID(userID, action.TagID); !found {
+			return nil, ErrRuleScope
+		}
+	}
+
+	return compiled, nil
+}
+
+// feed returns the feed with id owned by user, caching it in feeds
+func (r RulesService) feed(userID, id string, feeds map[models.ID]models.Feed) models.Feed {
+	feed, found := feeds[id]
+	if !found {
+		feed, _ = r.feedsRepo.FeedWithID(userID, id)
+		feeds[id] = feed
+	}
+
+	return feed
+}
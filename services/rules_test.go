@@ -0,0 +1,290 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type RulesSuite struct {
+	suite.Suite
+
+	service     services.Rules
+	bus         *events.Bus
+	db          *gorm.DB
+	entriesRepo repo.Entries
+	user        *models.User
+	feed        models.Feed
+	tag         models.Tag
+}
+
+func sponsoredRule(actions ...models.RuleAction) models.Rule {
+	return models.Rule{
+		Name: "sponsored",
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorMatches, Value: "^sponsored"},
+		},
+		Actions: actions,
+	}
+}
+
+func (t *RulesSuite) newEntry(title string, mark models.Marker) models.Entry {
+	entry := models.Entry{
+		ID:    utils.CreateID(),
+		Title: title,
+		Mark:  mark,
+		Feed:  t.feed,
+	}
+
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	return entry
+}
+
+func (t *RulesSuite) TestNewRule() {
+	rule, err := t.service.New(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionTag, TagID: t.tag.ID}))
+	t.Require().NoError(err)
+	t.NotEmpty(rule.ID)
+
+	found, ok := t.service.Rule(t.user.ID, rule.ID)
+	t.Require().True(ok)
+	t.Equal("sponsored", found.Name)
+	t.Len(found.Actions, 1)
+
+	rules, _ := t.service.List(t.user.ID, models.Page{Count: 10})
+	t.Len(rules, 1)
+}
+
+func (t *RulesSuite) TestNewInvalidRule() {
+	_, err := t.service.New(t.user.ID, sponsoredRule())
+	t.Equal(services.ErrRuleActions, err)
+
+	_, err = t.service.New(t.user.ID, models.Rule{Actions: []models.RuleAction{{Type: models.RuleActionSave}}})
+	t.Equal(services.ErrRuleConditions, err)
+
+	_, err = t.service.New(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionTag, TagID: "bogus"}))
+	t.Equal(services.ErrRuleScope, err)
+
+	for _, field := range []models.RuleField{models.RuleFieldFeed, models.RuleFieldCategory} {
+		_, err = t.service.New(t.user.ID, models.Rule{
+			Conditions: []models.RuleCondition{{Field: field, Operator: models.RuleOperatorEquals, Value: "bogus"}},
+			Actions:    []models.RuleAction{{Type: models.RuleActionSave}},
+		})
+		t.Equal(services.ErrRuleScope, err)
+	}
+}
+
+func (t *RulesSuite) TestUpdateRule() {
+	rule, err := t.service.New(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionSave}))
+	t.Require().NoError(err)
+
+	updated, err := t.service.Update(t.user.ID, models.Rule{
+		ID:   rule.ID,
+		Name: "feed",
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldFeed, Operator: models.RuleOperatorEquals, Value: t.feed.ID},
+		},
+		Actions: []models.RuleAction{{Type: models.RuleActionDelete}},
+	})
+	t.Require().NoError(err)
+	t.Equal("feed", updated.Name)
+
+	found, _ := t.service.Rule(t.user.ID, rule.ID)
+	t.Require().Len(found.Actions, 1)
+	t.Equal(models.RuleActionDelete, found.Actions[0].Type)
+
+	_, err = t.service.Update(t.user.ID, models.Rule{ID: rule.ID})
+	t.Equal(services.ErrRuleConditions, err)
+
+	_, err = t.service.Update(t.user.ID, models.Rule{ID: "bogus"})
+	t.Equal(services.ErrRuleNotFound, err)
+}
+
+func (t *RulesSuite) TestDeleteRule() {
+	rule, err := t.service.New(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionSave}))
+	t.Require().NoError(err)
+
+	t.NoError(t.service.Delete(t.user.ID, rule.ID))
+	t.Equal(services.ErrRuleNotFound, t.service.Delete(t.user.ID, rule.ID))
+}
+
+func (t *RulesSuite) TestApplyRule() {
+	sub := t.bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	sponsored := t.newEntry("Sponsored: a gadget", models.MarkerUnread)
+	other := t.newEntry("News", models.MarkerUnread)
+
+	rule, err := t.service.New(t.user.ID, sponsoredRule(
+		models.RuleAction{Type: models.RuleActionMark, Marker: "read"},
+		models.RuleAction{Type: models.RuleActionTag, TagID: t.tag.ID},
+		models.RuleAction{Type: models.RuleActionSave},
+	))
+	t.Require().NoError(err)
+
+	matched, err := t.service.Apply(t.user.ID, rule.ID)
+	t.Require().NoError(err)
+	t.Equal(1, matched)
+
+	entry, _ := t.entriesRepo.EntryWithID(t.user.ID, sponsored.ID)
+	t.Equal(models.MarkerRead, entry.Mark)
+	t.True(entry.Saved)
+	t.True(t.entriesRepo.HasTag(t.user.ID, sponsored.ID, t.tag.ID))
+
+	entry, _ = t.entriesRepo.EntryWithID(t.user.ID, other.ID)
+	t.Equal(models.MarkerUnread, entry.Mark)
+	t.False(entry.Saved)
+
+	t.Require().Len(sub.Events(), 1)
+
+	event := <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Equal(sponsored.ID, event.EntryID)
+	t.Equal(-1, event.Unread)
+
+	_, err = t.service.Apply(t.user.ID, "bogus")
+	t.Equal(services.ErrRuleNotFound, err)
+}
+
+func (t *RulesSuite) TestApplyDeleteRule() {
+	sponsored := t.newEntry("Sponsored: a gadget", models.MarkerUnread)
+	other := t.newEntry("News", models.MarkerUnread)
+
+	saved := t.newEntry("Sponsored: a saved gadget", models.MarkerUnread)
+	t.Require().NoError(t.entriesRepo.Save(t.user.ID, saved.ID, true))
+
+	tagged := t.newEntry("Sponsored: a tagged gadget", models.MarkerUnread)
+	t.Require().NoError(t.entriesRepo.TagEntries(t.user.ID, t.tag.ID, []string{tagged.ID}))
+
+	rule, err := t.service.New(t.user.ID, sponsoredRule(
+		models.RuleAction{Type: models.RuleActionDelete},
+		models.RuleAction{Type: models.RuleActionMark, Marker: "read"},
+	))
+	t.Require().NoError(err)
+
+	matched, err := t.service.Apply(t.user.ID, rule.ID)
+	t.Require().NoError(err)
+	t.Equal(3, matched)
+
+	_, found := t.entriesRepo.EntryWithID(t.user.ID, sponsored.ID)
+	t.False(found)
+
+	_, found = t.entriesRepo.EntryWithID(t.user.ID, other.ID)
+	t.True(found)
+
+	// Saved and tagged entries are kept but the other actions still apply
+	for _, id := range []string{saved.ID, tagged.ID} {
+		entry, found := t.entriesRepo.EntryWithID(t.user.ID, id)
+		t.Require().True(found)
+		t.Equal(models.MarkerRead, entry.Mark)
+	}
+}
+
+func (t *RulesSuite) TestTestRule() {
+	sponsored := t.newEntry("Sponsored: a gadget", models.MarkerUnread)
+	t.newEntry("News", models.MarkerUnread)
+
+	entries, next, err := t.service.Test(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionDelete}),
+		models.Page{Count: 10, Marker: models.MarkerAny})
+	t.Require().NoError(err)
+	t.Empty(next)
+	t.Require().Len(entries, 1)
+	t.Equal(sponsored.ID, entries[0].ID)
+
+	// Nothing is applied
+	_, found := t.entriesRepo.EntryWithID(t.user.ID, sponsored.ID)
+	t.True(found)
+
+	rules, _ := t.service.List(t.user.ID, models.Page{Count: 10})
+	t.Empty(rules)
+
+	// Matching entries past the first page of entries are found
+	for idx := 0; idx < 150; idx++ {
+		t.newEntry("News", models.MarkerUnread)
+	}
+
+	last := t.newEntry("Sponsored: another gadget", models.MarkerUnread)
+
+	first, next, err := t.service.Test(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionDelete}),
+		models.Page{Count: 1, Marker: models.MarkerAny})
+	t.Require().NoError(err)
+	t.Require().Len(first, 1)
+	t.NotEmpty(next)
+
+	second, next, err := t.service.Test(t.user.ID, sponsoredRule(models.RuleAction{Type: models.RuleActionDelete}),
+		models.Page{Count: 1, Marker: models.MarkerAny, ContinuationID: next})
+	t.Require().NoError(err)
+	t.Empty(next)
+	t.Require().Len(second, 1)
+	t.ElementsMatch([]string{sponsored.ID, last.ID}, []string{first[0].ID, second[0].ID})
+
+	_, _, err = t.service.Test(t.user.ID, sponsoredRule(), models.Page{Count: 10})
+	t.Equal(services.ErrRuleActions, err)
+}
+
+func (t *RulesSuite) SetupTest() {
+	var err error
+
+	t.db, err = gorm.Open("sqlite3", ":memory:")
+	t.Require().NoError(err)
+
+	sql.AutoMigrateTables(t.db)
+
+	feedsRepo := sql.NewFeeds(t.db)
+	tagsRepo := sql.NewTags(t.db)
+	t.entriesRepo = sql.NewEntries(t.db)
+
+	t.bus = events.NewBus()
+
+	t.service = services.NewRulesService(sql.NewRules(t.db), feedsRepo, sql.NewCategories(t.db), tagsRepo,
+		t.entriesRepo, services.WithEvents(t.bus))
+
+	t.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(t.db).Create(t.user)
+
+	t.feed = models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Example",
+		Subscription: "http://example.com",
+	}
+	feedsRepo.Create(t.user.ID, &t.feed)
+
+	t.tag = models.Tag{ID: utils.CreateID(), Name: "ads"}
+	tagsRepo.Create(t.user.ID, &t.tag)
+}
+
+func (t *RulesSuite) TearDownTest() {
+	t.NoError(t.db.Close())
+}
+
+func TestRules(t *testing.T) {
+	suite.Run(t, new(RulesSuite))
+}
@@ -0,0 +1,66 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+)
+
+//go:generate mockgen -source=search.go -destination=search_mock.go -package=services
+
+type (
+	// Search defines the Search service interface
+	Search interface {
+		// Entries returns all entries owned by user that match a query
+		Entries(userID string, query models.SearchQuery, page models.Page) ([]models.Entry, string, error)
+	}
+
+	// SearchService implementation
+	SearchService struct {
+		repo repo.Search
+	}
+)
+
+var (
+	// ErrSearchQueryEmpty signals that a search query has no terms
+	ErrSearchQueryEmpty = errors.New("search query is empty")
+)
+
+func NewSearchService(searchRepo repo.Search) SearchService {
+	return SearchService{
+		searchRepo,
+	}
+}
+
+// Entries returns all entries owned by user that match a query
+func (s SearchService) Entries(
+	userID string,
+	query models.SearchQuery,
+	page models.Page) (entries []models.Entry, next string, err error) {
+	if strings.TrimSpace(query.Text) == "" {
+		return nil, "", ErrSearchQueryEmpty
+	}
+
+	entries, next = s.repo.Entries(userID, query, page)
+
+	return entries, next, nil
+}
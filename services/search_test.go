@@ -0,0 +1,101 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type SearchSuite struct {
+	suite.Suite
+
+	service     services.Search
+	entriesRepo repo.Entries
+	db          *gorm.DB
+	user        *models.User
+}
+
+func (s *SearchSuite) TestEntries() {
+	s.entriesRepo.Create(s.user.ID, &models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Kernel release",
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	})
+	s.entriesRepo.Create(s.user.ID, &models.Entry{
+		ID:        utils.CreateID(),
+		Title:     "Gardening tips",
+		Mark:      models.MarkerUnread,
+		Published: time.Now(),
+	})
+
+	entries, next, err := s.service.Entries(s.user.ID, models.SearchQuery{Text: "kernel"}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.NoError(err)
+	s.Empty(next)
+	s.Require().Len(entries, 1)
+	s.Equal("Kernel release", entries[0].Title)
+}
+
+func (s *SearchSuite) TestEntriesWithEmptyQuery() {
+	_, _, err := s.service.Entries(s.user.ID, models.SearchQuery{Text: "  "}, models.Page{
+		Count:  5,
+		Marker: models.MarkerAny,
+	})
+	s.Equal(services.ErrSearchQueryEmpty, err)
+}
+
+func (s *SearchSuite) SetupTest() {
+	var err error
+
+	s.db, err = gorm.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	sql.AutoMigrateTables(s.db)
+
+	s.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(s.db).Create(s.user)
+
+	s.entriesRepo = sql.NewEntries(s.db)
+
+	s.service = services.NewSearchService(sql.NewSearch(s.db))
+}
+
+func (s *SearchSuite) TearDownTest() {
+	err := s.db.Close()
+	s.NoError(err)
+}
+
+func TestSearch(t *testing.T) {
+	suite.Run(t, new(SearchSuite))
+}
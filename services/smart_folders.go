@@ -0,0 +1,232 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+//go:generate mockgen -source=smart_folders.go -destination=smart_folders_mock.go -package=services
+
+type (
+	// SmartFolders defines the SmartFolders service interface
+	SmartFolders interface {
+		// New creates a smart folder for user
+		New(userID string, folder models.SmartFolder) (models.SmartFolder, error)
+
+		// SmartFolder returns a smart folder with id owned by user
+		SmartFolder(userID, id string) (models.SmartFolder, bool)
+
+		// List returns the smart folders owned by user
+		List(userID string, page models.Page) ([]models.SmartFolder, string)
+
+		// Update replaces the name and criteria of a smart folder owned by user
+		Update(userID string, folder models.SmartFolder) (models.SmartFolder, error)
+
+		// Delete a smart folder with id owned by user
+		Delete(userID, id string) error
+
+		// Mark the entries of a smart folder
+		Mark(userID, id string, marker models.Marker) error
+
+		// Entries returns the entries of a smart folder
+		Entries(userID string, page models.Page) ([]models.Entry, string, error)
+
+		// Stats returns statistics on the entries of a smart folder
+		Stats(userID, id string) (models.Stats, error)
+	}
+
+	// SmartFoldersService implementation
+	SmartFoldersService struct {
+		foldersRepo repo.SmartFolders
+		feedsRepo   repo.Feeds
+		ctgsRepo    repo.Categories
+		tagsRepo    repo.Tags
+
+		events *events.Bus
+	}
+)
+
+var (
+	// ErrSmartFolderNotFound signals that a smart folder could not be found
+	ErrSmartFolderNotFound = errors.New("smart folder not found")
+
+	// ErrSmartFolderCriteria signals that the marker or number of days of a smart folder is invalid
+	ErrSmartFolderCriteria = errors.New("invalid smart folder criteria")
+
+	// ErrSmartFolderScope signals that the feed, category or tag a smart folder refers to does not exist
+	ErrSmartFolderScope = errors.New("smart folder refers to a missing feed, category or tag")
+)
+
+// NewSmartFoldersService creates a SmartFolders service
+func NewSmartFoldersService(
+	foldersRepo repo.SmartFolders,
+	feedsRepo repo.Feeds,
+	ctgsRepo repo.Categories,
+	tagsRepo repo.Tags,
+	opts ...Option) SmartFoldersService {
+	return SmartFoldersService{
+		foldersRepo: foldersRepo,
+		feedsRepo:   feedsRepo,
+		ctgsRepo:    ctgsRepo,
+		tagsRepo:    tagsRepo,
+		events:      newOptions(opts).events,
+	}
+}
+
+// New creates a smart folder for user
+func (s SmartFoldersService) New(userID string, folder models.SmartFolder) (models.SmartFolder, error) {
+	if err := s.validate(userID, &folder); err != nil {
+		return models.SmartFolder{}, err
+	}
+
+	folder.ID = utils.CreateID()
+
+	s.foldersRepo.Create(userID, &folder)
+
+	return folder, nil
+}
+
+// SmartFolder returns a smart folder with id owned by user
+func (s SmartFoldersService) SmartFolder(userID, id string) (models.SmartFolder, bool) {
+	return s.foldersRepo.SmartFolderWithID(userID, id)
+}
+
+// List returns the smart folders owned by user
+func (s SmartFoldersService) List(userID string, page models.Page) ([]models.SmartFolder, string) {
+	return s.foldersRepo.List(userID, page)
+}
+
+// Update replaces the name and criteria of a smart folder owned by user
+func (s SmartFoldersService) Update(userID string, folder models.SmartFolder) (models.SmartFolder, error) {
+	if _, found := s.foldersRepo.SmartFolderWithID(userID, folder.ID); !found {
+		return models.SmartFolder{}, ErrSmartFolderNotFound
+	}
+
+	if err := s.validate(userID, &folder); err != nil {
+		return models.SmartFolder{}, err
+	}
+
+	if err := s.foldersRepo.Update(userID, &folder); err == repo.ErrModelNotFound {
+		return models.SmartFolder{}, ErrSmartFolderNotFound
+	} else if err != nil {
+		return models.SmartFolder{}, err
+	}
+
+	return folder, nil
+}
+
+// Delete a smart folder with id owned by user
+func (s SmartFoldersService) Delete(userID, id string) error {
+	err := s.foldersRepo.Delete(userID, id)
+	if err == repo.ErrModelNotFound {
+		return ErrSmartFolderNotFound
+	}
+
+	return err
+}
+
+// Mark the entries of a smart folder
+func (s SmartFoldersService) Mark(userID, id string, marker models.Marker) error {
+	stats, err := s.foldersRepo.Stats(userID, id)
+	if err == repo.ErrModelNotFound {
+		return ErrSmartFolderNotFound
+	} else if err != nil {
+		return err
+	}
+
+	err = s.foldersRepo.Mark(userID, id, marker)
+	if err == repo.ErrModelNotFound {
+		return ErrSmartFolderNotFound
+	} else if err != nil {
+		return err
+	}
+
+	s.events.Publish(events.Event{
+		Type:          events.EntryMarked,
+		UserID:        userID,
+		SmartFolderID: id,
+		Marker:        marker,
+		Unread:        unreadDelta(stats, marker),
+	})
+
+	return nil
+}
+
+// Entries returns the entries of a smart folder
+func (s SmartFoldersService) Entries(userID string, page models.Page) ([]models.Entry, string, error) {
+	if _, found := s.foldersRepo.SmartFolderWithID(userID, page.FilterID); !found {
+		return nil, "", ErrSmartFolderNotFound
+	}
+
+	entries, next := s.foldersRepo.Entries(userID, page)
+
+	return entries, next, nil
+}
+
+// Stats returns statistics on the entries of a smart folder
+func (s SmartFoldersService) Stats(userID, id string) (models.Stats, error) {
+	stats, err := s.foldersRepo.Stats(userID, id)
+	if err == repo.ErrModelNotFound {
+		return models.Stats{}, ErrSmartFolderNotFound
+	}
+
+	return stats, err
+}
+
+// validate checks the criteria of a smart folder and that the feed, category
+// and tag it refers to are owned by user
+func (s SmartFoldersService) validate(userID string, folder *models.SmartFolder) error {
+	folder.Marker = strings.ToLower(folder.Marker)
+
+	switch folder.Marker {
+	case "", "any", "read", "unread":
+	default:
+		return ErrSmartFolderCriteria
+	}
+
+	if folder.Days < 0 {
+		return ErrSmartFolderCriteria
+	}
+
+	if folder.FeedID != "" {
+		if _, found := s.feedsRepo.FeedWithID(userID, folder.FeedID); !found {
+			return ErrSmartFolderScope
+		}
+	}
+
+	if folder.CategoryID != "" {
+		if _, found := s.ctgsRepo.CategoryWithID(userID, folder.CategoryID); !found {
+			return ErrSmartFolderScope
+		}
+	}
+
+	if folder.TagID != "" {
+		if _, found := s.tagsRepo.TagWithID(userID, folder.TagID); !found {
+			return ErrSmartFolderScope
+		}
+	}
+
+	return nil
+}
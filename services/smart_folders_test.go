@@ -0,0 +1,224 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type SmartFoldersSuite struct {
+	suite.Suite
+
+	service     services.SmartFolders
+	bus         *events.Bus
+	db          *gorm.DB
+	entriesRepo repo.Entries
+	user        *models.User
+	feed        models.Feed
+	ctg         models.Category
+	tag         models.Tag
+}
+
+func (t *SmartFoldersSuite) newEntry(title string, mark models.Marker) models.Entry {
+	entry := models.Entry{
+		ID:        utils.CreateID(),
+		Title:     title,
+		Mark:      mark,
+		Published: time.Now(),
+		Feed:      t.feed,
+	}
+
+	t.entriesRepo.Create(t.user.ID, &entry)
+
+	return entry
+}
+
+func (t *SmartFoldersSuite) TestNewSmartFolder() {
+	folder, err := t.service.New(t.user.ID, models.SmartFolder{
+		Name:       "security",
+		Marker:     "Unread",
+		FeedID:     t.feed.ID,
+		CategoryID: t.ctg.ID,
+		TagID:      t.tag.ID,
+		Title:      "cve",
+		Days:       7,
+	})
+	t.Require().NoError(err)
+	t.NotEmpty(folder.ID)
+	t.Equal("unread", folder.Marker)
+
+	found, ok := t.service.SmartFolder(t.user.ID, folder.ID)
+	t.Require().True(ok)
+	t.Equal("security", found.Name)
+
+	folders, _ := t.service.List(t.user.ID, models.Page{Count: 10})
+	t.Len(folders, 1)
+}
+
+func (t *SmartFoldersSuite) TestNewInvalidSmartFolder() {
+	_, err := t.service.New(t.user.ID, models.SmartFolder{Marker: "starred"})
+	t.Equal(services.ErrSmartFolderCriteria, err)
+
+	_, err = t.service.New(t.user.ID, models.SmartFolder{Days: -1})
+	t.Equal(services.ErrSmartFolderCriteria, err)
+
+	for _, folder := range []models.SmartFolder{{FeedID: "bogus"}, {CategoryID: "bogus"}, {TagID: "bogus"}} {
+		_, err = t.service.New(t.user.ID, folder)
+		t.Equal(services.ErrSmartFolderScope, err)
+	}
+}
+
+func (t *SmartFoldersSuite) TestUpdateSmartFolder() {
+	folder, err := t.service.New(t.user.ID, models.SmartFolder{Name: "security", Title: "cve"})
+	t.Require().NoError(err)
+
+	updated, err := t.service.Update(t.user.ID, models.SmartFolder{ID: folder.ID, Name: "saved", Saved: true})
+	t.Require().NoError(err)
+	t.Equal("saved", updated.Name)
+
+	found, _ := t.service.SmartFolder(t.user.ID, folder.ID)
+	t.True(found.Saved)
+	t.Empty(found.Title)
+
+	_, err = t.service.Update(t.user.ID, models.SmartFolder{ID: folder.ID, TagID: "bogus"})
+	t.Equal(services.ErrSmartFolderScope, err)
+
+	_, err = t.service.Update(t.user.ID, models.SmartFolder{ID: "bogus"})
+	t.Equal(services.ErrSmartFolderNotFound, err)
+}
+
+func (t *SmartFoldersSuite) TestDeleteSmartFolder() {
+	folder, err := t.service.New(t.user.ID, models.SmartFolder{Name: "security"})
+	t.Require().NoError(err)
+
+	t.NoError(t.service.Delete(t.user.ID, folder.ID))
+	t.Equal(services.ErrSmartFolderNotFound, t.service.Delete(t.user.ID, folder.ID))
+}
+
+func (t *SmartFoldersSuite) TestSmartFolderEntries() {
+	match := t.newEntry("CVE-2021-3449", models.MarkerUnread)
+	t.newEntry("Release notes", models.MarkerUnread)
+
+	folder, err := t.service.New(t.user.ID, models.SmartFolder{Name: "security", Title: "cve"})
+	t.Require().NoError(err)
+
+	entries, _, err := t.service.Entries(t.user.ID, models.Page{
+		FilterID: folder.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	t.Require().NoError(err)
+	t.Require().Len(entries, 1)
+	t.Equal(match.ID, entries[0].ID)
+
+	_, _, err = t.service.Entries(t.user.ID, models.Page{FilterID: "bogus", Count: 10})
+	t.Equal(services.ErrSmartFolderNotFound, err)
+}
+
+func (t *SmartFoldersSuite) TestMarkSmartFolder() {
+	sub := t.bus.Subscribe(t.user.ID, 0)
+	defer sub.Close()
+
+	match := t.newEntry("CVE-2021-3449", models.MarkerUnread)
+	t.newEntry("CVE-2021-3450", models.MarkerUnread)
+	other := t.newEntry("Release notes", models.MarkerUnread)
+
+	folder, err := t.service.New(t.user.ID, models.SmartFolder{Name: "security", Title: "cve"})
+	t.Require().NoError(err)
+
+	t.NoError(t.service.Mark(t.user.ID, folder.ID, models.MarkerRead))
+
+	entry, _ := t.entriesRepo.EntryWithID(t.user.ID, match.ID)
+	t.Equal(models.MarkerRead, entry.Mark)
+
+	entry, _ = t.entriesRepo.EntryWithID(t.user.ID, other.ID)
+	t.Equal(models.MarkerUnread, entry.Mark)
+
+	stats, err := t.service.Stats(t.user.ID, folder.ID)
+	t.Require().NoError(err)
+	t.Equal(models.Stats{Read: 2, Total: 2}, stats)
+
+	t.Require().Len(sub.Events(), 1)
+
+	event := <-sub.Events()
+	t.Equal(events.EntryMarked, event.Type)
+	t.Equal(folder.ID, event.SmartFolderID)
+	t.Equal(-2, event.Unread)
+
+	t.Equal(services.ErrSmartFolderNotFound, t.service.Mark(t.user.ID, "bogus", models.MarkerRead))
+
+	_, err = t.service.Stats(t.user.ID, "bogus")
+	t.Equal(services.ErrSmartFolderNotFound, err)
+}
+
+func (t *SmartFoldersSuite) SetupTest() {
+	var err error
+
+	t.db, err = gorm.Open("sqlite3", ":memory:")
+	t.Require().NoError(err)
+
+	sql.AutoMigrateTables(t.db)
+
+	feedsRepo := sql.NewFeeds(t.db)
+	ctgsRepo := sql.NewCategories(t.db)
+	tagsRepo := sql.NewTags(t.db)
+	t.entriesRepo = sql.NewEntries(t.db)
+
+	t.bus = events.NewBus()
+
+	t.service = services.NewSmartFoldersService(sql.NewSmartFolders(t.db), feedsRepo, ctgsRepo, tagsRepo,
+		services.WithEvents(t.bus))
+
+	t.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(t.db).Create(t.user)
+
+	t.feed = models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Example",
+		Subscription: "http://example.com",
+	}
+	feedsRepo.Create(t.user.ID, &t.feed)
+
+	t.ctg = models.Category{ID: utils.CreateID(), Name: "security"}
+	ctgsRepo.Create(t.user.ID, &t.ctg)
+
+	t.tag = models.Tag{ID: utils.CreateID(), Name: "later"}
+	tagsRepo.Create(t.user.ID, &t.tag)
+}
+
+func (t *SmartFoldersSuite) TearDownTest() {
+	t.NoError(t.db.Close())
+}
+
+func TestSmartFolders(t *testing.T) {
+	suite.Run(t, new(SmartFoldersSuite))
+}
@@ -0,0 +1,443 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+)
+
+//go:generate mockgen -source=stream.go -destination=stream_mock.go -package=services
+
+// Types of the messages sent to stream subscribers
+const (
+	// StreamEntry messages hold a new entry
+	StreamEntry = "entry"
+
+	// StreamMark messages hold the entry, feed or category that was marked
+	StreamMark = "mark"
+
+	// StreamUnread messages hold how much the number of unread entries changed by
+	StreamUnread = "unread"
+
+	// StreamReset messages tell subscribers that messages were missed and
+	// that they should reload what they display
+	StreamReset = "reset"
+)
+
+const (
+	// streamHistory is how many messages are kept per user for subscribers to resume from
+	streamHistory = 256
+
+	// streamUsers is how many users messages are kept for. The messages of the
+	// user that received one the longest time ago, and who is not subscribed,
+	// are forgotten first.
+	streamUsers = 1024
+
+	// streamBuffer is how many messages a subscriber may fall behind before it is dropped
+	streamBuffer = 64
+
+	streamEventBuffer = 1024
+)
+
+type (
+	// StreamMessage is a change pushed to stream subscribers. Its ID can be
+	// handed back to resume the stream after it.
+	StreamMessage struct {
+		ID   string      `json:"id"`
+		Type string      `json:"type"`
+		Data interface{} `json:"data"`
+
+		seq uint64
+	}
+
+	// StreamMarkChange describes the entries that were marked. Marks applied
+	// to all entries of a user hold no IDs.
+	StreamMarkChange struct {
+		EntryID       models.ID     `json:"entryId,omitempty"`
+		FeedID        models.ID     `json:"feedId,omitempty"`
+		CategoryID    models.ID     `json:"categoryId,omitempty"`
+		SmartFolderID models.ID     `json:"smartFolderId,omitempty"`
+		Marker        models.Marker `json:"marker"`
+	}
+
+	// StreamUnreadDelta describes how much the number of unread entries of
+	// a user changed by and where
+	StreamUnreadDelta struct {
+		EntryID       models.ID `json:"entryId,omitempty"`
+		FeedID        models.ID `json:"feedId,omitempty"`
+		CategoryID    models.ID `json:"categoryId,omitempty"`
+		SmartFolderID models.ID `json:"smartFolderId,omitempty"`
+		Delta         int       `json:"delta"`
+	}
+
+	// Stream defines the Stream service interface
+	Stream interface {
+		// Subscribe to the messages of user. Messages that followed lastID are
+		// sent first. A reset message is sent first if they are no longer known.
+		Subscribe(userID, lastID string) StreamSubscription
+	}
+
+	// StreamSubscription receives the messages of a user
+	StreamSubscription interface {
+		// Messages returns the channel messages are delivered on. It is closed
+		// when the subscription is closed or when it falls too far behind.
+		Messages() <-chan StreamMessage
+
+		// Close ends the subscription
+		Close()
+	}
+
+	// StreamService implementation. Once started, it turns the events
+	// published on its bus into messages.
+	StreamService struct {
+		bus *events.Bus
+		sub *events.Subscription
+
+		// epoch tells the IDs of messages sent before a restart apart
+		epoch string
+
+		mu  sync.Mutex
+		seq uint64
+
+		// reset is the sequence number of the latest reset sent to every
+		// subscriber. Messages sent before it cannot be resumed from.
+		reset uint64
+
+		// forgotten is the sequence number of the latest message of the users
+		// whose messages are no longer kept
+		forgotten uint64
+
+		logs        map[models.ID]*streamLog
+		subscribers map[models.ID]map[*streamSubscription]struct{}
+
+		wg sync.WaitGroup
+	}
+
+	// streamLog holds the latest messages of a user
+	streamLog struct {
+		messages []StreamMessage
+
+		// evicted is the sequence number of the latest message that is no longer kept
+		evicted uint64
+	}
+
+	streamSubscription struct {
+		stream *StreamService
+		userID models.ID
+
+		messages chan StreamMessage
+		closed   bool
+	}
+)
+
+var errStreamID = errors.New("invalid stream message id")
+
+// NewStreamService creates a Stream service. Messages are only sent if the
+// service is given a bus with WithEvents.
+func NewStreamService(opts ...Option) *StreamService {
+	return &StreamService{
+		bus:         newOptions(opts).events,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		logs:        make(map[models.ID]*streamLog),
+		subscribers: make(map[models.ID]map[*streamSubscription]struct{}),
+	}
+}
+
+// Start turning events into messages
+func (s *StreamService) Start() {
+	if s.bus == nil {
+		return
+	}
+
+	s.sub = s.bus.Subscribe("", streamEventBuffer, events.EntryCreated, events.EntryMarked)
+
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		var dropped uint64
+
+		for event := range s.sub.Events() {
+			// Events that were missed cannot be told apart, so every
+			// subscriber is told to reload
+			if n := s.sub.Dropped(); n != dropped {
+				dropped = n
+				s.resetAll()
+			}
+
+			s.publish(&event)
+		}
+	}()
+}
+
+// Stop turning events into messages and close every subscription
+func (s *StreamService) Stop() {
+	if s.sub != nil {
+		s.sub.Close()
+	}
+
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			sub.close()
+		}
+	}
+}
+
+// Subscribe to the messages of user
+func (s *StreamService) Subscribe(userID, lastID string) StreamSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replay []StreamMessage
+
+	if lastID != "" {
+		var err error
+		if replay, err = s.after(userID, lastID); err != nil {
+			replay = []StreamMessage{s.message(StreamReset, nil)}
+		}
+	}
+
+	sub := &streamSubscription{
+		stream:   s,
+		userID:   userID,
+		messages: make(chan StreamMessage, len(replay)+streamBuffer),
+	}
+
+	for idx := range replay {
+		sub.messages <- replay[idx]
+	}
+
+	if s.subscribers[userID] == nil {
+		s.subscribers[userID] = make(map[*streamSubscription]struct{})
+	}
+
+	s.subscribers[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// after returns the messages of user that followed the one with id. It must
+// be called with mu held.
+func (s *StreamService) after(userID, id string) ([]StreamMessage, error) {
+	seq, err := s.parseID(id)
+	if err != nil || seq > s.seq || seq < s.reset {
+		return nil, errStreamID
+	}
+
+	log, found := s.logs[userID]
+	if !found {
+		// The messages of the user may have been forgotten
+		if seq < s.forgotten {
+			return nil, errStreamID
+		}
+
+		return nil, nil
+	}
+
+	// Messages sent since id that are no longer kept would be missed
+	if seq < log.evicted {
+		return nil, errStreamID
+	}
+
+	for idx := range log.messages {
+		if log.messages[idx].seq > seq {
+			return append([]StreamMessage(nil), log.messages[idx:]...), nil
+		}
+	}
+
+	return nil, nil
+}
+
+// publish turns event into messages and sends them to the subscribers of its user
+func (s *StreamService) publish(event *events.Event) {
+	// Entries of muted feeds are left out like they are from the entries of the user
+	if event.Type == events.EntryCreated && event.Entry != nil && models.FeedMuted(&event.Entry.Feed, time.Now()) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []StreamMessage
+
+	switch event.Type {
+	case events.EntryCreated:
+		if event.Entry != nil {
+			messages = append(messages, s.message(StreamEntry, event.Entry))
+		}
+	case events.EntryMarked:
+		messages = append(messages, s.message(StreamMark, StreamMarkChange{
+			EntryID:       event.EntryID,
+			FeedID:        event.FeedID,
+			CategoryID:    event.CategoryID,
+			SmartFolderID: event.SmartFolderID,
+			Marker:        event.Marker,
+		}))
+	}
+
+	if event.Unread != 0 {
+		messages = append(messages, s.message(StreamUnread, StreamUnreadDelta{
+			EntryID:       event.EntryID,
+			FeedID:        event.FeedID,
+			CategoryID:    event.CategoryID,
+			SmartFolderID: event.SmartFolderID,
+			Delta:         event.Unread,
+		}))
+	}
+
+	log, found := s.logs[event.UserID]
+	if !found && len(messages) != 0 {
+		log = &streamLog{}
+		s.logs[event.UserID] = log
+
+		if len(s.logs) > streamUsers {
+			s.forgetIdle()
+		}
+	}
+
+	for idx := range messages {
+		log.messages = append(log.messages, messages[idx])
+		if len(log.messages) > streamHistory {
+			log.evicted = log.messages[0].seq
+			log.messages = log.messages[1:]
+		}
+
+		for sub := range s.subscribers[event.UserID] {
+			select {
+			case sub.messages <- messages[idx]:
+			default:
+				// Subscribers that fall behind are dropped. They can resume
+				// from the last message they received.
+				sub.close()
+			}
+		}
+	}
+}
+
+// forgetIdle drops the messages of the user who received one the longest time
+// ago and is not subscribed. It must be called with mu held.
+func (s *StreamService) forgetIdle() {
+	var (
+		idle    models.ID
+		idleSeq uint64
+	)
+
+	for userID, log := range s.logs {
+		if len(s.subscribers[userID]) != 0 || len(log.messages) == 0 {
+			continue
+		}
+
+		if seq := log.messages[len(log.messages)-1].seq; idle == "" || seq < idleSeq {
+			idle, idleSeq = userID, seq
+		}
+	}
+
+	if idle == "" {
+		return
+	}
+
+	delete(s.logs, idle)
+
+	if idleSeq > s.forgotten {
+		s.forgotten = idleSeq
+	}
+}
+
+// resetAll sends a reset message to every subscriber
+func (s *StreamService) resetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg := s.message(StreamReset, nil)
+	s.reset = msg.seq
+
+	for _, subs := range s.subscribers {
+		for sub := range subs {
+			select {
+			case sub.messages <- msg:
+			default:
+				sub.close()
+			}
+		}
+	}
+}
+
+// message creates a message with the next ID. It must be called with mu held.
+func (s *StreamService) message(messageType string, data interface{}) StreamMessage {
+	s.seq++
+
+	return StreamMessage{
+		ID:   s.epoch + "-" + strconv.FormatUint(s.seq, 10),
+		Type: messageType,
+		Data: data,
+		seq:  s.seq,
+	}
+}
+
+// parseID returns the sequence number of a message ID created by this service
+func (s *StreamService) parseID(id string) (uint64, error) {
+	parts := strings.SplitN(id, "-", 2)
+	if len(parts) != 2 || parts[0] != s.epoch {
+		return 0, errStreamID
+	}
+
+	return strconv.ParseUint(parts[1], 10, 64)
+}
+
+// Messages returns the channel messages are delivered on
+func (s *streamSubscription) Messages() <-chan StreamMessage {
+	return s.messages
+}
+
+// Close ends the subscription
+func (s *streamSubscription) Close() {
+	s.stream.mu.Lock()
+	defer s.stream.mu.Unlock()
+
+	s.close()
+}
+
+// close ends the subscription. It must be called with the stream's mu held.
+func (s *streamSubscription) close() {
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+
+	delete(s.stream.subscribers[s.userID], s)
+
+	if len(s.stream.subscribers[s.userID]) == 0 {
+		delete(s.stream.subscribers, s.userID)
+	}
+
+	close(s.messages)
+}
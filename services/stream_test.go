@@ -0,0 +1,267 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type StreamSuite struct {
+	suite.Suite
+
+	bus     *events.Bus
+	service *services.StreamService
+	userID  string
+}
+
+func (t *StreamSuite) receive(sub services.StreamSubscription) services.StreamMessage {
+	select {
+	case msg, ok := <-sub.Messages():
+		t.Require().True(ok, "subscription was closed")
+		return msg
+	case <-time.After(time.Second * 5):
+		t.FailNow("no message was received")
+		return services.StreamMessage{}
+	}
+}
+
+func (t *StreamSuite) TestNewEntries() {
+	sub := t.service.Subscribe(t.userID, "")
+	defer sub.Close()
+
+	entry := models.Entry{ID: utils.CreateID(), Title: "New"}
+
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "other", Entry: &entry, Unread: 1})
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.userID, FeedID: "feed", Entry: &entry, Unread: 1})
+
+	msg := t.receive(sub)
+	t.Equal(services.StreamEntry, msg.Type)
+	t.Equal(&entry, msg.Data)
+
+	msg = t.receive(sub)
+	t.Equal(services.StreamUnread, msg.Type)
+	t.Equal(services.StreamUnreadDelta{FeedID: "feed", Delta: 1}, msg.Data)
+}
+
+func (t *StreamSuite) TestSkipEntriesOfMutedFeeds() {
+	sub := t.service.Subscribe(t.userID, "")
+	defer sub.Close()
+
+	muted := models.Entry{ID: utils.CreateID(), Feed: models.Feed{ID: utils.CreateID(), Muted: true}}
+	entry := models.Entry{ID: utils.CreateID(), Feed: models.Feed{ID: utils.CreateID()}}
+
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.userID, Entry: &muted, Unread: 1})
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.userID, Entry: &entry})
+
+	msg := t.receive(sub)
+	t.Equal(services.StreamEntry, msg.Type)
+	t.Equal(&entry, msg.Data)
+}
+
+func (t *StreamSuite) TestMarks() {
+	sub := t.service.Subscribe(t.userID, "")
+	defer sub.Close()
+
+	t.bus.Publish(events.Event{Type: events.FeedUpdated, UserID: t.userID})
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, CategoryID: "ctg",
+		Marker: models.MarkerRead, Unread: -3})
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: "entry",
+		Marker: models.MarkerRead})
+
+	msg := t.receive(sub)
+	t.Equal(services.StreamMark, msg.Type)
+	t.Equal(services.StreamMarkChange{CategoryID: "ctg", Marker: models.MarkerRead}, msg.Data)
+
+	msg = t.receive(sub)
+	t.Equal(services.StreamUnread, msg.Type)
+	t.Equal(services.StreamUnreadDelta{CategoryID: "ctg", Delta: -3}, msg.Data)
+
+	msg = t.receive(sub)
+	t.Equal(services.StreamMark, msg.Type)
+	t.Equal(services.StreamMarkChange{EntryID: "entry", Marker: models.MarkerRead}, msg.Data)
+}
+
+func (t *StreamSuite) TestResume() {
+	sub := t.service.Subscribe(t.userID, "")
+
+	for idx := 0; idx < 3; idx++ {
+		t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+	}
+
+	first := t.receive(sub)
+	t.receive(sub)
+	last := t.receive(sub)
+
+	sub.Close()
+
+	_, ok := <-sub.Messages()
+	t.False(ok)
+
+	sub = t.service.Subscribe(t.userID, first.ID)
+	defer sub.Close()
+
+	t.Len(sub.Messages(), 2)
+	t.receive(sub)
+	t.Equal(last, t.receive(sub))
+
+	resumed := t.service.Subscribe(t.userID, last.ID)
+	defer resumed.Close()
+
+	t.Empty(resumed.Messages())
+}
+
+func (t *StreamSuite) TestResumeFromUnknownID() {
+	sub := t.service.Subscribe(t.userID, "bogus")
+	defer sub.Close()
+
+	t.Equal(services.StreamReset, t.receive(sub).Type)
+}
+
+func (t *StreamSuite) TestResumeAfterMissedMessages() {
+	sub := t.service.Subscribe(t.userID, "")
+
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+
+	first := t.receive(sub)
+
+	sub.Close()
+
+	// Only the latest messages are kept
+	for idx := 0; idx < 300; idx++ {
+		t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+	}
+
+	t.Eventually(func() bool {
+		sub := t.service.Subscribe(t.userID, first.ID)
+		defer sub.Close()
+
+		select {
+		case msg := <-sub.Messages():
+			return msg.Type == services.StreamReset
+		default:
+			return false
+		}
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func (t *StreamSuite) TestForgetIdleUsers() {
+	sub := t.service.Subscribe(t.userID, "")
+
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+
+	last := t.receive(sub)
+	sub.Close()
+
+	// Events are published in batches that fit in the buffer of the service
+	// so that no reset is sent
+	other := t.service.Subscribe("other", "")
+	defer other.Close()
+
+	for batch := 0; batch < 3; batch++ {
+		for idx := 0; idx < 500; idx++ {
+			t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: utils.CreateID(), EntryID: utils.CreateID()})
+		}
+
+		t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: "other", EntryID: utils.CreateID()})
+		t.Equal(services.StreamMark, t.receive(other).Type)
+	}
+
+	// The messages of the least recently active user were forgotten first
+	resumed := t.service.Subscribe(t.userID, last.ID)
+	defer resumed.Close()
+
+	t.Equal(services.StreamReset, t.receive(resumed).Type)
+}
+
+func (t *StreamSuite) TestDropSlowSubscribers() {
+	sub := t.service.Subscribe(t.userID, "")
+	defer sub.Close()
+
+	for idx := 0; idx < 100; idx++ {
+		t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+	}
+
+	// The subscription is closed once its buffer is full
+	t.Eventually(func() bool {
+		return len(sub.Messages()) == cap(sub.Messages())
+	}, time.Second*5, time.Millisecond*10)
+
+	for range sub.Messages() {
+	}
+}
+
+func (t *StreamSuite) TestResetAfterMissedEvents() {
+	sub := t.service.Subscribe(t.userID, "")
+	defer sub.Close()
+
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.userID, EntryID: utils.CreateID()})
+
+	first := t.receive(sub)
+
+	// Events of another user are published faster than they are turned into messages
+	for idx := 0; idx < 100000 && len(sub.Messages()) == 0; idx++ {
+		t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: "other", EntryID: utils.CreateID()})
+	}
+
+	msg := t.receive(sub)
+	t.Equal(services.StreamReset, msg.Type)
+
+	// Messages sent before the reset cannot be resumed from
+	resumed := t.service.Subscribe(t.userID, first.ID)
+	defer resumed.Close()
+
+	t.Equal(services.StreamReset, t.receive(resumed).Type)
+
+	resumed = t.service.Subscribe(t.userID, msg.ID)
+	defer resumed.Close()
+
+	t.Empty(resumed.Messages())
+}
+
+func (t *StreamSuite) TestStopClosesSubscriptions() {
+	sub := t.service.Subscribe(t.userID, "")
+
+	t.service.Stop()
+
+	_, ok := <-sub.Messages()
+	t.False(ok)
+}
+
+func (t *StreamSuite) SetupTest() {
+	t.bus = events.NewBus()
+	t.service = services.NewStreamService(services.WithEvents(t.bus))
+	t.service.Start()
+
+	t.userID = utils.CreateID()
+}
+
+func (t *StreamSuite) TearDownTest() {
+	t.service.Stop()
+}
+
+func TestStream(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
@@ -0,0 +1,602 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+//go:generate mockgen -source=webhooks.go -destination=webhooks_mock.go -package=services
+
+// WebhookTestEvent is the type of the events sent to test a webhook
+const WebhookTestEvent = "webhook.test"
+
+// Headers sent along with every delivery. The signature is the hex encoded
+// HMAC-SHA256 of the body keyed with the webhook's secret, prefixed by "sha256=".
+const (
+	WebhookEventHeader     = "X-Syndication-Event"
+	WebhookDeliveryHeader  = "X-Syndication-Delivery"
+	WebhookSignatureHeader = "X-Syndication-Signature"
+)
+
+const (
+	webhookWorkers     = 4
+	webhookQueueSize   = 100
+	webhookEventBuffer = 1024
+
+	// Failed deliveries are retried webhookMaxAttempts times in total, waiting
+	// twice as long after every attempt
+	webhookMaxAttempts = 6
+	webhookRetryDelay  = time.Minute
+
+	// webhookLease is how long a delivery that is being attempted is not picked again
+	webhookLease = time.Minute * 5
+
+	// webhookRetention is how long finished deliveries are kept for
+	webhookRetention = time.Hour * 24 * 30
+
+	webhookSecretLength   = 32
+	maxWebhookResponseLog = 1 << 10
+)
+
+type (
+	// Webhooks defines the Webhooks service interface
+	Webhooks interface {
+		// New creates a webhook for user
+		New(userID string, hook models.Webhook) (models.Webhook, error)
+
+		// Webhook returns a webhook with id owned by user
+		Webhook(userID, id string) (models.Webhook, bool)
+
+		// List returns the webhooks owned by user
+		List(userID string, page models.Page) ([]models.Webhook, string)
+
+		// Update replaces the URL, events and scope of a webhook owned by user
+		Update(userID string, hook models.Webhook) (models.Webhook, error)
+
+		// RotateSecret replaces the secret of a webhook with id owned by user with a new one
+		RotateSecret(userID, id string) (models.Webhook, error)
+
+		// Delete a webhook with id owned by user
+		Delete(userID, id string) error
+
+		// Deliveries returns the deliveries of a webhook owned by user, the most recent first
+		Deliveries(userID string, page models.Page) ([]models.WebhookDelivery, string, error)
+
+		// Test queues a test event for a webhook owned by user
+		Test(userID, id string) (models.WebhookDelivery, error)
+	}
+
+	// WebhooksService implementation. Once started, events published on its
+	// bus are delivered to the webhooks they match in the background.
+	WebhooksService struct {
+		webhooksRepo repo.Webhooks
+		feedsRepo    repo.Feeds
+		ctgsRepo     repo.Categories
+		tagsRepo     repo.Tags
+		entriesRepo  repo.Entries
+
+		bus *events.Bus
+		sub *events.Subscription
+
+		ticker *time.Ticker
+		quit   chan struct{}
+		wake   chan struct{}
+		queue  chan models.WebhookDelivery
+
+		schedulerWg sync.WaitGroup
+		workersWg   sync.WaitGroup
+	}
+)
+
+var (
+	// ErrWebhookNotFound signals that a webhook could not be found
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrWebhookURL signals that the URL of a webhook is not an absolute HTTP URL
+	ErrWebhookURL = errors.New("invalid webhook url")
+
+	// ErrWebhookEvents signals that a webhook lists an unknown event type
+	ErrWebhookEvents = errors.New("unknown webhook event type")
+
+	// ErrWebhookScope signals that the feed, category or tag a webhook is scoped to does not exist
+	ErrWebhookScope = errors.New("webhook scope not found")
+)
+
+// NewWebhooksService creates a Webhooks service. Events are only delivered if
+// the service is given a bus with WithEvents.
+func NewWebhooksService(
+	webhooksRepo repo.Webhooks,
+	feedsRepo repo.Feeds,
+	ctgsRepo repo.Categories,
+	tagsRepo repo.Tags,
+	entriesRepo repo.Entries,
+	opts ...Option) *WebhooksService {
+	return &WebhooksService{
+		webhooksRepo: webhooksRepo,
+		feedsRepo:    feedsRepo,
+		ctgsRepo:     ctgsRepo,
+		tagsRepo:     tagsRepo,
+		entriesRepo:  entriesRepo,
+		bus:          newOptions(opts).events,
+		quit:         make(chan struct{}),
+		wake:         make(chan struct{}, 1),
+		queue:        make(chan models.WebhookDelivery, webhookQueueSize),
+	}
+}
+
+// New creates a webhook for user. A secret is generated unless one is given.
+func (w *WebhooksService) New(userID string, hook models.Webhook) (models.Webhook, error) {
+	if err := w.validate(userID, &hook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	if hook.Secret == "" {
+		hook.Secret = newWebhookSecret()
+	}
+
+	hook.ID = utils.CreateID()
+
+	w.webhooksRepo.Create(userID, &hook)
+
+	return hook, nil
+}
+
+// Webhook returns a webhook with id owned by user
+func (w *WebhooksService) Webhook(userID, id string) (models.Webhook, bool) {
+	return w.webhooksRepo.WebhookWithID(userID, id)
+}
+
+// List returns the webhooks owned by user
+func (w *WebhooksService) List(userID string, page models.Page) ([]models.Webhook, string) {
+	return w.webhooksRepo.List(userID, page)
+}
+
+// Update replaces the URL, events and scope of a webhook owned by user. Its
+// secret is only replaced if a new one is given.
+func (w *WebhooksService) Update(userID string, hook models.Webhook) (models.Webhook, error) {
+	current, found := w.webhooksRepo.WebhookWithID(userID, hook.ID)
+	if !found {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+
+	if err := w.validate(userID, &hook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	current.URL = hook.URL
+	current.Events = hook.Events
+	current.FeedID = hook.FeedID
+	current.CategoryID = hook.CategoryID
+	current.TagID = hook.TagID
+
+	if hook.Secret != "" {
+		current.Secret = hook.Secret
+	}
+
+	if err := w.webhooksRepo.Update(userID, &current); err == repo.ErrModelNotFound {
+		return models.Webhook{}, ErrWebhookNotFound
+	} else if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return current, nil
+}
+
+// RotateSecret replaces the secret of a webhook with id owned by user with a new one
+func (w *WebhooksService) RotateSecret(userID, id string) (models.Webhook, error) {
+	hook, found := w.webhooksRepo.WebhookWithID(userID, id)
+	if !found {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+
+	hook.Secret = newWebhookSecret()
+
+	if err := w.webhooksRepo.Update(userID, &hook); err == repo.ErrModelNotFound {
+		return models.Webhook{}, ErrWebhookNotFound
+	} else if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return hook, nil
+}
+
+// Delete a webhook with id owned by user
+func (w *WebhooksService) Delete(userID, id string) error {
+	err := w.webhooksRepo.Delete(userID, id)
+	if err == repo.ErrModelNotFound {
+		return ErrWebhookNotFound
+	}
+
+	return err
+}
+
+// Deliveries returns the deliveries of a webhook owned by user, the most recent first
+func (w *WebhooksService) Deliveries(userID string, page models.Page) ([]models.WebhookDelivery, string, error) {
+	if _, found := w.webhooksRepo.WebhookWithID(userID, page.FilterID); !found {
+		return nil, "", ErrWebhookNotFound
+	}
+
+	deliveries, next := w.webhooksRepo.ListDeliveries(userID, page)
+
+	return deliveries, next, nil
+}
+
+// Test queues a test event for a webhook owned by user. It is sent in the
+// background as soon as possible and the pending delivery is returned. Test
+// deliveries are not retried.
+func (w *WebhooksService) Test(userID, id string) (models.WebhookDelivery, error) {
+	hook, found := w.webhooksRepo.WebhookWithID(userID, id)
+	if !found {
+		return models.WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	delivery, err := newDelivery(&hook, &events.Event{Type: WebhookTestEvent, UserID: userID, Time: time.Now()})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	w.webhooksRepo.CreateDelivery(&delivery)
+
+	w.wakeUp()
+
+	return delivery, nil
+}
+
+// Start delivering events. Failed deliveries are retried every interval at most.
+func (w *WebhooksService) Start(interval time.Duration) {
+	if w.bus != nil {
+		// Every event must be recorded, even while deliveries are recorded
+		// slower than events are published
+		w.sub = w.bus.SubscribeQueued("", webhookEventBuffer)
+
+		w.workersWg.Add(1)
+
+		go func() {
+			defer w.workersWg.Done()
+
+			for event := range w.sub.Events() {
+				w.dispatch(&event)
+			}
+		}()
+	}
+
+	w.workersWg.Add(webhookWorkers)
+
+	for i := 0; i < webhookWorkers; i++ {
+		go w.deliveryHandler()
+	}
+
+	w.ticker = time.NewTicker(interval)
+
+	w.schedulerWg.Add(1)
+
+	go w.schedule()
+}
+
+// Stop delivering events. Deliveries that are being attempted are finished first.
+func (w *WebhooksService) Stop() {
+	if w.sub != nil {
+		w.sub.Close()
+	}
+
+	w.ticker.Stop()
+
+	close(w.quit)
+	w.schedulerWg.Wait()
+
+	close(w.queue)
+	w.workersWg.Wait()
+}
+
+func (w *WebhooksService) schedule() {
+	defer w.schedulerWg.Done()
+
+	for {
+		select {
+		case now := <-w.ticker.C:
+			if deleted := w.webhooksRepo.DeleteDeliveries(now.Add(-webhookRetention)); deleted > 0 {
+				log.Infof("Deleted %d webhook deliveries", deleted)
+			}
+
+			w.queueDue()
+		case <-w.wake:
+			w.queueDue()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// queueDue queues every delivery that is due to be attempted
+func (w *WebhooksService) queueDue() {
+	for {
+		now := time.Now()
+
+		deliveries := w.webhooksRepo.ListDueDeliveries(now, webhookQueueSize)
+		if len(deliveries) == 0 {
+			return
+		}
+
+		for idx := range deliveries {
+			// Lease the delivery so that it is not picked again while it is attempted
+			deliveries[idx].NextAttemptAt = now.Add(webhookLease)
+			if err := w.webhooksRepo.UpdateDelivery(&deliveries[idx]); err != nil {
+				log.Error(err)
+				continue
+			}
+
+			select {
+			case w.queue <- deliveries[idx]:
+			case <-w.quit:
+				return
+			}
+		}
+	}
+}
+
+func (w *WebhooksService) deliveryHandler() {
+	defer w.workersWg.Done()
+
+	for delivery := range w.queue {
+		hook := delivery.Webhook
+		w.attempt(&delivery, &hook, delivery.Event != WebhookTestEvent)
+	}
+}
+
+// dispatch records a delivery of event for every webhook it matches
+func (w *WebhooksService) dispatch(event *events.Event) {
+	// Entries of muted feeds are left out like they are from the entries of the user
+	if event.Type == events.EntryCreated && event.Entry != nil && models.FeedMuted(&event.Entry.Feed, time.Now()) {
+		return
+	}
+
+	hooks := w.webhooksRepo.ListAll(event.UserID)
+
+	queued := false
+
+	for idx := range hooks {
+		if !w.matches(&hooks[idx], event) {
+			continue
+		}
+
+		delivery, err := newDelivery(&hooks[idx], event)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		w.webhooksRepo.CreateDelivery(&delivery)
+
+		queued = true
+	}
+
+	if queued {
+		w.wakeUp()
+	}
+}
+
+// wakeUp has the scheduler queue the deliveries that are due
+func (w *WebhooksService) wakeUp() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// matches reports whether event is of one of the types hook lists and
+// concerns its scope
+func (w *WebhooksService) matches(hook *models.Webhook, event *events.Event) bool {
+	if types := eventTypes(hook.Events); len(types) != 0 && !contains(types, event.Type) {
+		return false
+	}
+
+	if hook.FeedID == "" && hook.CategoryID == "" && hook.TagID == "" {
+		return true
+	}
+
+	feedID, entryID, ctgID := event.FeedID, event.EntryID, event.CategoryID
+
+	if event.Entry != nil {
+		entryID = event.Entry.ID
+	}
+
+	if event.Feed != nil {
+		feedID, ctgID = event.Feed.ID, event.Feed.CategoryID
+	}
+
+	if feedID == "" && entryID != "" {
+		if entry, found := w.entriesRepo.EntryWithID(event.UserID, entryID); found {
+			feedID = entry.FeedID
+		}
+	}
+
+	if ctgID == "" && feedID != "" {
+		if feed, found := w.feedsRepo.FeedWithID(event.UserID, feedID); found {
+			ctgID = feed.CategoryID
+		}
+	}
+
+	return (hook.FeedID == "" || hook.FeedID == feedID) &&
+		(hook.CategoryID == "" || hook.CategoryID == ctgID) &&
+		(hook.TagID == "" || (entryID != "" && w.entriesRepo.HasTag(event.UserID, entryID, hook.TagID)))
+}
+
+// attempt sends delivery to hook and records the outcome. Failed deliveries
+// are retried with exponential backoff if retry is true.
+func (w *WebhooksService) attempt(delivery *models.WebhookDelivery, hook *models.Webhook, retry bool) {
+	status, err := send(delivery, hook)
+
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = time.Time{}
+	case retry && delivery.Attempts < webhookMaxAttempts:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(webhookRetryDelay << uint(delivery.Attempts-1))
+	default:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Time{}
+	}
+
+	if err := w.webhooksRepo.UpdateDelivery(delivery); err != nil {
+		log.Error(err)
+	}
+}
+
+// validate checks the URL, events and scope of hook
+func (w *WebhooksService) validate(userID string, hook *models.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrWebhookURL
+	}
+
+	for _, t := range eventTypes(hook.Events) {
+		if !contains(events.Types, t) {
+			return ErrWebhookEvents
+		}
+	}
+
+	hook.Events = strings.Join(eventTypes(hook.Events), ",")
+
+	if hook.FeedID != "" {
+		if _, found := w.feedsRepo.FeedWithID(userID, hook.FeedID); !found {
+			return ErrWebhookScope
+		}
+	}
+
+	if hook.CategoryID != "" {
+		if _, found := w.ctgsRepo.CategoryWithID(userID, hook.CategoryID); !found {
+			return ErrWebhookScope
+		}
+	}
+
+	if hook.TagID != "" {
+		if _, found := w.tagsRepo.TagWithID(userID, hook.TagID); !found {
+			return ErrWebhookScope
+		}
+	}
+
+	return nil
+}
+
+// send posts the payload of delivery to hook and returns the status of the response
+func send(delivery *models.WebhookDelivery, hook *models.Webhook) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write([]byte(delivery.Payload))
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := utils.NewHTTPClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseLog))
+
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp.StatusCode, nil
+}
+
+func newDelivery(hook *models.Webhook, event *events.Event) (models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return models.WebhookDelivery{
+		ID:            utils.CreateID(),
+		WebhookID:     hook.ID,
+		Event:         event.Type,
+		Payload:       string(payload),
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, webhookSecretLength)
+
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// eventTypes splits a comma separated list of event types
+func eventTypes(list string) []string {
+	var types []string
+
+	for _, t := range strings.Split(list, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,466 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	WebhooksSuite struct {
+		suite.Suite
+
+		service      *services.WebhooksService
+		bus          *events.Bus
+		db           *gorm.DB
+		webhooksRepo repo.Webhooks
+		feedsRepo    repo.Feeds
+		entriesRepo  repo.Entries
+		user         *models.User
+	}
+
+	// webhookReceiver records the deliveries it receives and answers with status
+	webhookReceiver struct {
+		*httptest.Server
+
+		status     int32
+		deliveries chan receivedDelivery
+	}
+
+	receivedDelivery struct {
+		header http.Header
+		body   []byte
+	}
+)
+
+func newWebhookReceiver(status int) *webhookReceiver {
+	receiver := &webhookReceiver{
+		status:     int32(status),
+		deliveries: make(chan receivedDelivery, 10),
+	}
+
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		receiver.deliveries <- receivedDelivery{r.Header, body}
+
+		w.WriteHeader(int(atomic.LoadInt32(&receiver.status)))
+	}))
+
+	return receiver
+}
+
+func (t *WebhooksSuite) receive(receiver *webhookReceiver) receivedDelivery {
+	select {
+	case delivery := <-receiver.deliveries:
+		return delivery
+	case <-time.After(time.Second * 5):
+		t.FailNow("no delivery was received")
+		return receivedDelivery{}
+	}
+}
+
+func (t *WebhooksSuite) newFeed() models.Feed {
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Example",
+		Subscription: "http://example.com/" + utils.CreateID(),
+	}
+
+	t.feedsRepo.Create(t.user.ID, &feed)
+
+	return feed
+}
+
+func (t *WebhooksSuite) TestNewWebhook() {
+	hook, err := t.service.New(t.user.ID, models.Webhook{
+		URL:    "https://example.com/hook",
+		Events: " entry.created, feed.failed ,",
+	})
+	t.Require().NoError(err)
+	t.NotEmpty(hook.ID)
+	t.NotEmpty(hook.Secret)
+	t.Equal("entry.created,feed.failed", hook.Events)
+
+	found, ok := t.service.Webhook(t.user.ID, hook.ID)
+	t.True(ok)
+	t.Equal(hook.Secret, found.Secret)
+
+	hooks, _ := t.service.List(t.user.ID, models.Page{Count: 10})
+	t.Len(hooks, 1)
+}
+
+func (t *WebhooksSuite) TestNewInvalidWebhook() {
+	_, err := t.service.New(t.user.ID, models.Webhook{URL: "example.com/hook"})
+	t.Equal(services.ErrWebhookURL, err)
+
+	_, err = t.service.New(t.user.ID, models.Webhook{URL: "ftp://example.com/hook"})
+	t.Equal(services.ErrWebhookURL, err)
+
+	_, err = t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", Events: "entry.bogus"})
+	t.Equal(services.ErrWebhookEvents, err)
+
+	_, err = t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", FeedID: "bogus"})
+	t.Equal(services.ErrWebhookScope, err)
+
+	_, err = t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", CategoryID: "bogus"})
+	t.Equal(services.ErrWebhookScope, err)
+
+	_, err = t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", TagID: "bogus"})
+	t.Equal(services.ErrWebhookScope, err)
+}
+
+func (t *WebhooksSuite) TestUpdateWebhook() {
+	feed := t.newFeed()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", Secret: "secret"})
+	t.Require().NoError(err)
+
+	updated, err := t.service.Update(t.user.ID, models.Webhook{
+		ID:     hook.ID,
+		URL:    "https://example.com/other",
+		FeedID: feed.ID,
+	})
+	t.Require().NoError(err)
+	t.Equal("https://example.com/other", updated.URL)
+	t.Equal(feed.ID, updated.FeedID)
+	t.Equal("secret", updated.Secret)
+
+	_, err = t.service.Update(t.user.ID, models.Webhook{ID: "bogus", URL: "https://example.com/hook"})
+	t.Equal(services.ErrWebhookNotFound, err)
+
+	_, err = t.service.Update(t.user.ID, models.Webhook{ID: hook.ID, URL: "bogus"})
+	t.Equal(services.ErrWebhookURL, err)
+}
+
+func (t *WebhooksSuite) TestRotateWebhookSecret() {
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook", Secret: "secret"})
+	t.Require().NoError(err)
+
+	rotated, err := t.service.RotateSecret(t.user.ID, hook.ID)
+	t.Require().NoError(err)
+	t.NotEmpty(rotated.Secret)
+	t.NotEqual("secret", rotated.Secret)
+
+	found, _ := t.service.Webhook(t.user.ID, hook.ID)
+	t.Equal(rotated.Secret, found.Secret)
+
+	_, err = t.service.RotateSecret(t.user.ID, "bogus")
+	t.Equal(services.ErrWebhookNotFound, err)
+}
+
+func (t *WebhooksSuite) TestDeleteWebhook() {
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: "https://example.com/hook"})
+	t.Require().NoError(err)
+
+	t.NoError(t.service.Delete(t.user.ID, hook.ID))
+	t.Equal(services.ErrWebhookNotFound, t.service.Delete(t.user.ID, hook.ID))
+}
+
+func (t *WebhooksSuite) TestTestWebhook() {
+	receiver := newWebhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: receiver.URL, Secret: "secret"})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Hour)
+	defer t.service.Stop()
+
+	// Test events are sent in the background
+	delivery, err := t.service.Test(t.user.ID, hook.ID)
+	t.Require().NoError(err)
+	t.Equal(models.WebhookDeliveryPending, delivery.Status)
+
+	received := t.receive(receiver)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(received.body)
+
+	t.Equal("sha256="+hex.EncodeToString(mac.Sum(nil)), received.header.Get(services.WebhookSignatureHeader))
+	t.Equal(services.WebhookTestEvent, received.header.Get(services.WebhookEventHeader))
+	t.Equal(delivery.ID, received.header.Get(services.WebhookDeliveryHeader))
+
+	var event events.Event
+	t.Require().NoError(json.Unmarshal(received.body, &event))
+	t.Equal(services.WebhookTestEvent, event.Type)
+
+	var deliveries []models.WebhookDelivery
+
+	t.Eventually(func() bool {
+		deliveries, _, err = t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: 10})
+		return err == nil && len(deliveries) == 1 && deliveries[0].Status == models.WebhookDeliveryDelivered
+	}, time.Second*5, time.Millisecond*10)
+
+	t.Equal(delivery.ID, deliveries[0].ID)
+	t.Equal(http.StatusOK, deliveries[0].ResponseStatus)
+	t.Equal(1, deliveries[0].Attempts)
+
+	_, err = t.service.Test(t.user.ID, "bogus")
+	t.Equal(services.ErrWebhookNotFound, err)
+
+	_, _, err = t.service.Deliveries(t.user.ID, models.Page{FilterID: "bogus", Count: 10})
+	t.Equal(services.ErrWebhookNotFound, err)
+}
+
+func (t *WebhooksSuite) TestTestWebhookFailure() {
+	receiver := newWebhookReceiver(http.StatusInternalServerError)
+	defer receiver.Close()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: receiver.URL})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Hour)
+	defer t.service.Stop()
+
+	_, err = t.service.Test(t.user.ID, hook.ID)
+	t.Require().NoError(err)
+
+	// Test deliveries are not retried
+	var deliveries []models.WebhookDelivery
+
+	t.Eventually(func() bool {
+		deliveries, _, _ = t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: 10})
+		return len(deliveries) == 1 && deliveries[0].Status == models.WebhookDeliveryFailed
+	}, time.Second*5, time.Millisecond*10)
+
+	t.Equal(http.StatusInternalServerError, deliveries[0].ResponseStatus)
+	t.Equal(1, deliveries[0].Attempts)
+	t.NotEmpty(deliveries[0].LastError)
+}
+
+func (t *WebhooksSuite) TestDeliverEvents() {
+	receiver := newWebhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	feed := t.newFeed()
+	other := t.newFeed()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{
+		URL:    receiver.URL,
+		Events: events.EntryCreated,
+		FeedID: feed.ID,
+	})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Millisecond * 10)
+	defer t.service.Stop()
+
+	t.bus.Publish(events.Event{Type: events.FeedUpdated, UserID: t.user.ID, Feed: &feed})
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.user.ID, Feed: &other,
+		Entry: &models.Entry{ID: utils.CreateID(), Title: "Other"}})
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: "other", Feed: &feed,
+		Entry: &models.Entry{ID: utils.CreateID(), Title: "Other user"}})
+
+	muted := feed
+	muted.Muted = true
+
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.user.ID, Feed: &feed,
+		Entry: &models.Entry{ID: utils.CreateID(), Title: "Muted", Feed: muted}})
+	t.bus.Publish(events.Event{Type: events.EntryCreated, UserID: t.user.ID, Feed: &feed,
+		Entry: &models.Entry{ID: utils.CreateID(), Title: "Match"}})
+
+	received := t.receive(receiver)
+	t.Equal(events.EntryCreated, received.header.Get(services.WebhookEventHeader))
+
+	var event events.Event
+	t.Require().NoError(json.Unmarshal(received.body, &event))
+	t.Require().NotNil(event.Entry)
+	t.Equal("Match", event.Entry.Title)
+
+	t.Eventually(func() bool {
+		deliveries, _, _ := t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: 10})
+		return len(deliveries) == 1 && deliveries[0].Status == models.WebhookDeliveryDelivered
+	}, time.Second*5, time.Millisecond*10)
+
+	select {
+	case <-receiver.deliveries:
+		t.Fail("unexpected delivery")
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func (t *WebhooksSuite) TestDeliverEventsPublishedInBursts() {
+	// Deliveries are held up so that only recording them takes time
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: server.URL, Events: events.FeedUpdated})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Hour)
+	defer t.service.Stop()
+	defer close(release)
+
+	// More events are published at once than the subscription holds
+	const published = 1500
+
+	for idx := 0; idx < published; idx++ {
+		t.bus.Publish(events.Event{Type: events.FeedUpdated, UserID: t.user.ID})
+	}
+
+	t.Eventually(func() bool {
+		deliveries, _, _ := t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: published})
+		return len(deliveries) == published
+	}, time.Second*20, time.Millisecond*100)
+}
+
+func (t *WebhooksSuite) TestDeliverEventsScopedToTag() {
+	receiver := newWebhookReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	feed := t.newFeed()
+
+	tagged := models.Entry{ID: utils.CreateID(), Title: "Tagged", Feed: feed}
+	untagged := models.Entry{ID: utils.CreateID(), Title: "Untagged", Feed: feed}
+
+	t.entriesRepo.Create(t.user.ID, &tagged)
+	t.entriesRepo.Create(t.user.ID, &untagged)
+
+	tag := models.Tag{ID: utils.CreateID(), Name: "tag"}
+	sql.NewTags(t.db).Create(t.user.ID, &tag)
+	t.Require().NoError(t.entriesRepo.TagEntries(t.user.ID, tag.ID, []string{tagged.ID}))
+
+	_, err := t.service.New(t.user.ID, models.Webhook{URL: receiver.URL, TagID: tag.ID})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Millisecond * 10)
+	defer t.service.Stop()
+
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.user.ID, EntryID: untagged.ID})
+	t.bus.Publish(events.Event{Type: events.EntryMarked, UserID: t.user.ID, EntryID: tagged.ID})
+
+	var event events.Event
+	t.Require().NoError(json.Unmarshal(t.receive(receiver).body, &event))
+	t.Equal(tagged.ID, event.EntryID)
+}
+
+func (t *WebhooksSuite) TestRetryFailedDeliveries() {
+	receiver := newWebhookReceiver(http.StatusServiceUnavailable)
+	defer receiver.Close()
+
+	hook, err := t.service.New(t.user.ID, models.Webhook{URL: receiver.URL})
+	t.Require().NoError(err)
+
+	t.service.Start(time.Millisecond * 10)
+	defer t.service.Stop()
+
+	t.bus.Publish(events.Event{Type: events.FeedCreated, UserID: t.user.ID, FeedID: utils.CreateID()})
+
+	t.receive(receiver)
+
+	var delivery models.WebhookDelivery
+
+	t.Require().Eventually(func() bool {
+		deliveries, _, _ := t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: 1})
+		if len(deliveries) != 1 || deliveries[0].Attempts != 1 {
+			return false
+		}
+
+		delivery = deliveries[0]
+
+		return true
+	}, time.Second*5, time.Millisecond*10)
+
+	t.Equal(models.WebhookDeliveryPending, delivery.Status)
+	t.Equal(http.StatusServiceUnavailable, delivery.ResponseStatus)
+	t.WithinDuration(time.Now().Add(time.Minute), delivery.NextAttemptAt, time.Second*5)
+
+	// Make the retry due right away
+	atomic.StoreInt32(&receiver.status, http.StatusOK)
+
+	delivery.NextAttemptAt = time.Now()
+	t.Require().NoError(t.webhooksRepo.UpdateDelivery(&delivery))
+
+	t.receive(receiver)
+
+	t.Eventually(func() bool {
+		deliveries, _, _ := t.service.Deliveries(t.user.ID, models.Page{FilterID: hook.ID, Count: 1})
+		return len(deliveries) == 1 && deliveries[0].Status == models.WebhookDeliveryDelivered &&
+			deliveries[0].Attempts == 2
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func (t *WebhooksSuite) SetupSuite() {
+	// Test servers listen on loopback addresses
+	t.Require().NoError(utils.ConfigureFetcher(utils.FetcherConfig{
+		AllowedNetworks: []string{"127.0.0.0/8", "::1"},
+	}))
+}
+
+func (t *WebhooksSuite) TearDownSuite() {
+	t.NoError(utils.ConfigureFetcher(utils.FetcherConfig{}))
+}
+
+func (t *WebhooksSuite) SetupTest() {
+	var err error
+
+	t.db, err = gorm.Open("sqlite3", ":memory:")
+	t.Require().NoError(err)
+
+	// Deliveries are made from several goroutines
+	t.db.DB().SetMaxOpenConns(1)
+
+	sql.AutoMigrateTables(t.db)
+
+	t.webhooksRepo = sql.NewWebhooks(t.db)
+	t.feedsRepo = sql.NewFeeds(t.db)
+	t.entriesRepo = sql.NewEntries(t.db)
+
+	t.bus = events.NewBus()
+
+	t.service = services.NewWebhooksService(t.webhooksRepo, t.feedsRepo, sql.NewCategories(t.db), sql.NewTags(t.db),
+		t.entriesRepo, services.WithEvents(t.bus))
+
+	t.user = &models.User{
+		ID:       utils.CreateID(),
+		Username: "gopher",
+	}
+	sql.NewUsers(t.db).Create(t.user)
+}
+
+func (t *WebhooksSuite) TearDownTest() {
+	t.NoError(t.db.Close())
+}
+
+func TestWebhooks(t *testing.T) {
+	suite.Run(t, new(WebhooksSuite))
+}
@@ -0,0 +1,54 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"errors"
+)
+
+//go:generate mockgen -source=websub.go -destination=websub_mock.go -package=services
+
+type (
+	// WebSubSubscriber receives the requests WebSub hubs make to subscription callbacks
+	WebSubSubscriber interface {
+		// Verify confirms a request made by the subscriber to a hub and returns the
+		// challenge sent by the hub
+		Verify(id, mode, topic, challenge string, leaseSeconds int) (string, error)
+
+		// Deliver ingests content pushed by a hub. signature is the X-Hub-Signature
+		// header the content was sent with.
+		Deliver(id, signature string, body []byte) error
+	}
+
+	// WebSubReleaser cancels the WebSub subscriptions of feeds that are deleted or moved
+	WebSubReleaser interface {
+		// Release cancels the subscriptions that push content to the feeds
+		// subscribed to url once no feed is subscribed to it anymore
+		Release(url string)
+	}
+)
+
+var (
+	// ErrWebSubNotFound signals that a WebSub subscription could not be found
+	// or does not match the request of a hub
+	ErrWebSubNotFound = errors.New("websub subscription not found")
+
+	// ErrWebSubSignature signals that pushed content was not signed with the
+	// secret of its subscription
+	ErrWebSubSignature = errors.New("websub signature is not valid")
+)
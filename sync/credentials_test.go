@@ -0,0 +1,199 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+func (s *SyncTestSuite) newAuthenticatedFeed(subscription string, credentials *models.FeedCredentials) (
+	*models.User, models.Feed) {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Private",
+		Subscription: subscription,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	s.Require().NoError(s.feedsRepo.SetCredentials(user.ID, feed.ID, credentials))
+
+	return user, feed
+}
+
+func (s *SyncTestSuite) TestSyncSendsCredentials() {
+	subscription := s.ts.URL + "/rss_private.xml"
+
+	user, feed := s.newAuthenticatedFeed(subscription, &models.FeedCredentials{
+		Token:  "token",
+		Cookie: "session=1",
+	})
+
+	// Feeds fetched with credentials are not shared with other subscribers
+	otherUser, otherFeed := s.newAuthenticatedFeed(subscription, &models.FeedCredentials{
+		Token:  "bogus",
+		Cookie: "session=1",
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+	serv.SyncUser(otherUser.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 5)
+
+	otherFeed, _ = s.feedsRepo.FeedWithID(otherUser.ID, otherFeed.ID)
+	s.Equal(models.FeedStatusError, otherFeed.Status)
+	s.Equal(http.StatusUnauthorized, otherFeed.HTTPStatus)
+
+	entries, _ = s.entriesRepo.ListFromFeed(otherUser.ID, models.Page{
+		FilterID: otherFeed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SyncTestSuite) TestSyncDropsCredentialsOnRedirectToOtherHost() {
+	var leaked int32
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "" || r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+			atomic.AddInt32(&leaked, 1)
+		}
+
+		if _, err := fmt.Fprint(w, rssFile); err != nil {
+			panic(err)
+		}
+	}))
+	defer other.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/rss.xml", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	user, feed := s.newAuthenticatedFeed(origin.URL+"/rss.xml", &models.FeedCredentials{
+		Token:   "token",
+		Cookie:  "session=1",
+		Headers: map[string]string{"X-Api-Key": "secret"},
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	s.Zero(atomic.LoadInt32(&leaked))
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 5)
+}
+
+func (s *SyncTestSuite) TestSyncThroughProxy() {
+	var proxied int32
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests to proxies carry absolute URLs
+		if !r.URL.IsAbs() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		atomic.AddInt32(&proxied, 1)
+
+		if _, err := fmt.Fprint(w, rssFile); err != nil {
+			panic(err)
+		}
+	}))
+	defer proxy.Close()
+
+	// The target is checked before the request is handed to the proxy
+	user, feed := s.newAuthenticatedFeed("http://203.0.113.10/rss.xml", &models.FeedCredentials{
+		Proxy: proxy.URL,
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	s.Equal(int32(1), atomic.LoadInt32(&proxied))
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 5)
+}
+
+func (s *SyncTestSuite) TestSyncThroughProxyBlocksPrivateTarget() {
+	var proxied int32
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+	}))
+	defer proxy.Close()
+
+	user, feed := s.newAuthenticatedFeed("http://169.254.169.254/latest/meta-data/", &models.FeedCredentials{
+		Proxy: proxy.URL,
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	s.Zero(atomic.LoadInt32(&proxied))
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusError, feed.Status)
+}
+
+func (s *SyncTestSuite) TestSyncWithUnreadableCredentials() {
+	user, feed := s.newAuthenticatedFeed(s.ts.URL+"/rss_private.xml", &models.FeedCredentials{
+		Token: "token",
+	})
+
+	// Credentials encrypted with another secret cannot be read
+	feedsRepo := sql.NewFeeds(s.db, sql.WithCredentialsSecret("other"))
+
+	serv := sync.NewService(feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusError, feed.Status)
+	s.Equal(utils.ErrDecrypt.Error(), feed.LastError)
+}
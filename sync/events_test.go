@@ -0,0 +1,85 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"github.com/jmartinezhern/syndication/events"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+func (s *SyncTestSuite) TestSyncPublishesEvents() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	missing := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Missing",
+		Subscription: s.ts.URL + "/rss_missing.xml",
+	}
+	s.feedsRepo.Create(user.ID, &missing)
+
+	bus := events.NewBus()
+
+	created := bus.Subscribe(user.ID, 100, events.EntryCreated)
+	defer created.Close()
+
+	failed := bus.Subscribe(user.ID, 0, events.FeedFailed)
+	defer failed.Close()
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithEvents(bus))
+	serv.SyncUser(user.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    100,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().NotEmpty(entries)
+	s.Require().Len(created.Events(), len(entries))
+
+	for range entries {
+		event := <-created.Events()
+		s.Equal(feed.ID, event.FeedID)
+		s.NotEmpty(event.Entry.ID)
+		s.Equal(1, event.Unread)
+	}
+
+	s.Require().Len(failed.Events(), 1)
+
+	event := <-failed.Events()
+	s.Equal(missing.ID, event.Feed.ID)
+	s.Equal(models.FeedStatusError, event.Feed.Status)
+	s.Equal("server responded with status 404", event.Error)
+
+	// Entries are only published when they are added
+	_, err := serv.RefreshFeed(user.ID, &feed)
+	s.NoError(err)
+	s.Empty(created.Events())
+}
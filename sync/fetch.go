@@ -0,0 +1,241 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+// fetch is the outcome of fetching a feed URL, shared by every subscription to it
+type fetch struct {
+	done chan struct{}
+
+	fetchedAt time.Time
+
+	// expires is when the feed should be fetched again. Until then, the
+	// outcome is reused.
+	expires time.Time
+
+	feed    models.Feed
+	entries []models.Entry
+	err     error
+}
+
+// pull returns the feed a subscription points to. Subscriptions to the same
+// normalized URL share a single fetch that is reused until it expires, unless
+// reuse is false. Concurrent pulls of a URL always wait for the running fetch.
+// Feeds fetched with credentials are never shared, scraper feeds are only
+// shared with the ones using the same selectors and plugin feeds with the ones
+// using the same plugin. The returned time is when the feed was actually fetched.
+func (s *Service) pull(feed *models.Feed, credentials *models.FeedCredentials, reuse bool) (
+	models.Feed, []models.Entry, time.Time, error) {
+	key := utils.NormalizeURL(feed.Subscription)
+
+	switch {
+	case credentials != nil:
+		key += " " + feed.ID
+	case feed.Kind == models.FeedKindScraper:
+		key += fmt.Sprintf(" %q", feed.Selectors)
+	case feed.Kind == models.FeedKindPlugin:
+		key = fmt.Sprintf("%s %q", feed.Plugin, feed.Subscription)
+	}
+
+	waited := false
+
+	s.fetchesMu.Lock()
+
+	previous := s.fetches[key]
+	for previous != nil && !previous.finished() {
+		s.fetchesMu.Unlock()
+
+		<-previous.done
+
+		waited = true
+
+		s.fetchesMu.Lock()
+
+		previous = s.fetches[key]
+	}
+
+	if previous != nil && (reuse || waited) && time.Now().Before(previous.expires) {
+		s.fetchesMu.Unlock()
+
+		fetchedFeed, entries, err := previous.resultFor(feed)
+
+		return fetchedFeed, entries, previous.fetchedAt, err
+	}
+
+	current := &fetch{done: make(chan struct{})}
+	s.fetches[key] = current
+
+	s.fetchesMu.Unlock()
+
+	s.fetchURL(current, feed, credentials, previous)
+
+	close(current.done)
+
+	fetchedFeed, entries, err := current.resultFor(feed)
+
+	return fetchedFeed, entries, current.fetchedAt, err
+}
+
+// fetchURL fetches the subscription of feed into current. The validators of a
+// previous successful fetch are sent along so that its outcome can be reused
+// if nothing changed. Without one, like after a restart, the validators stored
+// with feed are sent instead.
+func (s *Service) fetchURL(current *fetch, feed *models.Feed, credentials *models.FeedCredentials, previous *fetch) {
+	etag, lastModified := feed.Etag, feed.LastModified
+
+	reusable := previous != nil && previous.err == nil
+	if reusable {
+		etag, lastModified = previous.feed.Etag, previous.feed.LastModified
+	}
+
+	current.fetchedAt = time.Now()
+
+	var (
+		fetchedFeed models.Feed
+		entries     []models.Entry
+		err         error
+	)
+
+	switch feed.Kind {
+	case models.FeedKindScraper:
+		fetchedFeed, entries, err = utils.ScrapePage(feed.Subscription, etag, lastModified, feed.Selectors, credentials)
+	case models.FeedKindPlugin:
+		fetchedFeed, entries, err = s.plugins.Source(feed.Plugin, feed.Subscription)
+	default:
+		fetchedFeed, entries, err = utils.PullFeedWithCredentials(feed.Subscription, etag, lastModified, credentials)
+	}
+
+	switch {
+	case err == utils.ErrNotModified && !reusable:
+		current.feed = storedVersion(feed, &fetchedFeed)
+		current.err = err
+
+		// Only subscriptions holding the same version of the feed can reuse
+		// the outcome, so it is not shared
+		current.expires = current.fetchedAt
+
+		return
+	case err == utils.ErrNotModified && etag+lastModified != "":
+		current.feed = previous.feed
+		current.feed.Subscription = fetchedFeed.Subscription
+		current.feed.LastUpdated = fetchedFeed.LastUpdated
+		current.feed.MaxAge = fetchedFeed.MaxAge
+		current.entries = previous.entries
+	case err != nil:
+		current.feed = fetchedFeed
+		current.err = err
+		current.expires = current.fetchedAt.Add(s.minInterval)
+
+		return
+	default:
+		current.feed = fetchedFeed
+		current.entries = entries
+	}
+
+	current.expires = s.nextFetchAt(current.fetchedAt, &current.feed)
+}
+
+// storedVersion returns the feed a fetch found unchanged since feed was last
+// updated with it. The hints of the feed are those stored with feed.
+func storedVersion(feed, fetched *models.Feed) models.Feed {
+	version := models.Feed{
+		Subscription:    fetched.Subscription,
+		Etag:            fetched.Etag,
+		LastModified:    fetched.LastModified,
+		LastUpdated:     fetched.LastUpdated,
+		HTTPStatus:      fetched.HTTPStatus,
+		MaxAge:          fetched.MaxAge,
+		TTL:             feed.TTL,
+		SkipHours:       feed.SkipHours,
+		SkipDays:        feed.SkipDays,
+		PostingInterval: feed.PostingInterval,
+	}
+
+	if version.Etag == "" && version.LastModified == "" {
+		version.Etag, version.LastModified = feed.Etag, feed.LastModified
+	}
+
+	return version
+}
+
+func (f *fetch) finished() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// resultFor returns the outcome of f to a subscription. If the subscription
+// already holds the fetched version of the feed, only the feed's validators,
+// status, hints and hub are returned along with utils.ErrNotModified.
+func (f *fetch) resultFor(feed *models.Feed) (models.Feed, []models.Entry, error) {
+	if f.err != nil {
+		return f.feed, nil, f.err
+	}
+
+	if !unchanged(feed, &f.feed) {
+		return f.feed, append([]models.Entry(nil), f.entries...), nil
+	}
+
+	return models.Feed{
+		Subscription:    f.feed.Subscription,
+		Etag:            f.feed.Etag,
+		LastModified:    f.feed.LastModified,
+		LastUpdated:     f.feed.LastUpdated,
+		HTTPStatus:      http.StatusNotModified,
+		TTL:             f.feed.TTL,
+		SkipHours:       f.feed.SkipHours,
+		SkipDays:        f.feed.SkipDays,
+		PostingInterval: f.feed.PostingInterval,
+		MaxAge:          f.feed.MaxAge,
+		Hub:             f.feed.Hub,
+		Topic:           f.feed.Topic,
+	}, nil, utils.ErrNotModified
+}
+
+// evictFetches forgets the fetches that expired more than maxInterval ago
+func (s *Service) evictFetches(now time.Time) {
+	s.fetchesMu.Lock()
+	defer s.fetchesMu.Unlock()
+
+	for key, f := range s.fetches {
+		if f.finished() && now.Sub(f.expires) > s.maxInterval {
+			delete(s.fetches, key)
+		}
+	}
+}
+
+// unchanged reports whether feed was last updated with the version of the
+// feed identified by the validators of fetched
+func unchanged(feed, fetched *models.Feed) bool {
+	if fetched.Etag != "" {
+		return feed.Etag == fetched.Etag
+	}
+
+	return fetched.LastModified != "" && feed.LastModified == fetched.LastModified
+}
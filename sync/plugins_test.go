@@ -0,0 +1,170 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+const issuesPlugin = `cat <<'JSON'
+{
+  "feed": {"title": "Issues"},
+  "entries": [
+    {"id": "SYN-1", "title": "Crash on startup", "content": "<p>Fails <script>alert(1)</script></p>"},
+    {"id": "SYN-2", "title": "Spam"}
+  ]
+}
+JSON`
+
+// pluginRegistry returns a registry of shell script plugins
+func (s *SyncTestSuite) pluginRegistry(scripts map[string]string) *plugins.Registry {
+	dir := s.T().TempDir()
+
+	var configs []plugins.Config
+
+	for name, script := range scripts {
+		path := filepath.Join(dir, name)
+		s.Require().NoError(ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0700))
+
+		configs = append(configs, plugins.Config{Name: name, Path: path})
+	}
+
+	registry, err := plugins.NewRegistry(configs)
+	s.Require().NoError(err)
+
+	return registry
+}
+
+func (s *SyncTestSuite) newPluginFeed(feed models.Feed) (*models.User, models.Feed) {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed.ID = utils.CreateID()
+	s.feedsRepo.Create(user.ID, &feed)
+
+	return user, feed
+}
+
+func (s *SyncTestSuite) TestSyncPluginFeed() {
+	registry := s.pluginRegistry(map[string]string{
+		"issues": issuesPlugin,
+		// Drops entries titled Spam and prefixes the others
+		"triage": `sed -e 's/,{"id":"SYN-2"[^}]*}//' -e 's/{"id":"SYN-2"[^}]*},\{0,1\}//' -e 's/"title":"/"title":"[SYN] /g'`,
+	})
+
+	user, feed := s.newPluginFeed(models.Feed{
+		Title:        "Issues",
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+		Processors:   "triage",
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithPlugins(registry))
+
+	added, err := serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+	s.Equal(1, added)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("[SYN] Crash on startup", entries[0].Title)
+	s.NotContains(entries[0].Content, "script")
+
+	// Known entries are not added or processed again, dropped ones are processed again
+	added, err = serv.RefreshFeed(user.ID, &feed)
+	s.NoError(err)
+	s.Zero(added)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusOK, feed.Status)
+}
+
+func (s *SyncTestSuite) TestSyncWithFailingProcessor() {
+	registry := s.pluginRegistry(map[string]string{
+		"issues": issuesPlugin,
+		"broken": "echo 'out of quota' >&2; exit 1",
+	})
+
+	user, feed := s.newPluginFeed(models.Feed{
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+		Processors:   "broken",
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithPlugins(registry))
+	serv.SyncUser(user.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(entries)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusError, feed.Status)
+	s.Contains(feed.LastError, "out of quota")
+}
+
+func (s *SyncTestSuite) TestSyncWithUnknownPlugin() {
+	user, feed := s.newPluginFeed(models.Feed{
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	_, err := serv.RefreshFeed(user.ID, &feed)
+	s.Error(err)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusError, feed.Status)
+}
+
+func (s *SyncTestSuite) TestSyncProcessesRegularFeeds() {
+	registry := s.pluginRegistry(map[string]string{
+		"none": `echo '{"entries": []}'`,
+	})
+
+	user, feed := s.newPluginFeed(models.Feed{
+		Subscription: s.ts.URL + "/rss.xml",
+		Processors:   "none",
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithPlugins(registry))
+
+	added, err := serv.RefreshFeed(user.ID, &feed)
+	s.NoError(err)
+	s.Zero(added)
+}
@@ -0,0 +1,146 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+func (s *SyncTestSuite) TestSyncAppliesRules() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	tag := models.Tag{ID: utils.CreateID(), Name: "triaged"}
+	sql.NewTags(s.db).Create(user.ID, &tag)
+
+	rulesRepo := sql.NewRules(s.db)
+
+	rulesRepo.Create(user.ID, &models.Rule{
+		ID: utils.CreateID(),
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorEquals, Value: "item 1"},
+		},
+		Actions: []models.RuleAction{{Type: models.RuleActionDelete}},
+	})
+	rulesRepo.Create(user.ID, &models.Rule{
+		ID: utils.CreateID(),
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorMatches, Value: "^item [23]$"},
+			{Field: models.RuleFieldFeed, Operator: models.RuleOperatorEquals, Value: feed.ID},
+		},
+		Actions: []models.RuleAction{
+			{Type: models.RuleActionMark, Marker: "read"},
+			{Type: models.RuleActionSave},
+			{Type: models.RuleActionTag, TagID: tag.ID},
+		},
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithRules(rulesRepo))
+	serv.SyncUser(user.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 4)
+
+	for _, entry := range entries {
+		s.NotEqual("Item 1", entry.Title)
+
+		matched := entry.Title == "Item 2" || entry.Title == "Item 3"
+		s.Equal(matched, entry.Saved, entry.Title)
+		s.Equal(matched, entry.Mark == models.MarkerRead, entry.Title)
+		s.Equal(matched, s.entriesRepo.HasTag(user.ID, entry.ID, tag.ID), entry.Title)
+	}
+}
+
+func (s *SyncTestSuite) TestSyncDoesNotProcessDeletedEntries() {
+	// The processor keeps every entry and logs the ones it is given
+	processed := filepath.Join(s.T().TempDir(), "processed")
+
+	registry := s.pluginRegistry(map[string]string{
+		"issues": issuesPlugin,
+		"log":    "tee -a " + processed,
+	})
+
+	user, feed := s.newPluginFeed(models.Feed{
+		Subscription: "project = SYN",
+		Kind:         models.FeedKindPlugin,
+		Plugin:       "issues",
+		Processors:   "log",
+	})
+
+	rulesRepo := sql.NewRules(s.db)
+
+	rulesRepo.Create(user.ID, &models.Rule{
+		ID: utils.CreateID(),
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorEquals, Value: "spam"},
+		},
+		Actions: []models.RuleAction{{Type: models.RuleActionDelete}},
+	})
+	rulesRepo.Create(user.ID, &models.Rule{
+		ID: utils.CreateID(),
+		Conditions: []models.RuleCondition{
+			{Field: models.RuleFieldTitle, Operator: models.RuleOperatorMatches, Value: "crash"},
+		},
+		Actions: []models.RuleAction{{Type: models.RuleActionSave}},
+	})
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention,
+		sync.WithPlugins(registry), sync.WithRules(rulesRepo))
+
+	added, err := serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+	s.Equal(1, added)
+
+	added, err = serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+	s.Zero(added)
+
+	log, err := ioutil.ReadFile(processed)
+	s.Require().NoError(err)
+	s.Contains(string(log), "SYN-1")
+	s.NotContains(string(log), "SYN-2")
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal("Crash on startup", entries[0].Title)
+	s.True(entries[0].Saved)
+}
@@ -0,0 +1,95 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+const hoursPerWeek = 24 * 7
+
+// nextFetchAt returns when a feed fetched at now should be fetched again. Feeds are
+// fetched as often as they post, or every interval if that is unknown, but never
+// before their TTL or max-age expire. The result is bounded by the minimum and
+// maximum intervals and then moved past the hours and days the feed asks to skip.
+func (s *Service) nextFetchAt(now time.Time, feed *models.Feed) time.Time {
+	interval := s.interval
+	if feed.PostingInterval > 0 {
+		interval = feed.PostingInterval
+	}
+
+	if ttl := time.Duration(feed.TTL) * time.Minute; ttl > interval {
+		interval = ttl
+	}
+
+	if feed.MaxAge > interval {
+		interval = feed.MaxAge
+	}
+
+	next := now.Add(s.bound(interval))
+
+	for i := 0; i < hoursPerWeek && skipped(feed, next); i++ {
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next
+}
+
+// retryAt returns when a feed that failed to be fetched errorCount consecutive times,
+// the last of which at now, should be fetched again. The interval doubles with every
+// failure but the server may ask for a longer one.
+func (s *Service) retryAt(now time.Time, errorCount int, err error) time.Time {
+	interval := s.bound(s.interval)
+	for i := 1; i < errorCount && interval < s.maxInterval; i++ {
+		interval *= 2
+	}
+
+	var httpErr utils.HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > interval {
+		interval = httpErr.RetryAfter
+	}
+
+	return now.Add(s.bound(interval))
+}
+
+func (s *Service) bound(interval time.Duration) time.Duration {
+	if interval < s.minInterval {
+		return s.minInterval
+	}
+
+	if interval > s.maxInterval {
+		return s.maxInterval
+	}
+
+	return interval
+}
+
+func skipped(feed *models.Feed, t time.Time) bool {
+	t = t.UTC()
+
+	return feed.SkipHours&(1<<t.Hour()) != 0 || feed.SkipDays&(1<<t.Weekday()) != 0
+}
+
+// paused reports whether feed is paused at now
+func paused(feed *models.Feed, now time.Time) bool {
+	return feed.Paused && (feed.PausedUntil == nil || feed.PausedUntil.After(now))
+}
@@ -0,0 +1,140 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"time"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+var changelogSelectors = models.ScraperSelectors{
+	Item:    ".release",
+	Title:   "h2",
+	Link:    "h2 a",
+	Date:    "time, .date",
+	Content: ".notes",
+}
+
+func (s *SyncTestSuite) TestScrapePage() {
+	feed, entries, err := utils.ScrapePage(s.ts.URL+"/changelog.html", "", "", changelogSelectors, nil)
+	s.Require().NoError(err)
+
+	s.Equal("Changelog", feed.Title)
+	s.Equal("Release notes", feed.Description)
+	s.Equal(s.ts.URL+"/changelog.html", feed.Source)
+	s.Equal("/releases/", feed.XMLBase)
+
+	s.Require().Len(entries, 3)
+
+	s.Equal("Version 2", entries[0].Title)
+	s.Equal(s.ts.URL+"/releases/v2", entries[0].Link)
+	s.Equal(s.ts.URL+"/releases/v2", entries[0].GUID)
+	s.Equal(time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC), entries[0].Published.UTC())
+	s.Contains(entries[0].Content, "search")
+
+	s.Equal("Version 1", entries[1].Title)
+	s.Equal(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), entries[1].Published.UTC())
+
+	// Items without links are identified by their content
+	s.Equal("Hotfix", entries[2].Title)
+	s.Empty(entries[2].Link)
+	s.NotEmpty(entries[2].GUID)
+	s.WithinDuration(time.Now(), entries[2].Published, 5*time.Second)
+
+	_, again, err := utils.ScrapePage(s.ts.URL+"/changelog.html", "", "", changelogSelectors, nil)
+	s.Require().NoError(err)
+	s.Equal(entries[2].GUID, again[2].GUID)
+}
+
+func (s *SyncTestSuite) TestScrapePageDefaults() {
+	_, entries, err := utils.ScrapePage(s.ts.URL+"/changelog.html", "", "", models.ScraperSelectors{
+		Item: ".release",
+	}, nil)
+	s.Require().NoError(err)
+	s.Require().Len(entries, 3)
+
+	// Without selectors, items are taken as a whole
+	s.Equal(s.ts.URL+"/releases/v2", entries[0].Link)
+	s.Contains(entries[0].Title, "Version 2")
+	s.Contains(entries[0].Content, "<h2>")
+}
+
+func (s *SyncTestSuite) TestScrapePageWithInvalidSelectors() {
+	_, _, err := utils.ScrapePage(s.ts.URL+"/changelog.html", "", "", models.ScraperSelectors{}, nil)
+	s.Equal(utils.ErrItemSelector, err)
+
+	_, _, err = utils.ScrapePage(s.ts.URL+"/changelog.html", "", "", models.ScraperSelectors{
+		Item:  ".release",
+		Title: "h2[",
+	}, nil)
+	s.Error(err)
+}
+
+func (s *SyncTestSuite) TestSyncScraperFeed() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Changelog",
+		Subscription: s.ts.URL + "/changelog.html",
+		Kind:         models.FeedKindScraper,
+		Selectors:    changelogSelectors,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	page := models.Page{
+		FilterID: feed.ID,
+		Count:    10,
+		Marker:   models.MarkerAny,
+	}
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, page)
+	s.Require().Len(entries, 3)
+
+	for _, entry := range entries {
+		// Content is sanitized like the content of feeds
+		s.NotContains(entry.Content, "script")
+		s.NotContains(entry.Content, "onerror")
+
+		if entry.Title == "Version 2" {
+			s.Contains(entry.Content, `src="`+s.ts.URL+`/releases/screenshot.png"`)
+		}
+	}
+
+	// Entries are only added once
+	_, err := serv.RefreshFeed(user.ID, &feed)
+	s.NoError(err)
+
+	entries, _ = s.entriesRepo.ListFromFeed(user.ID, page)
+	s.Len(entries, 3)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedKindScraper, feed.Kind)
+	s.Equal(changelogSelectors, feed.Selectors)
+	s.Equal(models.FeedStatusOK, feed.Status)
+}
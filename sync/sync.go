@@ -23,79 +23,367 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/jmartinezhern/syndication/events"
 	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/plugins"
 	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/sanitizer"
+	"github.com/jmartinezhern/syndication/services"
 	"github.com/jmartinezhern/syndication/utils"
 )
 
 const (
 	maxThreads           = 10
 	maxPageSizePerThread = 100
+
+	defaultMinInterval = time.Minute * 5
+	defaultMaxInterval = time.Hour * 12
+	defaultMaxErrors   = 10
 )
 
-// Service defines properties for running a Feed Sync Service.
-// Service will update all feeds for all users periodically.
-type Service struct {
-	ticker *time.Ticker
+type (
+	// Service defines properties for running a Feed Sync Service.
+	// Service will update every feed when it is due and prune its
+	// expired entries. Each feed URL is fetched once for all of the
+	// users subscribed to it and its entries are added to every
+	// subscription. The entries of feeds that are not fetched, like
+	// paused or disabled ones, are pruned by a periodic pass over
+	// every user.
+	Service struct {
+		ticker *time.Ticker
+
+		quit      chan bool
+		feedQueue chan models.Feed
+
+		wg sync.WaitGroup
+
+		interval    time.Duration
+		minInterval time.Duration
+		maxInterval time.Duration
+
+		maxErrors int
+
+		// fetches holds the latest fetch of every feed URL, shared by all of
+		// its subscriptions
+		fetchesMu sync.Mutex
+		fetches   map[string]*fetch
+
+		// feedLocks serializes the updates of a subscription, whether it is
+		// scheduled, refreshed or pushed, so that its entries are added once
+		feedLocksMu sync.Mutex
+		feedLocks   map[string]*feedLock
+
+		websub *WebSubSubscriber
+
+		plugins *plugins.Registry
 
-	quit      chan bool
-	userQueue chan models.User
+		events *events.Bus
 
-	wg sync.WaitGroup
+		rulesRepo repo.Rules
 
-	interval time.Duration
+		feedsRepo   repo.Feeds
+		entriesRepo repo.Entries
 
-	usersRepo   repo.Users
-	feedsRepo   repo.Feeds
-	entriesRepo repo.Entries
+		retention services.Retention
+
+		usersRepo     repo.Users
+		pruneInterval time.Duration
+		nextPruneAt   time.Time
+	}
+
+	// Option configures a Service
+	Option func(*Service)
+
+	feedLock struct {
+		sync.Mutex
+
+		// holders counts the callers holding or waiting for the lock
+		holders int
+	}
+)
+
+// WithIntervalBounds bounds the time between two fetches of a feed
+func WithIntervalBounds(min, max time.Duration) Option {
+	return func(s *Service) {
+		s.minInterval = min
+		s.maxInterval = max
+	}
 }
 
-func (s *Service) syncUserHandler() {
+// WithMaxErrors disables feeds that failed to be fetched count consecutive times
+func WithMaxErrors(count int) Option {
+	return func(s *Service) {
+		s.maxErrors = count
+	}
+}
+
+// WithPlugins makes the plugins of registry available to feeds
+func WithPlugins(registry *plugins.Registry) Option {
+	return func(s *Service) {
+		s.plugins = registry
+	}
+}
+
+// WithEvents publishes the entries that are added and the feeds that fail on bus
+func WithEvents(bus *events.Bus) Option {
+	return func(s *Service) {
+		s.events = bus
+	}
+}
+
+// WithRules applies the rules of users to the entries that are added
+func WithRules(rulesRepo repo.Rules) Option {
+	return func(s *Service) {
+		s.rulesRepo = rulesRepo
+	}
+}
+
+// WithPruning prunes the expired entries of every user in usersRepo every interval
+func WithPruning(usersRepo repo.Users, interval time.Duration) Option {
+	return func(s *Service) {
+		s.usersRepo = usersRepo
+		s.pruneInterval = interval
+	}
+}
+
+func (s *Service) syncFeedHandler() {
 	defer s.wg.Done()
 
 	for {
-		user, ok := <-s.userQueue
+		feed, ok := <-s.feedQueue
 		if !ok {
 			return
 		}
 
-		s.SyncUser(user.ID)
+		if _, err := s.updateFeed(feed.UserID, &feed, true); err != nil {
+			log.Error(err)
+		}
+
+		s.retention.PruneFeed(feed.UserID, &feed)
 	}
 }
 
-func (s *Service) updateFeed(userID string, feed *models.Feed) {
-	if !time.Now().After(feed.LastUpdated.Add(s.interval)) {
-		return
+// updateFeed fetches feed and stores its new entries. It returns how many
+// entries were added. A recent fetch of the same URL for another subscription
+// is reused if reuse is true.
+func (s *Service) updateFeed(userID string, feed *models.Feed, reuse bool) (int, error) {
+	unlock := s.lockFeed(feed.ID)
+	defer unlock()
+
+	var credentials *models.FeedCredentials
+
+	if len(feed.EncryptedCredentials) != 0 {
+		var err error
+
+		credentials, err = s.feedsRepo.Credentials(userID, feed.ID)
+		if err != nil {
+			s.recordFailure(userID, feed, 0, time.Now(), err)
+
+			return 0, err
+		}
 	}
 
-	fetchedFeed, entries, err := utils.PullFeed(feed.Subscription, feed.Etag)
-	if err != nil {
-		log.Error(err)
-		return
+	fetchedFeed, entries, fetchedAt, err := s.pull(feed, credentials, reuse)
+	if err != nil && err != utils.ErrNotModified {
+		s.recordFailure(userID, feed, fetchedFeed.HTTPStatus, fetchedAt, err)
+
+		return 0, err
+	}
+
+	notModified := err == utils.ErrNotModified
+
+	// Entries are processed before the feed is updated so that they are
+	// fetched again if processing fails
+	var ingest *services.Ingest
+
+	if !notModified {
+		if ingest, err = s.ingest(userID, feed, &fetchedFeed, entries); err != nil {
+			s.recordFailure(userID, feed, fetchedFeed.HTTPStatus, fetchedAt, err)
+
+			return 0, err
+		}
 	}
 
 	fetchedFeed.ID = feed.ID
 
+	// Subscriptions to the same URL are scheduled together since they
+	// are computed from the same fetch
+	fetchedFeed.NextFetchAt = s.nextFetchAt(fetchedAt, &fetchedFeed)
+
+	moved := fetchedFeed.Subscription != "" && fetchedFeed.Subscription != feed.Subscription
+	previous := feed.Subscription
+
+	if moved {
+		s.recordRedirect(userID, feed, fetchedFeed.Subscription)
+	}
+
+	// Feeds whose updates are pushed are only polled as a fallback. Hubs
+	// cannot push content that requires credentials.
+	if s.websub != nil && credentials == nil && fetchedFeed.Hub != "" &&
+		s.websub.subscribe(feed.Subscription, &fetchedFeed) {
+		fetchedFeed.NextFetchAt = fetchedAt.Add(s.maxInterval)
+	}
+
 	if err = s.feedsRepo.Update(userID, &fetchedFeed); err != nil {
-		log.Error(err)
-		return
+		return 0, err
+	}
+
+	if moved && s.websub != nil {
+		s.websub.Release(previous)
+	}
+
+	// Update ignores zero values, so hints the feed no longer gives and the
+	// error count are cleared separately
+	if err = s.feedsRepo.UpdateSchedule(userID, &fetchedFeed); err != nil {
+		return 0, err
+	}
+
+	err = s.feedsRepo.UpdateStatus(userID, &models.Feed{
+		ID:          feed.ID,
+		Status:      models.FeedStatusOK,
+		HTTPStatus:  fetchedFeed.HTTPStatus,
+		NextFetchAt: fetchedFeed.NextFetchAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if notModified {
+		return 0, nil
+	}
+
+	return ingest.Store(s.entriesRepo, s.events, userID, feed), nil
+}
+
+// ingest returns the entries of fetchedFeed that feed does not have yet, once
+// the rules of user are applied to them and they are passed through the
+// processors of feed
+func (s *Service) ingest(userID string, feed, fetchedFeed *models.Feed,
+	entries []models.Entry) (*services.Ingest, error) {
+	document := fetchedFeed.Subscription
+	if document == "" {
+		document = feed.Subscription
 	}
 
-	for idx := range entries {
-		if _, found := s.entriesRepo.EntryWithGUID(userID, entries[idx].GUID); !found {
-			entries[idx].ID = utils.CreateID()
-			entries[idx].Feed = *feed
-			s.entriesRepo.Create(userID, &entries[idx])
+	base := sanitizer.ResolveBase(document, fetchedFeed.Source, fetchedFeed.XMLBase)
+
+	ingest := services.NewIngest(s.entriesRepo, s.rulesRepo, userID, feed, base, entries)
+
+	return ingest, ingest.Process(s.plugins, plugins.Names(feed.Processors), feed)
+}
+
+// lockFeed waits until no one else updates the feed with id and returns the
+// function that lets others update it again
+func (s *Service) lockFeed(id string) func() {
+	s.feedLocksMu.Lock()
+
+	lock, found := s.feedLocks[id]
+	if !found {
+		lock = &feedLock{}
+		s.feedLocks[id] = lock
+	}
+
+	lock.holders++
+
+	s.feedLocksMu.Unlock()
+
+	lock.Lock()
+
+	return func() {
+		lock.Unlock()
+
+		s.feedLocksMu.Lock()
+		defer s.feedLocksMu.Unlock()
+
+		lock.holders--
+		if lock.holders == 0 {
+			delete(s.feedLocks, id)
+		}
+	}
+}
+
+// RefreshFeed fetches a feed owned by user right away, whether it is due or
+// not and even if it is paused, and returns how many new entries were added.
+// A disabled feed is enabled again if it is fetched successfully.
+func (s *Service) RefreshFeed(userID string, feed *models.Feed) (int, error) {
+	return s.updateFeed(userID, feed, false)
+}
+
+// reschedule sets when feed is fetched next
+func (s *Service) reschedule(userID string, feed *models.Feed, next time.Time) error {
+	return s.feedsRepo.Update(userID, &models.Feed{ID: feed.ID, NextFetchAt: next})
+}
+
+// recordFailure records that feed failed to be fetched at now and backs off
+// from fetching it. The feed is disabled once it failed maxErrors consecutive times.
+func (s *Service) recordFailure(userID string, feed *models.Feed, httpStatus int, now time.Time, err error) {
+	status := models.Feed{
+		ID:         feed.ID,
+		Status:     models.FeedStatusError,
+		ErrorCount: feed.ErrorCount + 1,
+		LastError:  err.Error(),
+		HTTPStatus: httpStatus,
+	}
+
+	status.NextFetchAt = s.retryAt(now, status.ErrorCount, err)
+
+	switch {
+	case feed.Status == models.FeedStatusDisabled:
+		// A disabled feed that is refreshed is only enabled if it succeeds
+		status.Status = models.FeedStatusDisabled
+	case s.maxErrors > 0 && status.ErrorCount >= s.maxErrors:
+		status.Status = models.FeedStatusDisabled
+
+		event := models.FeedEvent{
+			ID:     utils.CreateID(),
+			FeedID: feed.ID,
+			Type:   models.FeedEventDisabled,
+			From:   feed.Subscription,
 		}
+		if err := s.feedsRepo.CreateEvent(userID, &event); err != nil {
+			log.Error(err)
+		}
+	}
+
+	if err := s.feedsRepo.UpdateStatus(userID, &status); err != nil {
+		log.Error(err)
+	}
+
+	if failed, found := s.feedsRepo.FeedWithID(userID, feed.ID); found {
+		s.events.Publish(events.Event{
+			Type:   events.FeedFailed,
+			UserID: userID,
+			Feed:   &failed,
+			Error:  err.Error(),
+		})
+	}
+}
+
+// recordRedirect moves feed to location and records it in the feed's history
+func (s *Service) recordRedirect(userID string, feed *models.Feed, location string) {
+	err := s.feedsRepo.CreateEvent(userID, &models.FeedEvent{
+		ID:     utils.CreateID(),
+		FeedID: feed.ID,
+		Type:   models.FeedEventRedirect,
+		From:   feed.Subscription,
+		To:     location,
+	})
+	if err != nil {
+		log.Error(err)
 	}
+
+	feed.Subscription = location
 }
 
+// SyncUser fetches every feed owned by user that is due and neither disabled nor paused
 func (s *Service) SyncUser(userID string) {
 	var (
 		feeds          []models.Feed
 		continuationID string
 	)
 
+	now := time.Now()
+
 	for {
 		feeds, continuationID = s.feedsRepo.List(userID, models.Page{
 			ContinuationID: continuationID,
@@ -103,7 +391,14 @@ func (s *Service) SyncUser(userID string) {
 		})
 
 		for idx := range feeds {
-			s.updateFeed(userID, &feeds[idx])
+			if feeds[idx].Status == models.FeedStatusDisabled || paused(&feeds[idx], now) ||
+				feeds[idx].NextFetchAt.After(now) {
+				continue
+			}
+
+			if _, err := s.updateFeed(userID, &feeds[idx], true); err != nil {
+				log.Error(err)
+			}
 		}
 
 		if continuationID == "" {
@@ -112,16 +407,53 @@ func (s *Service) SyncUser(userID string) {
 	}
 }
 
-func (s *Service) syncUsers() {
+func (s *Service) syncDueFeeds() {
 	for {
-		// List up to maxThreads of users per iteration
-		users, continuationID := s.usersRepo.List(models.Page{ContinuationID: "", Count: maxThreads})
-		if len(users) == 0 {
+		now := time.Now()
+
+		feeds := s.feedsRepo.ListDue(now, maxThreads)
+		if len(feeds) == 0 {
+			break
+		}
+
+		leased := 0
+
+		for idx := range feeds {
+			// Lease the feed so that it is not picked again while it is fetched.
+			// The lease ends when the feed is rescheduled.
+			if err := s.reschedule(feeds[idx].UserID, &feeds[idx], now.Add(s.maxInterval)); err != nil {
+				log.Error(err)
+				continue
+			}
+
+			leased++
+
+			s.feedQueue <- feeds[idx]
+		}
+
+		// Feeds that cannot be leased would be listed again and again
+		if leased == 0 {
 			break
 		}
+	}
+}
+
+// pruneUsers deletes the expired entries of every user, including the ones of
+// feeds that are not fetched
+func (s *Service) pruneUsers() {
+	var (
+		users          []models.User
+		continuationID string
+	)
+
+	for {
+		users, continuationID = s.usersRepo.List(models.Page{
+			ContinuationID: continuationID,
+			Count:          maxPageSizePerThread,
+		})
 
 		for idx := range users {
-			s.userQueue <- users[idx]
+			s.retention.Prune(users[idx].ID)
 		}
 
 		if continuationID == "" {
@@ -134,8 +466,19 @@ func (s *Service) scheduleTask() {
 	go func() {
 		for {
 			select {
-			case <-s.ticker.C:
-				s.syncUsers()
+			case now := <-s.ticker.C:
+				s.feedsRepo.ClearExpired(now)
+				s.syncDueFeeds()
+				s.evictFetches(now)
+
+				if s.usersRepo != nil && !now.Before(s.nextPruneAt) {
+					s.pruneUsers()
+					s.nextPruneAt = now.Add(s.pruneInterval)
+				}
+
+				if s.websub != nil {
+					s.websub.renew(now)
+				}
 			case <-s.quit:
 				return
 			}
@@ -143,19 +486,26 @@ func (s *Service) scheduleTask() {
 	}()
 }
 
-// Start a SyncService
+// Start a SyncService. Feeds are fetched every interval unless they hint
+// at a different schedule.
 func (s *Service) Start(interval time.Duration) {
 	s.interval = interval
 
-	s.userQueue = make(chan models.User, maxThreads)
+	s.feedQueue = make(chan models.Feed, maxThreads)
 
 	s.wg.Add(maxThreads)
 
 	for i := 0; i < maxThreads; i++ {
-		go s.syncUserHandler()
+		go s.syncFeedHandler()
+	}
+
+	// Due feeds must be picked up at least as often as the shortest interval allows
+	tick := s.interval
+	if s.minInterval < tick {
+		tick = s.minInterval
 	}
 
-	s.ticker = time.NewTicker(s.interval)
+	s.ticker = time.NewTicker(tick)
 	s.scheduleTask()
 }
 
@@ -165,18 +515,35 @@ func (s *Service) Stop() {
 
 	s.quit <- true
 
-	close(s.userQueue)
+	close(s.feedQueue)
 
 	s.wg.Wait()
 }
 
-// NewService creates a new SyncService object
-func NewService(feedsRepo repo.Feeds, usersRepo repo.Users, entriesRepo repo.Entries) Service {
-	return Service{
+// NewService creates a new SyncService object. Expired entries of a feed are
+// pruned with retention after the feed is fetched and, with WithPruning,
+// periodically.
+func NewService(
+	feedsRepo repo.Feeds,
+	entriesRepo repo.Entries,
+	retention services.Retention,
+	opts ...Option) *Service {
+	s := &Service{
 		quit:        make(chan bool),
 		wg:          sync.WaitGroup{},
+		minInterval: defaultMinInterval,
+		maxInterval: defaultMaxInterval,
+		maxErrors:   defaultMaxErrors,
+		fetches:     make(map[string]*fetch),
+		feedLocks:   make(map[string]*feedLock),
 		feedsRepo:   feedsRepo,
-		usersRepo:   usersRepo,
 		entriesRepo: entriesRepo,
+		retention:   retention,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
@@ -18,12 +18,15 @@
 package sync_test
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,6 +36,7 @@ import (
 	"github.com/jmartinezhern/syndication/models"
 	"github.com/jmartinezhern/syndication/repo"
 	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
 	"github.com/jmartinezhern/syndication/sync"
 	"github.com/jmartinezhern/syndication/utils"
 )
@@ -119,44 +123,754 @@ const (
 	</rss>
 	`
 
-	rssFeedTag = "123456"
+	rssHTMLFile = `<rss version="2.0" xml:base="http://localhost:8090/blog/">
+	  <channel>
+	    <title>HTML Test</title>
+	    <link>http://localhost:8090</link>
+	    <description>Testing html content</description>
+	    <item>
+	      <title>Item 1</title>
+	      <guid>html1@test</guid>
+	      <description>&lt;p onclick="steal()"&gt;Read &lt;a href="post"&gt;more&lt;/a&gt;&lt;/p&gt;&lt;script&gt;steal()&lt;/script&gt;</description>
+	    </item>
+	  </channel>
+	</rss>
+	`
+
+	rssScheduleFile = `<rss version="2.0">
+	  <channel>
+	    <title>Schedule Test</title>
+	    <ttl>120</ttl>
+	    <skipHours>%s</skipHours>
+	    <skipDays>%s</skipDays>
+	    <item>
+	      <guid>schedule3@test</guid>
+	      <pubDate>Sun, 19 May 2002 19:00:00 GMT</pubDate>
+	    </item>
+	    <item>
+	      <guid>schedule2@test</guid>
+	      <pubDate>Sun, 19 May 2002 17:00:00 GMT</pubDate>
+	    </item>
+	    <item>
+	      <guid>schedule1@test</guid>
+	      <pubDate>Sun, 19 May 2002 15:00:00 GMT</pubDate>
+	    </item>
+	  </channel>
+	</rss>
+	`
+
+	changelogPage = `<!DOCTYPE html>
+	<html>
+	  <head>
+	    <title>Changelog</title>
+	    <meta name="description" content="Release notes">
+	    <base href="/releases/">
+	  </head>
+	  <body>
+	    <div class="release">
+	      <h2><a href="v2">Version 2</a></h2>
+	      <time datetime="2021-06-02T10:00:00Z">June 2</time>
+	      <div class="notes"><p>Adds <img src="screenshot.png" onerror="steal()"> search</p><script>steal()</script></div>
+	    </div>
+	    <div class="release">
+	      <h2><a href="v1">Version 1</a></h2>
+	      <span class="date">May 1, 2021</span>
+	      <div class="notes"><p>First release</p></div>
+	    </div>
+	    <div class="release">
+	      <h2>Hotfix</h2>
+	      <div class="notes"><p>No link</p></div>
+	    </div>
+	  </body>
+	</html>
+	`
+
+	rssWebSubFile = `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+	  <channel>
+	    <title>WebSub Feed</title>
+	    <link>http://example.com/</link>
+	    <atom:link rel="hub" href="%s"/>
+	    <atom:link rel="self" href="%s"/>
+	    %s
+	  </channel>
+	</rss>
+	`
+
+	rssFeedTag      = "123456"
+	rssLastModified = "Mon, 01 Feb 2021 10:00:00 GMT"
+)
+
+type (
+	SyncTestSuite struct {
+		suite.Suite
+
+		syncDBPath string
+
+		// sharedFetches counts the requests made for /rss_shared.xml
+		sharedFetches int32
+
+		ts          *httptest.Server
+		db          *gorm.DB
+		ctgsRepo    repo.Categories
+		feedsRepo   repo.Feeds
+		usersRepo   repo.Users
+		entriesRepo repo.Entries
+		retention   services.Retention
+	}
 )
 
-type (
-	SyncTestSuite struct {
-		suite.Suite
+var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+// loopbackFetcherConfig allows fetching from test servers
+var loopbackFetcherConfig = utils.FetcherConfig{
+	AllowedNetworks: []string{"127.0.0.0/8", "::1"},
+}
+
+func randStringRunes(n int) string {
+	b := make([]rune, n)
+
+	for i := range b {
+		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+	}
+
+	return string(b)
+}
+
+func (s *SyncTestSuite) TestPullUnreachableFeed() {
+	_, _, err := utils.PullFeed("Sync Test", s.ts.URL+"/bogus.xml", "")
+	s.Error(err)
+}
+
+func (s *SyncTestSuite) TestPullFeedWithBadSubscription() {
+	_, _, err := utils.PullFeed("Sync Test", "bogus", "")
+	s.Error(err)
+}
+
+func (s *SyncTestSuite) TestPullFeedReturnsValidators() {
+	feed, entries, err := utils.PullFeed(s.ts.URL+"/rss_cached.xml", "", "")
+	s.Require().NoError(err)
+	s.Len(entries, 5)
+	s.Equal(rssFeedTag, feed.Etag)
+	s.Equal(rssLastModified, feed.LastModified)
+}
+
+func (s *SyncTestSuite) TestPullFeedNotModified() {
+	feed, entries, err := utils.PullFeed(s.ts.URL+"/rss_cached.xml", rssFeedTag, "")
+	s.Equal(utils.ErrNotModified, err)
+	s.Empty(entries)
+	s.Equal(rssFeedTag, feed.Etag)
+	s.False(feed.LastUpdated.IsZero())
+
+	_, _, err = utils.PullFeed(s.ts.URL+"/rss_cached.xml", "", rssLastModified)
+	s.Equal(utils.ErrNotModified, err)
+}
+
+func (s *SyncTestSuite) TestSyncUserStoresValidators() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_cached.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(rssFeedTag, feed.Etag)
+	s.Equal(rssLastModified, feed.LastModified)
+}
+
+func (s *SyncTestSuite) TestSyncUserSkipsPausedFeeds() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	paused := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Paused",
+		Subscription: s.ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &paused)
+	s.Require().NoError(s.feedsRepo.SetPaused(user.ID, paused.ID, true, nil))
+
+	past := time.Now().Add(-time.Minute)
+
+	expired := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Expired",
+		Subscription: s.ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &expired)
+	s.Require().NoError(s.feedsRepo.SetPaused(user.ID, expired.ID, true, &past))
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	feed, _ := s.feedsRepo.FeedWithID(user.ID, paused.ID)
+	s.Equal("Paused", feed.Title)
+	s.True(feed.LastUpdated.IsZero())
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, expired.ID)
+	s.Equal("RSS Test", feed.Title)
+}
+
+func (s *SyncTestSuite) TestSyncUserNotModified() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_cached.xml",
+		Etag:         rssFeedTag,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal("Sync Test", feed.Title)
+	s.Equal(rssLastModified, feed.LastModified)
+	s.False(feed.LastUpdated.IsZero())
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SyncTestSuite) TestSyncSendsStoredValidators() {
+	var ifNoneMatch, ifModifiedSince atomic.Value
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch.Store(r.Header.Get("If-None-Match"))
+		ifModifiedSince.Store(r.Header.Get("If-Modified-Since"))
+
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: ts.URL + "/rss.xml",
+		Etag:         rssFeedTag,
+		LastModified: rssLastModified,
+		TTL:          90,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	// A new service has no previous fetch to take validators from
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	s.Equal(rssFeedTag, ifNoneMatch.Load())
+	s.Equal(rssLastModified, ifModifiedSince.Load())
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(models.FeedStatusOK, feed.Status)
+	s.Equal(http.StatusNotModified, feed.HTTPStatus)
+	s.Equal(rssFeedTag, feed.Etag)
+	s.Equal(90, feed.TTL)
+}
+
+func (s *SyncTestSuite) TestPullFeedWithRedirectLoop() {
+	_, _, err := utils.PullFeed(s.ts.URL+"/rss_loop.xml", "", "")
+	s.Error(err)
+}
+
+func (s *SyncTestSuite) TestSyncUserFollowsRedirects() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_moved.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	// Only the permanent redirect rewrites the subscription
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(s.ts.URL+"/rss_temp.xml", feed.Subscription)
+
+	events, _ := s.feedsRepo.ListEvents(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+	})
+	s.Require().Len(events, 1)
+	s.Equal(s.ts.URL+"/rss_moved.xml", events[0].From)
+	s.Equal(s.ts.URL+"/rss_temp.xml", events[0].To)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 5)
+}
+
+func (s *SyncTestSuite) TestPullFeedSchedulingHints() {
+	feed, _, err := utils.PullFeed(s.ts.URL+"/rss_schedule.xml?hour=0&hour=13&hour=24&day=Saturday", "", "")
+	s.Require().NoError(err)
+	s.Equal(120, feed.TTL)
+	s.Equal(1<<0|1<<13, feed.SkipHours)
+	s.Equal(1<<time.Saturday, feed.SkipDays)
+	s.Equal(2*time.Hour, feed.PostingInterval)
+	s.Equal(10*time.Minute, feed.MaxAge)
+}
+
+func (s *SyncTestSuite) TestPullUnavailableFeed() {
+	_, _, err := utils.PullFeed(s.ts.URL+"/rss_unavailable.xml", "", "")
+
+	var httpErr utils.HTTPError
+	s.Require().True(errors.As(err, &httpErr))
+	s.Equal(http.StatusServiceUnavailable, httpErr.StatusCode)
+	s.Equal(time.Hour, httpErr.RetryAfter)
+}
+
+func (s *SyncTestSuite) TestSyncUserSchedulesNextFetch() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	// The feed posts every two hours, but asks not to be fetched
+	// during the two hours that follow.
+	now := time.Now().UTC()
+	skip := fmt.Sprintf("hour=%d&hour=%d", now.Add(2*time.Hour).Hour(), now.Add(3*time.Hour).Hour())
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_schedule.xml?" + skip,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.False(feed.NextFetchAt.Before(now.Add(3 * time.Hour).Truncate(time.Hour).Add(time.Hour)))
+	s.True(feed.NextFetchAt.Before(now.Add(5 * time.Hour)))
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 3)
+
+	// The feed is not due, so syncing again does not fetch it
+	s.feedsRepo.Update(user.ID, &models.Feed{ID: feed.ID, Subscription: s.ts.URL + "/rss.xml"})
+
+	serv.SyncUser(user.ID)
+
+	entries, _ = s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 3)
+}
+
+func (s *SyncTestSuite) TestSyncUserClearsDroppedHints() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	// The feed used to ask not to be fetched at any time, but no longer does
+	feed := models.Feed{
+		ID:              utils.CreateID(),
+		Title:           "Sync Test",
+		Subscription:    s.ts.URL + "/rss_minimal.xml",
+		TTL:             120,
+		SkipHours:       1<<24 - 1,
+		SkipDays:        1<<7 - 1,
+		PostingInterval: 2 * time.Hour,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Zero(feed.TTL)
+	s.Zero(feed.SkipHours)
+	s.Zero(feed.SkipDays)
+	s.Zero(feed.PostingInterval)
+	s.True(feed.NextFetchAt.Before(time.Now().Add(2 * time.Hour)))
+}
+
+func (s *SyncTestSuite) TestSyncUserHonorsRetryAfter() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_unavailable.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	now := time.Now()
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.WithinDuration(now.Add(time.Hour), feed.NextFetchAt, time.Minute)
+}
+
+func (s *SyncTestSuite) TestSyncUserRecordsFailures() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_missing.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention,
+		sync.WithIntervalBounds(time.Minute, time.Hour))
+
+	now := time.Now()
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(models.FeedStatusError, feed.Status)
+	s.Equal(1, feed.ErrorCount)
+	s.Equal(http.StatusNotFound, feed.HTTPStatus)
+	s.Equal("server responded with status 404", feed.LastError)
+	s.WithinDuration(now.Add(time.Minute), feed.NextFetchAt, 5*time.Second)
+
+	// Every consecutive failure doubles the time until the next fetch
+	for i := 0; i < 2; i++ {
+		s.NoError(s.feedsRepo.UpdateStatus(user.ID, &models.Feed{
+			ID:         feed.ID,
+			Status:     feed.Status,
+			ErrorCount: feed.ErrorCount,
+			LastError:  feed.LastError,
+			HTTPStatus: feed.HTTPStatus,
+		}))
+
+		serv.SyncUser(user.ID)
+
+		feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	}
+
+	s.Equal(3, feed.ErrorCount)
+	s.WithinDuration(now.Add(4*time.Minute), feed.NextFetchAt, 5*time.Second)
+
+	// A successful fetch resets the error count
+	s.NoError(s.feedsRepo.UpdateStatus(user.ID, &models.Feed{ID: feed.ID, ErrorCount: feed.ErrorCount}))
+	s.NoError(s.feedsRepo.Update(user.ID, &models.Feed{ID: feed.ID, Subscription: s.ts.URL + "/rss.xml"}))
+
+	serv.SyncUser(user.ID)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusOK, feed.Status)
+	s.Zero(feed.ErrorCount)
+	s.Empty(feed.LastError)
+	s.Equal(http.StatusOK, feed.HTTPStatus)
+	s.WithinDuration(time.Now(), feed.LastUpdated, 5*time.Second)
+}
+
+func (s *SyncTestSuite) TestSyncUserDisablesFailingFeeds() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_missing.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	s.NoError(s.feedsRepo.UpdateStatus(user.ID, &models.Feed{
+		ID:         feed.ID,
+		Status:     models.FeedStatusError,
+		ErrorCount: 2,
+	}))
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithMaxErrors(3))
+
+	serv.SyncUser(user.ID)
+
+	feed, found := s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Require().True(found)
+	s.Equal(models.FeedStatusDisabled, feed.Status)
+	s.Equal(3, feed.ErrorCount)
+
+	events, _ := s.feedsRepo.ListEvents(user.ID, models.Page{FilterID: feed.ID, Count: 5})
+	s.Require().Len(events, 1)
+	s.Equal(models.FeedEventDisabled, events[0].Type)
+
+	// Disabled feeds are never fetched, even if they are due
+	s.NoError(s.feedsRepo.UpdateStatus(user.ID, &models.Feed{
+		ID:         feed.ID,
+		Status:     feed.Status,
+		ErrorCount: feed.ErrorCount,
+	}))
+
+	serv.SyncUser(user.ID)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(3, feed.ErrorCount)
+}
+
+func (s *SyncTestSuite) TestRefreshFeed() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	// Refreshing fetches the feed even if it is neither due nor enabled
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss.xml",
+		NextFetchAt:  time.Now().Add(time.Hour),
+		Status:       models.FeedStatusDisabled,
+		ErrorCount:   10,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	newEntries, err := serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+	s.Equal(5, newEntries)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusOK, feed.Status)
+	s.Zero(feed.ErrorCount)
+
+	newEntries, err = serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+	s.Zero(newEntries)
+}
+
+func (s *SyncTestSuite) TestSyncKeepsUserOverrides() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	ctg := models.Category{ID: utils.CreateID(), Name: "News"}
+	s.ctgsRepo.Create(user.ID, &ctg)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		DisplayTitle: "My Feed",
+		Notes:        "Checked daily",
+		Category:     ctg,
+		Subscription: s.ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	_, err := serv.RefreshFeed(user.ID, &feed)
+	s.Require().NoError(err)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal("RSS Test", feed.Title)
+	s.Equal("My Feed", feed.DisplayTitle)
+	s.Equal("Checked daily", feed.Notes)
+	s.Equal(ctg.ID, feed.Category.ID)
+}
+
+func (s *SyncTestSuite) TestRefreshMissingFeed() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_missing.xml",
+		Status:       models.FeedStatusDisabled,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
 
-		syncDBPath string
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
 
-		ts          *httptest.Server
-		db          *gorm.DB
-		ctgsRepo    repo.Categories
-		feedsRepo   repo.Feeds
-		usersRepo   repo.Users
-		entriesRepo repo.Entries
+	_, err := serv.RefreshFeed(user.ID, &feed)
+	s.EqualError(err, "server responded with status 404")
+
+	// A failed refresh does not enable the feed again
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusDisabled, feed.Status)
+	s.Equal(1, feed.ErrorCount)
+}
+
+func (s *SyncTestSuite) TestSyncSharesFetches() {
+	users := make([]*models.User, 2)
+	feeds := make([]models.Feed, 2)
+
+	// Both subscriptions point to the same feed
+	subscriptions := []string{s.ts.URL + "/rss_shared.xml", strings.ToUpper(s.ts.URL[:4]) + s.ts.URL[4:] +
+		"/rss_shared.xml#feed"}
+
+	for idx := range users {
+		users[idx] = &models.User{
+			ID:       utils.CreateID(),
+			Username: randStringRunes(8),
+		}
+		s.usersRepo.Create(users[idx])
+
+		feeds[idx] = models.Feed{
+			ID:           utils.CreateID(),
+			Title:        "Sync Test",
+			Subscription: subscriptions[idx],
+		}
+		s.feedsRepo.Create(users[idx].ID, &feeds[idx])
 	}
-)
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
 
-func randStringRunes(n int) string {
-	b := make([]rune, n)
+	fetches := atomic.LoadInt32(&s.sharedFetches)
 
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+	for idx := range users {
+		serv.SyncUser(users[idx].ID)
 	}
 
-	return string(b)
-}
+	s.Equal(fetches+1, atomic.LoadInt32(&s.sharedFetches))
 
-func (s *SyncTestSuite) TestPullUnreachableFeed() {
-	_, _, err := utils.PullFeed("Sync Test", s.ts.URL+"/bogus.xml")
-	s.Error(err)
+	entries := make([][]models.Entry, len(users))
+
+	for idx := range users {
+		entries[idx], _ = s.entriesRepo.ListFromFeed(users[idx].ID, models.Page{
+			FilterID: feeds[idx].ID,
+			Count:    10,
+			Marker:   models.MarkerAny,
+		})
+		s.Require().Len(entries[idx], 5)
+	}
+
+	// Every user keeps their own copy of the entries
+	s.NotEqual(entries[0][0].ID, entries[1][0].ID)
+	s.NoError(s.entriesRepo.Mark(users[0].ID, entries[0][0].ID, models.MarkerRead))
+
+	entry, found := s.entriesRepo.EntryWithID(users[1].ID, entries[1][0].ID)
+	s.Require().True(found)
+	s.Equal(models.MarkerUnread, entry.Mark)
+
+	// Refreshing a feed does not reuse the shared fetch
+	_, err := serv.RefreshFeed(users[0].ID, &feeds[0])
+	s.NoError(err)
+	s.Equal(fetches+2, atomic.LoadInt32(&s.sharedFetches))
 }
 
-func (s *SyncTestSuite) TestPullFeedWithBadSubscription() {
-	_, _, err := utils.PullFeed("Sync Test", "bogus")
-	s.Error(err)
+func (s *SyncTestSuite) TestSyncAndRefreshAddEntriesOnce() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_slow.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	// The scheduled sync and the refresh share the slow fetch and add its
+	// entries at the same time
+	synced := make(chan struct{})
+
+	go func() {
+		serv.SyncUser(user.ID)
+		close(synced)
+	}()
+
+	refreshed := feed
+
+	_, err := serv.RefreshFeed(user.ID, &refreshed)
+	s.NoError(err)
+
+	<-synced
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    20,
+		Marker:   models.MarkerAny,
+	})
+
+	guids := map[string]bool{}
+	for _, entry := range entries {
+		s.False(guids[entry.GUID], entry.GUID)
+		guids[entry.GUID] = true
+	}
+
+	s.Len(guids, 5)
 }
 
 func (s *SyncTestSuite) TestSyncWithEtags() {
@@ -174,7 +888,7 @@ func (s *SyncTestSuite) TestSyncWithEtags() {
 
 	s.feedsRepo.Create(user.ID, &feed)
 
-	_, entries, err := utils.PullFeed(feed.Subscription, "")
+	_, entries, err := utils.PullFeed(feed.Subscription, "", "")
 	s.Require().NoError(err)
 	s.Require().Len(entries, 5)
 
@@ -182,7 +896,7 @@ func (s *SyncTestSuite) TestSyncWithEtags() {
 		s.entriesRepo.Create(user.ID, &entries[idx])
 	}
 
-	serv := sync.NewService(s.feedsRepo, s.usersRepo, s.entriesRepo)
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
 
 	serv.SyncUser(user.ID)
 
@@ -210,7 +924,7 @@ func (s *SyncTestSuite) TestSyncUser() {
 	}
 	s.feedsRepo.Create(user.ID, &feed)
 
-	serv := sync.NewService(s.feedsRepo, s.usersRepo, s.entriesRepo)
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
 
 	serv.SyncUser(user.ID)
 
@@ -221,11 +935,74 @@ func (s *SyncTestSuite) TestSyncUser() {
 		Newest:         true,
 		Marker:         models.MarkerAny,
 	})
-	s.Len(entries, 5)
+	s.Require().Len(entries, 5)
+	s.Equal("Single test item", entries[0].Summary)
+}
+
+func (s *SyncTestSuite) TestSyncSanitizesContent() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_html.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    1,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(
+		`<p>Read <a href="http://localhost:8090/blog/post" rel="noopener noreferrer">more</a></p>`,
+		entries[0].Summary,
+	)
+}
+
+func (s *SyncTestSuite) TestSyncResolvesXMLBaseAgainstDocument() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/feeds/rss_relative_base.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.SyncUser(user.ID)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    1,
+		Marker:   models.MarkerAny,
+	})
+	s.Require().Len(entries, 1)
+	s.Equal(
+		`<p>Read <a href="`+s.ts.URL+`/feeds/posts/post" rel="noopener noreferrer">more</a></p>`,
+		entries[0].Summary,
+	)
 }
 
 func (s *SyncTestSuite) TestSyncService() {
-	for i := 0; i < 10; i++ {
+	userIDs := make([]string, 10)
+
+	for i := range userIDs {
 		user := models.User{
 			ID:       utils.CreateID(),
 			Username: "test" + strconv.Itoa(i),
@@ -238,9 +1015,11 @@ func (s *SyncTestSuite) TestSyncService() {
 			Subscription: s.ts.URL + "/rss_minimal.xml",
 		}
 		s.feedsRepo.Create(user.ID, &feed)
+
+		userIDs[i] = user.ID
 	}
 
-	serv := sync.NewService(s.feedsRepo, s.usersRepo, s.entriesRepo)
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
 
 	serv.Start(time.Second)
 
@@ -248,22 +1027,173 @@ func (s *SyncTestSuite) TestSyncService() {
 
 	serv.Stop()
 
-	users, _ := s.usersRepo.List(models.Page{
-		ContinuationID: "",
-		Count:          10,
-	})
-
-	s.Require().Len(users, 10)
-
-	for idx := range users {
-		entries, _ := s.entriesRepo.List(users[idx].ID, models.Page{
+	for _, userID := range userIDs {
+		entries, _ := s.entriesRepo.List(userID, models.Page{
 			ContinuationID: "",
 			Count:          100,
 			Newest:         true,
 			Marker:         models.MarkerAny,
 		})
-		s.Len(entries, 5, "Entries are missing for user with id %s", users[idx].ID)
+		s.Len(entries, 5, "Entries are missing for user with id %s", userID)
+	}
+}
+
+// unleasableFeeds fails to write feeds, which leaves due feeds due
+type unleasableFeeds struct {
+	repo.Feeds
+}
+
+func (unleasableFeeds) Update(userID string, feed *models.Feed) error {
+	return errors.New("database is locked")
+}
+
+func (s *SyncTestSuite) TestSyncServiceSkipsFeedsItCannotLease() {
+	var fetches int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+
+		if _, err := fmt.Fprint(w, rssMinimalFile); err != nil {
+			panic(err)
+		}
+	}))
+	defer ts.Close()
+
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: ts.URL + "/rss.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(unleasableFeeds{s.feedsRepo}, s.entriesRepo, s.retention)
+
+	serv.Start(time.Second)
+
+	time.Sleep(time.Second + (time.Millisecond * 500))
+
+	stopped := make(chan struct{})
+
+	go func() {
+		serv.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		s.FailNow("the service kept listing the same due feeds")
+	}
+
+	s.Zero(atomic.LoadInt32(&fetches))
+}
+
+func (s *SyncTestSuite) TestSyncServicePrunesEntries() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_minimal.xml",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	expired := models.Entry{
+		ID:        utils.CreateID(),
+		CreatedAt: time.Now().AddDate(0, 0, -40),
+		Title:     "Expired",
+		Feed:      feed,
+		Mark:      models.MarkerRead,
+		Published: time.Now().AddDate(0, 0, -40),
+	}
+	s.entriesRepo.Create(user.ID, &expired)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+
+	serv.Start(time.Second)
+
+	time.Sleep(time.Second + (time.Millisecond * 500))
+
+	serv.Stop()
+
+	_, found := s.entriesRepo.EntryWithID(user.ID, expired.ID)
+	s.False(found)
+}
+
+func (s *SyncTestSuite) TestSyncServicePrunesFeedsThatAreNotFetched() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: s.ts.URL + "/rss_minimal.xml",
+		Paused:       true,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	expired := models.Entry{
+		ID:        utils.CreateID(),
+		CreatedAt: time.Now().AddDate(0, 0, -40),
+		Title:     "Expired",
+		Feed:      feed,
+		Mark:      models.MarkerRead,
+		Published: time.Now().AddDate(0, 0, -40),
 	}
+	s.entriesRepo.Create(user.ID, &expired)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, sync.WithPruning(s.usersRepo, time.Hour))
+
+	serv.Start(time.Second)
+
+	time.Sleep(time.Second + (time.Millisecond * 500))
+
+	serv.Stop()
+
+	_, found := s.entriesRepo.EntryWithID(user.ID, expired.ID)
+	s.False(found)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SyncTestSuite) TestSyncRecordsBlockedFeed() {
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Blocked",
+		Subscription: "http://169.254.169.254/latest/meta-data/",
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv := sync.NewService(s.feedsRepo, s.entriesRepo, s.retention)
+	serv.SyncUser(user.ID)
+
+	feed, _ = s.feedsRepo.FeedWithID(user.ID, feed.ID)
+	s.Equal(models.FeedStatusError, feed.Status)
+	s.Contains(feed.LastError, "address is not allowed")
 }
 
 func (s *SyncTestSuite) SetupTest() {
@@ -271,8 +1201,9 @@ func (s *SyncTestSuite) SetupTest() {
 
 	s.usersRepo = sql.NewUsers(s.db)
 	s.ctgsRepo = sql.NewCategories(s.db)
-	s.feedsRepo = sql.NewFeeds(s.db)
+	s.feedsRepo = sql.NewFeeds(s.db, sql.WithCredentialsSecret("secret"))
 	s.entriesRepo = sql.NewEntries(s.db)
+	s.retention = services.NewRetentionService(30, s.feedsRepo, s.entriesRepo)
 }
 
 func (s *SyncTestSuite) SetupSuite() {
@@ -286,11 +1217,16 @@ func (s *SyncTestSuite) SetupSuite() {
 
 	s.db, err = gorm.Open("sqlite3", s.syncDBPath)
 	s.Require().NoError(err)
+
+	// Test servers listen on loopback addresses
+	s.Require().NoError(utils.ConfigureFetcher(loopbackFetcherConfig))
 }
 
 func (s *SyncTestSuite) TearDownSuite() {
 	s.NoError(s.db.Close())
 
+	s.NoError(utils.ConfigureFetcher(utils.FetcherConfig{}))
+
 	s.NoError(os.Remove(s.syncDBPath))
 }
 
@@ -300,6 +1236,111 @@ func TestSyncTestSuite(t *testing.T) {
 	syncSuite.ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var resp string
 
+		switch r.URL.Path {
+		case "/rss_moved.xml":
+			http.Redirect(w, r, "/rss_temp.xml", http.StatusMovedPermanently)
+			return
+		case "/rss_temp.xml":
+			http.Redirect(w, r, "/rss.xml", http.StatusFound)
+			return
+		case "/rss_loop.xml":
+			http.Redirect(w, r, "/rss_loop.xml", http.StatusMovedPermanently)
+			return
+		case "/rss_missing.xml":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/rss_unavailable.xml":
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "/rss_shared.xml":
+			atomic.AddInt32(&syncSuite.sharedFetches, 1)
+
+			if _, err := fmt.Fprint(w, rssFile); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_websub.xml":
+			self := "http://" + r.Host + r.URL.String()
+			if _, err := fmt.Fprintf(w, rssWebSubFile, r.URL.Query().Get("hub"), self,
+				"<item><guid>websub1@test</guid><title>First</title></item>"); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_websub_header.xml":
+			w.Header().Add("Link", `<https://hub.example.com/>; rel="hub", <https://example.com/feed>; rel="self"`)
+
+			if _, err := fmt.Fprintf(w, rssWebSubFile, "https://other.example.com/", "https://example.com/other", ""); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_private.xml":
+			if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Cookie") != "session=1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if _, err := fmt.Fprint(w, rssFile); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/changelog.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+			if _, err := fmt.Fprint(w, changelogPage); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_slow.xml":
+			time.Sleep(500 * time.Millisecond)
+
+			if _, err := fmt.Fprint(w, rssFile); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/feeds/rss_relative_base.xml":
+			if _, err := fmt.Fprint(w, strings.Replace(rssHTMLFile,
+				`xml:base="http://localhost:8090/blog/"`, `xml:base="posts/"`, 1)); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_schedule.xml":
+			var hours, days string
+
+			for _, hour := range r.URL.Query()["hour"] {
+				hours += "<hour>" + hour + "</hour>"
+			}
+
+			for _, day := range r.URL.Query()["day"] {
+				days += "<day>" + day + "</day>"
+			}
+
+			w.Header().Set("Cache-Control", "public, max-age=600")
+
+			if _, err := fmt.Fprintf(w, rssScheduleFile, hours, days); err != nil {
+				panic(err)
+			}
+
+			return
+		}
+
+		if r.URL.Path == "/rss_cached.xml" {
+			w.Header().Set("ETag", rssFeedTag)
+			w.Header().Set("Last-Modified", rssLastModified)
+
+			if r.Header.Get("If-Modified-Since") == rssLastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
 		match := r.Header.Get("If-None-Match")
 
 		if match == rssFeedTag {
@@ -308,10 +1349,12 @@ func TestSyncTestSuite(t *testing.T) {
 		}
 
 		switch r.URL.Path {
-		case "/rss.xml":
+		case "/rss.xml", "/rss_cached.xml":
 			resp = rssFile
 		case "/rss_minimal.xml":
 			resp = rssMinimalFile
+		case "/rss_html.xml":
+			resp = rssHTMLFile
 		default:
 			resp = ""
 		}
@@ -0,0 +1,378 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+const (
+	webSubSecretBytes = 32
+	webSubRenewBatch  = 100
+
+	// webSubDefaultLease is assumed when a hub does not state the lease it grants
+	webSubDefaultLease = time.Hour * 24
+
+	// webSubRetryInterval is how long a subscription request may go unverified
+	// before it is sent again
+	webSubRetryInterval = time.Hour
+
+	// webSubDeniedInterval is how long to wait before subscribing to a topic again
+	// after the hub denied it
+	webSubDeniedInterval = time.Hour * 24
+)
+
+var signatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// WebSubSubscriber subscribes to the WebSub hubs feeds advertise so that
+// their updates are pushed instead of polled. Pushed content is added to
+// feeds the same way the Service adds fetched content.
+type WebSubSubscriber struct {
+	callbackURL string
+	websubRepo  repo.WebSub
+
+	service *Service
+}
+
+// NewWebSubSubscriber creates a WebSubSubscriber. Hubs reach the subscriber
+// through callbackURL, the public URL of the server.
+func NewWebSubSubscriber(callbackURL string, websubRepo repo.WebSub) *WebSubSubscriber {
+	return &WebSubSubscriber{
+		callbackURL: strings.TrimRight(callbackURL, "/"),
+		websubRepo:  websubRepo,
+	}
+}
+
+// WithWebSub subscribes to the hubs of feeds with subscriber
+func WithWebSub(subscriber *WebSubSubscriber) Option {
+	return func(s *Service) {
+		s.websub = subscriber
+		subscriber.service = s
+	}
+}
+
+// Verify confirms a subscription request made to a hub and returns the
+// challenge sent by the hub. Hubs can also deny a request.
+func (w *WebSubSubscriber) Verify(id, mode, topic, challenge string, leaseSeconds int) (string, error) {
+	sub, found := w.websubRepo.SubscriptionWithID(id)
+	if !found || sub.Topic != topic {
+		return "", services.ErrWebSubNotFound
+	}
+
+	now := time.Now()
+
+	switch mode {
+	case "subscribe":
+		lease := time.Duration(leaseSeconds) * time.Second
+		if lease <= 0 {
+			lease = webSubDefaultLease
+		}
+
+		sub.State = models.WebSubActive
+		sub.ExpiresAt = now.Add(lease)
+		sub.RenewAt = now.Add(lease * 9 / 10)
+	case "denied":
+		sub.State = models.WebSubDenied
+		sub.ExpiresAt = time.Time{}
+		sub.RenewAt = now.Add(webSubDeniedInterval)
+	case "unsubscribe":
+		// Only subscriptions that are no longer wanted can be cancelled
+		if sub.State != models.WebSubUnsubscribing {
+			return "", services.ErrWebSubNotFound
+		}
+
+		if err := w.websubRepo.Delete(sub.ID); err != nil {
+			return "", err
+		}
+
+		return challenge, nil
+	default:
+		return "", services.ErrWebSubNotFound
+	}
+
+	if err := w.websubRepo.Update(&sub); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// Deliver adds the entries of content pushed by a hub to every regular feed
+// subscribed to its topic that is not paused. Content that was not signed with
+// the subscription's secret, or pushed to a subscription that is being cancelled,
+// is rejected.
+func (w *WebSubSubscriber) Deliver(id, signature string, body []byte) error {
+	sub, found := w.websubRepo.SubscriptionWithID(id)
+	if !found || sub.State == models.WebSubUnsubscribing {
+		return services.ErrWebSubNotFound
+	}
+
+	if !validSignature(sub.Secret, signature, body) {
+		return services.ErrWebSubSignature
+	}
+
+	pushedFeed, entries, err := utils.ParseFeed(body)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	feeds := w.subscribers(&sub)
+	for idx := range feeds {
+		if paused(&feeds[idx], now) {
+			continue
+		}
+
+		userID := feeds[idx].UserID
+
+		w.deliver(userID, &feeds[idx], &pushedFeed, entries)
+	}
+
+	return nil
+}
+
+// deliver adds the pushed entries that a subscriber feed does not have yet
+func (w *WebSubSubscriber) deliver(userID string, feed, pushedFeed *models.Feed, entries []models.Entry) {
+	unlock := w.service.lockFeed(feed.ID)
+	defer unlock()
+
+	ingest, err := w.service.ingest(userID, feed, pushedFeed, entries)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	ingest.Store(w.service.entriesRepo, w.service.events, userID, feed)
+}
+
+// subscribe makes sure that the topic of a feed fetched from url is subscribed
+// to at the hub it advertises. It reports whether the feed's updates are pushed.
+func (w *WebSubSubscriber) subscribe(url string, feed *models.Feed) bool {
+	topic := feed.Topic
+	if topic == "" {
+		topic = url
+	}
+
+	sub, found := w.websubRepo.SubscriptionWithTopic(topic)
+	if found && sub.Hub == feed.Hub && sub.State != models.WebSubUnsubscribing {
+		return active(&sub)
+	}
+
+	if !found {
+		sub = models.WebSubSubscription{
+			ID:     utils.CreateID(),
+			Topic:  topic,
+			URL:    url,
+			Secret: newWebSubSecret(),
+			State:  models.WebSubPending,
+		}
+		w.websubRepo.Create(&sub)
+	}
+
+	// The topic moved to another hub or is wanted again
+	sub.Hub = feed.Hub
+	sub.URL = url
+	sub.State = models.WebSubPending
+
+	if err := w.request(&sub, "subscribe"); err != nil {
+		log.Error(err)
+		return false
+	}
+
+	// Hubs may verify requests before responding to them
+	sub, _ = w.websubRepo.SubscriptionWithID(sub.ID)
+
+	return active(&sub)
+}
+
+// Release cancels the subscriptions that push content to the feeds subscribed
+// to url once no regular feed is subscribed to it anymore
+func (w *WebSubSubscriber) Release(url string) {
+	subs := w.websubRepo.ListWithURL(url)
+
+	for idx := range subs {
+		if subs[idx].State != models.WebSubUnsubscribing {
+			w.release(&subs[idx])
+		}
+	}
+}
+
+// release cancels sub if no regular feed is subscribed to it. It reports
+// whether sub was cancelled.
+func (w *WebSubSubscriber) release(sub *models.WebSubSubscription) bool {
+	if len(w.subscribers(sub)) != 0 {
+		return false
+	}
+
+	sub.State = models.WebSubUnsubscribing
+
+	if err := w.request(sub, "unsubscribe"); err != nil {
+		log.Error(err)
+	}
+
+	return true
+}
+
+// renew requests the subscriptions that are due again. Subscriptions that are
+// no longer wanted are cancelled instead and deleted once their lease ends.
+func (w *WebSubSubscriber) renew(now time.Time) {
+	subs := w.websubRepo.ListDue(now, webSubRenewBatch)
+
+	for idx := range subs {
+		sub := &subs[idx]
+
+		switch {
+		case sub.State == models.WebSubUnsubscribing && !now.Before(sub.ExpiresAt):
+			if err := w.websubRepo.Delete(sub.ID); err != nil {
+				log.Error(err)
+			}
+		case sub.State == models.WebSubUnsubscribing:
+			if err := w.request(sub, "unsubscribe"); err != nil {
+				log.Error(err)
+			}
+		case !w.release(sub):
+			if err := w.request(sub, "subscribe"); err != nil {
+				log.Error(err)
+			}
+		}
+	}
+}
+
+// request asks the hub of sub to subscribe to its topic, or to unsubscribe
+// from it, depending on mode. The request is sent again after
+// webSubRetryInterval unless the hub verifies it.
+func (w *WebSubSubscriber) request(sub *models.WebSubSubscription, mode string) error {
+	sub.RenewAt = time.Now().Add(webSubRetryInterval)
+
+	// The hub may verify the request before responding to it, so the
+	// subscription must be stored first
+	if err := w.websubRepo.Update(sub); err != nil {
+		return err
+	}
+
+	// Hubs are advertised by feeds, so they are requested with the same
+	// restrictions as feeds
+	resp, err := utils.NewHTTPClient().PostForm(sub.Hub, url.Values{
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {w.callbackURL + "/v1/websub/" + sub.ID},
+		"hub.secret":   {sub.Secret},
+	})
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err = resp.Body.Close(); err != nil {
+			log.Warn(err)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("hub %s refused to %s %s with status %d", sub.Hub, mode, sub.Topic, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// subscribers returns the regular feeds, of all users, that content pushed to
+// sub is added to. Feeds that moved to the topic of sub are included.
+func (w *WebSubSubscriber) subscribers(sub *models.WebSubSubscription) []models.Feed {
+	feeds := w.service.feedsRepo.ListWithSubscription(sub.URL)
+	if utils.NormalizeURL(sub.Topic) != utils.NormalizeURL(sub.URL) {
+		feeds = append(feeds, w.service.feedsRepo.ListWithSubscription(sub.Topic)...)
+	}
+
+	subscribers := feeds[:0]
+
+	for idx := range feeds {
+		if pushable(&feeds[idx]) {
+			subscribers = append(subscribers, feeds[idx])
+		}
+	}
+
+	return subscribers
+}
+
+// pushable reports whether pushed content can be added to feed. Entries of
+// scraper and plugin feeds are not taken from feed documents.
+func pushable(feed *models.Feed) bool {
+	return feed.Kind == ""
+}
+
+func active(sub *models.WebSubSubscription) bool {
+	return sub.State == models.WebSubActive && time.Now().Before(sub.ExpiresAt)
+}
+
+// validSignature checks an X-Hub-Signature header of the form method=signature
+func validSignature(secret, header string, body []byte) bool {
+	parts := strings.SplitN(header, "=", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	newHash, ok := signatureHashes[strings.ToLower(parts[0])]
+	if !ok {
+		return false
+	}
+
+	signature, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+func newWebSubSecret() string {
+	secret := make([]byte, webSubSecretBytes)
+
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(secret)
+}
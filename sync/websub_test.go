@@ -0,0 +1,518 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package sync_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/jmartinezhern/syndication/controller/rest"
+	"github.com/jmartinezhern/syndication/models"
+	"github.com/jmartinezhern/syndication/repo/sql"
+	"github.com/jmartinezhern/syndication/services"
+	"github.com/jmartinezhern/syndication/sync"
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type (
+	// stubHub is a WebSub hub that verifies subscription requests before
+	// responding to them and publishes content on demand
+	stubHub struct {
+		*httptest.Server
+
+		mu       gosync.Mutex
+		requests []url.Values
+		deny     bool
+	}
+)
+
+func newStubHub() *stubHub {
+	hub := &stubHub{}
+
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		hub.mu.Lock()
+		hub.requests = append(hub.requests, r.PostForm)
+		deny := hub.deny
+		hub.mu.Unlock()
+
+		query := url.Values{
+			"hub.topic": {r.PostForm.Get("hub.topic")},
+		}
+
+		switch {
+		case deny:
+			query.Set("hub.mode", "denied")
+		case r.PostForm.Get("hub.mode") == "unsubscribe":
+			query.Set("hub.mode", "unsubscribe")
+			query.Set("hub.challenge", "challenge")
+		default:
+			query.Set("hub.mode", "subscribe")
+			query.Set("hub.challenge", "challenge")
+			query.Set("hub.lease_seconds", "86400")
+		}
+
+		resp, err := http.Get(r.PostForm.Get("hub.callback") + "?" + query.Encode())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		defer resp.Body.Close()
+
+		challenge, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK || (!deny && string(challenge) != "challenge") {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	return hub
+}
+
+func (h *stubHub) lastRequest() url.Values {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.requests) == 0 {
+		return nil
+	}
+
+	return h.requests[len(h.requests)-1]
+}
+
+func (h *stubHub) requestCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.requests)
+}
+
+// publish pushes content to the last subscriber, signed with secret
+func (h *stubHub) publish(content, secret string) (int, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(content))
+
+	req, err := http.NewRequest(http.MethodPost, h.lastRequest().Get("hub.callback"), strings.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/rss+xml")
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.StatusCode, resp.Body.Close()
+}
+
+func (s *SyncTestSuite) newWebSubService(opts ...sync.Option) (*sync.Service, *httptest.Server) {
+	serv, _, callback := s.newWebSubSubscriber(opts...)
+
+	return serv, callback
+}
+
+func (s *SyncTestSuite) newWebSubSubscriber(opts ...sync.Option) (
+	*sync.Service, *sync.WebSubSubscriber, *httptest.Server) {
+	e := echo.New()
+	e.HideBanner = true
+
+	callback := httptest.NewServer(e)
+
+	subscriber := sync.NewWebSubSubscriber(callback.URL, sql.NewWebSub(s.db))
+	rest.NewWebSubController(subscriber, e)
+
+	opts = append(opts, sync.WithWebSub(subscriber))
+
+	return sync.NewService(s.feedsRepo, s.entriesRepo, s.retention, opts...), subscriber, callback
+}
+
+func (s *SyncTestSuite) TestPullFeedWebSubLinks() {
+	feed, _, err := utils.PullFeed(s.ts.URL+"/rss_websub.xml?hub=https://hub.example.com/", "", "")
+	s.Require().NoError(err)
+	s.Equal("https://hub.example.com/", feed.Hub)
+	s.Equal(s.ts.URL+"/rss_websub.xml?hub=https://hub.example.com/", feed.Topic)
+
+	// Link headers take precedence over the document
+	feed, _, err = utils.PullFeed(s.ts.URL+"/rss_websub_header.xml", "", "")
+	s.Require().NoError(err)
+	s.Equal("https://hub.example.com/", feed.Hub)
+	s.Equal("https://example.com/feed", feed.Topic)
+}
+
+func (s *SyncTestSuite) TestWebSubPushesEntries() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	serv, callback := s.newWebSubService(sync.WithIntervalBounds(time.Minute, time.Hour))
+	defer callback.Close()
+
+	subscription := s.ts.URL + "/rss_websub.xml?hub=" + url.QueryEscape(hub.URL)
+
+	users := make([]*models.User, 2)
+	feeds := make([]models.Feed, 2)
+
+	for idx := range users {
+		users[idx] = &models.User{
+			ID:       utils.CreateID(),
+			Username: randStringRunes(8),
+		}
+		s.usersRepo.Create(users[idx])
+
+		feeds[idx] = models.Feed{
+			ID:           utils.CreateID(),
+			Title:        "Sync Test",
+			Subscription: subscription,
+		}
+		s.feedsRepo.Create(users[idx].ID, &feeds[idx])
+	}
+
+	now := time.Now()
+
+	for idx := range users {
+		serv.SyncUser(users[idx].ID)
+	}
+
+	// The topic is subscribed to once for every subscriber
+	s.Require().Equal(1, hub.requestCount())
+
+	request := hub.lastRequest()
+	s.Equal("subscribe", request.Get("hub.mode"))
+	s.Equal(subscription, request.Get("hub.topic"))
+	s.True(strings.HasPrefix(request.Get("hub.callback"), callback.URL+"/v1/websub/"))
+
+	sub, found := sql.NewWebSub(s.db).SubscriptionWithTopic(subscription)
+	s.Require().True(found)
+	s.Equal(models.WebSubActive, sub.State)
+	s.Equal(request.Get("hub.secret"), sub.Secret)
+
+	// Pushed feeds are only polled as a fallback
+	for idx := range users {
+		feed, _ := s.feedsRepo.FeedWithID(users[idx].ID, feeds[idx].ID)
+		s.WithinDuration(now.Add(time.Hour), feed.NextFetchAt, 5*time.Second)
+	}
+
+	content := fmt.Sprintf(rssWebSubFile, hub.URL, subscription,
+		"<item><guid>websub2@test</guid><title>Pushed</title></item>")
+
+	// Content that is not signed with the subscription's secret is ignored
+	status, err := hub.publish(content, "bogus")
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, status)
+
+	for idx := range users {
+		entries, _ := s.entriesRepo.ListFromFeed(users[idx].ID, models.Page{
+			FilterID: feeds[idx].ID,
+			Count:    5,
+			Marker:   models.MarkerAny,
+		})
+		s.Len(entries, 1)
+	}
+
+	status, err = hub.publish(content, sub.Secret)
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, status)
+
+	for idx := range users {
+		entries, _ := s.entriesRepo.ListFromFeed(users[idx].ID, models.Page{
+			FilterID: feeds[idx].ID,
+			Count:    5,
+			Marker:   models.MarkerAny,
+		})
+		s.Require().Len(entries, 2)
+
+		titles := []string{entries[0].Title, entries[1].Title}
+		s.ElementsMatch([]string{"First", "Pushed"}, titles)
+	}
+}
+
+func (s *SyncTestSuite) TestWebSubDenied() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	hub.deny = true
+
+	serv, callback := s.newWebSubService()
+	defer callback.Close()
+
+	subscription := s.ts.URL + "/rss_websub.xml?denied&hub=" + url.QueryEscape(hub.URL)
+
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Title:        "Sync Test",
+		Subscription: subscription,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv.SyncUser(user.ID)
+
+	sub, found := sql.NewWebSub(s.db).SubscriptionWithTopic(subscription)
+	s.Require().True(found)
+	s.Equal(models.WebSubDenied, sub.State)
+	s.True(sub.RenewAt.After(time.Now().Add(time.Hour)))
+}
+
+func (s *SyncTestSuite) TestWebSubRenewsSubscriptions() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	serv, callback := s.newWebSubService(sync.WithIntervalBounds(10*time.Millisecond, time.Hour))
+	defer callback.Close()
+
+	websubRepo := sql.NewWebSub(s.db)
+
+	sub := models.WebSubSubscription{
+		ID:        utils.CreateID(),
+		Topic:     s.ts.URL + "/rss_websub.xml?renew",
+		URL:       s.ts.URL + "/rss_websub.xml?renew",
+		Hub:       hub.URL,
+		Secret:    "secret",
+		State:     models.WebSubActive,
+		ExpiresAt: time.Now().Add(time.Minute),
+		RenewAt:   time.Now().Add(-time.Minute),
+	}
+	websubRepo.Create(&sub)
+
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	// The feed is not due, so only its subscription is renewed
+	s.feedsRepo.Create(user.ID, &models.Feed{
+		ID:           utils.CreateID(),
+		Subscription: sub.URL,
+		NextFetchAt:  time.Now().Add(time.Hour),
+	})
+
+	serv.Start(time.Hour)
+	defer serv.Stop()
+
+	s.Eventually(func() bool {
+		sub, _ = websubRepo.SubscriptionWithID(sub.ID)
+		return sub.ExpiresAt.After(time.Now().Add(time.Hour))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.Equal(sub.Topic, hub.lastRequest().Get("hub.topic"))
+	s.True(sub.RenewAt.After(time.Now().Add(time.Hour)))
+}
+
+func (s *SyncTestSuite) TestWebSubCancelsUnwantedSubscriptions() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	serv, callback := s.newWebSubService(sync.WithIntervalBounds(10*time.Millisecond, time.Hour))
+	defer callback.Close()
+
+	websubRepo := sql.NewWebSub(s.db)
+
+	// No feed is subscribed to the topic anymore
+	sub := models.WebSubSubscription{
+		ID:        utils.CreateID(),
+		Topic:     s.ts.URL + "/rss_websub.xml?unwanted",
+		URL:       s.ts.URL + "/rss_websub.xml?unwanted",
+		Hub:       hub.URL,
+		Secret:    "secret",
+		State:     models.WebSubActive,
+		ExpiresAt: time.Now().Add(time.Minute),
+		RenewAt:   time.Now().Add(-time.Minute),
+	}
+	websubRepo.Create(&sub)
+
+	serv.Start(time.Hour)
+	defer serv.Stop()
+
+	s.Eventually(func() bool {
+		_, found := websubRepo.SubscriptionWithID(sub.ID)
+		return !found
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.Equal("unsubscribe", hub.lastRequest().Get("hub.mode"))
+	s.Equal(sub.Topic, hub.lastRequest().Get("hub.topic"))
+}
+
+func (s *SyncTestSuite) TestWebSubReleasesDeletedFeeds() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	serv, subscriber, callback := s.newWebSubSubscriber()
+	defer callback.Close()
+
+	feedsService := services.NewFeedsService(s.feedsRepo, s.ctgsRepo, s.entriesRepo, services.WithWebSub(subscriber))
+
+	subscription := s.ts.URL + "/rss_websub.xml?release&hub=" + url.QueryEscape(hub.URL)
+
+	users := make([]*models.User, 2)
+	feeds := make([]models.Feed, 2)
+
+	for idx := range users {
+		users[idx] = &models.User{
+			ID:       utils.CreateID(),
+			Username: randStringRunes(8),
+		}
+		s.usersRepo.Create(users[idx])
+
+		feeds[idx] = models.Feed{
+			ID:           utils.CreateID(),
+			Subscription: subscription,
+		}
+		s.feedsRepo.Create(users[idx].ID, &feeds[idx])
+
+		serv.SyncUser(users[idx].ID)
+	}
+
+	websubRepo := sql.NewWebSub(s.db)
+
+	sub, found := websubRepo.SubscriptionWithTopic(subscription)
+	s.Require().True(found)
+	s.Equal(models.WebSubActive, sub.State)
+
+	// The topic is kept as long as a feed is subscribed to it
+	s.NoError(feedsService.Delete(users[0].ID, feeds[0].ID))
+	s.Equal("subscribe", hub.lastRequest().Get("hub.mode"))
+
+	_, found = websubRepo.SubscriptionWithID(sub.ID)
+	s.True(found)
+
+	// Moving the last feed cancels the subscription
+	s.NoError(feedsService.Update(users[1].ID, &models.Feed{ID: feeds[1].ID, Subscription: s.ts.URL + "/rss.xml"}))
+	s.Equal("unsubscribe", hub.lastRequest().Get("hub.mode"))
+	s.Equal(subscription, hub.lastRequest().Get("hub.topic"))
+
+	_, found = websubRepo.SubscriptionWithID(sub.ID)
+	s.False(found)
+}
+
+func (s *SyncTestSuite) TestWebSubPushesToRegularFeedsOnly() {
+	hub := newStubHub()
+	defer hub.Close()
+
+	serv, callback := s.newWebSubService()
+	defer callback.Close()
+
+	subscription := s.ts.URL + "/rss_websub.xml?regular&hub=" + url.QueryEscape(hub.URL)
+
+	user := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(user)
+
+	feed := models.Feed{
+		ID:           utils.CreateID(),
+		Subscription: subscription,
+	}
+	s.feedsRepo.Create(user.ID, &feed)
+
+	serv.SyncUser(user.ID)
+
+	// A scraper feed of the same page must not ingest the pushed document
+	other := &models.User{
+		ID:       utils.CreateID(),
+		Username: randStringRunes(8),
+	}
+	s.usersRepo.Create(other)
+
+	scraper := models.Feed{
+		ID:           utils.CreateID(),
+		Subscription: subscription,
+		Kind:         models.FeedKindScraper,
+		Selectors:    models.ScraperSelectors{Item: "item"},
+		NextFetchAt:  time.Now().Add(time.Hour),
+	}
+	s.feedsRepo.Create(other.ID, &scraper)
+
+	sub, found := sql.NewWebSub(s.db).SubscriptionWithTopic(subscription)
+	s.Require().True(found)
+
+	content := fmt.Sprintf(rssWebSubFile, hub.URL, subscription,
+		"<item><guid>websub3@test</guid><title>Pushed</title></item>")
+
+	status, err := hub.publish(content, sub.Secret)
+	s.Require().NoError(err)
+	s.Equal(http.StatusNoContent, status)
+
+	entries, _ := s.entriesRepo.ListFromFeed(user.ID, models.Page{
+		FilterID: feed.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Len(entries, 2)
+
+	entries, _ = s.entriesRepo.ListFromFeed(other.ID, models.Page{
+		FilterID: scraper.ID,
+		Count:    5,
+		Marker:   models.MarkerAny,
+	})
+	s.Empty(entries)
+}
+
+func (s *SyncTestSuite) TestWebSubVerify() {
+	subscriber := sync.NewWebSubSubscriber("http://localhost", sql.NewWebSub(s.db))
+
+	_, err := subscriber.Verify("bogus", "subscribe", "topic", "challenge", 60)
+	s.Equal(services.ErrWebSubNotFound, err)
+
+	sub := models.WebSubSubscription{
+		ID:    utils.CreateID(),
+		Topic: s.ts.URL + "/rss_websub.xml?verify",
+		State: models.WebSubPending,
+	}
+	sql.NewWebSub(s.db).Create(&sub)
+
+	_, err = subscriber.Verify(sub.ID, "subscribe", "topic", "challenge", 60)
+	s.Equal(services.ErrWebSubNotFound, err)
+
+	_, err = subscriber.Verify(sub.ID, "unsubscribe", sub.Topic, "challenge", 60)
+	s.Equal(services.ErrWebSubNotFound, err)
+
+	challenge, err := subscriber.Verify(sub.ID, "subscribe", sub.Topic, "challenge", 60)
+	s.NoError(err)
+	s.Equal("challenge", challenge)
+
+	sub, _ = sql.NewWebSub(s.db).SubscriptionWithID(sub.ID)
+	s.Equal(models.WebSubActive, sub.State)
+	s.WithinDuration(time.Now().Add(time.Minute), sub.ExpiresAt, 5*time.Second)
+}
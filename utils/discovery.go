@@ -0,0 +1,190 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/jmartinezhern/syndication/models"
+)
+
+var (
+	// commonFeedPaths are probed when a page does not link to any feed
+	commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml", "/rss"}
+
+	// feedLinkTypes are the types of alternate links that point to feeds
+	feedLinkTypes = map[string]bool{
+		"application/rss+xml":   true,
+		"application/atom+xml":  true,
+		"application/rdf+xml":   true,
+		"application/feed+json": true,
+	}
+)
+
+// DiscoverFeeds returns the feeds available at pageURL. If pageURL is a feed, it is the
+// only candidate. Otherwise, the feeds the page links to are returned. If there are none,
+// common feed locations of the site are probed and the first feed found is returned.
+func DiscoverFeeds(pageURL string) ([]models.FeedCandidate, error) {
+	body, location, err := fetchDocument(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if feed, err := gofeed.NewParser().Parse(bytes.NewReader(body)); err == nil {
+		return []models.FeedCandidate{{Title: feed.Title, Subscription: location.String()}}, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	base := location
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = location.ResolveReference(ref)
+		}
+	}
+
+	candidates := linkedFeeds(doc, base)
+	if len(candidates) != 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		candidate, err := probeFeed(location.ResolveReference(&url.URL{Path: path}).String())
+		if err == nil {
+			return []models.FeedCandidate{candidate}, nil
+		}
+	}
+
+	return []models.FeedCandidate{}, nil
+}
+
+// linkedFeeds returns the feeds declared by alternate links in a page
+func linkedFeeds(doc *goquery.Document, base *url.URL) []models.FeedCandidate {
+	candidates := []models.FeedCandidate{}
+	seen := map[string]bool{}
+
+	doc.Find("link[href]").Each(func(_ int, link *goquery.Selection) {
+		if !hasRel(link.AttrOr("rel", ""), "alternate") {
+			return
+		}
+
+		linkType := strings.ToLower(strings.TrimSpace(link.AttrOr("type", "")))
+		if !feedLinkTypes[linkType] {
+			return
+		}
+
+		ref, err := url.Parse(strings.TrimSpace(link.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+
+		subscription := base.ResolveReference(ref).String()
+		if seen[subscription] {
+			return
+		}
+
+		seen[subscription] = true
+
+		candidate := models.FeedCandidate{
+			Title:        strings.TrimSpace(link.AttrOr("title", "")),
+			Subscription: subscription,
+		}
+
+		if candidate.Title == "" {
+			// Fall back to the title declared by the feed itself
+			if feed, err := probeFeed(subscription); err == nil {
+				candidate.Title = feed.Title
+			}
+		}
+
+		candidates = append(candidates, candidate)
+	})
+
+	return candidates
+}
+
+// probeFeed fetches feedURL and returns it as a candidate if it is a feed
+func probeFeed(feedURL string) (models.FeedCandidate, error) {
+	body, location, err := fetchDocument(feedURL)
+	if err != nil {
+		return models.FeedCandidate{}, err
+	}
+
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return models.FeedCandidate{}, err
+	}
+
+	return models.FeedCandidate{Title: feed.Title, Subscription: location.String()}, nil
+}
+
+// fetchDocument returns the body of a document and the URL it was fetched from
+// after following redirects
+func fetchDocument(documentURL string) ([]byte, *url.URL, error) {
+	client := NewHTTPClient()
+	client.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		return nil
+	}
+
+	resp, err := client.Get(documentURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if err = resp.Body.Close(); err != nil {
+			log.Warn(err)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, nil, fmt.Errorf("fetching %s failed with status %d", documentURL, resp.StatusCode)
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, resp.Request.URL, nil
+}
+
+func hasRel(rel, value string) bool {
+	for _, field := range strings.Fields(rel) {
+		if strings.EqualFold(field, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,406 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultConnectTimeout  = 10 * time.Second
+	defaultReadTimeout     = 30 * time.Second
+	defaultMaxResponseSize = 10 << 20
+	defaultUserAgent       = "Syndication (+https://github.com/jmartinezhern/syndication)"
+)
+
+var (
+	// ErrBlockedAddress signals that a host resolved to an address that may not be fetched
+	ErrBlockedAddress = errors.New("address is not allowed")
+
+	// ErrResponseTooLarge signals that a response body exceeds the maximum response size
+	ErrResponseTooLarge = errors.New("response is too large")
+
+	// ErrContentType signals that a response is not of an accepted content type
+	ErrContentType = errors.New("content type is not accepted")
+
+	// defaultContentTypes are the media types accepted when none are configured.
+	// Types ending in "/*" accept every subtype.
+	defaultContentTypes = []string{
+		"text/*",
+		"application/xml",
+		"application/rss+xml",
+		"application/atom+xml",
+		"application/rdf+xml",
+		"application/json",
+		"application/feed+json",
+		"application/xhtml+xml",
+	}
+
+	// blockedNetworks are private networks that may only be fetched if allowed explicitly.
+	// Loopback, link-local and unspecified addresses are blocked as well.
+	blockedNetworks = parseNetworks(
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"100.64.0.0/10",
+		"fc00::/7",
+	)
+
+	fetcherMu sync.RWMutex
+	fetcher   = newFetcher(FetcherConfig{}, nil)
+)
+
+type (
+	// FetcherConfig configures how feeds and web pages are fetched. Zero values
+	// fall back to the defaults.
+	FetcherConfig struct {
+		// ConnectTimeout bounds the time it takes to connect to a server
+		ConnectTimeout time.Duration
+
+		// ReadTimeout bounds the time it takes to receive a response once connected
+		ReadTimeout time.Duration
+
+		// MaxResponseSize is the largest response body, in bytes, that is read
+		MaxResponseSize int64
+
+		// UserAgent is sent with every request
+		UserAgent string
+
+		// ContentTypes are the accepted media types of responses. Types ending
+		// in "/*" accept every subtype. Responses without a content type are
+		// always accepted.
+		ContentTypes []string
+
+		// AllowedNetworks are addresses or CIDR ranges that may be fetched even
+		// though they are loopback, link-local or private
+		AllowedNetworks []string
+	}
+
+	// guardedFetcher holds the effective fetcher configuration and the transport
+	// shared by every client it creates
+	guardedFetcher struct {
+		config    FetcherConfig
+		allowed   []*net.IPNet
+		dialer    *net.Dialer
+		transport http.RoundTripper
+	}
+
+	userAgentTransport struct {
+		userAgent string
+		base      http.RoundTripper
+	}
+)
+
+// ConfigureFetcher replaces the configuration used to fetch feeds and web pages.
+// Clients created earlier keep the previous timeouts.
+func ConfigureFetcher(config FetcherConfig) error {
+	allowed := make([]*net.IPNet, 0, len(config.AllowedNetworks))
+
+	for _, network := range config.AllowedNetworks {
+		ipNet, err := parseNetwork(network)
+		if err != nil {
+			return err
+		}
+
+		allowed = append(allowed, ipNet)
+	}
+
+	fetcherMu.Lock()
+	defer fetcherMu.Unlock()
+
+	fetcher = newFetcher(config, allowed)
+
+	return nil
+}
+
+// NewHTTPClient returns a client that enforces the fetcher configuration. It
+// refuses to connect to blocked addresses and identifies itself with the
+// configured user agent.
+func NewHTTPClient() *http.Client {
+	f := currentFetcher()
+
+	return &http.Client{
+		Transport: f.transport,
+		Timeout:   f.config.ConnectTimeout + f.config.ReadTimeout,
+	}
+}
+
+// newProxiedHTTPClient returns a client like NewHTTPClient that connects through
+// a proxy. The address of the proxy is checked like any other address.
+func newProxiedHTTPClient(proxy string) (*http.Client, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	f := currentFetcher()
+
+	return &http.Client{
+		Transport: f.newTransport(http.ProxyURL(proxyURL)),
+		Timeout:   f.config.ConnectTimeout + f.config.ReadTimeout,
+	}, nil
+}
+
+func newFetcher(config FetcherConfig, allowed []*net.IPNet) *guardedFetcher {
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaultConnectTimeout
+	}
+
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = defaultReadTimeout
+	}
+
+	if config.MaxResponseSize <= 0 {
+		config.MaxResponseSize = defaultMaxResponseSize
+	}
+
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = defaultContentTypes
+	}
+
+	f := &guardedFetcher{
+		config:  config,
+		allowed: allowed,
+	}
+
+	f.dialer = &net.Dialer{
+		Timeout: config.ConnectTimeout,
+		// Addresses are checked once resolved so that host names cannot be
+		// used to reach blocked networks
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return f.checkAddress(address)
+		},
+	}
+
+	f.transport = f.newTransport(http.ProxyFromEnvironment)
+
+	return f
+}
+
+func (f *guardedFetcher) newTransport(proxy func(*http.Request) (*url.URL, error)) http.RoundTripper {
+	return userAgentTransport{
+		userAgent: f.config.UserAgent,
+		base: &http.Transport{
+			Proxy:                 f.guardProxy(proxy),
+			DialContext:           f.dialer.DialContext,
+			TLSHandshakeTimeout:   f.config.ConnectTimeout,
+			ResponseHeaderTimeout: f.config.ReadTimeout,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// guardProxy checks the target of requests that are sent through a proxy. The
+// dialer only sees the address of the proxy in that case, so the target host is
+// resolved and checked here before the request is handed over.
+func (f *guardedFetcher) guardProxy(
+	proxy func(*http.Request) (*url.URL, error),
+) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(req)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+
+		if err := f.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+
+		return proxyURL, nil
+	}
+}
+
+// checkHost resolves host and checks every address it resolves to.
+func (f *guardedFetcher) checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if err := f.checkAddress(net.JoinHostPort(addr.IP.String(), "0")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func currentFetcher() *guardedFetcher {
+	fetcherMu.RLock()
+	defer fetcherMu.RUnlock()
+
+	return fetcher
+}
+
+func (f *guardedFetcher) checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("%s: %w", host, ErrBlockedAddress)
+	}
+
+	for _, network := range f.allowed {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	if isBlockedIP(ip) {
+		return fmt.Errorf("%s: %w", ip, ErrBlockedAddress)
+	}
+
+	return nil
+}
+
+// readBody reads the body of a response if its content type is accepted and
+// its size does not exceed the maximum response size
+func readBody(resp *http.Response) ([]byte, error) {
+	f := currentFetcher()
+
+	if contentType := resp.Header.Get("Content-Type"); !f.acceptsContentType(contentType) {
+		return nil, fmt.Errorf("%s: %w", contentType, ErrContentType)
+	}
+
+	if resp.ContentLength > f.config.MaxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, f.config.MaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > f.config.MaxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	return body, nil
+}
+
+func (f *guardedFetcher) acceptsContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, accepted := range f.config.ContentTypes {
+		accepted = strings.ToLower(strings.TrimSpace(accepted))
+
+		if prefix := strings.TrimSuffix(accepted, "*"); prefix != accepted {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+		} else if mediaType == accepted {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t userAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Header.Get("User-Agent") == "" {
+		r = r.Clone(r.Context())
+		r.Header.Set("User-Agent", t.userAgent)
+	}
+
+	return t.base.RoundTrip(r)
+}
+
+func isBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseNetwork parses a CIDR range or a single address
+func parseNetwork(network string) (*net.IPNet, error) {
+	network = strings.TrimSpace(network)
+
+	if strings.Contains(network, "/") {
+		_, ipNet, err := net.ParseCIDR(network)
+		return ipNet, err
+	}
+
+	ip := net.ParseIP(network)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid network %q", network)
+	}
+
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func parseNetworks(networks ...string) []*net.IPNet {
+	ipNets := make([]*net.IPNet, len(networks))
+
+	for idx, network := range networks {
+		ipNet, err := parseNetwork(network)
+		if err != nil {
+			panic(err)
+		}
+
+		ipNets[idx] = ipNet
+	}
+
+	return ipNets
+}
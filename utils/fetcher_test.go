@@ -0,0 +1,252 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const fetcherTestFeed = `<rss version="2.0"><channel><title>Test</title>
+<item><guid>1@test</guid><title>First</title></item>
+<item><guid>2@test</guid><title>Second</title></item>
+</channel></rss>`
+
+type FetcherSuite struct {
+	suite.Suite
+
+	ts *httptest.Server
+}
+
+// loopbackFetcherConfig allows fetching from test servers
+var loopbackFetcherConfig = FetcherConfig{
+	AllowedNetworks: []string{"127.0.0.0/8", "::1"},
+}
+
+func (s *FetcherSuite) configureFetcher(config FetcherConfig) {
+	s.Require().NoError(ConfigureFetcher(config))
+	s.T().Cleanup(func() {
+		s.NoError(ConfigureFetcher(loopbackFetcherConfig))
+	})
+}
+
+func (s *FetcherSuite) TestIsBlockedIP() {
+	for _, tc := range []struct {
+		address string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.1.2.3", true},
+		{"10.0.0.1", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"100.64.0.1", true},
+		{"169.254.169.254", true},
+		{"224.0.0.251", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"::", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"ff02::1", true},
+		{"::ffff:10.0.0.1", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"203.0.113.10", false},
+		{"2001:4860:4860::8888", false},
+	} {
+		s.Equal(tc.blocked, isBlockedIP(net.ParseIP(tc.address)), tc.address)
+	}
+}
+
+func (s *FetcherSuite) TestCheckAddress() {
+	f := newFetcher(FetcherConfig{}, parseNetworks("10.1.0.0/16", "::1"))
+
+	s.NoError(f.checkAddress("203.0.113.10:80"))
+	s.NoError(f.checkAddress("10.1.2.3:80"))
+	s.NoError(f.checkAddress("[::1]:443"))
+	s.True(errors.Is(f.checkAddress("10.2.0.1:80"), ErrBlockedAddress))
+	s.True(errors.Is(f.checkAddress("127.0.0.1:80"), ErrBlockedAddress))
+	s.True(errors.Is(f.checkAddress("example.com:80"), ErrBlockedAddress))
+	s.Error(f.checkAddress("10.1.2.3"))
+}
+
+func (s *FetcherSuite) TestReadBody() {
+	config := loopbackFetcherConfig
+	config.MaxResponseSize = 8
+	s.configureFetcher(config)
+
+	for _, tc := range []struct {
+		body          string
+		contentLength int64
+		contentType   string
+		err           error
+	}{
+		{"12345678", 8, "", nil},
+		{"12345678", -1, "application/rss+xml; charset=utf-8", nil},
+		{"123456789", 9, "", ErrResponseTooLarge},
+		{"123456789", -1, "", ErrResponseTooLarge},
+		{"1234", 9, "", ErrResponseTooLarge},
+		{"1234", 4, "image/png", ErrContentType},
+		{"1234", 4, "text/", ErrContentType},
+	} {
+		resp := &http.Response{
+			Header:        http.Header{},
+			Body:          ioutil.NopCloser(strings.NewReader(tc.body)),
+			ContentLength: tc.contentLength,
+		}
+		if tc.contentType != "" {
+			resp.Header.Set("Content-Type", tc.contentType)
+		}
+
+		body, err := readBody(resp)
+		if tc.err != nil {
+			s.True(errors.Is(err, tc.err), tc)
+			continue
+		}
+
+		s.NoError(err, tc)
+		s.Equal(tc.body, string(body))
+	}
+}
+
+func (s *FetcherSuite) TestPullFeedBlocksPrivateAddresses() {
+	s.configureFetcher(FetcherConfig{})
+
+	for _, url := range []string{
+		s.ts.URL + "/rss.xml",
+		"http://10.0.0.1/rss.xml",
+		"http://192.168.1.1/rss.xml",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/rss.xml",
+		"http://[::1]/rss.xml",
+		"http://[fd00::1]/rss.xml",
+	} {
+		_, _, err := PullFeed(url, "", "")
+		s.True(errors.Is(err, ErrBlockedAddress), url)
+	}
+}
+
+func (s *FetcherSuite) TestPullFeedFromAllowedNetwork() {
+	s.configureFetcher(FetcherConfig{
+		AllowedNetworks: []string{"127.0.0.1"},
+	})
+
+	_, entries, err := PullFeed(s.ts.URL+"/rss.xml", "", "")
+	s.NoError(err)
+	s.Len(entries, 2)
+}
+
+func (s *FetcherSuite) TestConfigureFetcherWithInvalidNetwork() {
+	s.Error(ConfigureFetcher(FetcherConfig{
+		AllowedNetworks: []string{"localhost"},
+	}))
+}
+
+func (s *FetcherSuite) TestPullFeedTooLarge() {
+	config := loopbackFetcherConfig
+	config.MaxResponseSize = 64
+	s.configureFetcher(config)
+
+	_, _, err := PullFeed(s.ts.URL+"/rss.xml", "", "")
+	s.Equal(ErrResponseTooLarge, err)
+}
+
+func (s *FetcherSuite) TestPullFeedWithUnacceptedContentType() {
+	_, _, err := PullFeed(s.ts.URL+"/image.png", "", "")
+	s.True(errors.Is(err, ErrContentType))
+
+	config := loopbackFetcherConfig
+	config.ContentTypes = []string{"image/*"}
+	s.configureFetcher(config)
+
+	_, entries, err := PullFeed(s.ts.URL+"/image.png", "", "")
+	s.NoError(err)
+	s.Len(entries, 2)
+}
+
+func (s *FetcherSuite) TestPullFeedTimesOut() {
+	config := loopbackFetcherConfig
+	config.ReadTimeout = 50 * time.Millisecond
+	s.configureFetcher(config)
+
+	_, _, err := PullFeed(s.ts.URL+"/rss_slow.xml", "", "")
+	s.Error(err)
+}
+
+func (s *FetcherSuite) TestPullFeedSendsUserAgent() {
+	feed, _, err := PullFeed(s.ts.URL+"/rss_agent.xml", "", "")
+	s.Require().NoError(err)
+	s.Equal(defaultUserAgent, feed.Title)
+
+	config := loopbackFetcherConfig
+	config.UserAgent = "Test Agent"
+	s.configureFetcher(config)
+
+	feed, _, err = PullFeed(s.ts.URL+"/rss_agent.xml", "", "")
+	s.Require().NoError(err)
+	s.Equal("Test Agent", feed.Title)
+}
+
+func (s *FetcherSuite) SetupSuite() {
+	s.ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rss_agent.xml":
+			// The title of the feed is the user agent that requested it
+			if _, err := fmt.Fprintf(w, `<rss version="2.0"><channel><title>%s</title></channel></rss>`,
+				html.EscapeString(r.UserAgent())); err != nil {
+				panic(err)
+			}
+
+			return
+		case "/rss_slow.xml":
+			time.Sleep(500 * time.Millisecond)
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+		}
+
+		if _, err := fmt.Fprint(w, fetcherTestFeed); err != nil {
+			panic(err)
+		}
+	}))
+
+	// Test servers listen on loopback addresses
+	s.Require().NoError(ConfigureFetcher(loopbackFetcherConfig))
+}
+
+func (s *FetcherSuite) TearDownSuite() {
+	s.ts.Close()
+
+	s.NoError(ConfigureFetcher(FetcherConfig{}))
+}
+
+func TestFetcherSuite(t *testing.T) {
+	suite.Run(t, new(FetcherSuite))
+}
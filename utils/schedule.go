@@ -0,0 +1,147 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+)
+
+// maxPostingSamples is the number of recent items used to estimate how often a feed posts
+const maxPostingSamples = 10
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseMaxAge returns the max-age directive of a Cache-Control header
+func parseMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`))
+		if err != nil || seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	return 0
+}
+
+// parseRetryAfter returns the delay requested by a Retry-After header, which
+// holds either a number of seconds or a date
+func parseRetryAfter(retryAfter string, now time.Time) time.Duration {
+	retryAfter = strings.TrimSpace(retryAfter)
+	if retryAfter == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(retryAfter)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+
+	return date.Sub(now)
+}
+
+// rssSchedule returns the ttl, in minutes, of an RSS document along with the
+// hours and days it asks to be skipped as bit masks
+func rssSchedule(body []byte) (ttl, skipHours, skipDays int) {
+	feed, err := (&rss.Parser{}).Parse(bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(feed.TTL)); err == nil && minutes > 0 {
+		ttl = minutes
+	}
+
+	for _, hour := range feed.SkipHours {
+		// Hours range from 0 to 23, but some feeds use 24 for midnight
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h >= 0 && h <= 24 {
+			skipHours |= 1 << (h % 24)
+		}
+	}
+
+	for _, day := range feed.SkipDays {
+		if d, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]; ok {
+			skipDays |= 1 << d
+		}
+	}
+
+	return ttl, skipHours, skipDays
+}
+
+// postingInterval returns the average time between the most recent items of a feed
+// or zero if it cannot be estimated
+func postingInterval(items []*gofeed.Item) time.Duration {
+	var dates []time.Time
+
+	for _, item := range items {
+		if item.PublishedParsed != nil {
+			dates = append(dates, *item.PublishedParsed)
+		} else if item.UpdatedParsed != nil {
+			dates = append(dates, *item.UpdatedParsed)
+		}
+	}
+
+	if len(dates) < 2 {
+		return 0
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].After(dates[j])
+	})
+
+	if len(dates) > maxPostingSamples {
+		dates = dates[:maxPostingSamples]
+	}
+
+	interval := dates[0].Sub(dates[len(dates)-1]) / time.Duration(len(dates)-1)
+	if interval < 0 {
+		return 0
+	}
+
+	return interval
+}
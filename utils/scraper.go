@@ -0,0 +1,239 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+
+	"github.com/jmartinezhern/syndication/models"
+)
+
+// maxScrapedTitleLength limits titles taken from the text of an item
+const maxScrapedTitleLength = 120
+
+var (
+	// ErrItemSelector signals that a scraper feed has no item selector
+	ErrItemSelector = errors.New("item selector is required")
+
+	// dateLayouts are the date formats commonly found on web pages
+	dateLayouts = []string{
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC822Z,
+		time.RFC822,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02",
+		"January 2, 2006",
+		"Jan 2, 2006",
+		"January 2 2006",
+		"Jan 2 2006",
+		"2 January 2006",
+		"2 Jan 2006",
+		"02 Jan 2006",
+		"Monday, January 2, 2006",
+		"Mon, January 2, 2006",
+		"Mon, Jan 2, 2006",
+	}
+)
+
+// ValidateSelectors checks that selectors hold an item selector and that every
+// selector is a valid CSS selector
+func ValidateSelectors(selectors models.ScraperSelectors) error {
+	if strings.TrimSpace(selectors.Item) == "" {
+		return ErrItemSelector
+	}
+
+	for _, selector := range []string{selectors.Item, selectors.Title, selectors.Link, selectors.Date, selectors.Content} {
+		if selector == "" {
+			continue
+		}
+
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+	}
+
+	return nil
+}
+
+// ScrapePage fetches a web page and extracts entries from it with selectors.
+// Entries are the elements matched by the item selector. Their title, link,
+// date and content are taken from the first element matched by the other
+// selectors within each item. The page is fetched like PullFeedWithCredentials
+// fetches feeds.
+func ScrapePage(pageURL, etag, lastModified string, selectors models.ScraperSelectors,
+	credentials *models.FeedCredentials) (models.Feed, []models.Entry, error) {
+	if err := ValidateSelectors(selectors); err != nil {
+		return models.Feed{}, nil, err
+	}
+
+	result, err := fetchBody(pageURL, etag, lastModified, credentials)
+	if err == ErrNotModified {
+		return notModifiedFeed(&result), nil, err
+	} else if err != nil {
+		return models.Feed{HTTPStatus: result.statusCode}, nil, err
+	}
+
+	feed, entries, err := scrapeDocument(result.body, result.url, selectors)
+	if err != nil {
+		return models.Feed{HTTPStatus: result.statusCode}, nil, err
+	}
+
+	setFetchResult(&feed, &result)
+
+	return feed, entries, nil
+}
+
+func scrapeDocument(body []byte, pageURL *url.URL, selectors models.ScraperSelectors) (
+	models.Feed, []models.Entry, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return models.Feed{}, nil, err
+	}
+
+	feed := models.Feed{
+		Title:       collapseSpaces(doc.Find("title").First().Text()),
+		Description: strings.TrimSpace(doc.Find(`meta[name="description"]`).AttrOr("content", "")),
+		Source:      pageURL.String(),
+	}
+
+	base := pageURL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			feed.XMLBase = href
+			base = pageURL.ResolveReference(ref)
+		}
+	}
+
+	entries := []models.Entry{}
+
+	doc.Find(selectors.Item).Each(func(_ int, item *goquery.Selection) {
+		entry := scrapeItem(item, base, selectors)
+		if entry.Title != "" || entry.Link != "" || entry.Content != "" {
+			entries = append(entries, entry)
+		}
+	})
+
+	return feed, entries, nil
+}
+
+func scrapeItem(item *goquery.Selection, base *url.URL, selectors models.ScraperSelectors) models.Entry {
+	entry := models.Entry{
+		Mark: models.MarkerUnread,
+	}
+
+	if selectors.Title != "" {
+		entry.Title = collapseSpaces(item.Find(selectors.Title).First().Text())
+	} else {
+		entry.Title = truncate(collapseSpaces(item.Text()), maxScrapedTitleLength)
+	}
+
+	entry.Link = scrapeLink(item, base, selectors.Link)
+
+	if selectors.Content != "" {
+		entry.Content, _ = item.Find(selectors.Content).First().Html()
+	} else {
+		entry.Content, _ = item.Html()
+	}
+
+	entry.Content = strings.TrimSpace(entry.Content)
+
+	if selectors.Date != "" {
+		date := item.Find(selectors.Date).First()
+		entry.Published = parseDate(date.AttrOr("datetime", date.Text()))
+	}
+
+	if entry.Published.IsZero() {
+		entry.Published = time.Now()
+	}
+
+	entry.Updated = entry.Published
+
+	// Pages carry no identifiers, the link or, lacking one, the content of
+	// an item identifies it
+	if entry.Link != "" {
+		entry.GUID = entry.Link
+	} else {
+		entry.GUID = fmt.Sprintf("%x", sha256.Sum256([]byte(entry.Title+entry.Content)))
+	}
+
+	return entry
+}
+
+// scrapeLink returns the absolute URL an item links to. The link is the href of
+// the element matched by selector, or of the first link within it.
+func scrapeLink(item *goquery.Selection, base *url.URL, selector string) string {
+	link := item
+	if selector != "" {
+		link = item.Find(selector).First()
+	}
+
+	href, ok := link.Attr("href")
+	if !ok {
+		href, ok = link.Find("a[href]").First().Attr("href")
+	}
+
+	if !ok {
+		return ""
+	}
+
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+
+	return base.ResolveReference(ref).String()
+}
+
+func parseDate(value string) time.Time {
+	value = collapseSpaces(value)
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+
+	return time.Time{}
+}
+
+func collapseSpaces(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	return strings.TrimSpace(string(runes[:length])) + "…"
+}
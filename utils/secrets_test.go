@@ -0,0 +1,92 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/jmartinezhern/syndication/utils"
+)
+
+type SecretsSuite struct {
+	suite.Suite
+
+	key []byte
+}
+
+func (s *SecretsSuite) TestEncryptDecrypt() {
+	ciphertext, err := utils.Encrypt(s.key, []byte("hunter2"))
+	s.Require().NoError(err)
+	s.False(bytes.Contains(ciphertext, []byte("hunter2")))
+
+	plaintext, err := utils.Decrypt(s.key, ciphertext)
+	s.Require().NoError(err)
+	s.Equal("hunter2", string(plaintext))
+
+	// Every ciphertext gets its own nonce
+	other, err := utils.Encrypt(s.key, []byte("hunter2"))
+	s.Require().NoError(err)
+	s.NotEqual(ciphertext, other)
+}
+
+func (s *SecretsSuite) TestDecryptWithWrongKey() {
+	ciphertext, err := utils.Encrypt(s.key, []byte("hunter2"))
+	s.Require().NoError(err)
+
+	_, err = utils.Decrypt(utils.DeriveKey("other secret", "credentials"), ciphertext)
+	s.Equal(utils.ErrDecrypt, err)
+}
+
+func (s *SecretsSuite) TestDecryptTamperedCiphertext() {
+	ciphertext, err := utils.Encrypt(s.key, []byte("hunter2"))
+	s.Require().NoError(err)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+
+	_, err = utils.Decrypt(s.key, ciphertext)
+	s.Equal(utils.ErrDecrypt, err)
+
+	_, err = utils.Decrypt(s.key, ciphertext[:4])
+	s.Equal(utils.ErrDecrypt, err)
+}
+
+func (s *SecretsSuite) TestEncryptWithInvalidKey() {
+	_, err := utils.Encrypt([]byte("short"), []byte("hunter2"))
+	s.Error(err)
+
+	_, err = utils.Decrypt([]byte("short"), []byte("ciphertext"))
+	s.Error(err)
+}
+
+func (s *SecretsSuite) TestDeriveKey() {
+	s.Len(s.key, 32)
+	s.Equal(s.key, utils.DeriveKey("secret", "credentials"))
+	s.NotEqual(s.key, utils.DeriveKey("secret", "other purpose"))
+	s.NotEqual(s.key, utils.DeriveKey("other secret", "credentials"))
+}
+
+func (s *SecretsSuite) SetupTest() {
+	s.key = utils.DeriveKey("secret", "credentials")
+}
+
+func TestSecretsSuite(t *testing.T) {
+	suite.Run(t, new(SecretsSuite))
+}
@@ -19,11 +19,17 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/rand"
+	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -48,26 +54,135 @@ const (
 	keyPValue                    = 1
 )
 
+// maxRedirects is the number of redirects followed when fetching a feed
+const maxRedirects = 5
+
+// xmlNamespace is the namespace bound to the xml prefix
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
 var (
-// ErrParsingFeed Signals that an error occurred while processing
-// a RSS or Atom feed
+	// ErrNotModified signals that a feed has not changed since it was last fetched
+	ErrNotModified = errors.New("feed not modified")
 )
 
-func fetchFeed(url, etag string) (gofeed.Feed, error) {
-	client := &http.Client{
-		CheckRedirect: func(r *http.Request, v []*http.Request) error { return http.ErrUseLastResponse },
+// HTTPError signals that a feed could not be fetched because its server
+// responded with an unexpected status
+type HTTPError struct {
+	StatusCode int
+
+	// RetryAfter is how long the server asked clients to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("server responded with status %d", e.StatusCode)
+}
+
+// fetchResult holds a fetched feed document along with its cache validators
+type fetchResult struct {
+	// url is the URL the document was fetched from after following redirects
+	url *url.URL
+
+	feed         gofeed.Feed
+	body         []byte
+	etag         string
+	lastModified string
+	statusCode   int
+
+	// links are the Link headers of the response
+	links []string
+
+	// location is the URL the feed permanently moved to, if any
+	location string
+
+	// maxAge is the freshness lifetime declared by the response
+	maxAge time.Duration
+}
+
+func fetchFeed(url, etag, lastModified string, credentials *models.FeedCredentials) (fetchResult, error) {
+	result, err := fetchBody(url, etag, lastModified, credentials)
+	if err != nil {
+		return result, err
+	}
+
+	fetchedFeed, err := gofeed.NewParser().Parse(bytes.NewReader(result.body))
+	if err != nil {
+		return fetchResult{statusCode: result.statusCode}, err
+	}
+
+	result.feed = *fetchedFeed
+
+	return result, nil
+}
+
+// fetchBody fetches a document along with its cache validators. The validators
+// of a previous fetch are sent along and ErrNotModified is returned if the
+// document has not changed since.
+func fetchBody(url, etag, lastModified string, credentials *models.FeedCredentials) (fetchResult, error) {
+	var location string
+
+	permanent := true
+
+	client := NewHTTPClient()
+
+	if credentials != nil && credentials.Proxy != "" {
+		proxiedClient, err := newProxiedHTTPClient(credentials.Proxy)
+		if err != nil {
+			return fetchResult{}, err
+		}
+
+		client = proxiedClient
+	}
+
+	client.CheckRedirect = func(r *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		// Credentials are only meant for the host of the subscription. Go drops
+		// the standard authentication headers on its own, but not custom ones.
+		if credentials != nil && r.URL.Host != via[0].URL.Host {
+			for name := range credentials.Headers {
+				r.Header.Del(name)
+			}
+
+			r.Header.Del("Authorization")
+			r.Header.Del("Cookie")
+		}
+
+		// A feed only moves if every redirect that led to it is permanent
+		switch r.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if permanent {
+				location = r.URL.String()
+			}
+		default:
+			permanent = false
+		}
+
+		return nil
 	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return gofeed.Feed{}, err
+		return fetchResult{}, err
+	}
+
+	if credentials != nil {
+		setCredentials(req, credentials)
+	}
+
+	if etag != "" {
+		req.Header.Add("If-None-Match", etag)
 	}
 
-	req.Header.Add("If-None-Match", etag)
+	if lastModified != "" {
+		req.Header.Add("If-Modified-Since", lastModified)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return gofeed.Feed{}, err
+		return fetchResult{}, err
 	}
 
 	defer func() {
@@ -76,19 +191,88 @@ func fetchFeed(url, etag string) (gofeed.Feed, error) {
 		}
 	}()
 
-	fetchedFeed, err := gofeed.NewParser().Parse(resp.Body)
+	result := fetchResult{
+		url:          resp.Request.URL,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		statusCode:   resp.StatusCode,
+		links:        resp.Header.Values("Link"),
+		location:     location,
+		maxAge:       parseMaxAge(resp.Header.Get("Cache-Control")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return result, ErrNotModified
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		return fetchResult{statusCode: resp.StatusCode}, HTTPError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	result.body, err = readBody(resp)
 	if err != nil {
-		return gofeed.Feed{}, err
+		return fetchResult{statusCode: resp.StatusCode}, err
+	}
+
+	return result, nil
+}
+
+// setCredentials adds the headers of credentials to a request
+func setCredentials(req *http.Request, credentials *models.FeedCredentials) {
+	for name, value := range credentials.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if credentials.Cookie != "" {
+		req.Header.Set("Cookie", credentials.Cookie)
+	}
+
+	switch {
+	case credentials.Token != "":
+		req.Header.Set("Authorization", "Bearer "+credentials.Token)
+	case credentials.Username != "" || credentials.Password != "":
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+}
+
+// documentBase returns the xml:base declared by the root element of a feed
+// document or by the channel element of an RSS document.
+func documentBase(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	for depth := 0; depth < 2; {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		for _, attr := range start.Attr {
+			if attr.Name.Space == xmlNamespace && attr.Name.Local == "base" {
+				return attr.Value
+			}
+		}
+
+		depth++
 	}
 
-	return *fetchedFeed, nil
+	return ""
 }
 
 func convertItemToEntry(item *gofeed.Item) models.Entry {
 	entry := models.Entry{
-		Title: item.Title,
-		Link:  item.Link,
-		Mark:  models.MarkerUnread,
+		Title:   item.Title,
+		Link:    item.Link,
+		Summary: item.Description,
+		Content: item.Content,
+		Mark:    models.MarkerUnread,
 	}
 
 	if item.Author != nil {
@@ -101,6 +285,12 @@ func convertItemToEntry(item *gofeed.Item) models.Entry {
 		entry.Published = time.Now()
 	}
 
+	if item.UpdatedParsed != nil {
+		entry.Updated = *item.UpdatedParsed
+	} else {
+		entry.Updated = entry.Published
+	}
+
 	if item.GUID != "" {
 		entry.GUID = item.GUID
 	} else {
@@ -111,28 +301,135 @@ func convertItemToEntry(item *gofeed.Item) models.Entry {
 	return entry
 }
 
-// PullFeed and return all entries for that feed. If getting the
-// subscription source or parsing the response fails, this function
-// will error.
-func PullFeed(url, etag string) (models.Feed, []models.Entry, error) {
-	fetchedFeed, err := fetchFeed(url, etag)
+// PullFeed and return all entries for that feed. The etag and lastModified
+// validators of a previous fetch are sent along with the request. If the feed
+// has not changed since, ErrNotModified is returned with a feed holding only
+// the validators, update time, status and subscription. The subscription of the
+// returned feed is only set if the feed permanently moved. If getting the
+// subscription source or parsing the response fails, this function will error
+// and the returned feed only holds the status of the response, if any.
+func PullFeed(url, etag, lastModified string) (models.Feed, []models.Entry, error) {
+	return PullFeedWithCredentials(url, etag, lastModified, nil)
+}
+
+// PullFeedWithCredentials is like PullFeed but sends credentials, if any, along
+// with the request and connects through their proxy.
+func PullFeedWithCredentials(url, etag, lastModified string, credentials *models.FeedCredentials) (
+	models.Feed, []models.Entry, error) {
+	result, err := fetchFeed(url, etag, lastModified, credentials)
+	if err == ErrNotModified {
+		return notModifiedFeed(&result), nil, err
+	} else if err != nil {
+		return models.Feed{HTTPStatus: result.statusCode}, nil, err
+	}
+
+	feed, entries := convertFeed(&result.feed, result.body)
+	setFetchResult(&feed, &result)
+
+	// Hubs advertised in HTTP headers take precedence over the ones in the document
+	if hub, topic := linkHeaderHub(result.links); hub != "" {
+		feed.Hub, feed.Topic = hub, topic
+	}
+
+	return feed, entries, nil
+}
+
+// notModifiedFeed returns a feed holding the validators, update time, status
+// and subscription of a fetch that found a document unchanged
+func notModifiedFeed(result *fetchResult) models.Feed {
+	feed := models.Feed{}
+	setFetchResult(&feed, result)
+
+	return feed
+}
+
+// setFetchResult sets the validators, update time, status and subscription
+// of the fetch a feed was parsed from
+func setFetchResult(feed *models.Feed, result *fetchResult) {
+	feed.Subscription = result.location
+	feed.Etag = result.etag
+	feed.LastModified = result.lastModified
+	feed.LastUpdated = time.Now()
+	feed.HTTPStatus = result.statusCode
+	feed.MaxAge = result.maxAge
+}
+
+// ParseFeed parses a feed document and returns all entries for that feed
+func ParseFeed(body []byte) (models.Feed, []models.Entry, error) {
+	parsedFeed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
 	if err != nil {
 		return models.Feed{}, nil, err
 	}
 
+	feed, entries := convertFeed(parsedFeed, body)
+	feed.LastUpdated = time.Now()
+
+	return feed, entries, nil
+}
+
+func convertFeed(parsedFeed *gofeed.Feed, body []byte) (models.Feed, []models.Entry) {
 	feed := models.Feed{
-		Title:       fetchedFeed.Title,
-		Description: fetchedFeed.Description,
-		Source:      fetchedFeed.Link,
-		LastUpdated: time.Now(),
+		Title:       parsedFeed.Title,
+		Description: parsedFeed.Description,
+		Source:      parsedFeed.Link,
+		XMLBase:     documentBase(body),
 	}
 
-	entries := make([]models.Entry, len(fetchedFeed.Items))
-	for idx, item := range fetchedFeed.Items {
+	if parsedFeed.Image != nil {
+		feed.Icon = parsedFeed.Image.URL
+	}
+
+	if parsedFeed.FeedType == "rss" {
+		feed.TTL, feed.SkipHours, feed.SkipDays = rssSchedule(body)
+	}
+
+	feed.Hub, feed.Topic = documentHub(body)
+
+	entries := make([]models.Entry, len(parsedFeed.Items))
+	for idx, item := range parsedFeed.Items {
 		entries[idx] = convertItemToEntry(item)
 	}
 
-	return feed, entries, nil
+	feed.PostingInterval = postingInterval(parsedFeed.Items)
+
+	return feed, entries
+}
+
+// NormalizeURL returns the canonical form of a feed URL so that subscriptions
+// to the same feed can be recognized. The scheme and host are lower cased,
+// default ports and fragments are dropped and an empty path becomes "/".
+// URLs that cannot be parsed are returned as is.
+func NormalizeURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	u.Fragment = ""
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String()
 }
 
 // CreatePasswordHashAndSalt for a given password
@@ -0,0 +1,125 @@
+/*
+ *   Copyright (C) 2021. Jorge Martinez Hernandez
+ *
+ *   This program is free software: you can redistribute it and/or modify
+ *   it under the terms of the GNU Affero General Public License as published by
+ *   the Free Software Foundation, either version 3 of the License, or
+ *   (at your option) any later version.
+ *
+ *   This program is distributed in the hope that it will be useful,
+ *   but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *   GNU Affero General Public License for more details.
+ *
+ *   You should have received a copy of the GNU Affero General Public License
+ *   along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// documentHub returns the WebSub hub and topic advertised by the link elements
+// of a feed document. Links inside items and entries are ignored.
+func documentHub(body []byte) (hub, topic string) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(start.Name.Local) {
+		case "item", "entry":
+			return
+		case "link":
+			var rel, href string
+
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+
+			if href == "" {
+				continue
+			}
+
+			for _, value := range strings.Fields(rel) {
+				switch strings.ToLower(value) {
+				case "hub":
+					if hub == "" {
+						hub = href
+					}
+				case "self":
+					if topic == "" {
+						topic = href
+					}
+				}
+			}
+		}
+	}
+}
+
+// linkHeaderHub returns the WebSub hub and topic advertised by Link headers
+func linkHeaderHub(headers []string) (hub, topic string) {
+	for _, header := range headers {
+		for _, link := range strings.Split(header, ",") {
+			params := strings.Split(link, ";")
+
+			target := strings.TrimSpace(params[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			target = strings.TrimSpace(target[1 : len(target)-1])
+
+			for _, param := range params[1:] {
+				name, value := splitParam(param)
+				if name != "rel" {
+					continue
+				}
+
+				for _, rel := range strings.Fields(value) {
+					switch strings.ToLower(rel) {
+					case "hub":
+						if hub == "" {
+							hub = target
+						}
+					case "self":
+						if topic == "" {
+							topic = target
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return hub, topic
+}
+
+func splitParam(param string) (name, value string) {
+	parts := strings.SplitN(param, "=", 2)
+
+	name = strings.ToLower(strings.TrimSpace(parts[0]))
+	if len(parts) == 2 {
+		value = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+	}
+
+	return name, value
+}